@@ -0,0 +1,454 @@
+package tender
+
+import (
+	"fmt"
+	"reflect"
+	"runtime"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	objectType = reflect.TypeOf((*Object)(nil)).Elem()
+	errorType  = reflect.TypeOf((*error)(nil)).Elem()
+	timeType   = reflect.TypeOf(time.Time{})
+)
+
+// Bind converts a Go value to an Object using reflection, so that Go APIs
+// can be used by scripts without hand-written wrappers:
+//
+//   - booleans, numbers, strings, []byte and time.Time become the matching
+//     tender values,
+//   - slices and arrays become arrays and maps with string keys become maps;
+//     their elements are bound recursively and the result is a copy,
+//   - functions become user functions that convert their arguments from and
+//     their results to tender values. If the last result is an error, a
+//     non-nil error is returned to the script as an error value. Several
+//     remaining results are returned as an array,
+//   - structs and pointers to structs become a MethodObject whose exported
+//     fields and methods are available as selectors, and whose fields can be
+//     assigned. A struct value is copied. The method table of the object is
+//     created once for each struct type.
+//
+// Struct fields and methods can be renamed with a `tender:"name"` tag on the
+// field, or hidden with `tender:"-"`. Objects are returned unchanged.
+func Bind(value interface{}) (Object, error) {
+	if value == nil {
+		return NullValue, nil
+	}
+	if o, ok := value.(Object); ok {
+		return o, nil
+	}
+	return bindValue(reflect.ValueOf(value))
+}
+
+func bindValue(v reflect.Value) (Object, error) {
+	if !v.IsValid() {
+		return NullValue, nil
+	}
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Interface, reflect.Func, reflect.Map,
+		reflect.Slice:
+		if v.IsNil() {
+			if v.Kind() == reflect.Slice {
+				return &Array{}, nil
+			}
+			return NullValue, nil
+		}
+	}
+	t := v.Type()
+	if t.Implements(objectType) && v.CanInterface() {
+		return v.Interface().(Object), nil
+	}
+	if t == timeType {
+		return &Time{Value: v.Interface().(time.Time)}, nil
+	}
+	if t.Implements(errorType) && v.CanInterface() {
+		return &Error{Value: &String{Value: v.Interface().(error).Error()}}, nil
+	}
+
+	switch v.Kind() {
+	case reflect.Bool:
+		return FromBool(v.Bool()), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32,
+		reflect.Int64:
+		return &Int{Value: v.Int()}, nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32,
+		reflect.Uint64, reflect.Uintptr:
+		return &Int{Value: int64(v.Uint())}, nil
+	case reflect.Float32, reflect.Float64:
+		return &Float{Value: v.Float()}, nil
+	case reflect.Complex64, reflect.Complex128:
+		return &Complex{Value: v.Complex()}, nil
+	case reflect.String:
+		if v.Len() > MaxStringLen {
+			return nil, ErrStringLimit
+		}
+		return &String{Value: v.String()}, nil
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			if v.Len() > MaxBytesLen {
+				return nil, ErrBytesLimit
+			}
+			b := make([]byte, v.Len())
+			reflect.Copy(reflect.ValueOf(b), v)
+			return &Bytes{Value: b}, nil
+		}
+		arr := make([]Object, v.Len())
+		for i := range arr {
+			o, err := bindValue(v.Index(i))
+			if err != nil {
+				return nil, err
+			}
+			arr[i] = o
+		}
+		return &Array{Value: arr}, nil
+	case reflect.Map:
+		if t.Key().Kind() != reflect.String {
+			return nil, fmt.Errorf("cannot bind map with %s keys", t.Key())
+		}
+		m := make(map[string]Object, v.Len())
+		iter := v.MapRange()
+		for iter.Next() {
+			o, err := bindValue(iter.Value())
+			if err != nil {
+				return nil, err
+			}
+			m[iter.Key().String()] = o
+		}
+		return &Map{Value: m}, nil
+	case reflect.Func:
+		return bindFunc(funcName(v), v), nil
+	case reflect.Interface:
+		return bindValue(v.Elem())
+	case reflect.Ptr:
+		if v.Elem().Kind() == reflect.Struct {
+			return bindStruct(v), nil
+		}
+		return bindValue(v.Elem())
+	case reflect.Struct:
+		p := reflect.New(t)
+		p.Elem().Set(v)
+		return bindStruct(p), nil
+	}
+	return nil, fmt.Errorf("cannot bind value of type %s", t)
+}
+
+// funcName returns the name of a function without its package path.
+func funcName(fn reflect.Value) string {
+	f := runtime.FuncForPC(fn.Pointer())
+	if f == nil {
+		return ""
+	}
+	name := f.Name()
+	if i := strings.LastIndex(name, "."); i >= 0 {
+		name = name[i+1:]
+	}
+	return name
+}
+
+var argumentNames = [...]string{
+	"first", "second", "third", "fourth", "fifth",
+	"sixth", "seventh", "eighth", "ninth", "tenth",
+}
+
+func argumentName(i int) string {
+	if i < len(argumentNames) {
+		return argumentNames[i]
+	}
+	return fmt.Sprintf("#%d", i+1)
+}
+
+// bindFunc returns a user function calling fn.
+func bindFunc(name string, fn reflect.Value) *UserFunction {
+	t := fn.Type()
+	return &UserFunction{
+		Name: name,
+		Value: func(args ...Object) (Object, error) {
+			in, err := bindArgs(t, 0, args)
+			if err != nil {
+				return nil, err
+			}
+			return bindResults(fn.Call(in))
+		},
+	}
+}
+
+// bindArgs converts the arguments of a call to a function of type t. The
+// first skip inputs of the function, such as the receiver of a method, are
+// left for the caller to set.
+func bindArgs(t reflect.Type, skip int, args []Object) ([]reflect.Value, error) {
+	numIn := t.NumIn() - skip
+	variadic := t.IsVariadic()
+	if variadic && len(args) < numIn-1 || !variadic && len(args) != numIn {
+		return nil, ErrWrongNumArguments
+	}
+	in := make([]reflect.Value, skip+len(args))
+	for i, arg := range args {
+		var at reflect.Type
+		if variadic && i >= numIn-1 {
+			at = t.In(t.NumIn() - 1).Elem()
+		} else {
+			at = t.In(skip + i)
+		}
+		v, ok := goValue(arg, at)
+		if !ok {
+			return nil, ErrInvalidArgumentType{
+				Name:     argumentName(i),
+				Expected: at.String(),
+				Found:    arg.TypeName(),
+			}
+		}
+		in[skip+i] = v
+	}
+	return in, nil
+}
+
+// bindResults converts the results of a function call.
+func bindResults(out []reflect.Value) (Object, error) {
+	if n := len(out); n > 0 && out[n-1].Type() == errorType {
+		if !out[n-1].IsNil() {
+			return wrapError(out[n-1].Interface().(error)), nil
+		}
+		out = out[:n-1]
+	}
+	switch len(out) {
+	case 0:
+		return NullValue, nil
+	case 1:
+		return bindValue(out[0])
+	}
+	arr := make([]Object, len(out))
+	for i, v := range out {
+		o, err := bindValue(v)
+		if err != nil {
+			return nil, err
+		}
+		arr[i] = o
+	}
+	return &Array{Value: arr}, nil
+}
+
+// goValue converts o to a Go value of type t.
+func goValue(o Object, t reflect.Type) (reflect.Value, bool) {
+	if m, ok := o.(*MethodObject); ok && m.Value != nil {
+		mv := reflect.ValueOf(m.Value)
+		switch {
+		case mv.Type().AssignableTo(t):
+			return mv, true
+		case mv.Kind() == reflect.Ptr && !mv.IsNil() &&
+			mv.Elem().Type().AssignableTo(t):
+			return mv.Elem(), true
+		}
+	}
+	if t.Kind() == reflect.Interface && t.NumMethod() == 0 {
+		if iv := ToInterface(o); iv != nil {
+			return reflect.ValueOf(iv), true
+		}
+		return reflect.Zero(t), true
+	}
+	if reflect.TypeOf(o).AssignableTo(t) {
+		return reflect.ValueOf(o), true
+	}
+	if t == timeType {
+		tv, ok := ToTime(o)
+		return reflect.ValueOf(tv), ok
+	}
+
+	v := reflect.New(t).Elem()
+	switch t.Kind() {
+	case reflect.Bool:
+		b, ok := o.(*Bool)
+		if !ok {
+			return v, false
+		}
+		v.SetBool(!b.IsFalsy())
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32,
+		reflect.Int64:
+		i, ok := ToInt64(o)
+		if !ok || v.OverflowInt(i) {
+			return v, false
+		}
+		v.SetInt(i)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32,
+		reflect.Uint64, reflect.Uintptr:
+		i, ok := ToInt64(o)
+		if !ok || i < 0 || v.OverflowUint(uint64(i)) {
+			return v, false
+		}
+		v.SetUint(uint64(i))
+	case reflect.Float32, reflect.Float64:
+		f, ok := ToFloat64(o)
+		if !ok {
+			return v, false
+		}
+		v.SetFloat(f)
+	case reflect.Complex64, reflect.Complex128:
+		c, ok := o.(*Complex)
+		if !ok {
+			return v, false
+		}
+		v.SetComplex(c.Value)
+	case reflect.String:
+		s, ok := ToString(o)
+		if !ok {
+			return v, false
+		}
+		v.SetString(s)
+	case reflect.Slice:
+		if t.Elem().Kind() == reflect.Uint8 {
+			if b, ok := ToByteSlice(o); ok {
+				v.SetBytes(b)
+				return v, true
+			}
+		}
+		var elems []Object
+		switch o := o.(type) {
+		case *Array:
+			elems = o.Value
+		case *ImmutableArray:
+			elems = o.Value
+		case *Null:
+			return v, true
+		default:
+			return v, false
+		}
+		v.Set(reflect.MakeSlice(t, len(elems), len(elems)))
+		for i, e := range elems {
+			ev, ok := goValue(e, t.Elem())
+			if !ok {
+				return v, false
+			}
+			v.Index(i).Set(ev)
+		}
+	case reflect.Map:
+		if t.Key().Kind() != reflect.String {
+			return v, false
+		}
+		var elems map[string]Object
+		switch o := o.(type) {
+		case *Map:
+			elems = o.Value
+		case *ImmutableMap:
+			elems = o.Value
+		case *Null:
+			return v, true
+		default:
+			return v, false
+		}
+		v.Set(reflect.MakeMapWithSize(t, len(elems)))
+		for key, e := range elems {
+			ev, ok := goValue(e, t.Elem())
+			if !ok {
+				return v, false
+			}
+			v.SetMapIndex(reflect.ValueOf(key).Convert(t.Key()), ev)
+		}
+	case reflect.Ptr:
+		if o == NullValue {
+			return v, true
+		}
+		ev, ok := goValue(o, t.Elem())
+		if !ok {
+			return v, false
+		}
+		p := reflect.New(t.Elem())
+		p.Elem().Set(ev)
+		v.Set(p)
+	case reflect.Interface, reflect.Func:
+		return v, o == NullValue
+	default:
+		return v, false
+	}
+	return v, true
+}
+
+var bindTables sync.Map // reflect.Type of the struct pointer to *MethodTable
+
+// bindTable returns the method table of the bound struct pointer type t, with
+// its exported fields and methods. It is created once for each type.
+func bindTable(t reflect.Type) *MethodTable {
+	if mt, ok := bindTables.Load(t); ok {
+		return mt.(*MethodTable)
+	}
+	var entries []methodEntry
+	fields := make(map[string]bool)
+	for _, f := range reflect.VisibleFields(t.Elem()) {
+		if f.PkgPath != "" {
+			continue
+		}
+		name := f.Name
+		if tag, ok := f.Tag.Lookup("tender"); ok {
+			if tag == "-" {
+				continue
+			}
+			name = tag
+		}
+		fields[name] = true
+		entries = append(entries, bindField(name, f.Index))
+	}
+	for i := 0; i < t.NumMethod(); i++ {
+		m := t.Method(i)
+		if fields[m.Name] {
+			continue
+		}
+		entries = append(entries, methodEntry{
+			name:   m.Name,
+			method: bindMethod(m),
+		})
+	}
+	mt, _ := bindTables.LoadOrStore(t, newMethodTable(t.Elem().String(), entries))
+	return mt.(*MethodTable)
+}
+
+// bindField returns the entry of the struct field with the index sequence.
+func bindField(name string, index []int) methodEntry {
+	return methodEntry{
+		name: name,
+		property: func(recv interface{}) Object {
+			f, err := reflect.ValueOf(recv).Elem().FieldByIndexErr(index)
+			if err != nil {
+				// field of a nil embedded struct pointer
+				return NullValue
+			}
+			if f.Kind() == reflect.Struct && f.Type() != timeType {
+				return bindStruct(f.Addr())
+			}
+			o, err := bindValue(f)
+			if err != nil {
+				return wrapError(err)
+			}
+			return o
+		},
+		set: func(recv interface{}, value Object) error {
+			f, err := reflect.ValueOf(recv).Elem().FieldByIndexErr(index)
+			if err != nil {
+				return err
+			}
+			v, ok := goValue(value, f.Type())
+			if !ok {
+				return ErrInvalidIndexValueType
+			}
+			f.Set(v)
+			return nil
+		},
+	}
+}
+
+// bindMethod returns a method calling m with its receiver.
+func bindMethod(m reflect.Method) MethodFunc {
+	return func(recv interface{}, args ...Object) (Object, error) {
+		in, err := bindArgs(m.Type, 1, args)
+		if err != nil {
+			return nil, err
+		}
+		in[0] = reflect.ValueOf(recv)
+		return bindResults(m.Func.Call(in))
+	}
+}
+
+// bindStruct returns the object of the pointer to a struct p.
+func bindStruct(p reflect.Value) *MethodObject {
+	return bindTable(p.Type()).New(p.Interface())
+}
@@ -0,0 +1,191 @@
+package tender
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+type bindPoint struct {
+	X, Y   int
+	Label  string `tender:"label"`
+	secret int
+	Hidden bool `tender:"-"`
+}
+
+func (p *bindPoint) Move(dx, dy int) {
+	p.X += dx
+	p.Y += dy
+}
+
+func (p bindPoint) Sum() int {
+	return p.X + p.Y
+}
+
+func (p *bindPoint) Div(d int) (int, error) {
+	if d == 0 {
+		return 0, errors.New("division by zero")
+	}
+	return p.X / d, nil
+}
+
+type bindShape struct {
+	bindPoint
+	Name   string
+	Points []*bindPoint
+	Tags   map[string]int
+	Center bindPoint
+}
+
+func (s *bindShape) Scale(k float64, names ...string) []float64 {
+	r := []float64{float64(s.X) * k, float64(s.Y) * k}
+	for range names {
+		r = append(r, k)
+	}
+	return r
+}
+
+func runBound(t *testing.T, src string, vars map[string]interface{}) (Object, error) {
+	t.Helper()
+	s := NewScript([]byte(src))
+	for name, v := range vars {
+		o, err := Bind(v)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := s.Add(name, o); err != nil {
+			t.Fatal(err)
+		}
+	}
+	c, err := s.Run()
+	if err != nil {
+		return nil, err
+	}
+	return c.Get("out").Object(), nil
+}
+
+func TestBind(t *testing.T) {
+	p := &bindPoint{X: 1, Y: 2, Label: "p"}
+	shape := &bindShape{
+		bindPoint: bindPoint{X: 3, Y: 4},
+		Name:      "s",
+		Points:    []*bindPoint{p},
+		Tags:      map[string]int{"a": 1},
+	}
+	vars := map[string]interface{}{
+		"p":     p,
+		"shape": shape,
+		"upper": strings.ToUpper,
+		"split": strings.Split,
+		"join":  func(sep string, parts ...string) string { return strings.Join(parts, sep) },
+		"pair":  func(a int, b string) (int, string) { return a * 2, b + b },
+		"fail":  func() error { return errors.New("failed") },
+		"ok":    func() error { return nil },
+		"swap":  func(m map[string]int, a []int64, b []byte) []interface{} { return []interface{}{len(m), a, b} },
+		"any":   func(v interface{}) string { return fmt.Sprintf("%T", v) },
+		"not":   func(b bool) bool { return !b },
+		"value": bindPoint{X: 5},
+	}
+	tests := []struct {
+		src      string
+		expected string
+	}{
+		{`out := [p.X, p.Y, p.label, p.secret, p.Hidden]`, `[1, 2, "p", null, null]`},
+		{`p.Move(2, 3); out := [p.X, p.Y, p.Sum()]`, `[3, 5, 8]`},
+		{`p.X = 10; p.label = "q"; out := [p.X, p.label]`, `[10, "q"]`},
+		{`out := [p.Div(3), is_error(p.Div(0)), string(p.Div(0))]`, `[3, true, "error: \"division by zero\""]`},
+		{`out := [shape.X, shape.Sum(), shape.Name, shape.Tags.a, shape.Points[0].X]`, `[3, 7, "s", 1, 10]`},
+		{`shape.Center.X = 7; out := [shape.Center.X, shape.Center.Sum()]`, `[7, 7]`},
+		{`out := [shape.Scale(0.5), shape.Scale(2, "a", "b")]`, `[[1.5, 2], [6, 8, 2, 2]]`},
+		{`out := [upper("abc"), split("a,b", ","), join("-", "x", "y"), join("+")]`, `["ABC", ["a", "b"], "x-y", ""]`},
+		{`out := [pair(2, "ab"), fail(), ok()]`, `[[4, "abab"], error: "failed", null]`},
+		{`out := swap({a: 1}, [1, 2], bytes("hi"))`, `[1, [1, 2], [104 105]]`},
+		{`out := [any(1), any("s"), any([1]), any(null), any(p)]`, `["int64", "string", "[]interface {}", "<nil>", "*tender.bindPoint"]`},
+		{`out := [value.X, value.Sum(), typeof(value), value == value, p == value]`, `[5, 5, "tender.bindPoint", true, false]`},
+		{`out := [shape.Center == shape.Center, shape.Points[0] == p, not(false)]`, `[true, true, true]`},
+		{`f := fn(o) { return o.X }; a := f(value); value.X = 6; out := [a, f(shape), f(value)]`, `[5, 3, 6]`},
+		{`out := []; for k, v in value { if k != "Move" && k != "Div" && k != "Sum" { out = append(out, k) } }`, `["X", "Y", "label"]`},
+	}
+	for _, tc := range tests {
+		out, err := runBound(t, tc.src, vars)
+		if err != nil {
+			t.Errorf("%s: %v", tc.src, err)
+			continue
+		}
+		if got := out.String(); got != tc.expected {
+			t.Errorf("%s: out = %s, expected %s", tc.src, got, tc.expected)
+		}
+	}
+	if p.X != 10 || p.Label != "q" {
+		t.Errorf("bound pointer not updated: %+v", p)
+	}
+	if shape.Center.X != 7 {
+		t.Errorf("nested struct not updated: %+v", shape.Center)
+	}
+
+	errorTests := []struct {
+		src      string
+		expected string
+	}{
+		{`p.Move(1)`, "wrong number of arguments"},
+		{`p.Move("a", 1)`, "'first' in call to 'user-function:Move': expected int, found string"},
+		{`upper(1, 2)`, "wrong number of arguments"},
+		{`not(1)`, "expected bool, found int"},
+		{`p.X = "s"`, "index value type: string"},
+		{`p.Z = 1`, "tender.bindPoint has no field 'Z'"},
+	}
+	for _, tc := range errorTests {
+		_, err := runBound(t, tc.src, vars)
+		if err == nil || !strings.Contains(err.Error(), tc.expected) {
+			t.Errorf("%s: error %v, expected %s", tc.src, err, tc.expected)
+		}
+	}
+}
+
+func TestBindValues(t *testing.T) {
+	var nilPoint *bindPoint
+	tests := []struct {
+		value    interface{}
+		expected string
+	}{
+		{nil, "null"},
+		{nilPoint, "null"},
+		{uint8(3), "3"},
+		{float32(1.5), "1.5"},
+		{[]string{"a"}, `["a"]`},
+		{[2]bool{true, false}, "[true, false]"},
+		{[]int(nil), "[]"},
+		{map[string][]int{"k": {1}}, "{k: [1]}"},
+		{errors.New("e"), `error: "e"`},
+		{&Int{Value: 1}, "1"},
+	}
+	for _, tc := range tests {
+		o, err := Bind(tc.value)
+		if err != nil {
+			t.Errorf("%#v: %v", tc.value, err)
+			continue
+		}
+		if got := o.String(); got != tc.expected {
+			t.Errorf("%#v: %s, expected %s", tc.value, got, tc.expected)
+		}
+	}
+	if _, err := Bind(map[int]int{}); err == nil {
+		t.Error("expected an error for a map with int keys")
+	}
+	if _, err := Bind(make(chan int)); err == nil {
+		t.Error("expected an error for a channel")
+	}
+
+	// the bound struct is returned to Go by ToInterface
+	p := &bindPoint{X: 1}
+	o, _ := Bind(p)
+	if ToInterface(o) != p {
+		t.Error("ToInterface did not return the bound pointer")
+	}
+	// method tables are created once for each type
+	q, _ := Bind(&bindPoint{})
+	if o.(*MethodObject).Methods != q.(*MethodObject).Methods {
+		t.Error("bind types not cached")
+	}
+}
@@ -12,7 +12,76 @@ var builtinFuncs []*BuiltinFunction
 
 // if needVMObj is true, VM will pass [VMObj, args...] to fn when calling it.
 func addBuiltinFunction(name string, fn CallableFunc, needVMObj bool) {
-	builtinFuncs = append(builtinFuncs, &BuiltinFunction{Name: name, Value: fn, NeedVMObj: needVMObj})
+	doc := builtinDocs[name]
+	builtinFuncs = append(builtinFuncs, &BuiltinFunction{
+		Name:      name,
+		Value:     fn,
+		NeedVMObj: needVMObj,
+		Usage:     doc[0],
+		Doc:       doc[1],
+	})
+}
+
+// builtinDocs are the usages and descriptions of the builtin functions, shown
+// by "tender doc" and the help builtin function.
+var builtinDocs = map[string][2]string{
+	"pointer":            {"pointer(v)", "Returns a pointer to the variable holding v, or to a copy of v."},
+	"deref":              {"deref(p pointer)", "Returns the value p points to."},
+	"set":                {"set(p pointer, v)", "Stores v in the variable p points to, and returns v."},
+	"is_pointer":         {"is_pointer(v) => bool", "Returns true if v is a pointer."},
+	"debug":              {"debug(...args)", "Prints debugging information about the arguments."},
+	"sysout":             {"sysout(...args)", "Writes the arguments to the standard output, supporting ANSI colors on all platforms."},
+	"print":              {"print(...args)", "Prints the arguments separated by spaces."},
+	"println":            {"println(...args)", "Prints the arguments separated by spaces, followed by a new line."},
+	"reverse":            {"reverse(v)", "Returns the array or string v reversed."},
+	"includes":           {"includes(v, x) => bool", "Returns true if the array or string v includes x."},
+	"indexof":            {"indexof(v, x) => int", "Returns the index of the first occurrence of x in the array or string v, or -1."},
+	"lastindexof":        {"lastindexof(v, x) => int", "Returns the index of the last occurrence of x in the array or string v, or -1."},
+	"cap":                {"cap(arr array) => int", "Returns the capacity of the array."},
+	"len":                {"len(v) => int", "Returns the number of elements of an array, string, bytes, map or module map."},
+	"copy":               {"copy(v)", "Returns a deep copy of v."},
+	"append":             {"append(arr array, ...items) => array", "Returns a new array with the items appended to arr."},
+	"delete":             {"delete(m map, key string)", "Deletes the element with the key from the map."},
+	"splice":             {"splice(arr array, start int, delete_count int, ...items) => array", "Deletes and inserts elements of the array, and returns the deleted elements."},
+	"sort":               {"sort(arr array) => array", "Sorts the array in place and returns it."},
+	"rune":               {"rune(c char) => int", "Returns the code point of the character."},
+	"string":             {"string(v) => string", "Converts v to a string."},
+	"int":                {"int(v, default) => int", "Converts v to an int, or returns default if it cannot be converted."},
+	"bigint":             {"bigint(v) => bigint", "Converts v to a bigint, or returns null if it cannot be converted."},
+	"bool":               {"bool(v) => bool", "Converts v to a bool."},
+	"float":              {"float(v, default) => float", "Converts v to a float, or returns default if it cannot be converted."},
+	"bigfloat":           {"bigfloat(v) => bigfloat", "Converts v to a bigfloat, or returns null if it cannot be converted."},
+	"complex":            {"complex(real float, imag float) => complex", "Returns the complex number with the real and imaginary parts."},
+	"char":               {"char(v, default) => char", "Converts v to a char, or returns default if it cannot be converted."},
+	"bytes":              {"bytes(...v) => bytes", "Converts v to bytes, concatenating several arguments, or returns new bytes of the size if v is an int."},
+	"time":               {"time(v, default) => time", "Converts v to a time, or returns default if it cannot be converted."},
+	"is_cycle":           {"is_cycle(v) => bool", "Returns true if v is a cycle."},
+	"is_int":             {"is_int(v) => bool", "Returns true if v is an int."},
+	"is_float":           {"is_float(v) => bool", "Returns true if v is a float."},
+	"is_bigint":          {"is_bigint(v) => bool", "Returns true if v is a bigint."},
+	"is_bigfloat":        {"is_bigfloat(v) => bool", "Returns true if v is a bigfloat."},
+	"is_complex":         {"is_complex(v) => bool", "Returns true if v is a complex number."},
+	"is_string":          {"is_string(v) => bool", "Returns true if v is a string."},
+	"is_bool":            {"is_bool(v) => bool", "Returns true if v is a bool."},
+	"is_char":            {"is_char(v) => bool", "Returns true if v is a char."},
+	"is_bytes":           {"is_bytes(v) => bool", "Returns true if v is bytes."},
+	"is_array":           {"is_array(v) => bool", "Returns true if v is an array."},
+	"is_immutable_array": {"is_immutable_array(v) => bool", "Returns true if v is an immutable array."},
+	"is_map":             {"is_map(v) => bool", "Returns true if v is a map."},
+	"is_immutable_map":   {"is_immutable_map(v) => bool", "Returns true if v is an immutable map."},
+	"is_iterable":        {"is_iterable(v) => bool", "Returns true if v can be iterated with a for-in loop."},
+	"is_time":            {"is_time(v) => bool", "Returns true if v is a time."},
+	"is_error":           {"is_error(v) => bool", "Returns true if v is an error."},
+	"is_null":            {"is_null(v) => bool", "Returns true if v is null."},
+	"is_function":        {"is_function(v) => bool", "Returns true if v is a function."},
+	"is_callable":        {"is_callable(v) => bool", "Returns true if v can be called."},
+	"typeof":             {"typeof(v) => string", "Returns the type name of v."},
+	"format":             {"format(format string, ...args) => string", "Returns the arguments formatted according to the format."},
+	"range":              {"range(start int, stop int, step int) => array", "Returns an array of the ints from start to stop, excluding stop."},
+	"help":               {"help(v)", "Prints the documentation of a function or a module."},
+	"go":                 {"go(fn, ...args)", "Runs fn(...args) concurrently, and returns an object with the wait, result and abort methods."},
+	"abort":              {"abort()", "Aborts the current VM and the goroutines it started."},
+	"makechan":           {"makechan(size int)", "Returns a channel with the buffer size, and the send, recv and close methods."},
 }
 
 func init() {
@@ -69,6 +138,7 @@ func init() {
 	addBuiltinFunction("typeof", builtinTypeOf, false)
 	addBuiltinFunction("format", builtinFormat, false)
 	addBuiltinFunction("range",  builtinRange, false)
+	addBuiltinFunction("help", builtinHelp, false)
 }
 
 // GetAllBuiltinFunctions returns all builtin function objects.
@@ -1417,3 +1487,11 @@ func builtinSplice(args ...Object) (Object, error) {
 	// return deleted items
 	return &Array{Value: deleted}, nil
 }
+
+func builtinHelp(args ...Object) (Object, error) {
+	if len(args) != 1 {
+		return nil, ErrWrongNumArguments
+	}
+	fmt.Print(helpText(args[0]))
+	return nil, nil
+}
@@ -1,9 +1,12 @@
 package tender
 
 import (
-	"encoding/gob"
+	"bytes"
+	"compress/flate"
+	"hash/crc32"
 	"math/big"
 	"io"
+	"io/ioutil"
 	"fmt"
 	"reflect"
 
@@ -15,6 +18,17 @@ type Bytecode struct {
 	FileSet      *parser.SourceFileSet
 	MainFunction *CompiledFunction
 	Constants    []Object
+	InlineCaches int // number of selector inline caches
+	NumGlobals   int // number of global variables used by the bytecode
+}
+
+// globalsSize returns the size of the globals a VM needs to run the
+// bytecode.
+func (b *Bytecode) globalsSize() int {
+	if b.NumGlobals > GlobalsSize {
+		return b.NumGlobals
+	}
+	return GlobalsSize
 }
 
 
@@ -33,16 +47,60 @@ type Bytecode struct {
     // return decoder.Decode(b)
 // }
 
-// Encode writes Bytecode data to the writer.
+// Encode writes Bytecode data to the writer. The data starts with a header
+// holding the format version, the tender version and a hash of the builtin
+// function table, which Decode validates.
 func (b *Bytecode) Encode(w io.Writer) error {
-	enc := gob.NewEncoder(w)
-	if err := enc.Encode(b.FileSet); err != nil {
+	return b.encode(w, false)
+}
+
+// EncodeCompressed writes Bytecode data to the writer like Encode, but
+// compresses everything after the header.
+func (b *Bytecode) EncodeCompressed(w io.Writer) error {
+	return b.encode(w, true)
+}
+
+func (b *Bytecode) encode(w io.Writer, compress bool) error {
+	enc := newBytecodeEncoder()
+	enc.fileSet(b.FileSet)
+	enc.compiledFunction(b.MainFunction)
+	enc.uvarint(uint64(b.InlineCaches))
+	enc.uvarint(uint64(b.NumGlobals))
+	if err := enc.objects(b.Constants); err != nil {
 		return err
 	}
-	if err := enc.Encode(b.MainFunction); err != nil {
+	payload := enc.payload()
+
+	var flags uint8
+	if compress {
+		var buf bytes.Buffer
+		zw, err := flate.NewWriter(&buf, flate.BestCompression)
+		if err != nil {
+			return err
+		}
+		if _, err := zw.Write(payload); err != nil {
+			return err
+		}
+		if err := zw.Close(); err != nil {
+			return err
+		}
+		payload = buf.Bytes()
+		flags |= BytecodeCompressed
+	}
+
+	header := BytecodeHeader{
+		FormatVersion: BytecodeFormatVersion,
+		Flags:         flags,
+		Version:       Version,
+		BuiltinsHash:  BuiltinsHash(),
+		PayloadSize:   uint32(len(payload)),
+		Checksum:      crc32.ChecksumIEEE(payload),
+	}
+	if err := header.write(w); err != nil {
 		return err
 	}
-	return enc.Encode(b.Constants)
+	_, err := w.Write(payload)
+	return err
 }
 
 // CountObjects returns the number of objects found in Constants.
@@ -85,29 +143,80 @@ func (b *Bytecode) Decode(r io.Reader, modules *ModuleMap) error {
 		modules = NewModuleMap()
 	}
 
-	dec := gob.NewDecoder(r)
-	if err := dec.Decode(&b.FileSet); err != nil {
+	header, err := ReadBytecodeHeader(r)
+	if err != nil {
 		return err
 	}
-	// TODO: files in b.FileSet.File does not have their 'set' field properly
-	//  set to b.FileSet as it's private field and not serialized by gob
-	//  encoder/decoder.
-	if err := dec.Decode(&b.MainFunction); err != nil {
+	// the payload is read as it comes rather than allocated from the size
+	// in the header, so that a short file cannot make it allocate much
+	payload, err := ioutil.ReadAll(io.LimitReader(r, int64(header.PayloadSize)))
+	if err != nil {
 		return err
 	}
-	if err := dec.Decode(&b.Constants); err != nil {
-		return err
+	if len(payload) != int(header.PayloadSize) {
+		return fmt.Errorf("%w: truncated data", ErrInvalidBytecode)
+	}
+	if crc32.ChecksumIEEE(payload) != header.Checksum {
+		return fmt.Errorf("%w: checksum mismatch", ErrInvalidBytecode)
 	}
-	for i, v := range b.Constants {
-		fv, err := fixDecodedObject(v, modules)
+
+	if header.Flags&BytecodeCompressed != 0 {
+		zr := flate.NewReader(bytes.NewReader(payload))
+		payload, err = ioutil.ReadAll(io.LimitReader(zr, int64(maxDecompressedSize)+1))
 		if err != nil {
-			return err
+			return fmt.Errorf("%w: %s", ErrInvalidBytecode, err.Error())
+		}
+		if len(payload) > maxDecompressedSize {
+			return fmt.Errorf("%w: decompressed data larger than %d bytes",
+				ErrInvalidBytecode, maxDecompressedSize)
 		}
-		b.Constants[i] = fv
+	}
+
+	dec := &bytecodeDecoder{data: payload, modules: modules}
+	if err := b.decode(dec); err != nil {
+		return fmt.Errorf("%w: %s", ErrInvalidBytecode, err.Error())
 	}
 	return nil
 }
 
+// maxDecodedGlobals is the maximum number of global variables of decoded
+// bytecode. A VM allocates all of them before running the bytecode.
+const maxDecodedGlobals = 1 << 20
+
+// maxDecompressedSize is the maximum size of the decompressed payload of
+// compressed bytecode, which keeps small files from decompressing to
+// gigabytes. It is a variable for the tests.
+var maxDecompressedSize = 256 << 20
+
+func (b *Bytecode) decode(dec *bytecodeDecoder) (err error) {
+	if err = dec.stringTable(); err != nil {
+		return
+	}
+	if b.FileSet, err = dec.fileSet(); err != nil {
+		return
+	}
+	if b.MainFunction, err = dec.compiledFunction(); err != nil {
+		return
+	}
+	if b.InlineCaches, err = dec.int(1 << 16); err != nil {
+		return
+	}
+	if b.NumGlobals, err = dec.int(maxDecodedGlobals); err != nil {
+		return
+	}
+	n, err := dec.count(len(dec.data))
+	if err != nil {
+		return
+	}
+	b.Constants = make([]Object, n)
+	for i := range b.Constants {
+		if b.Constants[i], err = dec.object(); err != nil {
+			return
+		}
+	}
+	return
+}
+
 // RemoveDuplicates finds and remove the duplicate values in Constants.
 // Note this function mutates Bytecode.
 func (b *Bytecode) RemoveDuplicates() {
@@ -209,6 +318,10 @@ func (b *Bytecode) RemoveDuplicates() {
 				indexMap[curIdx] = newIdx
 				deduped = append(deduped, c)
 			}
+		case *Bytes, *MethodObject:
+			// embedded files are not compared
+			indexMap[curIdx] = len(deduped)
+			deduped = append(deduped, c)
 		default:
 			panic(fmt.Errorf("unsupported top-level constant type: %s",
 				c.TypeName()))
@@ -230,90 +343,44 @@ func (b *Bytecode) RemoveDuplicates() {
 	}
 }
 
-func fixDecodedObject(o Object, modules *ModuleMap) (Object, error) {
-	switch o := o.(type) {
-	case *Bool:
-		if o.IsFalsy() {
-			return FalseValue, nil
-		}
-		return TrueValue, nil
-	case *Null:
-		return NullValue, nil
-	case *Array:
-		for i, v := range o.Value {
-			fv, err := fixDecodedObject(v, modules)
-			if err != nil {
-				return nil, err
-			}
-			o.Value[i] = fv
-		}
-	case *ImmutableArray:
-		for i, v := range o.Value {
-			fv, err := fixDecodedObject(v, modules)
-			if err != nil {
-				return nil, err
-			}
-			o.Value[i] = fv
-		}
-	case *Map:
-		for k, v := range o.Value {
-			fv, err := fixDecodedObject(v, modules)
-			if err != nil {
-				return nil, err
-			}
-			o.Value[k] = fv
-		}
-	case *ImmutableMap:
-		modName := inferModuleName(o)
-		if mod := modules.GetBuiltinModule(modName); mod != nil {
-			return mod.AsImmutableMap(modName), nil
-		}
-
-		for k, v := range o.Value {
-			// encoding of user function not supported
-			if _, isUserFunction := v.(*UserFunction); isUserFunction {
-				return nil, fmt.Errorf("user function not decodable")
-			}
-
-			fv, err := fixDecodedObject(v, modules)
-			if err != nil {
-				return nil, err
-			}
-			o.Value[k] = fv
-		}
-	}
-	return o, nil
-}
-
 func updateConstIndexes(insts []byte, indexMap map[int]int) {
 	i := 0
 	for i < len(insts) {
 		op := insts[i]
 		numOperands := parser.OpcodeOperands[op]
-		_, read := parser.ReadOperands(numOperands, insts[i+1:])
+		operands, read := parser.ReadOperands(numOperands, insts[i+1:])
 
-		switch op {
-		case parser.OpConstant:
-			curIdx := int(insts[i+2]) | int(insts[i+1])<<8
-			newIdx, ok := indexMap[curIdx]
+		cidxs := constOperands(op)
+		for _, n := range cidxs {
+			newIdx, ok := indexMap[operands[n]]
 			if !ok {
-				panic(fmt.Errorf("constant index not found: %d", curIdx))
+				panic(fmt.Errorf("constant index not found: %d", operands[n]))
 			}
-			copy(insts[i:], MakeInstruction(op, newIdx))
-		case parser.OpClosure:
-			curIdx := int(insts[i+2]) | int(insts[i+1])<<8
-			numFree := int(insts[i+3])
-			newIdx, ok := indexMap[curIdx]
-			if !ok {
-				panic(fmt.Errorf("constant index not found: %d", curIdx))
-			}
-			copy(insts[i:], MakeInstruction(op, newIdx, numFree))
+			operands[n] = newIdx
+		}
+		if len(cidxs) > 0 {
+			copy(insts[i:], MakeInstruction(op, operands...))
 		}
 
 		i += 1 + read
 	}
 }
 
+// constOperands returns the operands of the opcode that hold constant
+// indexes.
+func constOperands(op parser.Opcode) []int {
+	switch op {
+	case parser.OpConstant, parser.OpConstantW, parser.OpClosure,
+		parser.OpClosureW, parser.OpSelector:
+		return []int{0}
+	case parser.OpLocalBinaryOp:
+		return []int{1}
+	case parser.OpCheckType:
+		return []int{1, 2}
+	}
+	return nil
+}
+
 func inferModuleName(mod *ImmutableMap) string {
 	if modName, ok := mod.Value["__module_name__"].(*String); ok {
 		return modName.Value
@@ -0,0 +1,651 @@
+package tender
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"sort"
+	"time"
+
+	"github.com/2dprototype/tender/parser"
+)
+
+// object tags of the bytecode encoding
+const (
+	tagNull byte = iota
+	tagFalse
+	tagTrue
+	tagInt
+	tagFloat
+	tagString
+	tagChar
+	tagBigInt
+	tagBigFloat
+	tagComplex
+	tagBytes
+	tagCompiledFunction
+	tagBuiltinModule
+	tagArray
+	tagImmutableArray
+	tagMap
+	tagImmutableMap
+	tagError
+	tagTime
+	tagBuiltinFunction
+	tagFS
+)
+
+var errTruncated = errors.New("truncated data")
+
+// bytecodeEncoder writes the payload of encoded Bytecode. All strings are
+// stored once in a string table written in front of the body.
+type bytecodeEncoder struct {
+	strings []string
+	index   map[string]int
+	body    []byte
+}
+
+func newBytecodeEncoder() *bytecodeEncoder {
+	return &bytecodeEncoder{index: make(map[string]int)}
+}
+
+func (e *bytecodeEncoder) uvarint(v uint64) {
+	e.body = binary.AppendUvarint(e.body, v)
+}
+
+func (e *bytecodeEncoder) varint(v int64) {
+	e.body = binary.AppendVarint(e.body, v)
+}
+
+func (e *bytecodeEncoder) bytes(b []byte) {
+	e.uvarint(uint64(len(b)))
+	e.body = append(e.body, b...)
+}
+
+func (e *bytecodeEncoder) float(f float64) {
+	e.body = binary.BigEndian.AppendUint64(e.body, math.Float64bits(f))
+}
+
+func (e *bytecodeEncoder) string(s string) {
+	idx, ok := e.index[s]
+	if !ok {
+		idx = len(e.strings)
+		e.index[s] = idx
+		e.strings = append(e.strings, s)
+	}
+	e.uvarint(uint64(idx))
+}
+
+// payload returns the string table followed by the body.
+func (e *bytecodeEncoder) payload() []byte {
+	var out []byte
+	out = binary.AppendUvarint(out, uint64(len(e.strings)))
+	for _, s := range e.strings {
+		out = binary.AppendUvarint(out, uint64(len(s)))
+		out = append(out, s...)
+	}
+	return append(out, e.body...)
+}
+
+func (e *bytecodeEncoder) fileSet(fs *parser.SourceFileSet) {
+	if fs == nil {
+		e.uvarint(0)
+		return
+	}
+	e.uvarint(uint64(len(fs.Files)))
+	for _, f := range fs.Files {
+		e.string(f.Name)
+		e.uvarint(uint64(f.Base))
+		e.uvarint(uint64(f.Size))
+		e.uvarint(uint64(len(f.Lines)))
+		prev := 0
+		for _, l := range f.Lines {
+			e.uvarint(uint64(l - prev))
+			prev = l
+		}
+	}
+}
+
+func (e *bytecodeEncoder) compiledFunction(fn *CompiledFunction) {
+	e.uvarint(uint64(fn.NumLocals))
+	e.uvarint(uint64(fn.NumParameters))
+	if fn.VarArgs {
+		e.body = append(e.body, 1)
+	} else {
+		e.body = append(e.body, 0)
+	}
+	e.bytes(fn.Instructions)
+	for i := 0; i < fn.NumParameters; i++ {
+		var name string
+		if i < len(fn.Params) {
+			name = fn.Params[i]
+		}
+		e.string(name)
+	}
+	e.string(fn.Usage)
+	e.string(fn.Doc)
+
+	ips := make([]int, 0, len(fn.SourceMap))
+	for ip := range fn.SourceMap {
+		ips = append(ips, ip)
+	}
+	sort.Ints(ips)
+	e.uvarint(uint64(len(ips)))
+	prevIP, prevPos := 0, 0
+	for _, ip := range ips {
+		pos := int(fn.SourceMap[ip])
+		e.uvarint(uint64(ip - prevIP))
+		e.varint(int64(pos - prevPos))
+		prevIP, prevPos = ip, pos
+	}
+}
+
+func (e *bytecodeEncoder) object(o Object) error {
+	switch o := o.(type) {
+	case *Null:
+		e.body = append(e.body, tagNull)
+	case *Bool:
+		if o.IsFalsy() {
+			e.body = append(e.body, tagFalse)
+		} else {
+			e.body = append(e.body, tagTrue)
+		}
+	case *Int:
+		e.body = append(e.body, tagInt)
+		e.varint(o.Value)
+	case *Float:
+		e.body = append(e.body, tagFloat)
+		e.float(o.Value)
+	case *String:
+		e.body = append(e.body, tagString)
+		e.string(o.Value)
+	case *Char:
+		e.body = append(e.body, tagChar)
+		e.varint(int64(o.Value))
+	case *BigInt:
+		b, err := o.Value.GobEncode()
+		if err != nil {
+			return err
+		}
+		e.body = append(e.body, tagBigInt)
+		e.bytes(b)
+	case *BigFloat:
+		b, err := o.Value.GobEncode()
+		if err != nil {
+			return err
+		}
+		e.body = append(e.body, tagBigFloat)
+		e.bytes(b)
+	case *Complex:
+		e.body = append(e.body, tagComplex)
+		e.float(real(o.Value))
+		e.float(imag(o.Value))
+	case *Bytes:
+		e.body = append(e.body, tagBytes)
+		e.bytes(o.Value)
+	case *CompiledFunction:
+		e.body = append(e.body, tagCompiledFunction)
+		e.compiledFunction(o)
+	case *Array:
+		e.body = append(e.body, tagArray)
+		return e.objects(o.Value)
+	case *ImmutableArray:
+		e.body = append(e.body, tagImmutableArray)
+		return e.objects(o.Value)
+	case *Map:
+		e.body = append(e.body, tagMap)
+		return e.objectMap(o.Value)
+	case *ImmutableMap:
+		// builtin modules are stored by name and resolved when decoding
+		if modName := inferModuleName(o); modName != "" {
+			e.body = append(e.body, tagBuiltinModule)
+			e.string(modName)
+			return nil
+		}
+		e.body = append(e.body, tagImmutableMap)
+		return e.objectMap(o.Value)
+	case *Error:
+		e.body = append(e.body, tagError)
+		return e.object(o.Value)
+	case *Time:
+		b, err := o.Value.MarshalBinary()
+		if err != nil {
+			return err
+		}
+		e.body = append(e.body, tagTime)
+		e.bytes(b)
+	case *BuiltinFunction:
+		e.body = append(e.body, tagBuiltinFunction)
+		e.string(o.Name)
+	case *MethodObject:
+		fsys, ok := ToFS(o)
+		if !ok {
+			return fmt.Errorf("cannot encode object of type %s", o.TypeName())
+		}
+		e.body = append(e.body, tagFS)
+		names := fsys.Names()
+		e.uvarint(uint64(len(names)))
+		for _, name := range names {
+			e.string(name)
+			e.bytes(fsys.Files[name])
+		}
+	default:
+		return fmt.Errorf("cannot encode object of type %s", o.TypeName())
+	}
+	return nil
+}
+
+func (e *bytecodeEncoder) objects(objs []Object) error {
+	e.uvarint(uint64(len(objs)))
+	for _, o := range objs {
+		if err := e.object(o); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (e *bytecodeEncoder) objectMap(m map[string]Object) error {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	e.uvarint(uint64(len(keys)))
+	for _, k := range keys {
+		e.string(k)
+		if err := e.object(m[k]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// bytecodeDecoder reads the payload written by bytecodeEncoder.
+type bytecodeDecoder struct {
+	data    []byte
+	pos     int
+	strings []string
+	modules *ModuleMap
+	depth   int // nesting of the object being decoded
+}
+
+// maxDecodedDepth is the maximum nesting of decoded arrays, maps and errors,
+// which keeps corrupt data from overflowing the stack of the decoder.
+const maxDecodedDepth = 10000
+
+func (d *bytecodeDecoder) uvarint() (uint64, error) {
+	v, n := binary.Uvarint(d.data[d.pos:])
+	if n <= 0 {
+		return 0, errTruncated
+	}
+	d.pos += n
+	return v, nil
+}
+
+// int reads an unsigned integer that must not exceed max.
+func (d *bytecodeDecoder) int(max int) (int, error) {
+	v, err := d.uvarint()
+	if err != nil {
+		return 0, err
+	}
+	if max < 0 || v > uint64(max) {
+		return 0, fmt.Errorf("value %d out of range", v)
+	}
+	return int(v), nil
+}
+
+// count reads the number of the items that follow, which must not exceed max.
+// Every item takes at least one byte, so the count is also bounded by the
+// remaining data, which keeps corrupt counts from allocating large slices.
+func (d *bytecodeDecoder) count(max int) (int, error) {
+	if remaining := len(d.data) - d.pos; max > remaining {
+		max = remaining
+	}
+	return d.int(max)
+}
+
+func (d *bytecodeDecoder) varint() (int64, error) {
+	v, n := binary.Varint(d.data[d.pos:])
+	if n <= 0 {
+		return 0, errTruncated
+	}
+	d.pos += n
+	return v, nil
+}
+
+func (d *bytecodeDecoder) byte() (byte, error) {
+	if d.pos >= len(d.data) {
+		return 0, errTruncated
+	}
+	b := d.data[d.pos]
+	d.pos++
+	return b, nil
+}
+
+func (d *bytecodeDecoder) bytes() ([]byte, error) {
+	v, err := d.uvarint()
+	if err != nil {
+		return nil, err
+	}
+	// the length is checked after it has been read
+	if v > uint64(len(d.data)-d.pos) {
+		return nil, errTruncated
+	}
+	n := int(v)
+	b := d.data[d.pos : d.pos+n : d.pos+n]
+	d.pos += n
+	return b, nil
+}
+
+func (d *bytecodeDecoder) float() (float64, error) {
+	if d.pos+8 > len(d.data) {
+		return 0, errTruncated
+	}
+	v := binary.BigEndian.Uint64(d.data[d.pos:])
+	d.pos += 8
+	return math.Float64frombits(v), nil
+}
+
+func (d *bytecodeDecoder) string() (string, error) {
+	idx, err := d.int(len(d.strings) - 1)
+	if err != nil {
+		return "", err
+	}
+	return d.strings[idx], nil
+}
+
+func (d *bytecodeDecoder) stringTable() error {
+	n, err := d.count(len(d.data))
+	if err != nil {
+		return err
+	}
+	d.strings = make([]string, n)
+	for i := range d.strings {
+		b, err := d.bytes()
+		if err != nil {
+			return err
+		}
+		d.strings[i] = string(b)
+	}
+	return nil
+}
+
+func (d *bytecodeDecoder) fileSet() (*parser.SourceFileSet, error) {
+	fs := parser.NewFileSet()
+	n, err := d.count(len(d.data))
+	if err != nil {
+		return nil, err
+	}
+	for i := 0; i < n; i++ {
+		name, err := d.string()
+		if err != nil {
+			return nil, err
+		}
+		base, err := d.int(math.MaxInt32)
+		if err != nil {
+			return nil, err
+		}
+		size, err := d.int(math.MaxInt32)
+		if err != nil {
+			return nil, err
+		}
+		if base < fs.Base {
+			return nil, fmt.Errorf("invalid base of file %s", name)
+		}
+		numLines, err := d.count(size + 1)
+		if err != nil {
+			return nil, err
+		}
+		lines := make([]int, numLines)
+		prev := 0
+		for j := range lines {
+			delta, err := d.int(size)
+			if err != nil {
+				return nil, err
+			}
+			prev += delta
+			lines[j] = prev
+		}
+		f := fs.AddFile(name, base, size)
+		f.Lines = lines
+	}
+	return fs, nil
+}
+
+func (d *bytecodeDecoder) compiledFunction() (*CompiledFunction, error) {
+	numLocals, err := d.int(math.MaxInt32)
+	if err != nil {
+		return nil, err
+	}
+	numParams, err := d.count(numLocals)
+	if err != nil {
+		return nil, err
+	}
+	varArgs, err := d.byte()
+	if err != nil {
+		return nil, err
+	}
+	ins, err := d.bytes()
+	if err != nil {
+		return nil, err
+	}
+	params := make([]string, numParams)
+	for i := range params {
+		if params[i], err = d.string(); err != nil {
+			return nil, err
+		}
+	}
+	usage, err := d.string()
+	if err != nil {
+		return nil, err
+	}
+	doc, err := d.string()
+	if err != nil {
+		return nil, err
+	}
+	n, err := d.count(len(ins))
+	if err != nil {
+		return nil, err
+	}
+	sourceMap := make(map[int]parser.Pos, n)
+	ip, pos := 0, int64(0)
+	for i := 0; i < n; i++ {
+		deltaIP, err := d.int(len(ins))
+		if err != nil {
+			return nil, err
+		}
+		deltaPos, err := d.varint()
+		if err != nil {
+			return nil, err
+		}
+		ip += deltaIP
+		pos += deltaPos
+		sourceMap[ip] = parser.Pos(pos)
+	}
+	return &CompiledFunction{
+		Instructions:  ins,
+		NumLocals:     numLocals,
+		NumParameters: numParams,
+		VarArgs:       varArgs != 0,
+		SourceMap:     sourceMap,
+		Params:        params,
+		Usage:         usage,
+		Doc:           doc,
+	}, nil
+}
+
+func (d *bytecodeDecoder) object() (Object, error) {
+	if d.depth >= maxDecodedDepth {
+		return nil, fmt.Errorf("objects nested deeper than %d", maxDecodedDepth)
+	}
+	d.depth++
+	defer func() { d.depth-- }()
+
+	tag, err := d.byte()
+	if err != nil {
+		return nil, err
+	}
+	switch tag {
+	case tagNull:
+		return NullValue, nil
+	case tagFalse:
+		return FalseValue, nil
+	case tagTrue:
+		return TrueValue, nil
+	case tagInt:
+		v, err := d.varint()
+		if err != nil {
+			return nil, err
+		}
+		return &Int{Value: v}, nil
+	case tagFloat:
+		v, err := d.float()
+		if err != nil {
+			return nil, err
+		}
+		return &Float{Value: v}, nil
+	case tagString:
+		v, err := d.string()
+		if err != nil {
+			return nil, err
+		}
+		return &String{Value: v}, nil
+	case tagChar:
+		v, err := d.varint()
+		if err != nil {
+			return nil, err
+		}
+		return &Char{Value: rune(v)}, nil
+	case tagBigInt:
+		b, err := d.bytes()
+		if err != nil {
+			return nil, err
+		}
+		v := new(big.Int)
+		if err := v.GobDecode(b); err != nil {
+			return nil, err
+		}
+		return &BigInt{Value: v}, nil
+	case tagBigFloat:
+		b, err := d.bytes()
+		if err != nil {
+			return nil, err
+		}
+		v := new(big.Float)
+		if err := v.GobDecode(b); err != nil {
+			return nil, err
+		}
+		return &BigFloat{Value: v}, nil
+	case tagComplex:
+		re, err := d.float()
+		if err != nil {
+			return nil, err
+		}
+		im, err := d.float()
+		if err != nil {
+			return nil, err
+		}
+		return &Complex{Value: complex(re, im)}, nil
+	case tagBytes:
+		b, err := d.bytes()
+		if err != nil {
+			return nil, err
+		}
+		return &Bytes{Value: append([]byte{}, b...)}, nil
+	case tagCompiledFunction:
+		return d.compiledFunction()
+	case tagBuiltinModule:
+		name, err := d.string()
+		if err != nil {
+			return nil, err
+		}
+		mod := d.modules.GetBuiltinModule(name)
+		if mod == nil {
+			return nil, fmt.Errorf("module '%s' not found", name)
+		}
+		return mod.AsImmutableMap(name), nil
+	case tagArray, tagImmutableArray:
+		n, err := d.count(len(d.data))
+		if err != nil {
+			return nil, err
+		}
+		objs := make([]Object, n)
+		for i := range objs {
+			if objs[i], err = d.object(); err != nil {
+				return nil, err
+			}
+		}
+		if tag == tagArray {
+			return &Array{Value: objs}, nil
+		}
+		return &ImmutableArray{Value: objs}, nil
+	case tagMap, tagImmutableMap:
+		n, err := d.count(len(d.data))
+		if err != nil {
+			return nil, err
+		}
+		m := make(map[string]Object, n)
+		for i := 0; i < n; i++ {
+			k, err := d.string()
+			if err != nil {
+				return nil, err
+			}
+			if m[k], err = d.object(); err != nil {
+				return nil, err
+			}
+		}
+		if tag == tagMap {
+			return &Map{Value: m}, nil
+		}
+		return &ImmutableMap{Value: m}, nil
+	case tagError:
+		v, err := d.object()
+		if err != nil {
+			return nil, err
+		}
+		return &Error{Value: v}, nil
+	case tagTime:
+		b, err := d.bytes()
+		if err != nil {
+			return nil, err
+		}
+		var t time.Time
+		if err := t.UnmarshalBinary(b); err != nil {
+			return nil, err
+		}
+		return &Time{Value: t}, nil
+	case tagBuiltinFunction:
+		name, err := d.string()
+		if err != nil {
+			return nil, err
+		}
+		for _, fn := range builtinFuncs {
+			if fn.Name == name {
+				return fn, nil
+			}
+		}
+		return nil, fmt.Errorf("builtin function '%s' not found", name)
+	case tagFS:
+		n, err := d.count(len(d.data))
+		if err != nil {
+			return nil, err
+		}
+		files := make(map[string][]byte, n)
+		for i := 0; i < n; i++ {
+			name, err := d.string()
+			if err != nil {
+				return nil, err
+			}
+			data, err := d.bytes()
+			if err != nil {
+				return nil, err
+			}
+			files[name] = append([]byte{}, data...)
+		}
+		return NewFS(files), nil
+	}
+	return nil, fmt.Errorf("unknown object tag %d", tag)
+}
@@ -0,0 +1,306 @@
+package tender
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"runtime"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/2dprototype/tender/parser"
+)
+
+func TestBytecodeRoundTrip(t *testing.T) {
+	mods := NewModuleMap()
+	mods.AddBuiltinModule("mod", map[string]Object{"answer": &Int{Value: 42}})
+
+	s := NewScript([]byte(`
+mod := import("mod")
+// scale multiplies x.
+scale := fn(x, ...rest) { return x * 2.5 }
+pair := immutable({a: [1, "b", 'c'], d: 12345678901234567890})
+out := [scale(4), mod.answer, pair.a[1], -7, 1.5e300, string(pair.d)]`))
+	s.SetImports(mods)
+	c, err := s.Compile()
+	if err != nil {
+		t.Fatal(err)
+	}
+	b := c.bytecode
+	b.Constants = append(b.Constants,
+		&Bytes{Value: []byte{0, 1, 2}},
+		&Complex{Value: complex(1, -2)},
+		&BigFloat{Value: big.NewFloat(math.Pi)},
+		&Error{Value: &String{Value: "e"}},
+		&Time{Value: time.Date(2020, 1, 2, 3, 4, 5, 6, time.UTC)},
+		&Map{Value: map[string]Object{"a": &Array{Value: []Object{TrueValue, FalseValue, NullValue}}}},
+		builtinFuncs[0],
+		NewFS(map[string][]byte{"a.txt": []byte("a")}),
+	)
+
+	for _, compress := range []bool{false, true} {
+		var buf bytes.Buffer
+		if compress {
+			err = b.EncodeCompressed(&buf)
+		} else {
+			err = b.Encode(&buf)
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		encoded := append([]byte{}, buf.Bytes()...)
+
+		decoded := &Bytecode{}
+		if err := decoded.Decode(&buf, mods); err != nil {
+			t.Fatal(err)
+		}
+		if err := decoded.Verify(); err != nil {
+			t.Fatal(err)
+		}
+		if decoded.NumGlobals != b.NumGlobals || decoded.InlineCaches != b.InlineCaches ||
+			len(decoded.Constants) != len(b.Constants) {
+			t.Fatalf("decoded %d globals, %d caches, %d constants",
+				decoded.NumGlobals, decoded.InlineCaches, len(decoded.Constants))
+		}
+		for i, cn := range b.Constants {
+			d := decoded.Constants[i]
+			if d.TypeName() != cn.TypeName() || (!d.Equals(cn) && d.String() != cn.String()) {
+				t.Errorf("constant %d: %s %s, expected %s %s", i,
+					d.TypeName(), d, cn.TypeName(), cn)
+			}
+		}
+		if fmt.Sprint(decoded.FormatInstructions()) != fmt.Sprint(b.FormatInstructions()) {
+			t.Errorf("decoded instructions\n%v\nexpected\n%v",
+				decoded.FormatInstructions(), b.FormatInstructions())
+		}
+		pos := decoded.FileSet.Position(decoded.MainFunction.SourcePos(0))
+		if pos.Filename != "(main)" || pos.Line != 2 {
+			t.Errorf("position %s", pos)
+		}
+
+		// encoding is deterministic
+		buf.Reset()
+		if compress {
+			err = decoded.EncodeCompressed(&buf)
+		} else {
+			err = decoded.Encode(&buf)
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(buf.Bytes(), encoded) {
+			t.Errorf("re-encoded bytecode differs (compressed %t)", compress)
+		}
+
+		v := NewVM(decoded, nil, -1)
+		if err := v.Run(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// bytecode using a builtin module cannot be decoded without it
+	var buf bytes.Buffer
+	if err := b.Encode(&buf); err != nil {
+		t.Fatal(err)
+	}
+	err = (&Bytecode{}).Decode(&buf, NewModuleMap())
+	if err == nil || !strings.Contains(err.Error(), "module 'mod' not found") {
+		t.Errorf("error %v", err)
+	}
+}
+
+// TestBytecodeDecodeLimits checks that corrupt counts are rejected before
+// they are used to allocate memory.
+func TestBytecodeDecodeLimits(t *testing.T) {
+	uvarints := func(values ...uint64) []byte {
+		var b []byte
+		for _, v := range values {
+			b = binary.AppendUvarint(b, v)
+		}
+		return b
+	}
+	// string table with "f", a file set with one file, and the main function
+	// without instructions
+	strs := uvarints(1, 1)
+	strs = append(strs, 'f')
+	file := uvarints(1, 0, 1, 10, 1, 0)
+	main := append(uvarints(0, 0), 0)
+	main = append(main, uvarints(0, 0, 0, 0)...)
+
+	tests := []struct {
+		name    string
+		payload []byte
+	}{
+		{"strings", uvarints(1 << 40)},
+		{"files", append(strs, uvarints(1<<40)...)},
+		{"lines", append(strs, uvarints(1, 0, 1, math.MaxInt32-1, math.MaxInt32)...)},
+		{"params", bytes.Join([][]byte{strs, file,
+			uvarints(math.MaxInt32, math.MaxInt32)}, nil)},
+		{"source map", bytes.Join([][]byte{strs, file,
+			append(uvarints(0, 0), 0), uvarints(3, 1, 2, 3, 0, 0, 1<<30)}, nil)},
+		{"globals", bytes.Join([][]byte{strs, file, main,
+			uvarints(0, 1<<30)}, nil)},
+		{"constants", bytes.Join([][]byte{strs, file, main,
+			uvarints(0, 0, 1<<40)}, nil)},
+		{"array", bytes.Join([][]byte{strs, file, main,
+			uvarints(0, 0, 1), {tagArray}, uvarints(1 << 40)}, nil)},
+		{"map", bytes.Join([][]byte{strs, file, main,
+			uvarints(0, 0, 1), {tagMap}, uvarints(1 << 40)}, nil)},
+		{"nesting", bytes.Join([][]byte{strs, file, main, uvarints(0, 0, 1),
+			bytes.Repeat([]byte{tagArray, 1}, 1<<20), {tagNull}}, nil)},
+	}
+	var before, after runtime.MemStats
+	for _, tc := range tests {
+		runtime.ReadMemStats(&before)
+		err := (&Bytecode{}).decode(&bytecodeDecoder{data: tc.payload})
+		runtime.ReadMemStats(&after)
+		if err == nil {
+			t.Errorf("%s: no error", tc.name)
+		}
+		if alloc := after.TotalAlloc - before.TotalAlloc; alloc > 1<<20 {
+			t.Errorf("%s: allocated %d bytes", tc.name, alloc)
+		}
+	}
+
+	// the valid prefix of the cases decodes
+	payload := bytes.Join([][]byte{strs, file, main, uvarints(0, 0, 0)}, nil)
+	if err := (&Bytecode{}).decode(&bytecodeDecoder{data: payload}); err != nil {
+		t.Error(err)
+	}
+	// and so do arrays nested below the limit
+	payload = bytes.Join([][]byte{strs, file, main, uvarints(0, 0, 1),
+		bytes.Repeat([]byte{tagArray, 1}, maxDecodedDepth-1), {tagNull}}, nil)
+	if err := (&Bytecode{}).decode(&bytecodeDecoder{data: payload}); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestBytecodeDecompressedSize(t *testing.T) {
+	c, err := NewScript([]byte(`out := "` + strings.Repeat("a", 1000) + `"`)).Compile()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	if err := c.bytecode.EncodeCompressed(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if err := (&Bytecode{}).Decode(bytes.NewReader(buf.Bytes()), nil); err != nil {
+		t.Fatal(err)
+	}
+
+	defer func(size int) { maxDecompressedSize = size }(maxDecompressedSize)
+	maxDecompressedSize = 1000
+	err = (&Bytecode{}).Decode(bytes.NewReader(buf.Bytes()), nil)
+	if !errors.Is(err, ErrInvalidBytecode) || !strings.Contains(err.Error(), "decompressed data larger") {
+		t.Errorf("error %v, expected decompressed data larger than the limit", err)
+	}
+}
+
+// benchmarkBytecode returns the bytecode of a script with many functions and
+// constants.
+func benchmarkBytecode(b *testing.B) *Bytecode {
+	var src strings.Builder
+	for i := 0; i < 200; i++ {
+		fmt.Fprintf(&src, "f%d := fn(a, b) { x := a * %d + b; return x + %q + %d.5 }\n",
+			i, i, fmt.Sprint("s", i), i)
+	}
+	c, err := NewScript([]byte(src.String())).Compile()
+	if err != nil {
+		b.Fatal(err)
+	}
+	return c.bytecode
+}
+
+func BenchmarkBytecodeEncode(b *testing.B) {
+	bc := benchmarkBytecode(b)
+	var buf bytes.Buffer
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		buf.Reset()
+		if err := bc.Encode(&buf); err != nil {
+			b.Fatal(err)
+		}
+	}
+	b.ReportMetric(float64(buf.Len()), "bytes")
+}
+
+func BenchmarkBytecodeDecode(b *testing.B) {
+	var buf bytes.Buffer
+	if err := benchmarkBytecode(b).Encode(&buf); err != nil {
+		b.Fatal(err)
+	}
+	data := buf.Bytes()
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if err := (&Bytecode{}).Decode(bytes.NewReader(data), nil); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+var registerGob sync.Once
+
+// gobBytecode is the bytecode encoded with encoding/gob, as tender did before
+// it had its own encoding, to compare their speed.
+func gobBytecode(b *testing.B) []byte {
+	registerGob.Do(func() {
+		gob.Register(&CompiledFunction{})
+		gob.Register(&Int{})
+		gob.Register(&Float{})
+		gob.Register(&String{})
+	})
+	bc := benchmarkBytecode(b)
+	var buf bytes.Buffer
+	enc := gob.NewEncoder(&buf)
+	for _, v := range []interface{}{bc.FileSet, bc.MainFunction, bc.Constants} {
+		if err := enc.Encode(v); err != nil {
+			b.Fatal(err)
+		}
+	}
+	return buf.Bytes()
+}
+
+func BenchmarkBytecodeEncodeGob(b *testing.B) {
+	bc := benchmarkBytecode(b)
+	size := len(gobBytecode(b))
+	var buf bytes.Buffer
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		buf.Reset()
+		enc := gob.NewEncoder(&buf)
+		for _, v := range []interface{}{bc.FileSet, bc.MainFunction, bc.Constants} {
+			if err := enc.Encode(v); err != nil {
+				b.Fatal(err)
+			}
+		}
+	}
+	b.ReportMetric(float64(size), "bytes")
+}
+
+func BenchmarkBytecodeDecodeGob(b *testing.B) {
+	data := gobBytecode(b)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		dec := gob.NewDecoder(bytes.NewReader(data))
+		var (
+			fileSet *parser.SourceFileSet
+			main    *CompiledFunction
+			consts  []Object
+		)
+		for _, v := range []interface{}{&fileSet, &main, &consts} {
+			if err := dec.Decode(v); err != nil {
+				b.Fatal(err)
+			}
+		}
+	}
+}
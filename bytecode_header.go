@@ -0,0 +1,104 @@
+package tender
+
+import (
+	"encoding/binary"
+	"fmt"
+	"hash/fnv"
+	"io"
+)
+
+// BytecodeMagic are the first bytes of every compiled bytecode file.
+const BytecodeMagic = "TDO\x1a"
+
+// BytecodeFormatVersion is the version of the compiled bytecode format. It
+// must be increased whenever the encoding or the instruction set changes.
+const BytecodeFormatVersion uint16 = 8
+
+// BytecodeCompressed is the header flag of compressed bytecode.
+const BytecodeCompressed uint8 = 1 << 0
+
+// BytecodeHeader is the header written in front of encoded Bytecode.
+type BytecodeHeader struct {
+	FormatVersion uint16
+	Flags         uint8
+	Version       string // tender version that compiled the bytecode
+	BuiltinsHash  uint64 // hash of the builtin function table
+	PayloadSize   uint32
+	Checksum      uint32 // CRC-32 of the payload
+}
+
+// BuiltinsHash returns a hash of the names and order of the builtin
+// functions. Compiled bytecode refers to builtins by index, so it can only
+// run with the same builtin function table.
+func BuiltinsHash() uint64 {
+	h := fnv.New64a()
+	for _, fn := range builtinFuncs {
+		_, _ = h.Write([]byte(fn.Name))
+		_, _ = h.Write([]byte{0})
+	}
+	return h.Sum64()
+}
+
+// ReadBytecodeHeader reads and validates the header of encoded Bytecode.
+func ReadBytecodeHeader(r io.Reader) (*BytecodeHeader, error) {
+	magic := make([]byte, len(BytecodeMagic))
+	if _, err := io.ReadFull(r, magic); err != nil ||
+		string(magic) != BytecodeMagic {
+		return nil, fmt.Errorf("%w: not a compiled tender file",
+			ErrInvalidBytecode)
+	}
+
+	h := &BytecodeHeader{}
+	var versionLen uint8
+	if err := binary.Read(r, binary.BigEndian, &h.FormatVersion); err != nil {
+		return nil, fmt.Errorf("%w: truncated header", ErrInvalidBytecode)
+	}
+	if h.FormatVersion != BytecodeFormatVersion {
+		return nil, fmt.Errorf(
+			"%w: unsupported format version %d (expected %d)",
+			ErrInvalidBytecode, h.FormatVersion, BytecodeFormatVersion)
+	}
+	if err := binary.Read(r, binary.BigEndian, &h.Flags); err != nil {
+		return nil, fmt.Errorf("%w: truncated header", ErrInvalidBytecode)
+	}
+	if err := binary.Read(r, binary.BigEndian, &versionLen); err != nil {
+		return nil, fmt.Errorf("%w: truncated header", ErrInvalidBytecode)
+	}
+	version := make([]byte, versionLen)
+	if _, err := io.ReadFull(r, version); err != nil {
+		return nil, fmt.Errorf("%w: truncated header", ErrInvalidBytecode)
+	}
+	h.Version = string(version)
+	for _, v := range []interface{}{
+		&h.BuiltinsHash, &h.PayloadSize, &h.Checksum,
+	} {
+		if err := binary.Read(r, binary.BigEndian, v); err != nil {
+			return nil, fmt.Errorf("%w: truncated header", ErrInvalidBytecode)
+		}
+	}
+	if h.BuiltinsHash != BuiltinsHash() {
+		return nil, fmt.Errorf(
+			"%w: compiled by tender %s with different builtin functions; recompile the source file",
+			ErrInvalidBytecode, h.Version)
+	}
+	return h, nil
+}
+
+func (h *BytecodeHeader) write(w io.Writer) error {
+	version := h.Version
+	if len(version) > 255 {
+		version = version[:255]
+	}
+	if _, err := io.WriteString(w, BytecodeMagic); err != nil {
+		return err
+	}
+	for _, v := range []interface{}{
+		h.FormatVersion, h.Flags, uint8(len(version)), []byte(version),
+		h.BuiltinsHash, h.PayloadSize, h.Checksum,
+	} {
+		if err := binary.Write(w, binary.BigEndian, v); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,539 @@
+package tender
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/2dprototype/tender/parser"
+	"github.com/2dprototype/tender/token"
+)
+
+// CheckError represents an error found by the static type checker.
+type CheckError struct {
+	FileSet *parser.SourceFileSet
+	Node    parser.Node
+	Err     error
+}
+
+func (e *CheckError) Error() string {
+	filePos := e.FileSet.Position(e.Node.Pos())
+	return fmt.Sprintf("Type Error: %s\n\tat %s", e.Err.Error(), filePos)
+}
+
+// checkVar is a variable known to the checker.
+type checkVar struct {
+	typ      string // inferred type; or "" if unknown
+	declared string // annotated type; or "" if not annotated
+	params   []string
+	sig      *Signature
+	module   string
+}
+
+func (v *checkVar) Type() string {
+	if v.declared != "" {
+		return v.declared
+	}
+	return v.typ
+}
+
+type checkScope struct {
+	parent *checkScope
+	vars   map[string]*checkVar
+}
+
+func (s *checkScope) lookup(name string) *checkVar {
+	for ; s != nil; s = s.parent {
+		if v, ok := s.vars[name]; ok {
+			return v
+		}
+	}
+	return nil
+}
+
+// Checker checks type annotations of a parsed script without running it.
+// Unannotated values are never reported, so scripts without annotations
+// always pass.
+type Checker struct {
+	file    *parser.SourceFile
+	modules map[string]map[string]*Signature
+	scope   *checkScope
+	results []string
+	errors  []error
+}
+
+// NewChecker creates a Checker. signatures holds the member signatures of the
+// modules available to the script, keyed by module name and member name.
+func NewChecker(
+	file *parser.SourceFile,
+	signatures map[string]map[string]string,
+) *Checker {
+	modules := make(map[string]map[string]*Signature)
+	for name, members := range signatures {
+		sigs := make(map[string]*Signature)
+		for member, s := range members {
+			if sig, err := ParseSignature(s); err == nil {
+				sigs[member] = sig
+			}
+		}
+		modules[name] = sigs
+	}
+	return &Checker{
+		file:    file,
+		modules: modules,
+		scope:   &checkScope{vars: make(map[string]*checkVar)},
+	}
+}
+
+// Check checks the node and returns all type errors found.
+func (c *Checker) Check(node parser.Node) []error {
+	c.check(node)
+	return c.errors
+}
+
+func (c *Checker) check(node parser.Node) {
+	switch node := node.(type) {
+	case *parser.File:
+		for _, stmt := range node.Stmts {
+			c.check(stmt)
+		}
+	case *parser.BlockStmt:
+		c.openScope()
+		for _, stmt := range node.Stmts {
+			c.check(stmt)
+		}
+		c.closeScope()
+	case *parser.ExprStmt:
+		c.infer(node.Expr)
+	case *parser.IncDecStmt:
+		c.infer(node.Expr)
+	case *parser.AssignStmt:
+		c.checkAssign(node)
+	case *parser.FuncStmt:
+		v := c.define(node.Ident)
+		v.typ = "fn"
+		v.params, v.sig = c.funcSignature(node.Expr.Type)
+		c.checkFunc(node.Expr)
+	case *parser.ImportStmt:
+		v := c.define(node.Ident)
+		v.module = node.Expr.ModuleName
+	case *parser.FromImportStmt:
+		for _, name := range node.Names {
+			v := c.define(name.Var())
+			if sig := c.modules[node.Expr.ModuleName][name.Name.Name]; sig != nil {
+				v.typ = "fn"
+				v.sig = sig
+			}
+		}
+	case *parser.ExportDeclStmt:
+		c.check(node.Decl)
+	case *parser.IfStmt:
+		c.openScope()
+		if node.Init != nil {
+			c.check(node.Init)
+		}
+		c.infer(node.Cond)
+		c.check(node.Body)
+		if node.Else != nil {
+			c.check(node.Else)
+		}
+		c.closeScope()
+	case *parser.ForStmt:
+		c.openScope()
+		if node.Init != nil {
+			c.check(node.Init)
+		}
+		if node.Cond != nil {
+			c.infer(node.Cond)
+		}
+		if node.Post != nil {
+			c.check(node.Post)
+		}
+		c.check(node.Body)
+		c.closeScope()
+	case *parser.ForInStmt:
+		c.infer(node.Iterable)
+		c.openScope()
+		if node.Key != nil && node.Key.Name != "_" {
+			c.define(node.Key)
+		}
+		if node.Value != nil && node.Value.Name != "_" {
+			c.define(node.Value)
+		}
+		c.check(node.Body)
+		c.closeScope()
+	case *parser.ReturnStmt:
+		typ := "null"
+		if node.Result != nil {
+			typ = c.infer(node.Result)
+		}
+		if len(c.results) > 0 {
+			result := c.results[len(c.results)-1]
+			if !typesCompatible(typ, result) {
+				c.errorf(node, "invalid return type: expected %s, found %s",
+					result, typ)
+			}
+		}
+	case *parser.ExportStmt:
+		c.infer(node.Result)
+	}
+}
+
+func (c *Checker) checkAssign(node *parser.AssignStmt) {
+	if len(node.LHS) != 1 || len(node.RHS) != 1 {
+		for _, expr := range node.RHS {
+			c.infer(expr)
+		}
+		return
+	}
+	ident, ok := node.LHS[0].(*parser.Ident)
+	if !ok {
+		c.infer(node.LHS[0])
+		c.infer(node.RHS[0])
+		return
+	}
+
+	if node.Token == token.Define {
+		v := &checkVar{}
+		if ident.Type != nil {
+			c.checkTypeExpr(ident.Type)
+			v.declared = ident.Type.String()
+		}
+		// functions can refer to themselves, so define the variable
+		// before checking the function body.
+		if fn, ok := node.RHS[0].(*parser.FuncLit); ok {
+			v.params, v.sig = c.funcSignature(fn.Type)
+			c.scope.vars[ident.Name] = v
+		}
+		if imp, ok := node.RHS[0].(*parser.ImportExpr); ok {
+			v.module = imp.ModuleName
+		}
+		v.typ = c.infer(node.RHS[0])
+		// "var x: int" declares x without a value
+		_, isNull := node.RHS[0].(*parser.NullLit)
+		if !isNull && !typesCompatible(v.typ, v.declared) {
+			c.errorf(node, "cannot assign %s to '%s' (type %s)",
+				v.typ, ident.Name, v.declared)
+		}
+		c.scope.vars[ident.Name] = v
+		return
+	}
+
+	typ := c.infer(node.RHS[0])
+	v := c.scope.lookup(ident.Name)
+	if v == nil || node.Token != token.Assign {
+		return
+	}
+	if !typesCompatible(typ, v.declared) {
+		c.errorf(node, "cannot assign %s to '%s' (type %s)",
+			typ, ident.Name, v.declared)
+	}
+	switch {
+	case v.declared != "":
+	case c.scope.vars[ident.Name] == v:
+		// the code following the assignment in the block of the variable
+		// sees the new value
+		v.typ = typ
+	case v.typ != typ:
+		// the assignment in a nested block may not run, so the type is not
+		// known after the block
+		v.typ = ""
+	}
+}
+
+func (c *Checker) checkFunc(fn *parser.FuncLit) {
+	c.openScope()
+	for _, param := range fn.Type.Params.List {
+		c.define(param)
+	}
+	result := ""
+	if fn.Type.Result != nil {
+		c.checkTypeExpr(fn.Type.Result)
+		result = fn.Type.Result.String()
+	}
+	c.results = append(c.results, result)
+	for _, stmt := range fn.Body.Stmts {
+		c.check(stmt)
+	}
+	c.results = c.results[:len(c.results)-1]
+	c.closeScope()
+}
+
+// infer checks the expression and returns its type, or "" if the type cannot
+// be known statically.
+func (c *Checker) infer(expr parser.Expr) string {
+	switch expr := expr.(type) {
+	case *parser.IntLit:
+		return "int"
+	case *parser.FloatLit:
+		return "float"
+	case *parser.BigIntLit:
+		return "bigint"
+	case *parser.BigFloatLit:
+		return "bigfloat"
+	case *parser.ComplexLit:
+		return "complex"
+	case *parser.StringLit:
+		return "string"
+	case *parser.EmbedExpr:
+		switch {
+		case expr.Mode == parser.EmbedBytes:
+			return "bytes"
+		case expr.Mode == parser.EmbedDir:
+			return "map"
+		case expr.Mode == "" && embedMayBePattern(expr):
+			// a pattern unless a file has the path
+			return "map|string"
+		case expr.Mode == "":
+			return "string"
+		}
+	case *parser.CharLit:
+		return "char"
+	case *parser.BoolLit:
+		return "bool"
+	case *parser.NullLit:
+		return "null"
+	case *parser.ArrayLit:
+		for _, elem := range expr.Elements {
+			c.infer(elem)
+		}
+		return "array"
+	case *parser.MapLit:
+		for _, elem := range expr.Elements {
+			c.infer(elem.Value)
+		}
+		return "map"
+	case *parser.ErrorExpr:
+		c.infer(expr.Expr)
+		return "error"
+	case *parser.ImmutableExpr:
+		return c.infer(expr.Expr)
+	case *parser.ParenExpr:
+		return c.infer(expr.Expr)
+	case *parser.FuncLit:
+		c.checkFunc(expr)
+		return "fn"
+	case *parser.Ident:
+		if v := c.scope.lookup(expr.Name); v != nil {
+			return v.Type()
+		}
+	case *parser.UnaryExpr:
+		typ := c.infer(expr.Expr)
+		switch expr.Token {
+		case token.Not:
+			return "bool"
+		case token.Sub, token.Add:
+			if typ == "int" || typ == "float" {
+				return typ
+			}
+		}
+	case *parser.BinaryExpr:
+		return c.inferBinary(expr)
+	case *parser.CondExpr:
+		c.infer(expr.Cond)
+		t, f := c.infer(expr.True), c.infer(expr.False)
+		if t == f {
+			return t
+		}
+	case *parser.CallExpr:
+		return c.checkCall(expr)
+	case *parser.IndexExpr:
+		c.infer(expr.Expr)
+		c.infer(expr.Index)
+	case *parser.SliceExpr:
+		typ := c.infer(expr.Expr)
+		if expr.Low != nil {
+			c.infer(expr.Low)
+		}
+		if expr.High != nil {
+			c.infer(expr.High)
+		}
+		switch typ {
+		case "string", "bytes", "array":
+			return typ
+		}
+	case *parser.SelectorExpr:
+		c.infer(expr.Expr)
+	}
+	return ""
+}
+
+func (c *Checker) inferBinary(expr *parser.BinaryExpr) string {
+	lhs, rhs := c.infer(expr.LHS), c.infer(expr.RHS)
+	switch expr.Token {
+	case token.Equal, token.NotEqual, token.Less, token.Greater,
+		token.LessEq, token.GreaterEq, token.LAnd, token.LOr:
+		return "bool"
+	case token.Add:
+		if lhs == "string" {
+			return "string"
+		}
+		fallthrough
+	case token.Sub, token.Mul, token.Quo:
+		switch {
+		case lhs == "int" && rhs == "int":
+			return "int"
+		case (lhs == "int" || lhs == "float") &&
+			(rhs == "int" || rhs == "float"):
+			return "float"
+		}
+	}
+	return ""
+}
+
+func (c *Checker) checkCall(expr *parser.CallExpr) string {
+	var (
+		name   string
+		params []string
+		sig    *Signature
+	)
+	switch fn := expr.Func.(type) {
+	case *parser.Ident:
+		name = fn.Name
+		if v := c.scope.lookup(fn.Name); v != nil {
+			params, sig = v.params, v.sig
+		} else if s, ok := builtinSignatures[fn.Name]; ok {
+			sig, _ = ParseSignature(s)
+		}
+	case *parser.SelectorExpr:
+		c.infer(fn.Expr)
+		mod, ok1 := fn.Expr.(*parser.Ident)
+		sel, ok2 := fn.Sel.(*parser.StringLit)
+		if ok1 && ok2 {
+			name = mod.Name + "." + sel.Value
+			if v := c.scope.lookup(mod.Name); v != nil && v.module != "" {
+				sig = c.modules[v.module][sel.Value]
+			}
+		}
+	default:
+		c.infer(expr.Func)
+	}
+
+	args := make([]string, len(expr.Args))
+	for i, arg := range expr.Args {
+		args[i] = c.infer(arg)
+	}
+	if sig == nil {
+		return ""
+	}
+
+	if !expr.Ellipsis.IsValid() {
+		numParams := len(sig.Params)
+		if sig.VarArgs {
+			if len(args) < numParams-1 {
+				c.errorf(expr, "wrong number of arguments in call to '%s': want>=%d, got=%d",
+					name, numParams-1, len(args))
+				return resultType(sig)
+			}
+		} else if len(args) != numParams {
+			c.errorf(expr, "wrong number of arguments in call to '%s': want=%d, got=%d",
+				name, numParams, len(args))
+			return resultType(sig)
+		}
+		for i, typ := range args {
+			idx := i
+			if idx >= len(sig.Params) {
+				idx = len(sig.Params) - 1
+			}
+			if !typesCompatible(typ, sig.Params[idx]) {
+				argName := ordinal(i)
+				if idx < len(params) {
+					argName = params[idx]
+				}
+				c.errorf(expr.Args[i],
+					"invalid type for argument '%s' in call to '%s': expected %s, found %s",
+					argName, name, sig.Params[idx], typ)
+			}
+		}
+	}
+	return resultType(sig)
+}
+
+// funcSignature returns the parameter names and the signature of a function
+// type. Its type annotations are checked with the function by checkFunc.
+func (c *Checker) funcSignature(typ *parser.FuncType) ([]string, *Signature) {
+	sig := &Signature{Result: "any", VarArgs: typ.Params.VarArgs}
+	var names []string
+	for _, param := range typ.Params.List {
+		names = append(names, param.Name)
+		if param.Type != nil {
+			sig.Params = append(sig.Params, param.Type.String())
+		} else {
+			sig.Params = append(sig.Params, "any")
+		}
+	}
+	if typ.Result != nil {
+		sig.Result = typ.Result.String()
+	}
+	return names, sig
+}
+
+func (c *Checker) checkTypeExpr(typ *parser.TypeExpr) {
+	for _, name := range typ.Names {
+		if !IsKnownType(name) {
+			c.errorf(typ, "unknown type '%s'", name)
+		}
+	}
+}
+
+func (c *Checker) define(ident *parser.Ident) *checkVar {
+	v := &checkVar{}
+	if ident.Type != nil {
+		c.checkTypeExpr(ident.Type)
+		v.declared = ident.Type.String()
+	}
+	c.scope.vars[ident.Name] = v
+	return v
+}
+
+func (c *Checker) openScope() {
+	c.scope = &checkScope{parent: c.scope, vars: make(map[string]*checkVar)}
+}
+
+func (c *Checker) closeScope() {
+	c.scope = c.scope.parent
+}
+
+func (c *Checker) errorf(
+	node parser.Node,
+	format string,
+	args ...interface{},
+) {
+	c.errors = append(c.errors, &CheckError{
+		FileSet: c.file.Set(),
+		Node:    node,
+		Err:     fmt.Errorf(format, args...),
+	})
+}
+
+func resultType(sig *Signature) string {
+	if sig.Result == "any" {
+		return ""
+	}
+	return sig.Result
+}
+
+// typesCompatible returns true if a value of type actual may be used where
+// type expected is required. Unknown types are always compatible, and an int
+// may be used as a float.
+func typesCompatible(actual, expected string) bool {
+	if actual == "" || expected == "" {
+		return true
+	}
+	for _, a := range strings.Split(actual, "|") {
+		for _, e := range strings.Split(expected, "|") {
+			if a == e || a == "any" || e == "any" || !IsKnownType(e) ||
+				a == "int" && e == "float" {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func ordinal(i int) string {
+	names := []string{"first", "second", "third", "fourth", "fifth"}
+	if i < len(names) {
+		return names[i]
+	}
+	return fmt.Sprintf("#%d", i+1)
+}
@@ -0,0 +1,145 @@
+package tender
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/2dprototype/tender/parser"
+)
+
+func checkSource(t *testing.T, src string, signatures map[string]map[string]string) []string {
+	t.Helper()
+	fileSet := parser.NewFileSet()
+	srcFile := fileSet.AddFile("test", -1, len(src))
+	file, err := parser.NewParser(srcFile, []byte(src), nil).ParseFile()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var msgs []string
+	for _, err := range NewChecker(srcFile, signatures).Check(file) {
+		var cerr *CheckError
+		if !errors.As(err, &cerr) {
+			t.Fatalf("error %v, expected a check error", err)
+		}
+		msgs = append(msgs, cerr.Err.Error())
+	}
+	return msgs
+}
+
+func TestChecker(t *testing.T) {
+	signatures := map[string]map[string]string{
+		"geo": {
+			"area": "fn(int|float, int|float) -> float",
+			"sum":  "fn(...int) -> int",
+		},
+	}
+	tests := []struct {
+		src    string
+		errors []string
+	}{
+		// unannotated values are never reported
+		{`f := fn(a, b) { return a + b }; f(1, "s"); f("s", [])`, nil},
+
+		// annotated parameters
+		{`f := fn(a: int, b: string) { return b }; f(1, "s")`, nil},
+		{`f := fn(a: int, b: string) { return b }; f("1", "s")`,
+			[]string{"invalid type for argument 'a' in call to 'f': expected int, found string"}},
+		{`fn f(a: int|float) { return a }; f(1.5); f(true)`,
+			[]string{"invalid type for argument 'a' in call to 'f': expected int|float, found bool"}},
+		{`f := fn(a: int) { return a }; x := 1; f(x); f(y)`, nil},
+		{`f := fn(a: number) { return a }`, []string{"unknown type 'number'"}},
+
+		// annotated results
+		{`f := fn(a) -> int { return 1 }`, nil},
+		{`f := fn(a) -> int { return "s" }`,
+			[]string{"invalid return type: expected int, found string"}},
+		{`f := fn() -> string { return }`,
+			[]string{"invalid return type: expected string, found null"}},
+		{`f := fn() -> int { return 1 }; s: string := f()`,
+			[]string{"cannot assign int to 's' (type string)"}},
+		{`x: int := 1; x = "s"`, []string{"cannot assign string to 'x' (type int)"}},
+		{`fn area(w: float, h: float) -> float { return w * h }; area(2, 3); area(2.5, 1)`, nil},
+		{`f := fn() -> float { return 1 }; x: float := 2`, nil},
+		{`f := fn(a: int) { return a }; x: int := 1.5`,
+			[]string{"cannot assign float to 'x' (type int)"}},
+
+		// inferred types of reassigned variables
+		{`g := fn(a: int) { return a }; x := 1; x = "s"; g(x)`,
+			[]string{"invalid type for argument 'a' in call to 'g': expected int, found string"}},
+		{`g := fn(a: int) { return a }; x := 1; if true { x = "s" }; g(x)`, nil},
+		{`g := fn(a: int) { return a }; x := "s"; for i := 0; i < 2; i++ { x = 1 }; g(x)`, nil},
+		{`g := fn(a: int) { return a }; x := 1; if true { x = 2 }; g(x)`, nil},
+		{`g := fn(a: string) { return a }; x := 1; if true { x = 2 }; g(x)`,
+			[]string{"invalid type for argument 'a' in call to 'g': expected string, found int"}},
+		{`g := fn(a: int) { return a }; x := 1; h := fn() { x = "s" }; g(x)`, nil},
+
+		// embedded files; a path with pattern characters may name a file
+		{`b: bytes := embed bytes("a.txt"); m: map := embed dir("d")`, nil},
+		{`s: string := embed("a[1].txt"); m: map := embed("*.txt")`, nil},
+		{`x: int := embed("*.txt")`, []string{"cannot assign map|string to 'x' (type int)"}},
+
+		// arity of functions, builtins and module members
+		{`f := fn(a, b: int) { return a }; f(1)`,
+			[]string{"wrong number of arguments in call to 'f': want=2, got=1"}},
+		{`f := fn(a, ...b) { return a }; f(1, 2, 3); f()`,
+			[]string{"wrong number of arguments in call to 'f': want>=1, got=0"}},
+		{`len(1, 2)`, []string{"wrong number of arguments in call to 'len': want=1, got=2"}},
+		{`geo := import("geo"); geo.area(1, 2.5); geo.area(1)`,
+			[]string{"wrong number of arguments in call to 'geo.area': want=2, got=1"}},
+		{`geo := import("geo"); geo.area("1", 2)`,
+			[]string{"invalid type for argument 'first' in call to 'geo.area': expected int|float, found string"}},
+		{`geo := import("geo"); geo.sum(); geo.sum(1, 2, "3")`,
+			[]string{"invalid type for argument 'third' in call to 'geo.sum': expected int, found string"}},
+		{`from "geo" import area; area(1, 2, 3)`,
+			[]string{"wrong number of arguments in call to 'area': want=2, got=3"}},
+	}
+	for _, tc := range tests {
+		msgs := checkSource(t, tc.src, signatures)
+		if strings.Join(msgs, "\n") != strings.Join(tc.errors, "\n") {
+			t.Errorf("%s\nerrors %q, expected %q", tc.src, msgs, tc.errors)
+		}
+	}
+}
+
+func TestCheckTypeRuntime(t *testing.T) {
+	// without type checks, annotations are not checked at runtime
+	if _, err := NewScript([]byte(`f := fn(a: int) { return a }; out := f("s")`)).Run(); err != nil {
+		t.Errorf("error %v without type checks", err)
+	}
+
+	tests := []struct {
+		src string
+		err string // error of the call; or "" if it succeeds
+	}{
+		{`f := fn(a: int, b: string|null) { return a }; out := f(1, null)`, ""},
+		{`f := fn(a: int, b: string|null) { return a }; out := f(1, 2)`,
+			"invalid type for argument 'b': expected string|null, found int"},
+		{`f := fn(a: array) { return a }; out := f(immutable([1]))`, ""},
+		{`f := fn(g: fn) { return g() }; out := f(len)`,
+			"wrong number of arguments in call to 'builtin-function:len'"},
+		{`f := fn(g: fn) { return g }; out := f({})`,
+			"invalid type for argument 'g': expected fn, found map"},
+		// ints are converted for float parameters
+		{`area := fn(w: float, h: float) -> float { return w * h }; out := area(2, 3)
+if !is_float(out) || out != 6.0 { out = error(out) }`, ""},
+		{`f := fn(a: float) { return fn() { return a } }; out := f(2)()
+if !is_float(out) { out = error(out) }`, ""},
+		{`f := fn(a: int|float) { return a }; out := f(2)
+if !is_int(out) { out = error(out) }`, ""},
+		// captured parameters are checked through their free variable
+		{`f := fn(a: int) { return fn() { return a } }; out := f("s")`,
+			"invalid type for argument 'a': expected int, found string"},
+	}
+	for _, tc := range tests {
+		s := NewScript([]byte(tc.src))
+		s.EnableTypeChecks(true)
+		_, err := s.Run()
+		switch {
+		case tc.err == "" && err != nil:
+			t.Errorf("%s\nerror %v", tc.src, err)
+		case tc.err != "" && (err == nil || !strings.Contains(err.Error(), tc.err)):
+			t.Errorf("%s\nerror %v, expected %q", tc.src, err, tc.err)
+		}
+	}
+}
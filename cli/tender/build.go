@@ -0,0 +1,181 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+
+	"github.com/2dprototype/tender"
+	"github.com/2dprototype/tender/stdlib"
+)
+
+// buildMagic marks the end of an executable built by "tender build". The
+// compiled bytecode is appended to a copy of the tender binary, followed by
+// its size and buildMagic.
+const buildMagic = "TDRBUILD"
+
+const buildTrailerSize = 8 + len(buildMagic)
+
+// embeddedBytecode is the bytecode appended to the running executable, or
+// nil for the tender tool itself. It is read before init() so that flags are
+// not parsed for built executables.
+var embeddedBytecode, embeddedOffset = readEmbeddedBytecode()
+
+func readEmbeddedBytecode() ([]byte, int64) {
+	exe, err := os.Executable()
+	if err != nil {
+		return nil, -1
+	}
+	f, err := os.Open(exe)
+	if err != nil {
+		return nil, -1
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil || info.Size() < int64(buildTrailerSize) {
+		return nil, -1
+	}
+	trailer := make([]byte, buildTrailerSize)
+	if _, err := f.ReadAt(trailer, info.Size()-int64(buildTrailerSize)); err != nil {
+		return nil, -1
+	}
+	if string(trailer[8:]) != buildMagic {
+		return nil, -1
+	}
+	size := int64(binary.BigEndian.Uint64(trailer))
+	offset := info.Size() - int64(buildTrailerSize) - size
+	if size <= 0 || offset < 0 {
+		return nil, -1
+	}
+	data := make([]byte, size)
+	if _, err := f.ReadAt(data, offset); err != nil {
+		return nil, -1
+	}
+	return data, offset
+}
+
+// runEmbedded runs the bytecode appended to the executable. All command line
+// arguments are passed to the script.
+func runEmbedded() int {
+	modules := stdlib.GetModuleMap(stdlib.AllModuleNames()...)
+	bytecode := &tender.Bytecode{}
+	err := bytecode.Decode(bytes.NewReader(embeddedBytecode), modules)
+	if err == nil {
+		err = bytecode.Verify()
+	}
+	if err != nil {
+		printError(err.Error())
+		return 1
+	}
+
+	machine := tender.NewVM(bytecode, nil, -1)
+	machine.Args = os.Args
+	if err := machine.Run(); err != nil {
+		reportError(err)
+		return 1
+	}
+	return 0
+}
+
+// runBuild compiles a source file with its imported modules and embedded
+// files, and writes an executable that runs it.
+func runBuild(args []string) int {
+	fs := flag.NewFlagSet("build", flag.ContinueOnError)
+	output := fs.String("o", "", "Output executable file")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if fs.NArg() != 1 {
+		printError("usage: tender build [-o output] {input-file}")
+		return 2
+	}
+
+	inputFile := fs.Arg(0)
+	outputFile := *output
+	if outputFile == "" {
+		outputFile = basename(inputFile)
+	}
+	if runtime.GOOS == "windows" && filepath.Ext(outputFile) != ".exe" {
+		outputFile += ".exe"
+	}
+
+	if err := buildExecutable(inputFile, outputFile); err != nil {
+		printError(err.Error())
+		return 1
+	}
+	fmt.Println(outputFile)
+	return 0
+}
+
+func buildExecutable(inputFile, outputFile string) error {
+	data, err := ioutil.ReadFile(inputFile)
+	if err != nil {
+		return err
+	}
+	inputFile, err = filepath.Abs(inputFile)
+	if err != nil {
+		return err
+	}
+	if len(data) > 1 && string(data[:2]) == "#!" {
+		copy(data, "//")
+	}
+
+	modules := stdlib.GetModuleMap(stdlib.AllModuleNames()...)
+	bytecode, err := compileSrc(modules, data, inputFile, nil)
+	if err != nil {
+		return err
+	}
+	var payload bytes.Buffer
+	if err := bytecode.EncodeCompressed(&payload); err != nil {
+		return err
+	}
+
+	exe, err := os.Executable()
+	if err != nil {
+		return err
+	}
+	in, err := os.Open(exe)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.OpenFile(outputFile,
+		os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0755)
+	if err != nil {
+		return err
+	}
+
+	var src io.Reader = in
+	if embeddedOffset >= 0 {
+		// never copy the bytecode of a built executable
+		src = io.LimitReader(in, embeddedOffset)
+	}
+	trailer := make([]byte, 8, buildTrailerSize)
+	binary.BigEndian.PutUint64(trailer, uint64(payload.Len()))
+	trailer = append(trailer, buildMagic...)
+
+	_, err = io.Copy(out, src)
+	if err == nil {
+		_, err = out.Write(payload.Bytes())
+	}
+	if err == nil {
+		_, err = out.Write(trailer)
+	}
+	if cerr := out.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		_ = os.Remove(outputFile)
+		return errors.New("cannot write executable: " + err.Error())
+	}
+	return nil
+}
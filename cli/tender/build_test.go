@@ -0,0 +1,78 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+)
+
+// TestMain runs the bytecode of an executable built by the tests from the
+// test binary, like a built executable runs it.
+func TestMain(m *testing.M) {
+	if embeddedBytecode != nil {
+		os.Exit(runEmbedded())
+	}
+	os.Exit(m.Run())
+}
+
+// writeFiles writes files with their contents in dir.
+func writeFiles(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestBuild(t *testing.T) {
+	t.Setenv("TENDER_CACHE", "off")
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"src/main.td": `#!/usr/bin/env tender
+os := import("os")
+util := import("./lib/util")
+greeting := embed("data/greeting.txt")
+println(util.shout(greeting), os.args()[1:])`,
+		"src/lib/util.td":          `export { shout: fn(s) { return s + "!" } }`,
+		"src/data/greeting.txt":    "hello",
+		"elsewhere/data/greet.txt": "not this one",
+	})
+
+	exe := filepath.Join(dir, "elsewhere", "app")
+	if err := buildExecutable(filepath.Join(dir, "src", "main.td"), exe); err != nil {
+		t.Fatal(err)
+	}
+	// the sources are not needed to run the executable
+	if err := os.RemoveAll(filepath.Join(dir, "src")); err != nil {
+		t.Fatal(err)
+	}
+
+	var stdout, stderr bytes.Buffer
+	cmd := exec.Command(exe, "a", "-b", "--c=d")
+	cmd.Dir = filepath.Join(dir, "elsewhere")
+	cmd.Stdout, cmd.Stderr = &stdout, &stderr
+	if err := cmd.Run(); err != nil {
+		t.Fatalf("%v: %s", err, stderr.String())
+	}
+	expected := `hello! ["a", "-b", "--c=d"]` + "\n"
+	if stdout.String() != expected {
+		t.Errorf("output %q, expected %q", stdout.String(), expected)
+	}
+
+	// compile errors are returned and no executable is written
+	writeFiles(t, dir, map[string]string{"bad.td": `x := import("./missing")`})
+	bad := filepath.Join(dir, "bad")
+	if err := buildExecutable(filepath.Join(dir, "bad.td"), bad); err == nil {
+		t.Error("no error for a missing module")
+	}
+	if _, err := os.Stat(bad); !os.IsNotExist(err) {
+		t.Errorf("executable written for a compile error: %v", err)
+	}
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/2dprototype/tender"
+)
+
+// runCache manages the cache of compiled modules.
+func runCache(args []string) int {
+	if len(args) != 1 {
+		printCacheUsage()
+		return 2
+	}
+	dir, err := tender.DefaultModuleCacheDir()
+	if err != nil {
+		printError(err.Error())
+		return 1
+	}
+	switch args[0] {
+	case "clean":
+		if dir == "" {
+			return 0
+		}
+		if err := tender.CleanModuleCache(dir); err != nil {
+			printError(err.Error())
+			return 1
+		}
+		fmt.Printf("removed the compiled modules in %s\n", dir)
+	case "dir":
+		fmt.Println(dir)
+	default:
+		printCacheUsage()
+		return 2
+	}
+	return 0
+}
+
+// moduleCacheDir returns the directory of the module cache, or an empty
+// string if it is disabled or unavailable.
+func moduleCacheDir() string {
+	dir, err := tender.DefaultModuleCacheDir()
+	if err != nil {
+		return ""
+	}
+	return dir
+}
+
+func printCacheUsage() {
+	printError("usage: tender cache clean\n" +
+		"       tender cache dir")
+}
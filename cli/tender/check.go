@@ -0,0 +1,58 @@
+package main
+
+import (
+	"io/ioutil"
+	"path/filepath"
+
+	"github.com/2dprototype/tender"
+	"github.com/2dprototype/tender/parser"
+	"github.com/2dprototype/tender/stdlib"
+)
+
+// runCheck checks the type annotations of the given source files and prints
+// all errors found.
+func runCheck(args []string) int {
+	if len(args) == 0 {
+		printError("usage: tender check {input-file}...")
+		return 2
+	}
+
+	failed := false
+	for _, inputFile := range args {
+		errs, err := checkFile(inputFile)
+		if err != nil {
+			printError(err.Error())
+			failed = true
+			continue
+		}
+		for _, err := range errs {
+			printError(err.Error())
+			failed = true
+		}
+	}
+	if failed {
+		return 1
+	}
+	return 0
+}
+
+func checkFile(inputFile string) ([]error, error) {
+	src, err := ioutil.ReadFile(inputFile)
+	if err != nil {
+		return nil, err
+	}
+	if len(src) > 1 && string(src[:2]) == "#!" {
+		copy(src, "//")
+	}
+
+	fileSet := parser.NewFileSet()
+	srcFile := fileSet.AddFile(filepath.Base(inputFile), -1, len(src))
+	p := parser.NewParser(srcFile, src, nil)
+	file, err := p.ParseFile()
+	if err != nil {
+		return nil, err
+	}
+
+	checker := tender.NewChecker(srcFile, stdlib.ModuleSignatures)
+	return checker.Check(file), nil
+}
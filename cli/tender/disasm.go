@@ -0,0 +1,257 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/2dprototype/tender"
+	"github.com/2dprototype/tender/stdlib"
+)
+
+// runDisasm prints the disassembled bytecode of a source or compiled file, or
+// compares the bytecode of two files.
+func runDisasm(args []string) int {
+	fs := flag.NewFlagSet("disasm", flag.ContinueOnError)
+	asJSON := fs.Bool("json", false, "Print JSON output")
+	diff := fs.Bool("diff", false, "Compare the bytecode of two files")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+
+	if *diff {
+		if fs.NArg() != 2 {
+			printError("usage: tender disasm -diff {old-file} {new-file}")
+			return 2
+		}
+		return disasmDiff(os.Stdout, fs.Arg(0), fs.Arg(1))
+	}
+
+	if fs.NArg() != 1 {
+		printError("usage: tender disasm [-json] {input-file}")
+		return 2
+	}
+	inputFile := fs.Arg(0)
+	fns, err := disassembleFile(inputFile)
+	if err != nil {
+		printError(err.Error())
+		return 1
+	}
+
+	if *asJSON {
+		if err := printDisasmJSON(os.Stdout, fns); err != nil {
+			printError(err.Error())
+			return 1
+		}
+		return 0
+	}
+
+	printDisasm(os.Stdout, fns, newSourceLines(filepath.Dir(inputFile)))
+	return 0
+}
+
+// printDisasm prints disassembled functions, with the source lines of their
+// instructions if sources is not nil.
+func printDisasm(w io.Writer, fns []*tender.DisasmFunction, sources *sourceLines) {
+	for _, fn := range fns {
+		fmt.Fprintf(w, "== %s (locals=%d, params=%d, varargs=%t) ==\n",
+			fn.Name, fn.NumLocals, fn.NumParameters, fn.VarArgs)
+		lastSource := ""
+		for _, ins := range fn.Instructions {
+			if sources != nil && ins.Source != "" && ins.Line > 0 {
+				source := ins.Source[:strings.LastIndexByte(ins.Source, ':')]
+				if source != lastSource {
+					lastSource = source
+					fmt.Fprintf(w, "     | %s  %s\n", source, sources.line(ins.Source, ins.Line))
+				}
+			}
+			fmt.Fprintln(w, ins.String())
+		}
+		fmt.Fprintln(w)
+	}
+}
+
+// printDisasmJSON prints disassembled functions as an indented JSON array.
+func printDisasmJSON(w io.Writer, fns []*tender.DisasmFunction) error {
+	out, err := json.MarshalIndent(fns, "", "\t")
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintln(w, string(out))
+	return err
+}
+
+// disassembleFile compiles a source file, or decodes a compiled file, and
+// disassembles it.
+func disassembleFile(inputFile string) ([]*tender.DisasmFunction, error) {
+	data, err := ioutil.ReadFile(inputFile)
+	if err != nil {
+		return nil, err
+	}
+	inputFile, err = filepath.Abs(inputFile)
+	if err != nil {
+		return nil, err
+	}
+
+	modules := stdlib.GetModuleMap(stdlib.AllModuleNames()...)
+	var bytecode *tender.Bytecode
+	if filepath.Ext(inputFile) == sourceFileExt {
+		if len(data) > 1 && string(data[:2]) == "#!" {
+			copy(data, "//")
+		}
+		bytecode, err = compileSrc(modules, data, inputFile, nil)
+	} else {
+		bytecode = &tender.Bytecode{}
+		err = bytecode.Decode(bytes.NewReader(data), modules)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return bytecode.Disassemble(), nil
+}
+
+// disasmDiff prints the instructions that differ between two builds. Offsets
+// are not compared, so an inserted instruction shows up only once.
+func disasmDiff(w io.Writer, oldFile, newFile string) int {
+	oldFns, err := disassembleFile(oldFile)
+	if err != nil {
+		printError(err.Error())
+		return 2
+	}
+	newFns, err := disassembleFile(newFile)
+	if err != nil {
+		printError(err.Error())
+		return 2
+	}
+
+	changed := false
+	n := len(oldFns)
+	if len(newFns) > n {
+		n = len(newFns)
+	}
+	for i := 0; i < n; i++ {
+		var a, b []string
+		name := ""
+		if i < len(oldFns) {
+			a = diffLines(oldFns[i])
+			name = oldFns[i].Name
+		}
+		if i < len(newFns) {
+			b = diffLines(newFns[i])
+			name = newFns[i].Name
+		}
+		lines := diffSlices(a, b)
+		if lines == nil {
+			continue
+		}
+		changed = true
+		fmt.Fprintf(w, "== %s ==\n", name)
+		for _, l := range lines {
+			fmt.Fprintln(w, l)
+		}
+		fmt.Fprintln(w)
+	}
+	if changed {
+		return 1
+	}
+	return 0
+}
+
+func diffLines(fn *tender.DisasmFunction) []string {
+	lines := make([]string, len(fn.Instructions))
+	for i, ins := range fn.Instructions {
+		s := fmt.Sprintf("%-7s", ins.Opcode)
+		for _, o := range ins.Operands {
+			s += fmt.Sprintf(" %-5d", o)
+		}
+		if ins.Comment != "" {
+			s += " ; " + ins.Comment
+		}
+		lines[i] = s
+	}
+	return lines
+}
+
+// diffSlices returns the lines of a and b prefixed with "-", "+" or " " based
+// on their longest common subsequence, or nil if a and b are equal.
+func diffSlices(a, b []string) []string {
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else if lcs[i+1][j] >= lcs[i][j+1] {
+				lcs[i][j] = lcs[i+1][j]
+			} else {
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
+	}
+
+	var out []string
+	changed := false
+	i, j := 0, 0
+	for i < len(a) || j < len(b) {
+		switch {
+		case i < len(a) && j < len(b) && a[i] == b[j]:
+			out = append(out, "  "+a[i])
+			i++
+			j++
+		case i < len(a) && (j == len(b) || lcs[i+1][j] >= lcs[i][j+1]):
+			out = append(out, "- "+a[i])
+			i++
+			changed = true
+		default:
+			out = append(out, "+ "+b[j])
+			j++
+			changed = true
+		}
+	}
+	if !changed {
+		return nil
+	}
+	return out
+}
+
+// sourceLines reads source lines of the files referenced by the source map.
+type sourceLines struct {
+	dir   string
+	files map[string][]string
+}
+
+func newSourceLines(dir string) *sourceLines {
+	return &sourceLines{dir: dir, files: make(map[string][]string)}
+}
+
+func (s *sourceLines) line(pos string, line int) string {
+	name := pos
+	for i := 0; i < 2; i++ {
+		if idx := strings.LastIndexByte(name, ':'); idx > 0 {
+			name = name[:idx]
+		}
+	}
+	lines, ok := s.files[name]
+	if !ok {
+		path := name
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(s.dir, name)
+		}
+		if data, err := ioutil.ReadFile(path); err == nil {
+			lines = strings.Split(string(data), "\n")
+		}
+		s.files[name] = lines
+	}
+	if line < 1 || line > len(lines) {
+		return ""
+	}
+	return strings.TrimSpace(lines[line-1])
+}
@@ -0,0 +1,125 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/2dprototype/tender/stdlib"
+)
+
+var updateGolden = flag.Bool("update", false,
+	"rewrite the golden files of the disasm tests")
+
+const disasmDir = "testdata/disasm"
+
+// checkGolden compares the output with the golden file, or rewrites the file
+// with -update.
+func checkGolden(t *testing.T, name, out string) {
+	t.Helper()
+	path := filepath.Join(disasmDir, name)
+	if *updateGolden {
+		if err := os.WriteFile(path, []byte(out), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out != string(want) {
+		t.Errorf("output\n%s\nexpected (%s)\n%s", out, path, want)
+	}
+}
+
+func TestDisasm(t *testing.T) {
+	input := filepath.Join(disasmDir, "basic.td")
+	fns, err := disassembleFile(input)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var text bytes.Buffer
+	printDisasm(&text, fns, newSourceLines(disasmDir))
+	checkGolden(t, "basic.txt", text.String())
+
+	var js bytes.Buffer
+	if err := printDisasmJSON(&js, fns); err != nil {
+		t.Fatal(err)
+	}
+	checkGolden(t, "basic.json", js.String())
+
+	// a compiled file has the same instructions and source positions
+	data, err := os.ReadFile(input)
+	if err != nil {
+		t.Fatal(err)
+	}
+	modules := stdlib.GetModuleMap(stdlib.AllModuleNames()...)
+	bytecode, err := compileSrc(modules, data, input, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	compiled := filepath.Join(t.TempDir(), "basic.tdo")
+	f, err := os.Create(compiled)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := bytecode.Encode(f); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.Close(); err != nil {
+		t.Fatal(err)
+	}
+	fns, err = disassembleFile(compiled)
+	if err != nil {
+		t.Fatal(err)
+	}
+	js.Reset()
+	if err := printDisasmJSON(&js, fns); err != nil {
+		t.Fatal(err)
+	}
+	checkGolden(t, "basic.json", js.String())
+}
+
+func TestDisasmDiff(t *testing.T) {
+	var out bytes.Buffer
+	oldFile := filepath.Join(disasmDir, "old.td")
+	newFile := filepath.Join(disasmDir, "new.td")
+	if code := disasmDiff(&out, oldFile, newFile); code != 1 {
+		t.Errorf("exit code %d, expected 1", code)
+	}
+	checkGolden(t, "old_new.diff", out.String())
+
+	out.Reset()
+	if code := disasmDiff(&out, oldFile, oldFile); code != 0 || out.Len() != 0 {
+		t.Errorf("exit code %d with output %q for the same file", code, out.String())
+	}
+	if code := disasmDiff(&out, oldFile, filepath.Join(disasmDir, "none.td")); code != 2 {
+		t.Errorf("exit code %d, expected 2 for a missing file", code)
+	}
+}
+
+func TestDiffSlices(t *testing.T) {
+	tests := []struct {
+		a, b     string
+		expected string
+	}{
+		{"a b c", "a b c", ""},
+		{"", "", ""},
+		{"", "a", "+ a"},
+		{"a", "", "- a"},
+		{"a b c", "a x c", "  a,- b,+ x,  c"},
+		{"a b c d", "a c d e", "  a,- b,  c,  d,+ e"},
+		{"x a b", "a b y", "- x,  a,  b,+ y"},
+		{"a b a b", "b a b a", "- a,  b,  a,  b,+ a"},
+	}
+	for _, tc := range tests {
+		got := strings.Join(diffSlices(strings.Fields(tc.a), strings.Fields(tc.b)), ",")
+		if got != tc.expected {
+			t.Errorf("diffSlices(%q, %q) = %q, expected %q", tc.a, tc.b, got, tc.expected)
+		}
+	}
+}
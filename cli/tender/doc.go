@@ -0,0 +1,198 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"html"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/2dprototype/tender"
+	"github.com/2dprototype/tender/stdlib"
+)
+
+// runDoc prints the documentation of source modules, builtin modules and the
+// builtin functions as Markdown or HTML.
+func runDoc(args []string) int {
+	fs := flag.NewFlagSet("doc", flag.ContinueOnError)
+	asHTML := fs.Bool("html", false, "write HTML instead of Markdown")
+	outDir := fs.String("o", "", "write a file for each module in the directory")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if fs.NArg() == 0 {
+		printError("usage: tender doc [-html] [-o dir] {file.td | dir | module | builtins | stdlib}...")
+		return 2
+	}
+
+	var mods []*tender.ModuleDoc
+	for _, arg := range fs.Args() {
+		docs, err := moduleDocs(arg)
+		if err != nil {
+			printError(err.Error())
+			return 1
+		}
+		mods = append(mods, docs...)
+	}
+
+	render, ext := renderMarkdown, ".md"
+	if *asHTML {
+		render, ext = renderHTML, ".html"
+	}
+	if *outDir == "" {
+		for i, mod := range mods {
+			if i > 0 && !*asHTML {
+				fmt.Println()
+			}
+			os.Stdout.Write(render(mod))
+		}
+		return 0
+	}
+	if err := os.MkdirAll(*outDir, 0755); err != nil {
+		printError(err.Error())
+		return 1
+	}
+	for _, mod := range mods {
+		path := filepath.Join(*outDir, basename(mod.Name)+ext)
+		if err := ioutil.WriteFile(path, render(mod), 0644); err != nil {
+			printError(err.Error())
+			return 1
+		}
+	}
+	return 0
+}
+
+// moduleDocs returns the documentation of a source file, of the source files
+// in a directory, of a builtin module, of the builtin functions with
+// "builtins", or of all the builtin modules with "stdlib".
+func moduleDocs(arg string) ([]*tender.ModuleDoc, error) {
+	switch {
+	case arg == "builtins":
+		attrs := make(map[string]tender.Object)
+		for _, fn := range tender.GetAllBuiltinFunctions() {
+			attrs[fn.Name] = fn
+		}
+		return []*tender.ModuleDoc{tender.BuiltinModuleDoc("builtins",
+			"The builtin functions are available in every script without importing them.",
+			attrs)}, nil
+	case arg == "stdlib":
+		names := stdlib.AllModuleNames()
+		sort.Strings(names)
+		var mods []*tender.ModuleDoc
+		for _, name := range names {
+			mods = append(mods, tender.BuiltinModuleDoc(name, stdlib.ModuleDocs[name],
+				stdlib.BuiltinModules[name]))
+		}
+		return mods, nil
+	case stdlib.BuiltinModules[arg] != nil:
+		return []*tender.ModuleDoc{tender.BuiltinModuleDoc(arg, stdlib.ModuleDocs[arg],
+			stdlib.BuiltinModules[arg])}, nil
+	}
+
+	info, err := os.Stat(arg)
+	if err != nil {
+		return nil, err
+	}
+	if !info.IsDir() {
+		mod, err := sourceModuleDoc(arg)
+		if err != nil {
+			return nil, err
+		}
+		return []*tender.ModuleDoc{mod}, nil
+	}
+	files, err := filepath.Glob(filepath.Join(arg, "*"+sourceFileExt))
+	if err != nil {
+		return nil, err
+	}
+	var mods []*tender.ModuleDoc
+	for _, file := range files {
+		mod, err := sourceModuleDoc(file)
+		if err != nil {
+			return nil, err
+		}
+		mods = append(mods, mod)
+	}
+	return mods, nil
+}
+
+func sourceModuleDoc(path string) (*tender.ModuleDoc, error) {
+	src, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if len(src) > 1 && string(src[:2]) == "#!" {
+		copy(src, "//")
+	}
+	return tender.SourceModuleDoc(filepath.Base(path), src)
+}
+
+// renderMarkdown renders the documentation of a module as Markdown.
+func renderMarkdown(mod *tender.ModuleDoc) []byte {
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "# %s\n\n", basename(mod.Name))
+	if mod.Usage != "" {
+		fmt.Fprintf(&buf, "```\n%s\n```\n\n", mod.Usage)
+	}
+	if mod.Doc != "" {
+		fmt.Fprintf(&buf, "%s\n\n", mod.Doc)
+	}
+	for _, member := range mod.Members {
+		fmt.Fprintf(&buf, "## %s\n\n", member.Name)
+		if signature := memberSignature(member); signature != "" {
+			fmt.Fprintf(&buf, "```\n%s\n```\n\n", signature)
+		}
+		if member.Doc != "" {
+			fmt.Fprintf(&buf, "%s\n\n", member.Doc)
+		}
+	}
+	return append(bytes.TrimRight(buf.Bytes(), "\n"), '\n')
+}
+
+// renderHTML renders the documentation of a module as an HTML page.
+func renderHTML(mod *tender.ModuleDoc) []byte {
+	var buf bytes.Buffer
+	name := html.EscapeString(basename(mod.Name))
+	fmt.Fprintf(&buf, "<!DOCTYPE html>\n<html>\n<head>\n<meta charset=\"utf-8\">\n<title>%s</title>\n</head>\n<body>\n", name)
+	fmt.Fprintf(&buf, "<h1>%s</h1>\n", name)
+	if mod.Usage != "" {
+		fmt.Fprintf(&buf, "<pre>%s</pre>\n", html.EscapeString(mod.Usage))
+	}
+	writeHTMLParagraphs(&buf, mod.Doc)
+	for _, member := range mod.Members {
+		fmt.Fprintf(&buf, "<h2 id=\"%[1]s\">%[1]s</h2>\n", html.EscapeString(member.Name))
+		if signature := memberSignature(member); signature != "" {
+			fmt.Fprintf(&buf, "<pre>%s</pre>\n", html.EscapeString(signature))
+		}
+		writeHTMLParagraphs(&buf, member.Doc)
+	}
+	buf.WriteString("</body>\n</html>\n")
+	return buf.Bytes()
+}
+
+// writeHTMLParagraphs writes the paragraphs of a doc comment, separated by
+// empty lines.
+func writeHTMLParagraphs(buf *bytes.Buffer, doc string) {
+	for _, paragraph := range strings.Split(doc, "\n\n") {
+		if paragraph = strings.TrimSpace(paragraph); paragraph != "" {
+			fmt.Fprintf(buf, "<p>%s</p>\n", html.EscapeString(paragraph))
+		}
+	}
+}
+
+// memberSignature returns the usage of a function, or the value or type of
+// another member.
+func memberSignature(member *tender.MemberDoc) string {
+	switch {
+	case member.Usage != "":
+		return member.Usage
+	case member.Value != "":
+		return member.Name + " = " + member.Value
+	case member.Type != "":
+		return member.Name + " " + member.Type
+	}
+	return ""
+}
@@ -0,0 +1,327 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/charmbracelet/x/term"
+)
+
+// historySize is the number of lines kept in the history file.
+const historySize = 1000
+
+// errInterrupt is returned by readLine when the line is cancelled with
+// Ctrl-C.
+var errInterrupt = errors.New("interrupt")
+
+// lineEditor reads lines from a terminal with cursor movement, history and
+// tab completion, or plain lines from other inputs.
+type lineEditor struct {
+	out      io.Writer
+	reader   *bufio.Reader
+	fd       uintptr
+	terminal bool
+
+	history     []string
+	historyFile string
+
+	// complete returns the candidates to replace the word before the
+	// cursor with, and the offset of the word in the line.
+	complete func(line string) (candidates []string, start int)
+}
+
+func newLineEditor(in io.Reader, out io.Writer) *lineEditor {
+	e := &lineEditor{out: out, reader: bufio.NewReader(in)}
+	if f, ok := in.(*os.File); ok && term.IsTerminal(f.Fd()) {
+		e.fd = f.Fd()
+		e.terminal = true
+	}
+	return e
+}
+
+// loadHistory reads the history file, which lines are added to by
+// addHistory.
+func (e *lineEditor) loadHistory(path string) {
+	e.historyFile = path
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return
+	}
+	for _, line := range strings.Split(string(data), "\n") {
+		if line != "" {
+			e.history = append(e.history, line)
+		}
+	}
+	if len(e.history) > historySize {
+		e.history = e.history[len(e.history)-historySize:]
+		e.saveHistory()
+	}
+}
+
+// addHistory adds an entered line to the history and the history file.
+func (e *lineEditor) addHistory(line string) {
+	line = strings.TrimRight(line, "\n")
+	if strings.TrimSpace(line) == "" || strings.Contains(line, "\n") {
+		return
+	}
+	if n := len(e.history); n > 0 && e.history[n-1] == line {
+		return
+	}
+	e.history = append(e.history, line)
+	if e.historyFile == "" {
+		return
+	}
+	if len(e.history) > historySize {
+		e.history = e.history[len(e.history)-historySize:]
+		e.saveHistory()
+		return
+	}
+	f, err := os.OpenFile(e.historyFile, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return
+	}
+	_, _ = fmt.Fprintln(f, line)
+	_ = f.Close()
+}
+
+func (e *lineEditor) saveHistory() {
+	if e.historyFile == "" {
+		return
+	}
+	data := strings.Join(e.history, "\n") + "\n"
+	_ = os.MkdirAll(filepath.Dir(e.historyFile), 0755)
+	_ = os.WriteFile(e.historyFile, []byte(data), 0600)
+}
+
+// readLine prints the prompt and returns the line entered, without the line
+// ending. It returns io.EOF at the end of the input or when Ctrl-D is
+// pressed on an empty line, and errInterrupt when Ctrl-C is pressed.
+func (e *lineEditor) readLine(prompt string) (string, error) {
+	_, _ = fmt.Fprint(e.out, prompt)
+	if !e.terminal {
+		line, err := e.reader.ReadString('\n')
+		if err != nil && (err != io.EOF || line == "") {
+			return "", err
+		}
+		return strings.TrimRight(line, "\r\n"), nil
+	}
+
+	state, err := term.MakeRaw(e.fd)
+	if err != nil {
+		e.terminal = false
+		return e.readLine("")
+	}
+	defer func() { _ = term.Restore(e.fd, state) }()
+	return e.edit(prompt)
+}
+
+// edit reads keys in raw mode until the line is entered.
+func (e *lineEditor) edit(prompt string) (string, error) {
+	var line []rune
+	pos := 0
+	histIndex := len(e.history)
+	saved := ""
+
+	refresh := func() {
+		// the cursor is moved back from the end of the line
+		s := "\r" + prompt + string(line) + "\x1b[K"
+		if back := len(line) - pos; back > 0 {
+			s += fmt.Sprintf("\x1b[%dD", back)
+		}
+		_, _ = io.WriteString(e.out, s)
+	}
+	setLine := func(s string) {
+		line = []rune(s)
+		pos = len(line)
+		refresh()
+	}
+
+	for {
+		r, _, err := e.reader.ReadRune()
+		if err != nil {
+			return "", err
+		}
+		switch r {
+		case '\r', '\n':
+			_, _ = io.WriteString(e.out, "\r\n")
+			return string(line), nil
+		case 3: // Ctrl-C
+			_, _ = io.WriteString(e.out, "^C\r\n")
+			return "", errInterrupt
+		case 4: // Ctrl-D
+			if len(line) == 0 {
+				_, _ = io.WriteString(e.out, "\r\n")
+				return "", io.EOF
+			}
+			if pos < len(line) {
+				line = append(line[:pos], line[pos+1:]...)
+				refresh()
+			}
+		case 127, 8: // Backspace, Ctrl-H
+			if pos > 0 {
+				line = append(line[:pos-1], line[pos:]...)
+				pos--
+				refresh()
+			}
+		case 1: // Ctrl-A
+			pos = 0
+			refresh()
+		case 5: // Ctrl-E
+			pos = len(line)
+			refresh()
+		case 2: // Ctrl-B
+			if pos > 0 {
+				pos--
+				refresh()
+			}
+		case 6: // Ctrl-F
+			if pos < len(line) {
+				pos++
+				refresh()
+			}
+		case 11: // Ctrl-K
+			line = line[:pos]
+			refresh()
+		case 21: // Ctrl-U
+			line = line[pos:]
+			pos = 0
+			refresh()
+		case 23: // Ctrl-W
+			start := pos
+			for start > 0 && line[start-1] == ' ' {
+				start--
+			}
+			for start > 0 && line[start-1] != ' ' {
+				start--
+			}
+			line = append(line[:start], line[pos:]...)
+			pos = start
+			refresh()
+		case 12: // Ctrl-L
+			_, _ = io.WriteString(e.out, "\x1b[H\x1b[2J")
+			refresh()
+		case 16, 14: // Ctrl-P, Ctrl-N
+			histIndex, saved = e.moveHistory(histIndex, r == 16, string(line), saved, setLine)
+		case '\t':
+			e.completeLine(&line, &pos)
+			refresh()
+		case 27: // escape sequences
+			switch e.escapeSequence() {
+			case "[A", "OA":
+				histIndex, saved = e.moveHistory(histIndex, true, string(line), saved, setLine)
+			case "[B", "OB":
+				histIndex, saved = e.moveHistory(histIndex, false, string(line), saved, setLine)
+			case "[C", "OC":
+				if pos < len(line) {
+					pos++
+					refresh()
+				}
+			case "[D", "OD":
+				if pos > 0 {
+					pos--
+					refresh()
+				}
+			case "[H", "OH", "[1~", "[7~":
+				pos = 0
+				refresh()
+			case "[F", "OF", "[4~", "[8~":
+				pos = len(line)
+				refresh()
+			case "[3~":
+				if pos < len(line) {
+					line = append(line[:pos], line[pos+1:]...)
+					refresh()
+				}
+			}
+		default:
+			if unicode.IsPrint(r) {
+				line = append(line[:pos], append([]rune{r}, line[pos:]...)...)
+				pos++
+				refresh()
+			}
+		}
+	}
+}
+
+// escapeSequence reads the rest of an escape sequence, such as "[A" for the
+// up arrow key.
+func (e *lineEditor) escapeSequence() string {
+	r, _, err := e.reader.ReadRune()
+	if err != nil || (r != '[' && r != 'O') {
+		return ""
+	}
+	seq := []rune{r}
+	for {
+		r, _, err := e.reader.ReadRune()
+		if err != nil {
+			return ""
+		}
+		seq = append(seq, r)
+		if r >= 0x40 && r <= 0x7e && len(seq) > 1 {
+			return string(seq)
+		}
+	}
+}
+
+// moveHistory replaces the line with the previous or next history entry. The
+// line being edited is saved when leaving the end of the history.
+func (e *lineEditor) moveHistory(
+	index int,
+	up bool,
+	line, saved string,
+	setLine func(string),
+) (int, string) {
+	if index == len(e.history) {
+		saved = line
+	}
+	if up && index > 0 {
+		index--
+	} else if !up && index < len(e.history) {
+		index++
+	} else {
+		return index, saved
+	}
+	if index == len(e.history) {
+		setLine(saved)
+	} else {
+		setLine(e.history[index])
+	}
+	return index, saved
+}
+
+// completeLine completes the word before the cursor with the longest common
+// prefix of the candidates, and prints them if there are several.
+func (e *lineEditor) completeLine(line *[]rune, pos *int) {
+	if e.complete == nil {
+		return
+	}
+	before := string((*line)[:*pos])
+	candidates, start := e.complete(before)
+	if len(candidates) == 0 {
+		return
+	}
+	prefix := candidates[0]
+	for _, c := range candidates[1:] {
+		for !strings.HasPrefix(c, prefix) {
+			prefix = prefix[:len(prefix)-1]
+		}
+	}
+	word := before[start:]
+	if len(prefix) > len(word) {
+		completed := []rune(before[:start] + prefix)
+		*line = append(completed, (*line)[*pos:]...)
+		*pos = len(completed)
+		return
+	}
+	if len(candidates) > 1 {
+		sort.Strings(candidates)
+		_, _ = io.WriteString(e.out, "\r\n"+strings.Join(candidates, "  ")+"\r\n")
+	}
+}
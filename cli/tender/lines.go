@@ -0,0 +1,105 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/2dprototype/tender"
+)
+
+const (
+	evalInput  = "<eval>"
+	stdinInput = "<stdin>"
+)
+
+// readInput returns the program given with -e, read from stdin if inputFile
+// is "-", or read from inputFile, and the absolute path of its file. The
+// programs given with -e or read from stdin are named "<eval>" and "<stdin>"
+// in the current directory.
+func readInput(inputFile string) ([]byte, string, error) {
+	var data []byte
+	var err error
+	switch {
+	case evalSource != "":
+		data, inputFile = []byte(evalSource), evalInput
+	case inputFile == "-":
+		data, err = ioutil.ReadAll(os.Stdin)
+		inputFile = stdinInput
+	default:
+		data, err = ioutil.ReadFile(inputFile)
+	}
+	if err != nil {
+		return nil, "", err
+	}
+	inputFile, err = filepath.Abs(inputFile)
+	if err != nil {
+		return nil, "", err
+	}
+	return data, inputFile, nil
+}
+
+// isSourceInput returns true if the program was given with -e or read from
+// stdin.
+func isSourceInput(inputFile string) bool {
+	name := filepath.Base(inputFile)
+	return name == evalInput || name == stdinInput
+}
+
+// RunLines compiles the source code and executes it for each line read from
+// in, with the line bound to the global variable line. If print is true, the
+// line is written to out after each execution unless the program sets it to
+// null. The other global variables keep their values from line to line.
+func RunLines(
+	modules *tender.ModuleMap,
+	data []byte,
+	inputFile string,
+	in io.Reader,
+	out io.Writer,
+	print bool,
+) error {
+	symbolTable := tender.NewSymbolTable()
+	line := symbolTable.Define("line")
+	bytecode, err := compileSrc(modules, data, inputFile, symbolTable)
+	if err != nil {
+		return err
+	}
+
+	globals := make([]tender.Object, tender.GlobalsSize)
+	if bytecode.NumGlobals > len(globals) {
+		globals = make([]tender.Object, bytecode.NumGlobals)
+	}
+	w := bufio.NewWriter(out)
+	defer w.Flush()
+
+	reader := bufio.NewReader(in)
+	for {
+		s, err := reader.ReadString('\n')
+		if err != nil && (err != io.EOF || s == "") {
+			if err == io.EOF {
+				return nil
+			}
+			return err
+		}
+		s = strings.TrimSuffix(strings.TrimSuffix(s, "\n"), "\r")
+
+		globals[line.Index] = &tender.String{Value: s}
+		// output of the program is written after the printed lines
+		if err := w.Flush(); err != nil {
+			return err
+		}
+		if err := tender.NewVM(bytecode, globals, -1).Run(); err != nil {
+			return err
+		}
+		if print {
+			if v := globals[line.Index]; v != nil && v != tender.NullValue {
+				str, _ := tender.ToString(v)
+				fmt.Fprintln(w, str)
+			}
+		}
+	}
+}
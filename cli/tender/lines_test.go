@@ -0,0 +1,127 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/2dprototype/tender"
+)
+
+func TestRunLines(t *testing.T) {
+	tests := []struct {
+		name     string
+		src      string
+		input    string
+		print    bool
+		expected string   // written to out
+		emitted  []string // passed to emit
+	}{
+		{
+			name:     "-p",
+			src:      `line = line + "!"`,
+			input:    "a\nb\n",
+			print:    true,
+			expected: "a!\nb!\n",
+		},
+		{
+			name:     "-p unchanged",
+			src:      ``,
+			input:    "a\n\nb\n",
+			print:    true,
+			expected: "a\n\nb\n",
+		},
+		{
+			name:     "-n",
+			src:      `emit(line)`,
+			input:    "a\nb\n",
+			emitted:  []string{"a", "b"},
+			expected: "",
+		},
+		{
+			name:     "-n ignores line",
+			src:      `line = "x"`,
+			input:    "a\nb\n",
+			expected: "",
+		},
+		{
+			name:     "crlf",
+			src:      `emit(line); line = line + "!"`,
+			input:    "a\r\nb\r\n",
+			print:    true,
+			expected: "a!\nb!\n",
+			emitted:  []string{"a", "b"},
+		},
+		{
+			name:     "missing final newline",
+			src:      `emit(line)`,
+			input:    "a\nb",
+			print:    true,
+			expected: "a\nb\n",
+			emitted:  []string{"a", "b"},
+		},
+		{
+			name:     "null suppresses output",
+			src:      `if line == "b" { line = null }`,
+			input:    "a\nb\nc\n",
+			print:    true,
+			expected: "a\nc\n",
+		},
+		{
+			name:     "empty input",
+			src:      `emit(line)`,
+			input:    "",
+			print:    true,
+			expected: "",
+		},
+	}
+	for _, tc := range tests {
+		var emitted []string
+		modules := tender.NewModuleMap()
+		modules.AddBuiltinModule("test", map[string]tender.Object{
+			"emit": &tender.UserFunction{
+				Value: func(args ...tender.Object) (tender.Object, error) {
+					s, _ := tender.ToString(args[0])
+					emitted = append(emitted, s)
+					return tender.NullValue, nil
+				},
+			},
+		})
+		src := `emit := import("test").emit; ` + tc.src
+
+		var out bytes.Buffer
+		err := RunLines(modules, []byte(src), "lines.td",
+			strings.NewReader(tc.input), &out, tc.print)
+		if err != nil {
+			t.Errorf("%s: %v", tc.name, err)
+			continue
+		}
+		if out.String() != tc.expected {
+			t.Errorf("%s: output %q, expected %q", tc.name, out.String(), tc.expected)
+		}
+		if strings.Join(emitted, ",") != strings.Join(tc.emitted, ",") {
+			t.Errorf("%s: lines %q, expected %q", tc.name, emitted, tc.emitted)
+		}
+	}
+}
+
+func TestRunLinesErrors(t *testing.T) {
+	modules := tender.NewModuleMap()
+	var out bytes.Buffer
+	err := RunLines(modules, []byte(`line = line +`), "lines.td",
+		strings.NewReader("a\n"), &out, true)
+	if err == nil || !strings.Contains(err.Error(), "Parse Error") {
+		t.Errorf("expected a parse error, got %v", err)
+	}
+
+	// the lines before a runtime error are written
+	out.Reset()
+	err = RunLines(modules, []byte(`if line == "b" { line = line - 1 }`), "lines.td",
+		strings.NewReader("a\nb\nc\n"), &out, true)
+	if err == nil || !strings.Contains(err.Error(), "invalid operation") {
+		t.Errorf("expected a runtime error, got %v", err)
+	}
+	if out.String() != "a\n" {
+		t.Errorf("output %q, expected %q", out.String(), "a\n")
+	}
+}
@@ -1,18 +1,14 @@
 package main
 
 import (
-	"bufio"
 	"bytes"
 	"flag"
 	"fmt"
-	"io"
-	"io/ioutil"
 	"os"
 	"path/filepath"
 	"strings"
 	// "strconv"
 	"encoding/json"
-	"encoding/gob"
 	
 	_ "embed"
 
@@ -22,34 +18,6 @@ import (
 	"github.com/2dprototype/tender/v/colorable"
 )
 
-func init() {
-	gob.Register(&parser.SourceFileSet{})
-	gob.Register(&parser.SourceFile{})
-	gob.Register(&tender.Array{})
-	gob.Register(&tender.Bool{})
-	gob.Register(&tender.Bytes{})
-	gob.Register(&tender.Char{})
-	gob.Register(&tender.CompiledFunction{})
-	gob.Register(&tender.Error{})
-	gob.Register(&tender.Int{})
-	gob.Register(&tender.Float{})
-	gob.Register(&tender.BigInt{})
-	gob.Register(&tender.BigFloat{})
-	gob.Register(&tender.Complex{})
-	gob.Register(&tender.ImmutableArray{})
-	gob.Register(&tender.ImmutableMap{})
-	gob.Register(&tender.Map{})
-	gob.Register(&tender.String{})
-	gob.Register(&tender.Time{})
-	gob.Register(&tender.Null{})
-	gob.Register(&tender.UserFunction{})
-	gob.Register(&tender.BuiltinFunction{})
-	gob.Register(&stdlib.IOWriter{})
-	gob.Register(&stdlib.IOReader{})
-	// gob.Register(&colorable.writer{})
-	// gob.Register((*os.File)(nil))
-}
-
 const (
 	sourceFileExt = ".td"
 	replPrompt    = ">> "
@@ -61,9 +29,29 @@ var (
 	showHelp       bool
 	showVersion    bool
 	resolvePath    bool
+	typeCheck      bool
+	compress       bool
+	noOptimize     bool
+	registerVM     bool
+	evalSource     string
+	loopLines      bool
+	printLines     bool
+	errorFormat    string
 	// version       = "v1.0.0"
 )
 
+// commands are the subcommands of the tender tool. Each command receives the
+// remaining arguments and returns the exit code.
+var commands = map[string]func(args []string) int{
+	"build":  runBuild,
+	"cache":  runCache,
+	"check":  runCheck,
+	"disasm": runDisasm,
+	"doc":    runDoc,
+	"pkg":    runPkg,
+	"watch":  runWatch,
+}
+
 //go:embed version.txt
 var version string
 
@@ -73,16 +61,33 @@ func init() {
 	if isTerminal(os.Stdout) {
         isAnsiSupportedTerminal = true
     }
+	if embeddedBytecode != nil {
+		// built executables pass all arguments to the script
+		return
+	}
 	flag.BoolVar(&showHelp, "help", false, "Show help")
 	flag.StringVar(&compileOutput, "o", "", "Compile output file")
 	flag.StringVar(&parseOutput, "parse", "", "Parse output file")
 	flag.BoolVar(&showVersion, "version", false, "Show version")
 	flag.BoolVar(&showVersion, "v", false, "Show version")
 	flag.BoolVar(&resolvePath, "resolve", true, "Resolve relative import paths")
-	flag.Parse()
+	flag.BoolVar(&noOptimize, "O0", false, "Disable optimizations")
+	flag.BoolVar(&registerVM, "regvm", false, "Run on the experimental register VM")
+	flag.BoolVar(&compress, "compress", false, "Compress compiled output file")
+	flag.BoolVar(&typeCheck, "typecheck", false, "Check annotated argument types at runtime")
+	flag.StringVar(&evalSource, "e", "", "Run the program given as argument")
+	flag.BoolVar(&loopLines, "n", false, "Run the program for each line of stdin")
+	flag.BoolVar(&printLines, "p", false, "Run the program for each line of stdin and print it")
+	flag.StringVar(&errorFormat, "error-format", "text", "Format of the errors: text or json")
 }
 
 func main() {
+	tender.Version = strings.TrimSpace(version)
+	if embeddedBytecode != nil {
+		os.Exit(runEmbedded())
+	}
+	flag.Parse()
+
 	if showHelp {
 		doHelp()
 		os.Exit(2)
@@ -91,21 +96,28 @@ func main() {
 		return
 	}
 
+	if errorFormat != "text" && errorFormat != "json" {
+		printError("invalid -error-format " + errorFormat + ": use text or json")
+		os.Exit(2)
+	}
+
+	if cmd, ok := commands[flag.Arg(0)]; ok && evalSource == "" {
+		os.Exit(cmd(flag.Args()[1:]))
+	}
+
 	modules := stdlib.GetModuleMap(stdlib.AllModuleNames()...)
 	inputFile := flag.Arg(0)
-	if inputFile == "" {
+	if inputFile == "" && evalSource == "" {
+		if loopLines || printLines {
+			printError("usage: tender -n|-p {-e code | input-file | -}")
+			os.Exit(2)
+		}
 		// REPL
 		RunREPL(modules, os.Stdin, os.Stdout)
 		return
 	}
-	
-	inputData, err := ioutil.ReadFile(inputFile)
-	if err != nil {
-		printError(string(err.Error()))
-		os.Exit(1)
-	}
 
-	inputFile, err = filepath.Abs(inputFile)
+	inputData, inputFile, err := readInput(inputFile)
 	if err != nil {
 		printError(string(err.Error()))
 		os.Exit(1)
@@ -124,21 +136,32 @@ func main() {
 		return
 	}
 
-	if compileOutput != "" {
+	if loopLines || printLines {
+		if compileOutput != "" {
+			printError("-n and -p cannot be used with -o")
+			os.Exit(2)
+		}
+		err := RunLines(modules, inputData, inputFile, os.Stdin, os.Stdout,
+			printLines)
+		if err != nil {
+			reportError(err)
+			os.Exit(1)
+		}
+	} else if compileOutput != "" {
 		err := CompileOnly(modules, inputData, inputFile, compileOutput)
 		if err != nil {
-			printError(string(err.Error()))
+			reportError(err)
 			os.Exit(1)
 		}
-	} else if filepath.Ext(inputFile) == sourceFileExt {
+	} else if filepath.Ext(inputFile) == sourceFileExt || isSourceInput(inputFile) {
 		err := CompileAndRun(modules, inputData, inputFile)
 		if err != nil {
-			printError(string(err.Error()))
+			reportError(err)
 			os.Exit(1)
 		}
 	} else {
 		if err := RunCompiled(modules, inputData); err != nil {
-			printError(string(err.Error()))
+			reportError(err)
 			os.Exit(1)
 		}
 	}
@@ -186,7 +209,7 @@ func ParseOnly(src []byte, inputFile, outputFile string) (err error) {
 // CompileOnly compiles the source code and writes the compiled binary into
 // outputFile.
 func CompileOnly(modules *tender.ModuleMap, data []byte, inputFile, outputFile string) (err error) {
-	bytecode, err := compileSrc(modules, data, inputFile)
+	bytecode, err := compileSrc(modules, data, inputFile, nil)
 	if err != nil {
 		return
 	}
@@ -207,7 +230,11 @@ func CompileOnly(modules *tender.ModuleMap, data []byte, inputFile, outputFile s
 		}
 	}()
 	
-	err = bytecode.Encode(out)
+	if compress {
+		err = bytecode.EncodeCompressed(out)
+	} else {
+		err = bytecode.Encode(out)
+	}
 	if err != nil {
 		return
 	}
@@ -217,7 +244,7 @@ func CompileOnly(modules *tender.ModuleMap, data []byte, inputFile, outputFile s
 
 // CompileAndRun compiles the source code and executes it.
 func CompileAndRun(modules *tender.ModuleMap, data []byte, inputFile string) (err error) {
-	bytecode, err := compileSrc(modules, data, inputFile)
+	bytecode, err := compileSrc(modules, data, inputFile, nil)
 	if err != nil {
 		return
 	}
@@ -234,151 +261,138 @@ func RunCompiled(modules *tender.ModuleMap, data []byte) (err error) {
 	if err != nil {
 		return
 	}
+	err = bytecode.Verify()
+	if err != nil {
+		return
+	}
+	if registerVM {
+		bytecode.TranslateRegisters()
+	}
 
 	machine := tender.NewVM(bytecode, nil, -1)
 	err = machine.Run()
 	return
 }
 
-// RunREPL starts REPL.
-func RunREPL(modules *tender.ModuleMap, in io.Reader, out io.Writer) {
-	stdin := bufio.NewScanner(in)
-	fileSet := parser.NewFileSet()
-	globals := make([]tender.Object, tender.GlobalsSize)
-	symbolTable := tender.NewSymbolTable()
-	for idx, fn := range tender.GetAllBuiltinFunctions() {
-		symbolTable.DefineBuiltin(idx, fn.Name)
-	}
-
-	// embed println function
-	symbol := symbolTable.Define("__repl_println__")
-	globals[symbol.Index] = &tender.BuiltinFunction{
-		Name: "__repl_println__",
-		NeedVMObj: true,
-		Value: func(args ...tender.Object) (ret tender.Object, err error) { 
-			vm := args[0].(*tender.VMObj).Value
-			args = args[1:] 
-			if isAnsiSupportedTerminal {
-				str := ""
-				for i, arg := range args {
-					str += tender.ToStringPrettyColored(vm, arg)
-					if i < len(args) - 1 {
-						str += " "
-					}
-				}
-				fmt.Fprintln(colorable.NewColorableStdout(), str)
-				return
-			}
-			str := ""
-			for i, arg := range args {
-				str += tender.ToStringPretty(vm, arg)
-				if i < len(args) - 1 {
-					str += " "
-				}
-			}
-			fmt.Println(str)
-			return
-		},
-	}
-	
-	fmt.Println("tender " + version + " (REPL)")
-	fmt.Println(`Type ".exit" to end the program`)
-
-	var constants []tender.Object
-	for {
-		_, _ = fmt.Fprint(out, replPrompt)
-		scanned := stdin.Scan()
-		if !scanned {
-			return
-		}
-
-		line := stdin.Text()
-		if line == ".exit" {
-			os.Exit(0)
-		} else if line == ".stdlib" {
-			for k, _ := range stdlib.BuiltinModules {
-				fmt.Println("stdlib." + k)
-			}
-		}
-		srcFile := fileSet.AddFile("repl", -1, len(line))
-		p := parser.NewParser(srcFile, []byte(line), nil)
-		file, err := p.ParseFile()
-		if err != nil {
-			printError(string(err.Error()))
-			continue
-		}
-
-		file = addPrints(file)
-		c := tender.NewCompiler(srcFile, symbolTable, constants, modules, nil)
-		c.EnableFileImport(true)
-		if err := c.Compile(file); err != nil {
-			printError(string(err.Error()))
-			// _, _ = fmt.Fprintln(out, err.Error())
-			continue
-		}
-
-		bytecode := c.Bytecode()
-		machine := tender.NewVM(bytecode, globals, -1)
-		if err := machine.Run(); err != nil {
-			printError(string(err.Error()))
-			continue
-		}
-		constants = bytecode.Constants
-	}
+// compileSrc compiles the source code. The global variables of symbolTable,
+// if not nil, are defined in the program.
+func compileSrc(
+	modules *tender.ModuleMap,
+	src []byte,
+	inputFile string,
+	symbolTable *tender.SymbolTable,
+) (*tender.Bytecode, error) {
+	bytecode, _, err := compileSrcSources(modules, src, inputFile, symbolTable)
+	return bytecode, err
 }
 
-func compileSrc(modules *tender.ModuleMap, src []byte, inputFile string) (*tender.Bytecode, error) {	
+// compileSrcSources compiles the source code like compileSrc, and returns the
+// files it imports and embeds.
+func compileSrcSources(
+	modules *tender.ModuleMap,
+	src []byte,
+	inputFile string,
+	symbolTable *tender.SymbolTable,
+) (*tender.Bytecode, []string, error) {
 	fileSet := parser.NewFileSet()
 	srcFile := fileSet.AddFile(filepath.Base(inputFile), -1, len(src))
 
-	p := parser.NewParser(srcFile, src, nil)
+	p := parser.NewParserWithMode(srcFile, src, nil, parser.ParseComments)
 	file, err := p.ParseFile()
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
-	c := tender.NewCompiler(srcFile, nil, nil, modules, nil)
+	c := tender.NewCompiler(srcFile, symbolTable, nil, modules, nil)
 	c.EnableFileImport(true)
+	c.EnableTypeChecks(typeCheck)
+	c.EnableOptimizer(!noOptimize)
+	c.SetModuleCache(moduleCacheDir())
 	if resolvePath {
 		c.SetImportDir(filepath.Dir(inputFile))
 	}
 
 	if err := c.Compile(file); err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
 	bytecode := c.Bytecode()
 	bytecode.RemoveDuplicates()
-	return bytecode, nil
+	if registerVM {
+		bytecode.TranslateRegisters()
+	}
+	return bytecode, c.Sources(), nil
 }
 
 func doHelp() {
 	fmt.Println("Usage:")
 	fmt.Println()
 	fmt.Println("    tender [flags] {input-file}")
+	fmt.Println("    tender [flags] -e {code} [arguments]")
+	fmt.Println("    tender [flags] - [arguments]")
+	fmt.Println("    tender {command} [arguments]")
+	fmt.Println()
+	fmt.Println("Commands:")
+	fmt.Println()
+	fmt.Println("    build      build a standalone executable from a source file")
+	fmt.Println("    cache      manage the cache of compiled modules")
+	fmt.Println("               Use clean to remove it or dir to print its directory.")
+	fmt.Println("    check      check type annotations of source files")
+	fmt.Println("    disasm     print the bytecode of a source or compiled file")
+	fmt.Println("               Use -json for JSON output and -diff to compare two files.")
+	fmt.Println("    doc        print the documentation of modules as Markdown or HTML")
+	fmt.Println("               Use -html for HTML and -o to write a file per module.")
+	fmt.Println("    pkg        manage the packages required in tender.mod")
+	fmt.Println("               Use add, remove, update, verify or vendor.")
+	fmt.Println("    watch      run a source file again when it or the files it uses change")
 	fmt.Println()
 	fmt.Println("Flags:")
 	fmt.Println()
 	fmt.Println("    -o        compile output file")
 	fmt.Println("              Specify the name of the output file when compiling.")
+	fmt.Println("    -O0       disable optimizations")
+	fmt.Println("              Compile without constant folding and peephole optimization.")
+	fmt.Println("    -regvm    run on the register VM")
+	fmt.Println("              Run functions on the experimental register-based VM.")
+	fmt.Println("    -compress compress output file")
+	fmt.Println("              Compress the compiled bytecode written with -o.")
 	fmt.Println("    -version  show version")
 	fmt.Println("              Display the current version of the Tender tool.")
 	fmt.Println("    -v        show version")
 	fmt.Println("              Alias for -version. Display the current version of the Tender tool.")
 	fmt.Println("    -parse    parse file")
 	fmt.Println("              Parse the input file and display the parsed structure.")
+	fmt.Println("    -typecheck  check argument types")
+	fmt.Println("              Check annotated function argument types at runtime.")
+	fmt.Println("    -e        evaluate code")
+	fmt.Println("              Run the program given as argument instead of a file.")
+	fmt.Println("    -n        loop over lines")
+	fmt.Println("              Run the program for each line of stdin, bound to line.")
+	fmt.Println("    -p        loop over lines and print them")
+	fmt.Println("              Like -n, and print line after each run unless it is null.")
+	fmt.Println("    -error-format  format of the errors")
+	fmt.Println("              Use json to write errors as JSON objects on stderr.")
 	fmt.Println()
 	fmt.Println("Examples:")
 	fmt.Println()
 	fmt.Println("    tender")
 	fmt.Println()
 	fmt.Println("              Start the Tender REPL (Read-Eval-Print Loop) environment.")
+	fmt.Println("              Type .help in the REPL for its commands.")
 	fmt.Println()
 	fmt.Println("    tender myapp.td")
 	fmt.Println()
 	fmt.Println("              Compile and run the source file (myapp.td).")
 	fmt.Println("              The source file must have a .td extension.")
 	fmt.Println()
+	fmt.Println("    tender -e 'println(1 << 10)'")
+	fmt.Println()
+	fmt.Println("              Run a one-line program. Use - to read the program from stdin.")
+	fmt.Println()
+	fmt.Println("    cat notes.txt | tender -p -e 'line = import(\"strings\").to_upper(line)'")
+	fmt.Println()
+	fmt.Println("              Print the lines of notes.txt in upper case.")
+	fmt.Println()
 	fmt.Println("    tender -o myapp myapp.td")
 	fmt.Println()
 	fmt.Println("              Compile the source file (myapp.td) into a bytecode file (myapp).")
@@ -387,38 +401,40 @@ func doHelp() {
 	fmt.Println()
 	fmt.Println("              Run the compiled bytecode file (myapp).")
 	fmt.Println()
-}
-
-func addPrints(file *parser.File) *parser.File {
-	var stmts []parser.Stmt
-	for _, s := range file.Stmts {
-		switch s := s.(type) {
-		case *parser.ExprStmt:
-			stmts = append(stmts, &parser.ExprStmt{
-				Expr: &parser.CallExpr{
-					Func: &parser.Ident{Name: "__repl_println__"},
-					Args: []parser.Expr{s.Expr},
-				},
-			})
-		case *parser.AssignStmt:
-			stmts = append(stmts, s)
-
-			stmts = append(stmts, &parser.ExprStmt{
-				Expr: &parser.CallExpr{
-					Func: &parser.Ident{
-						Name: "__repl_println__",
-					},
-					Args: s.LHS,
-				},
-			})
-		default:
-			stmts = append(stmts, s)
-		}
-	}
-	return &parser.File{
-		InputFile: file.InputFile,
-		Stmts:     stmts,
-	}
+	fmt.Println("    tender build -o myapp myapp.td")
+	fmt.Println()
+	fmt.Println("              Build an executable (myapp) that runs myapp.td with its")
+	fmt.Println("              imported modules and embedded files.")
+	fmt.Println()
+	fmt.Println("    tender check myapp.td")
+	fmt.Println()
+	fmt.Println("              Report type annotation errors without running the file.")
+	fmt.Println()
+	fmt.Println("    tender disasm -diff old.tdo new.tdo")
+	fmt.Println()
+	fmt.Println("              Show the instructions that changed between two builds.")
+	fmt.Println()
+	fmt.Println("    tender doc -html -o docs lib/ strings builtins")
+	fmt.Println()
+	fmt.Println("              Write HTML pages documenting the modules in lib/, the")
+	fmt.Println("              strings module and the builtin functions into docs/.")
+	fmt.Println()
+	fmt.Println("    tender pkg add @user:repo@v1.0.0")
+	fmt.Println()
+	fmt.Println("              Require version v1.0.0 of the package in tender.mod and")
+	fmt.Println("              record its content hash in tender.lock.")
+	fmt.Println()
+	fmt.Println("    tender watch draw.td")
+	fmt.Println()
+	fmt.Println("              Run draw.td, and run it again whenever it, the modules it")
+	fmt.Println("              imports or the files it embeds are saved.")
+	fmt.Println()
+	fmt.Println("    tender cache clean")
+	fmt.Println()
+	fmt.Println("              Remove the imported modules cached to start scripts faster.")
+	fmt.Println("              Set TENDER_CACHE to another directory, or to off to disable")
+	fmt.Println("              the cache.")
+	fmt.Println()
 }
 
 func basename(s string) string {
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/2dprototype/tender/deps"
+)
+
+// runPkg manages the packages required in the tender.mod file of the
+// project in the current directory.
+func runPkg(args []string) int {
+	if len(args) == 0 {
+		printPkgUsage()
+		return 2
+	}
+	cwd, err := os.Getwd()
+	if err != nil {
+		printError(err.Error())
+		return 1
+	}
+	project, err := deps.FindProject(cwd)
+	if err != nil {
+		printError(err.Error())
+		return 1
+	}
+	if project == nil {
+		if args[0] != "add" {
+			printError("no " + deps.ManifestFile + " found in " + cwd +
+				" or its parent directories")
+			return 1
+		}
+		project, _ = deps.LoadProject(cwd)
+	}
+
+	src := deps.DefaultSource()
+	switch cmd, paths := args[0], args[1:]; cmd {
+	case "add":
+		if len(paths) == 0 {
+			printPkgUsage()
+			return 2
+		}
+		for _, path := range paths {
+			version := ""
+			if n := strings.LastIndexByte(path, '@'); n > 0 {
+				path, version = path[:n], path[n+1:]
+			}
+			dep, err := project.Add(src, path, version)
+			if err != nil {
+				printError(err.Error())
+				return 1
+			}
+			fmt.Printf("added %s %s\n", dep.Path, dep.Version)
+		}
+	case "remove":
+		if len(paths) == 0 {
+			printPkgUsage()
+			return 2
+		}
+		for _, path := range paths {
+			if err := project.Remove(path); err != nil {
+				printError(err.Error())
+				return 1
+			}
+			fmt.Printf("removed %s\n", path)
+		}
+	case "update":
+		updated, err := project.Update(src, paths...)
+		for _, dep := range updated {
+			fmt.Printf("updated %s %s\n", dep.Path, dep.Version)
+		}
+		if err != nil {
+			printError(err.Error())
+			return 1
+		}
+	case "verify":
+		if len(paths) != 0 {
+			printPkgUsage()
+			return 2
+		}
+		if err := project.Verify(src); err != nil {
+			printError(err.Error())
+			return 1
+		}
+		fmt.Printf("all %d packages verified\n", len(project.Manifest.Requires))
+		return 0
+	case "vendor":
+		if len(paths) != 0 {
+			printPkgUsage()
+			return 2
+		}
+		if err := project.Vendor(src); err != nil {
+			printError(err.Error())
+			return 1
+		}
+		fmt.Printf("%d packages copied to %s\n", len(project.Manifest.Requires),
+			filepath.Join(project.Dir, deps.VendorDir))
+		return 0
+	default:
+		printPkgUsage()
+		return 2
+	}
+	if err := project.Save(); err != nil {
+		printError(err.Error())
+		return 1
+	}
+	return 0
+}
+
+func printPkgUsage() {
+	printError("usage: tender pkg add {@user:repo[@version]}...\n" +
+		"       tender pkg remove {@user:repo}...\n" +
+		"       tender pkg update [@user:repo]...\n" +
+		"       tender pkg verify\n" +
+		"       tender pkg vendor")
+}
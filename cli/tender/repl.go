@@ -0,0 +1,503 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/2dprototype/tender"
+	"github.com/2dprototype/tender/parser"
+	"github.com/2dprototype/tender/stdlib"
+	"github.com/2dprototype/tender/token"
+	"github.com/2dprototype/tender/v/colorable"
+)
+
+const replContinuationPrompt = ".. "
+
+// replCommands are the meta-commands of the REPL, with their help.
+var replCommands = [][2]string{
+	{".help", "show this help"},
+	{".load file.td", "run a file in the session"},
+	{".save file.td", "save the inputs of the session to a file"},
+	{".type expr", "show the type of an expression"},
+	{".time code", "run code and show how long it took"},
+	{".disasm code", "show the bytecode of code without running it"},
+	{".reset", "forget all the variables of the session"},
+	{".stdlib", "list the standard library modules"},
+	{".exit", "end the session"},
+}
+
+// repl is a session of the REPL.
+type repl struct {
+	modules     *tender.ModuleMap
+	out         io.Writer
+	fileSet     *parser.SourceFileSet
+	symbolTable *tender.SymbolTable
+	globals     []tender.Object
+	constants   []tender.Object
+	inputs      []string // inputs run without errors, for .save
+	captured    tender.Object
+}
+
+// RunREPL starts REPL.
+func RunREPL(modules *tender.ModuleMap, in io.Reader, out io.Writer) {
+	r := &repl{modules: modules, out: out}
+	r.reset()
+
+	editor := newLineEditor(in, out)
+	editor.complete = r.complete
+	if home, err := os.UserHomeDir(); err == nil {
+		editor.loadHistory(filepath.Join(home, ".tender_history"))
+	}
+
+	fmt.Fprintln(out, "tender "+strings.TrimSpace(version)+" (REPL)")
+	fmt.Fprintln(out, `Type ".help" for more information or ".exit" to end the program`)
+
+	var input []string
+	for {
+		prompt := replPrompt
+		if len(input) > 0 {
+			prompt = replContinuationPrompt
+		}
+		line, err := editor.readLine(prompt)
+		if err == errInterrupt {
+			input = nil
+			continue
+		} else if err != nil {
+			return
+		}
+		editor.addHistory(line)
+
+		if len(input) == 0 && strings.HasPrefix(strings.TrimSpace(line), ".") {
+			if !r.command(strings.TrimSpace(line)) {
+				return
+			}
+			continue
+		}
+
+		// read more lines until the braces are balanced, or an empty line
+		input = append(input, line)
+		src := strings.Join(input, "\n")
+		if line != "" && incompleteInput(src) {
+			continue
+		}
+		input = nil
+		if strings.TrimSpace(src) == "" {
+			continue
+		}
+		if err := r.run(src, addPrints); err != nil {
+			r.reportError(err)
+			continue
+		}
+		r.inputs = append(r.inputs, src)
+	}
+}
+
+// reset starts a new session.
+func (r *repl) reset() {
+	r.fileSet = parser.NewFileSet()
+	r.globals = make([]tender.Object, tender.GlobalsSize)
+	r.constants = nil
+	r.inputs = nil
+	r.symbolTable = tender.NewSymbolTable()
+	for idx, fn := range tender.GetAllBuiltinFunctions() {
+		r.symbolTable.DefineBuiltin(idx, fn.Name)
+	}
+
+	// embed println function
+	symbol := r.symbolTable.Define("__repl_println__")
+	r.globals[symbol.Index] = &tender.BuiltinFunction{
+		Name:      "__repl_println__",
+		NeedVMObj: true,
+		Value: func(args ...tender.Object) (ret tender.Object, err error) {
+			vm := args[0].(*tender.VMObj).Value
+			args = args[1:]
+			if isAnsiSupportedTerminal {
+				str := ""
+				for i, arg := range args {
+					str += tender.ToStringPrettyColored(vm, arg)
+					if i < len(args)-1 {
+						str += " "
+					}
+				}
+				fmt.Fprintln(colorable.NewColorableStdout(), str)
+				return
+			}
+			str := ""
+			for i, arg := range args {
+				str += tender.ToStringPretty(vm, arg)
+				if i < len(args)-1 {
+					str += " "
+				}
+			}
+			fmt.Fprintln(r.out, str)
+			return
+		},
+	}
+
+	// capture function of .type
+	symbol = r.symbolTable.Define("__repl_capture__")
+	r.globals[symbol.Index] = &tender.BuiltinFunction{
+		Name: "__repl_capture__",
+		Value: func(args ...tender.Object) (tender.Object, error) {
+			if len(args) != 1 {
+				return nil, tender.ErrWrongNumArguments
+			}
+			r.captured = args[0]
+			return tender.NullValue, nil
+		},
+	}
+}
+
+// command runs a meta-command. It returns false if the session ends.
+func (r *repl) command(line string) bool {
+	name, arg := line, ""
+	if n := strings.IndexAny(line, " \t"); n > 0 {
+		name, arg = line[:n], strings.TrimSpace(line[n+1:])
+	}
+	switch name {
+	case ".exit":
+		return false
+	case ".help":
+		for _, cmd := range replCommands {
+			fmt.Fprintf(r.out, "%-16s %s\n", cmd[0], cmd[1])
+		}
+		fmt.Fprintln(r.out)
+		fmt.Fprintln(r.out, "Lines are joined until their braces are balanced or an empty line is entered.")
+		fmt.Fprintln(r.out, "Use the arrow keys to edit and recall lines, and tab to complete names.")
+	case ".stdlib":
+		var names []string
+		for name := range stdlib.BuiltinModules {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			fmt.Fprintln(r.out, name)
+		}
+	case ".reset":
+		r.reset()
+	case ".load":
+		if arg == "" {
+			r.printError("usage: .load file.td")
+			break
+		}
+		data, err := ioutil.ReadFile(arg)
+		if err != nil {
+			r.printError(err.Error())
+			break
+		}
+		if len(data) > 1 && string(data[:2]) == "#!" {
+			copy(data, "//")
+		}
+		if err := r.run(string(data), nil); err != nil {
+			r.printError(err.Error())
+			break
+		}
+		r.inputs = append(r.inputs, string(data))
+	case ".save":
+		if arg == "" {
+			r.printError("usage: .save file.td")
+			break
+		}
+		src := strings.Join(r.inputs, "\n")
+		if src != "" {
+			src += "\n"
+		}
+		if err := ioutil.WriteFile(arg, []byte(src), 0644); err != nil {
+			r.printError(err.Error())
+		}
+	case ".type":
+		if arg == "" {
+			r.printError("usage: .type expr")
+			break
+		}
+		r.captured = nil
+		if err := r.run(arg, captureExpr); err != nil {
+			r.printError(err.Error())
+			break
+		}
+		if r.captured != nil {
+			fmt.Fprintln(r.out, r.captured.TypeName())
+		}
+	case ".time":
+		if arg == "" {
+			r.printError("usage: .time code")
+			break
+		}
+		start := time.Now()
+		err := r.run(arg, addPrints)
+		elapsed := time.Since(start)
+		if err != nil {
+			r.printError(err.Error())
+			break
+		}
+		fmt.Fprintf(r.out, "time: %s\n", elapsed)
+	case ".disasm":
+		if arg == "" {
+			r.printError("usage: .disasm code")
+			break
+		}
+		bytecode, err := r.compile(arg, r.symbolTable.Fork(true), nil, nil)
+		if err != nil {
+			r.printError(err.Error())
+			break
+		}
+		printDisasm(r.out, bytecode.Disassemble(), nil)
+	default:
+		r.printError("unknown command " + name + ", type .help for the commands")
+	}
+	return true
+}
+
+// printError prints an error of a meta-command.
+func (r *repl) printError(msg string) {
+	if isAnsiSupportedTerminal {
+		printError(msg)
+		return
+	}
+	fmt.Fprintln(r.out, msg)
+}
+
+// reportError reports an error of an input like reportError, but writes it to
+// the output of the session unless it is written as JSON.
+func (r *repl) reportError(err error) {
+	if errorFormat == "json" {
+		reportError(err)
+		return
+	}
+	var rerr *tender.RuntimeError
+	if errors.As(err, &rerr) {
+		r.printError(rerr.Report())
+		return
+	}
+	r.printError(err.Error())
+}
+
+// compile compiles the source in the scope of the session.
+func (r *repl) compile(
+	src string,
+	symbolTable *tender.SymbolTable,
+	constants []tender.Object,
+	transform func(*parser.File) (*parser.File, error),
+) (*tender.Bytecode, error) {
+	srcFile := r.fileSet.AddFile("repl", -1, len(src))
+	p := parser.NewParserWithMode(srcFile, []byte(src), nil, parser.ParseComments)
+	file, err := p.ParseFile()
+	if err != nil {
+		return nil, err
+	}
+	if transform != nil {
+		if file, err = transform(file); err != nil {
+			return nil, err
+		}
+	}
+	c := tender.NewCompiler(srcFile, symbolTable, constants, r.modules, nil)
+	c.EnableFileImport(true)
+	if err := c.Compile(file); err != nil {
+		return nil, err
+	}
+	return c.Bytecode(), nil
+}
+
+// run compiles and runs the source in the session. The parsed source is
+// rewritten by transform if it is not nil, such as addPrints to print the
+// values of expressions and assignments.
+func (r *repl) run(
+	src string,
+	transform func(*parser.File) (*parser.File, error),
+) error {
+	bytecode, err := r.compile(src, r.symbolTable, r.constants, transform)
+	if err != nil {
+		return err
+	}
+	// functions assigned before a runtime error use the new constants
+	r.constants = bytecode.Constants
+	return tender.NewVM(bytecode, r.globals, -1).Run()
+}
+
+// complete returns the completions of the word at the end of line: the
+// meta-commands, the keywords, builtin functions and global variables, or the
+// members of maps, modules and objects.
+func (r *repl) complete(line string) ([]string, int) {
+	start := len(line)
+	for start > 0 && isWordChar(line[start-1]) {
+		start--
+	}
+	word := line[start:]
+
+	var names []string
+	prefix := word
+	if start == 0 && strings.HasPrefix(word, ".") {
+		for _, cmd := range replCommands {
+			names = append(names, strings.Fields(cmd[0])[0])
+		}
+	} else if n := strings.LastIndexByte(word, '.'); n >= 0 {
+		for _, name := range memberNames(r.lookup(word[:n])) {
+			names = append(names, word[:n+1]+name)
+		}
+	} else {
+		if word == "" {
+			return nil, start
+		}
+		for _, name := range r.symbolTable.Names() {
+			if !strings.HasPrefix(name, "__repl_") {
+				names = append(names, name)
+			}
+		}
+		for tok := token.Token(0); tok < 256; tok++ {
+			if tok.IsKeyword() {
+				names = append(names, tok.String())
+			}
+		}
+	}
+
+	var candidates []string
+	for _, name := range names {
+		if strings.HasPrefix(name, prefix) {
+			candidates = append(candidates, name)
+		}
+	}
+	sort.Strings(candidates)
+	return candidates, start
+}
+
+// lookup returns the value of a global variable or of a selector of global
+// variables such as "a.b", or nil.
+func (r *repl) lookup(expr string) tender.Object {
+	names := strings.Split(expr, ".")
+	symbol, _, ok := r.symbolTable.Resolve(names[0], false)
+	if !ok || symbol.Scope != tender.ScopeGlobal {
+		return nil
+	}
+	o := r.globals[symbol.Index]
+	for _, name := range names[1:] {
+		if o == nil {
+			return nil
+		}
+		v, err := o.IndexGet(&tender.String{Value: name})
+		if err != nil {
+			return nil
+		}
+		o = v
+	}
+	return o
+}
+
+// memberNames returns the names of the members of maps, modules and objects
+// with methods.
+func memberNames(o tender.Object) []string {
+	var names []string
+	switch o := o.(type) {
+	case *tender.Map:
+		for name := range o.Value {
+			names = append(names, name)
+		}
+	case *tender.ImmutableMap:
+		for name := range o.Value {
+			if name != "__module_name__" {
+				names = append(names, name)
+			}
+		}
+	case *tender.MethodObject:
+		names = o.Methods.Names()
+	}
+	return names
+}
+
+func isWordChar(c byte) bool {
+	return c == '_' || c == '.' || c >= '0' && c <= '9' ||
+		c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z'
+}
+
+// incompleteInput returns true if the source has unclosed parentheses,
+// brackets or braces, or an unterminated raw string or comment.
+func incompleteInput(src string) bool {
+	fileSet := parser.NewFileSet()
+	file := fileSet.AddFile("repl", -1, len(src))
+	unterminated := false
+	s := parser.NewScanner(file, []byte(src), func(_ parser.SourceFilePos, msg string) {
+		if msg == "raw string literal not terminated" || msg == "comment not terminated" {
+			unterminated = true
+		}
+	}, parser.DontInsertSemis)
+	depth := 0
+	for {
+		tok, _, _ := s.Scan()
+		switch tok {
+		case token.LParen, token.LBrack, token.LBrace:
+			depth++
+		case token.RParen, token.RBrack, token.RBrace:
+			depth--
+		case token.EOF:
+			return depth > 0 || unterminated
+		}
+	}
+}
+
+func addPrints(file *parser.File) (*parser.File, error) {
+	var stmts []parser.Stmt
+	for _, s := range file.Stmts {
+		switch s := s.(type) {
+		case *parser.ExprStmt:
+			stmts = append(stmts, &parser.ExprStmt{
+				Expr: &parser.CallExpr{
+					Func: &parser.Ident{Name: "__repl_println__"},
+					Args: []parser.Expr{s.Expr},
+				},
+			})
+		case *parser.AssignStmt:
+			stmts = append(stmts, s)
+
+			stmts = append(stmts, &parser.ExprStmt{
+				Expr: &parser.CallExpr{
+					Func: &parser.Ident{
+						Name: "__repl_println__",
+					},
+					Args: s.LHS,
+				},
+			})
+		default:
+			stmts = append(stmts, s)
+		}
+	}
+	return &parser.File{
+		InputFile: file.InputFile,
+		Stmts:     stmts,
+	}, nil
+}
+
+// captureExpr passes the value of the expression of the file to
+// __repl_capture__. The expression keeps its positions in the input, so
+// errors point at the input rather than at the call.
+func captureExpr(file *parser.File) (*parser.File, error) {
+	var expr parser.Expr
+	if len(file.Stmts) == 1 {
+		if s, ok := file.Stmts[0].(*parser.ExprStmt); ok {
+			expr = s.Expr
+		}
+	}
+	if expr == nil {
+		var errs parser.ErrorList
+		pos := file.Pos()
+		if len(file.Stmts) > 0 {
+			pos = file.Stmts[len(file.Stmts)-1].Pos()
+		}
+		errs.Add(file.InputFile.Position(pos), "expected one expression")
+		return nil, errs
+	}
+	return &parser.File{
+		InputFile: file.InputFile,
+		Stmts: []parser.Stmt{&parser.ExprStmt{
+			Expr: &parser.CallExpr{
+				Func: &parser.Ident{Name: "__repl_capture__"},
+				Args: []parser.Expr{expr},
+			},
+		}},
+	}, nil
+}
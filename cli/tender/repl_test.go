@@ -0,0 +1,313 @@
+package main
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/2dprototype/tender/stdlib"
+)
+
+func TestIncompleteInput(t *testing.T) {
+	tests := []struct {
+		src        string
+		incomplete bool
+	}{
+		{`1 + 2`, false},
+		{`f := fn(a) {`, true},
+		{"f := fn(a) {\n\treturn a\n}", false},
+		{`x := [1, 2,`, true},
+		{`println(x`, true},
+		{`m := {a: {b: 1}}`, false},
+		{`s := "{("`, false},
+		{`c := '{'`, false},
+		{"s := `raw", true},
+		{"s := `raw\nstring`", false},
+		{`/* comment`, true},
+		{"/* comment\n */ x", false},
+		{`x := 1 // {`, false},
+		{`)`, false},
+		{``, false},
+	}
+	for _, tc := range tests {
+		if got := incompleteInput(tc.src); got != tc.incomplete {
+			t.Errorf("incompleteInput(%q) = %v, expected %v", tc.src, got, tc.incomplete)
+		}
+	}
+}
+
+// runREPL runs a session of the REPL with the input and returns its output.
+func runREPL(t *testing.T, input string) string {
+	t.Helper()
+	t.Setenv("HOME", t.TempDir())
+	defer func(ansi bool) { isAnsiSupportedTerminal = ansi }(isAnsiSupportedTerminal)
+	isAnsiSupportedTerminal = false
+
+	var out bytes.Buffer
+	modules := stdlib.GetModuleMap(stdlib.AllModuleNames()...)
+	RunREPL(modules, strings.NewReader(input), &out)
+	return out.String()
+}
+
+func TestREPL(t *testing.T) {
+	dir := t.TempDir()
+	lib := filepath.Join(dir, "lib.td")
+	writeFiles(t, dir, map[string]string{
+		"lib.td": "#!/usr/bin/env tender\ny := 5\n",
+	})
+	saved := filepath.Join(dir, "saved.td")
+
+	tests := []struct {
+		name     string
+		input    string
+		expected []string // in order of the output
+		missing  []string
+	}{
+		{
+			name:     "expressions",
+			input:    "1 + 2\na := \"x\"\n",
+			expected: []string{"3", `"x"`},
+		},
+		{
+			name:     "continuation",
+			input:    "f := fn(a) {\nreturn a * 2\n}\nf(21)\n",
+			expected: []string{replContinuationPrompt, "42"},
+		},
+		{
+			name:     "empty line ends input",
+			input:    "x := (1 +\n\n2\n",
+			expected: []string{"Parse Error", "2"},
+		},
+		{
+			name:     "help",
+			input:    ".help\n",
+			expected: []string{".load file.td", ".exit", "Lines are joined"},
+		},
+		{
+			name:     "stdlib",
+			input:    ".stdlib\n",
+			expected: []string{"fmt", "math", "strings"},
+		},
+		{
+			name:     "type",
+			input:    "x := 1\n.type x\n.type [x]\n.type\n",
+			expected: []string{"int", "array", "usage: .type expr"},
+		},
+		{
+			name:  "type errors",
+			input: ".type x +\n.type y := 1\n.type 1; 2\n",
+			expected: []string{
+				"found 'EOF'", "repl:1:4",
+				"expected one expression", "repl:1:1",
+				"expected one expression", "repl:1:4",
+			},
+		},
+		{
+			name:     "time",
+			input:    ".time 1 + 1\n.time\n",
+			expected: []string{"2", "time: ", "usage: .time code"},
+		},
+		{
+			name:     "disasm",
+			input:    "v := 1\n.disasm v + 2\n.disasm\n",
+			expected: []string{"CONST", "usage: .disasm code"},
+		},
+		{
+			name:     "reset",
+			input:    "x := 1\n.reset\n.type x\n",
+			expected: []string{"unresolved reference 'x'"},
+		},
+		{
+			name:     "load",
+			input:    ".load " + lib + "\ny * 2\n.load\n.load " + filepath.Join(dir, "none.td") + "\n",
+			expected: []string{"10", "usage: .load file.td", "none.td"},
+		},
+		{
+			name:     "save",
+			input:    "x := 1\nundefined_var\ny := fn() {\nreturn x\n}\n.time z := 2\n.save " + saved + "\n.save\n",
+			expected: []string{"unresolved reference 'undefined_var'", "usage: .save file.td"},
+		},
+		{
+			name:     "runtime error",
+			input:    "a := 41\nb := \"s\" - 1\na + 1\n",
+			expected: []string{"Runtime Error", "invalid operation", "42"},
+		},
+		{
+			name:     "unknown command",
+			input:    ".bogus\n",
+			expected: []string{"unknown command"},
+		},
+		{
+			name:     "exit",
+			input:    "1 + 1\n.exit\n3 + 3\n",
+			expected: []string{"2"},
+			missing:  []string{"6"},
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			out := runREPL(t, tc.input)
+			rest := out
+			for _, s := range tc.expected {
+				n := strings.Index(rest, s)
+				if n < 0 {
+					t.Fatalf("output does not contain %q after the previous lines:\n%s", s, out)
+				}
+				rest = rest[n+len(s):]
+			}
+			for _, s := range tc.missing {
+				if strings.Contains(out, s) {
+					t.Errorf("output contains %q:\n%s", s, out)
+				}
+			}
+		})
+	}
+
+	// inputs with errors and the code run by .time are not saved
+	data, err := os.ReadFile(saved)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := "x := 1\ny := fn() {\nreturn x\n}\n"
+	if string(data) != expected {
+		t.Errorf("saved %q, expected %q", data, expected)
+	}
+}
+
+func TestREPLComplete(t *testing.T) {
+	r := &repl{modules: stdlib.GetModuleMap("math"), out: io.Discard}
+	r.reset()
+	if err := r.run(`math := import("math"); config := {name: 1, size: 2}; counter := 0`, nil); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		line       string
+		candidates []string
+		start      int
+	}{
+		{".he", []string{".help"}, 0},
+		{".s", []string{".save", ".stdlib"}, 0},
+		{"co", []string{"complex", "config", "continue", "copy", "counter"}, 0},
+		{"x := conf", []string{"config"}, 5},
+		{"config.s", []string{"config.size"}, 0},
+		{"math.ph", []string{"math.phi"}, 0},
+		{"math.Pi", nil, 0},
+		{"__repl_", nil, 0},
+		{"f(", nil, 2},
+	}
+	for _, tc := range tests {
+		candidates, start := r.complete(tc.line)
+		if strings.Join(candidates, " ") != strings.Join(tc.candidates, " ") || start != tc.start {
+			t.Errorf("complete(%q) = %v, %d, expected %v, %d",
+				tc.line, candidates, start, tc.candidates, tc.start)
+		}
+	}
+}
+
+func TestLineEditor(t *testing.T) {
+	const (
+		up    = "\x1b[A"
+		down  = "\x1b[B"
+		right = "\x1b[C"
+		left  = "\x1b[D"
+		home  = "\x1b[H"
+		del   = "\x1b[3~"
+	)
+	complete := func(line string) ([]string, int) {
+		var candidates []string
+		for _, name := range []string{"print", "println", "range"} {
+			if strings.HasPrefix(name, line) {
+				candidates = append(candidates, name)
+			}
+		}
+		return candidates, 0
+	}
+	tests := []struct {
+		name     string
+		keys     string
+		expected string
+		err      error
+	}{
+		{"enter", "abc\r", "abc", nil},
+		{"newline", "abc\n", "abc", nil},
+		{"backspace", "abd\x7fc\r", "abc", nil},
+		{"ctrl-h", "ab\x08\x08\x08xy\r", "xy", nil},
+		{"arrows", "ac" + left + "b" + right + "d\r", "abcd", nil},
+		{"home and end", "bc" + home + "a\x1b[Fd\r", "abcd", nil},
+		{"ctrl-a and ctrl-e", "bc\x01a\x05d\r", "abcd", nil},
+		{"ctrl-b and ctrl-f", "ac\x02b\x06d\r", "abcd", nil},
+		{"delete", "abxc" + left + left + del + "\r", "abc", nil},
+		{"ctrl-d deletes", "abxc\x02\x02\x04\r", "abc", nil},
+		{"ctrl-k", "abcdef\x02\x02\x02\x0b\r", "abc", nil},
+		{"ctrl-u", "xyzabc\x02\x02\x02\x15\r", "abc", nil},
+		{"ctrl-w", "foo bar baz\x17\x17qux\r", "foo qux", nil},
+		{"history up", up + up + "!\r", "first!", nil},
+		{"history down", up + up + down + "\r", "second", nil},
+		{"history restores line", "new" + up + down + "\r", "new", nil},
+		{"history ctrl-p", "\x10\r", "second", nil},
+		{"complete", "ra\t(\r", "range(", nil},
+		{"complete prefix", "pr\t\r", "print", nil},
+		{"complete none", "x\t\r", "x", nil},
+		{"unicode", "héllo\x7f\x7f\x7fy\r", "héy", nil},
+		{"ctrl-c", "abc\x03", "", errInterrupt},
+		{"ctrl-d", "\x04", "", io.EOF},
+		{"end of input", "abc", "", io.EOF},
+	}
+	for _, tc := range tests {
+		var out bytes.Buffer
+		e := newLineEditor(strings.NewReader(tc.keys), &out)
+		e.history = []string{"first", "second"}
+		e.complete = complete
+		line, err := e.edit(">> ")
+		if line != tc.expected || err != tc.err {
+			t.Errorf("%s: %q, %v, expected %q, %v", tc.name, line, err, tc.expected, tc.err)
+		}
+	}
+
+	// candidates are listed when they have no longer common prefix
+	var out bytes.Buffer
+	e := newLineEditor(strings.NewReader("print\t\r"), &out)
+	e.complete = complete
+	if _, err := e.edit(">> "); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out.String(), "print  println") {
+		t.Errorf("candidates not listed: %q", out.String())
+	}
+}
+
+func TestLineEditorPlain(t *testing.T) {
+	var out bytes.Buffer
+	e := newLineEditor(strings.NewReader("a\r\nb\nc"), &out)
+	var lines []string
+	for {
+		line, err := e.readLine("> ")
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			t.Fatal(err)
+		}
+		lines = append(lines, line)
+	}
+	if got := strings.Join(lines, ","); got != "a,b,c" {
+		t.Errorf("lines %q, expected a,b,c", got)
+	}
+	if out.String() != "> > > > " {
+		t.Errorf("prompts %q", out.String())
+	}
+
+	// the history file keeps distinct lines
+	path := filepath.Join(t.TempDir(), "history")
+	e.loadHistory(path)
+	for _, line := range []string{"x", "x", "", "y"} {
+		e.addHistory(line)
+	}
+	e = newLineEditor(strings.NewReader(""), io.Discard)
+	e.loadHistory(path)
+	if got := strings.Join(e.history, ","); got != "x,y" {
+		t.Errorf("history %q, expected x,y", got)
+	}
+}
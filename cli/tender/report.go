@@ -0,0 +1,72 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/2dprototype/tender"
+	"github.com/2dprototype/tender/parser"
+)
+
+// reportError prints an error, with the source line, the arguments and the
+// call stack of a runtime error. With --error-format=json, it writes the error
+// as a JSON object on a line of the standard error instead.
+func reportError(err error) {
+	if errorFormat == "json" {
+		data, jerr := json.Marshal(errorJSON(err))
+		if jerr != nil {
+			data, _ = json.Marshal(plainErrorJSON{Kind: "error", Message: err.Error()})
+		}
+		fmt.Fprintln(os.Stderr, string(data))
+		return
+	}
+	var rerr *tender.RuntimeError
+	if errors.As(err, &rerr) {
+		printError(rerr.Report())
+		return
+	}
+	printError(err.Error())
+}
+
+// plainErrorJSON is the JSON object of an error other than a runtime error,
+// with the same fields as the JSON object of a runtime error.
+type plainErrorJSON struct {
+	Kind    string         `json:"kind"`
+	Message string         `json:"message"`
+	Stack   []posErrorJSON `json:"stack"`
+}
+
+type posErrorJSON struct {
+	File   string `json:"file"`
+	Line   int    `json:"line"`
+	Column int    `json:"column"`
+}
+
+// errorJSON returns the value encoding an error as JSON: "runtime" or "panic"
+// errors, "compile" and "parse" errors with their position, or other errors.
+func errorJSON(err error) interface{} {
+	var rerr *tender.RuntimeError
+	var cerr *tender.CompilerError
+	var perr parser.ErrorList
+	switch {
+	case errors.As(err, &rerr):
+		return rerr
+	case errors.As(err, &cerr):
+		pos := cerr.FileSet.Position(cerr.Node.Pos())
+		return plainErrorJSON{
+			Kind:    "compile",
+			Message: cerr.Err.Error(),
+			Stack:   []posErrorJSON{{pos.Filename, pos.Line, pos.Column}},
+		}
+	case errors.As(err, &perr) && len(perr) > 0:
+		pos := perr[0].Pos
+		return plainErrorJSON{
+			Kind:    "parse",
+			Message: perr[0].Msg,
+			Stack:   []posErrorJSON{{pos.Filename, pos.Line, pos.Column}},
+		}
+	}
+	return plainErrorJSON{Kind: "error", Message: err.Error(), Stack: []posErrorJSON{}}
+}
@@ -0,0 +1,221 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/2dprototype/tender"
+	"github.com/2dprototype/tender/stdlib"
+)
+
+const (
+	// watchDelay is the time waited for more changes after a change, so that
+	// a file saved in several writes is compiled once.
+	watchDelay = 100 * time.Millisecond
+
+	// watchPollInterval is the interval of the checks for changes.
+	watchPollInterval = 250 * time.Millisecond
+
+	// watchAbortTimeout is the time waited for a run to end after it is
+	// aborted, for example by a builtin function blocking the VM.
+	watchAbortTimeout = 2 * time.Second
+
+	// modTimeResolution is how much the modification times of files can be
+	// behind the clock, as file systems truncate them or use a coarse clock.
+	modTimeResolution = time.Second
+)
+
+// runWatch runs a source file, and runs it again whenever it, the modules it
+// imports or the files it embeds change. A run still going on is aborted with
+// the goroutines it started.
+func runWatch(args []string) int {
+	fs := flag.NewFlagSet("watch", flag.ContinueOnError)
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if fs.NArg() < 1 {
+		printError("usage: tender watch {input-file} [arguments]")
+		return 2
+	}
+	inputFile, err := filepath.Abs(fs.Arg(0))
+	if err != nil {
+		printError(err.Error())
+		return 1
+	}
+	vmArgs := append([]string{os.Args[0]}, fs.Args()...)
+	modules := stdlib.GetModuleMap(stdlib.AllModuleNames()...)
+
+	watched := []string{inputFile}
+	for {
+		// the states of the files are recorded before they are read, so
+		// that the changes made while compiling are not missed
+		start := time.Now()
+		states := snapshot(watched)
+
+		var done chan error
+		var vm *tender.VM
+		bytecode, srcs, err := compileWatched(modules, inputFile)
+		if err != nil {
+			// the files of a failed compilation are not known, so the files
+			// of the last successful one are watched
+			reportError(err)
+		} else {
+			watched = append([]string{inputFile}, srcs...)
+		}
+
+		w, err := newWatcher(watched)
+		if err != nil {
+			printError(err.Error())
+			return 1
+		}
+		if changedSince(watched, states, start) {
+			w.notify()
+		}
+		if bytecode != nil {
+			vm = tender.NewVM(bytecode, nil, -1)
+			vm.Args = vmArgs
+			done = make(chan error, 1)
+			go func() { done <- vm.Run() }()
+		}
+		changed := false
+		for !changed {
+			select {
+			case err := <-done:
+				if err != nil {
+					reportError(err)
+				}
+				fmt.Println("[watch] waiting for changes")
+				done = nil
+			case <-w.C:
+				changed = true
+			}
+		}
+		// more changes are ignored until the run restarts
+		time.Sleep(watchDelay)
+		w.Close()
+
+		if done != nil {
+			vm.Abort()
+			select {
+			case <-done:
+			case <-time.After(watchAbortTimeout):
+				printError("[watch] the last run did not stop")
+			}
+		}
+		fmt.Println("[watch] restarting")
+	}
+}
+
+// compileWatched reads and compiles the source file, and returns its bytecode
+// and the files it imports and embeds.
+func compileWatched(modules *tender.ModuleMap, inputFile string) (*tender.Bytecode, []string, error) {
+	data, err := ioutil.ReadFile(inputFile)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(data) > 1 && string(data[:2]) == "#!" {
+		copy(data, "//")
+	}
+	return compileSrcSources(modules, data, inputFile, nil)
+}
+
+// watcher sends on C when the watched files, or the files in the watched
+// directories and their subdirectories, are written, created, removed or
+// renamed.
+type watcher struct {
+	C    chan struct{}
+	stop chan struct{}
+}
+
+func (w *watcher) notify() {
+	select {
+	case w.C <- struct{}{}:
+	default:
+	}
+}
+
+// Close stops watching.
+func (w *watcher) Close() {
+	close(w.stop)
+}
+
+// newPollWatcher returns a watcher comparing the modification times and sizes
+// of the files periodically.
+func newPollWatcher(paths []string) *watcher {
+	w := &watcher{C: make(chan struct{}, 1), stop: make(chan struct{})}
+	last := snapshot(paths)
+	go func() {
+		ticker := time.NewTicker(watchPollInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-w.stop:
+				return
+			case <-ticker.C:
+				current := snapshot(paths)
+				if !equalSnapshots(last, current) {
+					w.notify()
+				}
+				last = current
+			}
+		}
+	}()
+	return w
+}
+
+type fileState struct {
+	modTime time.Time
+	size    int64
+}
+
+// snapshot returns the states of the files, and of the files in the
+// directories and their subdirectories. Missing files are left out.
+func snapshot(paths []string) map[string]fileState {
+	states := make(map[string]fileState)
+	for _, path := range paths {
+		_ = filepath.Walk(path, func(path string, info os.FileInfo, err error) error {
+			if err == nil {
+				states[path] = fileState{modTime: info.ModTime(), size: info.Size()}
+			}
+			return nil
+		})
+	}
+	return states
+}
+
+// changedSince returns true if the watched files differ from their states,
+// were removed, or have no state and may have been modified after start.
+func changedSince(paths []string, states map[string]fileState, start time.Time) bool {
+	current := snapshot(paths)
+	for path, state := range current {
+		last, ok := states[path]
+		if !ok && state.modTime.After(start.Add(-modTimeResolution)) ||
+			ok && (!state.modTime.Equal(last.modTime) || state.size != last.size) {
+			return true
+		}
+	}
+	for _, path := range paths {
+		if _, ok := states[path]; ok {
+			if _, ok := current[path]; !ok {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func equalSnapshots(a, b map[string]fileState) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for path, state := range a {
+		if other, ok := b[path]; !ok || !state.modTime.Equal(other.modTime) || state.size != other.size {
+			return false
+		}
+	}
+	return true
+}
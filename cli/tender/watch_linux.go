@@ -0,0 +1,93 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"unsafe"
+
+	"golang.org/x/sys/unix"
+)
+
+const inotifyMask = unix.IN_MODIFY | unix.IN_CLOSE_WRITE | unix.IN_ATTRIB |
+	unix.IN_CREATE | unix.IN_DELETE | unix.IN_MOVED_FROM | unix.IN_MOVED_TO |
+	unix.IN_DELETE_SELF | unix.IN_MOVE_SELF
+
+// newWatcher returns a watcher of the files and directories using inotify,
+// or checking them periodically if inotify is not available.
+func newWatcher(paths []string) (*watcher, error) {
+	fd, err := unix.InotifyInit1(unix.IN_CLOEXEC | unix.IN_NONBLOCK)
+	if err != nil {
+		return newPollWatcher(paths), nil
+	}
+
+	// files are watched through their directory, as editors often replace
+	// them, and directories with their subdirectories
+	dirs := make(map[int]string)   // watch descriptor to directory
+	names := make(map[string]bool) // watched paths
+	watch := func(dir string) bool {
+		wd, err := unix.InotifyAddWatch(fd, dir, inotifyMask)
+		if err != nil {
+			return false
+		}
+		dirs[wd] = dir
+		return true
+	}
+	for _, path := range paths {
+		info, err := os.Stat(path)
+		if err != nil || !info.IsDir() {
+			names[path] = true
+			if !watch(filepath.Dir(path)) {
+				_ = unix.Close(fd)
+				return newPollWatcher(paths), nil
+			}
+			continue
+		}
+		_ = filepath.Walk(path, func(path string, info os.FileInfo, err error) error {
+			if err == nil && info.IsDir() {
+				names[path] = true
+				watch(path)
+			}
+			return nil
+		})
+	}
+
+	w := &watcher{C: make(chan struct{}, 1), stop: make(chan struct{})}
+	go func() {
+		defer func() { _ = unix.Close(fd) }()
+		buf := make([]byte, 64*(unix.SizeofInotifyEvent+unix.NAME_MAX+1))
+		fds := []unix.PollFd{{Fd: int32(fd), Events: unix.POLLIN}}
+		for {
+			select {
+			case <-w.stop:
+				return
+			default:
+			}
+			// the descriptor is polled with a timeout to see when the
+			// watcher is closed
+			n, err := unix.Poll(fds, int(watchPollInterval.Milliseconds()))
+			if err != nil && err != unix.EINTR {
+				return
+			}
+			if n <= 0 {
+				continue
+			}
+			n, err = unix.Read(fd, buf)
+			if err != nil {
+				continue
+			}
+			for offset := 0; offset+unix.SizeofInotifyEvent <= n; {
+				event := (*unix.InotifyEvent)(unsafe.Pointer(&buf[offset]))
+				start := offset + unix.SizeofInotifyEvent
+				name := string(bytes.TrimRight(buf[start:start+int(event.Len)], "\x00"))
+				offset = start + int(event.Len)
+
+				dir := dirs[int(event.Wd)]
+				if names[dir] || names[filepath.Join(dir, name)] {
+					w.notify()
+				}
+			}
+		}
+	}()
+	return w, nil
+}
@@ -0,0 +1,8 @@
+//go:build !linux
+
+package main
+
+// newWatcher returns a watcher of the files and directories.
+func newWatcher(paths []string) (*watcher, error) {
+	return newPollWatcher(paths), nil
+}
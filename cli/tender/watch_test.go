@@ -0,0 +1,69 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestChangedSince(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"main.td":       `import("./lib")`,
+		"lib.td":        `export 1`,
+		"data/text.txt": "a",
+	})
+	main := filepath.Join(dir, "main.td")
+	lib := filepath.Join(dir, "lib.td")
+	data := filepath.Join(dir, "data")
+	old := time.Now().Add(-time.Hour)
+	for _, path := range []string{main, lib, data, filepath.Join(data, "text.txt")} {
+		if err := os.Chtimes(path, old, old); err != nil {
+			t.Fatal(err)
+		}
+	}
+	paths := []string{main, lib, data}
+
+	tests := []struct {
+		name     string
+		change   func()
+		previous []string // watched when the states were recorded
+		expected bool
+	}{
+		{"unchanged", func() {}, paths, false},
+		{"unchanged new file", func() {}, []string{main}, false},
+		{"written", func() {
+			writeFiles(t, dir, map[string]string{"main.td": `import("./lib2")`})
+		}, paths, true},
+		{"written new file", func() {
+			writeFiles(t, dir, map[string]string{"lib.td": `export 2`})
+		}, []string{main}, true},
+		{"created in directory", func() {
+			writeFiles(t, dir, map[string]string{"data/more.txt": "b"})
+		}, paths, true},
+		{"removed", func() {
+			if err := os.Remove(lib); err != nil {
+				t.Fatal(err)
+			}
+		}, paths, true},
+	}
+	for _, tc := range tests {
+		start := time.Now()
+		states := snapshot(tc.previous)
+		tc.change()
+		if got := changedSince(paths, states, start); got != tc.expected {
+			t.Errorf("%s: changed %v, expected %v", tc.name, got, tc.expected)
+		}
+		writeFiles(t, dir, map[string]string{
+			"main.td": `import("./lib")`,
+			"lib.td":  `export 1`,
+		})
+		_ = os.Remove(filepath.Join(data, "more.txt"))
+		for _, path := range []string{main, lib, data} {
+			if err := os.Chtimes(path, old, old); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+}
@@ -0,0 +1,601 @@
+// Tendergen generates a tender module from the exported functions of a Go
+// package. It is meant to be run by go generate:
+//
+//	//go:generate go run github.com/2dprototype/tender/cli/tendergen -pkg path/filepath
+//
+// The generated file declares a module map named after the module, like the
+// modules of the standard library:
+//
+//	var filepathModule = map[string]tender.Object{
+//		"base": &tender.UserFunction{
+//			Name:  "base",
+//			Value: FuncASRS(filepath.Base),
+//		}, // base(path) => string
+//		...
+//	}
+//
+// and the function signatures for the type checker in filepathSignatures.
+// Function names are converted to snake case. Each function is wrapped with
+// the FuncA...R... adapter of its signature. Adapters declared in other
+// files of the output directory, like stdlib/func_typedefs.go, are reused;
+// missing ones are generated into the output file. Functions with parameter
+// or result types that have no conversion are skipped.
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/importer"
+	goparser "go/parser"
+	"go/token"
+	"go/types"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+var (
+	pkgPath    = flag.String("pkg", "", "import path of the Go package to wrap")
+	moduleName = flag.String("name", "", "module name (default: the package name)")
+	outFile    = flag.String("o", "", "output file (default: {name}_module.go)")
+	outPkg     = flag.String("package", "", "package name of the output file (default: $GOPACKAGE)")
+	funcList   = flag.String("funcs", "", "comma separated Go functions to wrap (default: all)")
+	register   = flag.Bool("register", false, "register the module in BuiltinModules and ModuleSignatures")
+)
+
+// goType describes how values of a Go type are converted.
+type goType struct {
+	code     string // type code in adapter names
+	name     string // Go type
+	prefix   string // prefix of argument variable names
+	conv     string // tender conversion function for arguments
+	expected string // expected type in argument errors
+	sig      string // type in type checker signatures
+	elem     *goType
+}
+
+var goTypes = map[string]*goType{}
+
+func init() {
+	for _, t := range []*goType{
+		{code: "I", name: "int", prefix: "i", conv: "ToInt", expected: "int(compatible)", sig: "int"},
+		{code: "I64", name: "int64", prefix: "i", conv: "ToInt64", expected: "int(compatible)", sig: "int"},
+		{code: "I32", name: "int32", prefix: "i", conv: "ToInt32", expected: "int(compatible)", sig: "int"},
+		{code: "u", name: "uint", prefix: "u", conv: "ToUint", expected: "int(compatible)", sig: "int"},
+		{code: "u8", name: "uint8", prefix: "u", conv: "ToUint8", expected: "int(compatible)", sig: "int"},
+		{code: "C", name: "rune", prefix: "c", conv: "ToRune", expected: "char(compatible)", sig: "char"},
+		{code: "F", name: "float64", prefix: "f", conv: "ToFloat64", expected: "float(compatible)", sig: "int|float"},
+		{code: "S", name: "string", prefix: "s", conv: "ToString", expected: "string(compatible)", sig: "string"},
+		{code: "B", name: "bool", prefix: "b", conv: "ToBool", expected: "bool(compatible)", sig: "bool"},
+		{code: "Y", name: "[]byte", prefix: "y", conv: "ToByteSlice", expected: "bytes(compatible)", sig: "bytes"},
+		{code: "T", name: "time.Time", prefix: "t", conv: "ToTime", expected: "time(compatible)", sig: "time"},
+	} {
+		goTypes[t.name] = t
+	}
+	goTypes["byte"] = goTypes["uint8"]
+	goTypes["[]string"] = &goType{code: "Ss", name: "[]string", prefix: "ss",
+		expected: "array", sig: "array", elem: goTypes["string"]}
+	goTypes["[]int"] = &goType{code: "Is", name: "[]int", prefix: "is",
+		expected: "array", sig: "array", elem: goTypes["int"]}
+}
+
+// signature is a function signature with convertible types.
+type signature struct {
+	params  []*goType
+	results []*goType // without a final error
+	err     bool      // whether the last result is an error
+}
+
+func (s *signature) adapter() string {
+	name := "FuncA"
+	for _, p := range s.params {
+		name += p.code
+	}
+	name += "R"
+	for _, r := range s.results {
+		name += r.code
+	}
+	if s.err {
+		name += "E"
+	}
+	return name
+}
+
+// String returns the signature as written in Go source.
+func (s *signature) String() string {
+	var params, results []string
+	for _, p := range s.params {
+		params = append(params, p.name)
+	}
+	for _, r := range s.results {
+		results = append(results, r.name)
+	}
+	if s.err {
+		results = append(results, "error")
+	}
+	str := "func(" + strings.Join(params, ", ") + ")"
+	switch len(results) {
+	case 0:
+	case 1:
+		str += " " + results[0]
+	default:
+		str += " (" + strings.Join(results, ", ") + ")"
+	}
+	return str
+}
+
+// resultType returns the type of the result for the type checker.
+func (s *signature) resultType() string {
+	result := "null"
+	switch len(s.results) {
+	case 0:
+		if s.err {
+			result = "bool"
+		}
+	case 1:
+		result = s.results[0].sig
+	default:
+		result = "array"
+	}
+	if s.err {
+		result += "|error"
+	}
+	return result
+}
+
+// checkerSignature returns the signature for the type checker.
+func (s *signature) checkerSignature() string {
+	var params []string
+	for _, p := range s.params {
+		params = append(params, p.sig)
+	}
+	return "fn(" + strings.Join(params, ", ") + ") -> " + s.resultType()
+}
+
+// convertSignature returns the signature of sig, or an error naming the
+// first type without a conversion.
+func convertSignature(sig *types.Signature) (*signature, error) {
+	if sig.Variadic() {
+		return nil, fmt.Errorf("variadic parameters")
+	}
+	if sig.TypeParams().Len() > 0 {
+		return nil, fmt.Errorf("type parameters")
+	}
+	s := &signature{}
+	for i := 0; i < sig.Params().Len(); i++ {
+		t, err := lookupType(sig.Params().At(i).Type())
+		if err != nil {
+			return nil, err
+		}
+		s.params = append(s.params, t)
+	}
+	n := sig.Results().Len()
+	if n > 0 && sig.Results().At(n-1).Type().String() == "error" {
+		s.err = true
+		n--
+	}
+	for i := 0; i < n; i++ {
+		t, err := lookupType(sig.Results().At(i).Type())
+		if err != nil {
+			return nil, err
+		}
+		s.results = append(s.results, t)
+	}
+	return s, nil
+}
+
+func lookupType(t types.Type) (*goType, error) {
+	name := types.TypeString(t, nil)
+	if gt, ok := goTypes[name]; ok {
+		return gt, nil
+	}
+	return nil, fmt.Errorf("unsupported type %s", name)
+}
+
+// snakeCase converts a Go function name to the snake case name of the
+// module member: HasPrefix becomes has_prefix and ParseURL parse_url.
+func snakeCase(name string) string {
+	runes := []rune(name)
+	var b strings.Builder
+	for i, r := range runes {
+		if i > 0 && unicode.IsUpper(r) {
+			prev := runes[i-1]
+			nextLower := i+1 < len(runes) && unicode.IsLower(runes[i+1])
+			if unicode.IsLower(prev) || unicode.IsDigit(prev) ||
+				unicode.IsUpper(prev) && nextLower {
+				b.WriteByte('_')
+			}
+		}
+		b.WriteRune(unicode.ToLower(r))
+	}
+	return b.String()
+}
+
+// existingDecls returns the adapters declared in the Go files of dir other
+// than skip, by the signature of their function parameter, and the names of
+// all top-level functions.
+func existingDecls(
+	dir, skip string,
+) (adapters map[string]string, funcs map[string]bool, pkg string, err error) {
+	adapters = make(map[string]string)
+	funcs = make(map[string]bool)
+	paths, err := filepath.Glob(filepath.Join(dir, "*.go"))
+	if err != nil {
+		return nil, nil, "", err
+	}
+	fset := token.NewFileSet()
+	for _, path := range paths {
+		if filepath.Base(path) == skip || strings.HasSuffix(path, "_test.go") {
+			continue
+		}
+		f, err := goparser.ParseFile(fset, path, nil, 0)
+		if err != nil {
+			return nil, nil, "", err
+		}
+		pkg = f.Name.Name
+		for _, decl := range f.Decls {
+			fd, ok := decl.(*ast.FuncDecl)
+			if !ok || fd.Recv != nil {
+				continue
+			}
+			funcs[fd.Name.Name] = true
+			params := fd.Type.Params.List
+			if !strings.HasPrefix(fd.Name.Name, "Func") || len(params) != 1 {
+				continue
+			}
+			if ft, ok := params[0].Type.(*ast.FuncType); ok {
+				adapters[types.ExprString(ft)] = fd.Name.Name
+			}
+		}
+	}
+	return adapters, funcs, pkg, nil
+}
+
+// generator writes the output file.
+type generator struct {
+	buf     bytes.Buffer
+	imports map[string]bool
+}
+
+func (g *generator) printf(format string, args ...interface{}) {
+	fmt.Fprintf(&g.buf, format, args...)
+}
+
+var argumentNames = []string{
+	"first", "second", "third", "fourth", "fifth",
+	"sixth", "seventh", "eighth", "ninth", "tenth",
+}
+
+func argumentName(i int) string {
+	if i < len(argumentNames) {
+		return argumentNames[i]
+	}
+	return fmt.Sprintf("#%d", i+1)
+}
+
+// argument writes the conversion of argument i to the variable v.
+func (g *generator) argument(t *goType, i int, v string) {
+	if t.elem == nil {
+		g.printf("%s, ok := tender.%s(args[%d])\n", v, t.conv, i)
+		g.printf("if !ok {\n")
+		g.argumentError(fmt.Sprintf("%q", argumentName(i)), t.expected,
+			fmt.Sprintf("args[%d]", i))
+		g.printf("}\n")
+		return
+	}
+
+	g.imports["fmt"] = true
+	g.printf("var %s %s\n", v, t.name)
+	g.printf("switch arg%d := args[%d].(type) {\n", i, i)
+	for _, arrayType := range []string{"Array", "ImmutableArray"} {
+		g.printf("case *tender.%s:\n", arrayType)
+		g.printf("for idx, a := range arg%d.Value {\n", i)
+		g.printf("as, ok := tender.%s(a)\n", t.elem.conv)
+		g.printf("if !ok {\n")
+		g.argumentError(
+			fmt.Sprintf("fmt.Sprintf(\"%s[%%d]\", idx)", argumentName(i)),
+			t.elem.expected, "a")
+		g.printf("}\n")
+		g.printf("%s = append(%s, as)\n", v, v)
+		g.printf("}\n")
+	}
+	g.printf("default:\n")
+	g.argumentError(fmt.Sprintf("%q", argumentName(i)), t.expected,
+		fmt.Sprintf("args[%d]", i))
+	g.printf("}\n")
+}
+
+func (g *generator) argumentError(name, expected, found string) {
+	g.printf("return nil, tender.ErrInvalidArgumentType{\n")
+	g.printf("Name: %s,\n", name)
+	g.printf("Expected: %q,\n", expected)
+	g.printf("Found: %s.TypeName(),\n", found)
+	g.printf("}\n")
+}
+
+// result writes the conversion of the result variable v and returns the
+// expression of the converted object.
+func (g *generator) result(t *goType, v string, i int) string {
+	switch t.name {
+	case "int", "int32", "uint", "uint8":
+		return fmt.Sprintf("&tender.Int{Value: int64(%s)}", v)
+	case "int64":
+		return fmt.Sprintf("&tender.Int{Value: %s}", v)
+	case "rune":
+		return fmt.Sprintf("&tender.Char{Value: %s}", v)
+	case "float64":
+		return fmt.Sprintf("&tender.Float{Value: %s}", v)
+	case "bool":
+		o := fmt.Sprintf("b%d", i)
+		g.printf("%s := tender.FalseValue\n", o)
+		g.printf("if %s {\n%s = tender.TrueValue\n}\n", v, o)
+		return o
+	case "string":
+		g.printf("if len(%s) > tender.MaxStringLen {\n", v)
+		g.printf("return nil, tender.ErrStringLimit\n}\n")
+		return fmt.Sprintf("&tender.String{Value: %s}", v)
+	case "[]byte":
+		g.printf("if len(%s) > tender.MaxBytesLen {\n", v)
+		g.printf("return nil, tender.ErrBytesLimit\n}\n")
+		return fmt.Sprintf("&tender.Bytes{Value: %s}", v)
+	case "time.Time":
+		return fmt.Sprintf("&tender.Time{Value: %s}", v)
+	}
+
+	// slices
+	arr := fmt.Sprintf("arr%d", i)
+	g.printf("%s := &tender.Array{}\n", arr)
+	g.printf("for _, elem := range %s {\n", v)
+	elem := g.result(t.elem, "elem", i)
+	g.printf("%s.Value = append(%s.Value, %s)\n", arr, arr, elem)
+	g.printf("}\n")
+	return arr
+}
+
+// adapter writes the adapter for the signature.
+func (g *generator) adapter(name string, s *signature) {
+	g.printf("\n")
+	comment := fmt.Sprintf("%s transform a function of '%s' signature into "+
+		"CallableFunc type.", name, s)
+	for _, line := range wrap(comment, 77) {
+		g.printf("// %s\n", line)
+	}
+	for _, t := range append(s.params[:len(s.params):len(s.params)],
+		s.results...) {
+		if t.name == "time.Time" {
+			g.imports["time"] = true
+		}
+	}
+	g.printf("func %s(fn %s) tender.CallableFunc {\n", name, s)
+	g.printf("return func(args ...tender.Object) (ret tender.Object, err error) {\n")
+	g.printf("if len(args) != %d {\n", len(s.params))
+	g.printf("return nil, tender.ErrWrongNumArguments\n}\n")
+
+	var args []string
+	for i, p := range s.params {
+		v := fmt.Sprintf("%s%d", p.prefix, i+1)
+		g.argument(p, i, v)
+		args = append(args, v)
+	}
+	call := fmt.Sprintf("fn(%s)", strings.Join(args, ", "))
+
+	switch {
+	case len(s.results) == 0 && !s.err:
+		g.printf("%s\n", call)
+		g.printf("return tender.NullValue, nil\n")
+	case len(s.results) == 0:
+		g.printf("return wrapError(%s), nil\n", call)
+	default:
+		var results []string
+		if len(s.results) == 1 {
+			results = []string{"res"}
+		} else {
+			for i := range s.results {
+				results = append(results, fmt.Sprintf("r%d", i+1))
+			}
+		}
+		lhs := results
+		if s.err {
+			lhs = append(lhs[:len(lhs):len(lhs)], "err")
+		}
+		g.printf("%s := %s\n", strings.Join(lhs, ", "), call)
+		if s.err {
+			g.printf("if err != nil {\nreturn wrapError(err), nil\n}\n")
+		}
+		var objs []string
+		for i, r := range s.results {
+			objs = append(objs, g.result(r, results[i], i+1))
+		}
+		if len(objs) == 1 {
+			g.printf("return %s, nil\n", objs[0])
+		} else {
+			g.printf("return &tender.Array{\nValue: []tender.Object{\n")
+			for _, o := range objs {
+				g.printf("%s,\n", o)
+			}
+			g.printf("},\n}, nil\n")
+		}
+	}
+	g.printf("}\n}\n")
+}
+
+// wrap splits text into lines of at most width characters.
+func wrap(text string, width int) []string {
+	var lines []string
+	line := ""
+	for _, word := range strings.Fields(text) {
+		if line != "" && len(line)+1+len(word) > width {
+			lines = append(lines, line)
+			line = ""
+		}
+		if line != "" {
+			line += " "
+		}
+		line += word
+	}
+	return append(lines, line)
+}
+
+// member is a function of the wrapped package.
+type member struct {
+	name    string // module member name
+	goName  string
+	params  []string
+	sig     *signature
+	adapter string
+}
+
+func main() {
+	flag.Parse()
+	if *pkgPath == "" {
+		fmt.Fprintln(os.Stderr, "usage: tendergen -pkg {import-path} [flags]")
+		flag.PrintDefaults()
+		os.Exit(2)
+	}
+	if err := generate(); err != nil {
+		fmt.Fprintln(os.Stderr, "tendergen:", err)
+		os.Exit(1)
+	}
+}
+
+func generate() error {
+	fset := token.NewFileSet()
+	pkg, err := importer.ForCompiler(fset, "source", nil).Import(*pkgPath)
+	if err != nil {
+		return err
+	}
+	name := *moduleName
+	if name == "" {
+		name = pkg.Name()
+	}
+	out := *outFile
+	if out == "" {
+		out = name + "_module.go"
+	}
+	adapters, funcs, dirPkg, err := existingDecls(filepath.Dir(out),
+		filepath.Base(out))
+	if err != nil {
+		return err
+	}
+	pkgName := *outPkg
+	if pkgName == "" {
+		pkgName = os.Getenv("GOPACKAGE")
+	}
+	if pkgName == "" {
+		pkgName = dirPkg
+	}
+	if pkgName == "" {
+		return fmt.Errorf("unknown output package name; use -package")
+	}
+
+	include := make(map[string]bool)
+	for _, fn := range strings.Split(*funcList, ",") {
+		if fn = strings.TrimSpace(fn); fn != "" {
+			include[fn] = true
+		}
+	}
+
+	var members []*member
+	newAdapters := make(map[string]*signature)
+	for _, goName := range pkg.Scope().Names() {
+		fn, ok := pkg.Scope().Lookup(goName).(*types.Func)
+		if !ok || !fn.Exported() || len(include) > 0 && !include[goName] {
+			continue
+		}
+		sig := fn.Type().(*types.Signature)
+		s, err := convertSignature(sig)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "tendergen: skipping %s: %s\n", goName, err)
+			continue
+		}
+		m := &member{name: snakeCase(goName), goName: goName, sig: s}
+		for i := 0; i < sig.Params().Len(); i++ {
+			p := sig.Params().At(i).Name()
+			if p == "" || p == "_" {
+				p = argumentName(i)
+			}
+			m.params = append(m.params, p)
+		}
+		if a, ok := adapters[s.String()]; ok {
+			m.adapter = a
+		} else {
+			m.adapter = s.adapter()
+			if funcs[m.adapter] {
+				return fmt.Errorf("adapter %s for '%s' is declared with a different signature",
+					m.adapter, s)
+			}
+			newAdapters[m.adapter] = s
+		}
+		members = append(members, m)
+	}
+	if len(members) == 0 {
+		return fmt.Errorf("no functions to wrap in %s", *pkgPath)
+	}
+
+	g := &generator{imports: map[string]bool{*pkgPath: true}}
+	varName := strings.Replace(snakeCase(name), "_", "", -1)
+	g.printf("\nvar %sModule = map[string]tender.Object{\n", varName)
+	for _, m := range members {
+		g.printf("%q: &tender.UserFunction{\n", m.name)
+		g.printf("Name: %q,\n", m.name)
+		g.printf("Value: %s(%s.%s),\n", m.adapter, pkg.Name(), m.goName)
+		g.printf("}, // %s(%s) => %s\n", m.name, strings.Join(m.params, ", "),
+			m.sig.resultType())
+	}
+	g.printf("}\n")
+
+	g.printf("\n// %sSignatures are the function signatures of the %s module "+
+		"for the\n// type checker.\n", varName, name)
+	g.printf("var %sSignatures = map[string]string{\n", varName)
+	for _, m := range members {
+		g.printf("%q: %q,\n", m.name, m.sig.checkerSignature())
+	}
+	g.printf("}\n")
+
+	if *register {
+		g.printf("\nfunc init() {\n")
+		g.printf("BuiltinModules[%q] = %sModule\n", name, varName)
+		g.printf("ModuleSignatures[%q] = %sSignatures\n", name, varName)
+		g.printf("}\n")
+	}
+
+	var names []string
+	for n := range newAdapters {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+	for _, n := range names {
+		g.adapter(n, newAdapters[n])
+	}
+	if len(names) > 0 && !funcs["wrapError"] {
+		g.printf("\nfunc wrapError(err error) tender.Object {\n")
+		g.printf("if err == nil {\nreturn tender.TrueValue\n}\n")
+		g.printf("return &tender.Error{Value: &tender.String{Value: err.Error()}}\n")
+		g.printf("}\n")
+	}
+
+	var head bytes.Buffer
+	fmt.Fprintf(&head, "// Code generated by tendergen %s; DO NOT EDIT.\n\n",
+		strings.Join(os.Args[1:], " "))
+	fmt.Fprintf(&head, "package %s\n\nimport (\n", pkgName)
+	var imports []string
+	for imp := range g.imports {
+		imports = append(imports, imp)
+	}
+	sort.Strings(imports)
+	for _, imp := range imports {
+		fmt.Fprintf(&head, "%q\n", imp)
+	}
+	fmt.Fprintf(&head, "\n%q\n)\n", "github.com/2dprototype/tender")
+	src, err := format.Source(append(head.Bytes(), g.buf.Bytes()...))
+	if err != nil {
+		return fmt.Errorf("formatting generated code: %s", err)
+	}
+	return ioutil.WriteFile(out, src, 0644)
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+)
+
+var updateGolden = flag.Bool("update", false,
+	"rewrite the golden file of the generated module")
+
+// TestGenerate generates a module from a few functions of the strings
+// package, compares it with the golden file and builds it.
+func TestGenerate(t *testing.T) {
+	args := []string{"-pkg", "strings", "-package", "gen",
+		"-funcs", "ContainsRune,Cut,EqualFold,Fields,Index,Join,Map,Repeat,ToUpper"}
+	defer func(args []string) { os.Args = args }(os.Args)
+	os.Args = append([]string{"tendergen"}, args...)
+	*pkgPath, *outPkg, *funcList = args[1], args[3], args[5]
+
+	// the module is built in a directory of this module, so that it can
+	// import tender; the underscore keeps ./... patterns from matching it
+	dir, err := ioutil.TempDir(".", "_gen")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	*outFile = filepath.Join(dir, "strings_module.go")
+	if err := generate(); err != nil {
+		t.Fatal(err)
+	}
+
+	src, err := ioutil.ReadFile(*outFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	golden := filepath.Join("testdata", "strings_module.go.golden")
+	if *updateGolden {
+		if err := ioutil.WriteFile(golden, src, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	expected, err := ioutil.ReadFile(golden)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(src, expected) {
+		t.Errorf("generated module differs from %s; run the test with -update "+
+			"to see the changes", golden)
+	}
+
+	out, err := exec.Command("go", "vet", "./"+filepath.Base(dir)).CombinedOutput()
+	if err != nil {
+		t.Errorf("generated module does not build: %v\n%s", err, out)
+	}
+}
@@ -2,16 +2,13 @@ package tender
 
 import (
 	"fmt"
-	"os"
 	"io"
 	"io/ioutil"
 	"path/filepath"
 	"reflect"
-	"strings"
 
 	"github.com/2dprototype/tender/parser"
 	"github.com/2dprototype/tender/token"
-	"github.com/2dprototype/tender/utils"
 )
 
 
@@ -21,6 +18,7 @@ type compilationScope struct {
 	Instructions []byte
 	SymbolInit   map[string]bool
 	SourceMap    map[int]parser.Pos
+	farJumps     map[int]int // jumps whose target does not fit 2 bytes
 }
 
 // loop represents a loop construct that the compiler uses to track the current
@@ -48,13 +46,26 @@ type Compiler struct {
 	parent          *Compiler
 	modulePath      string
 	importDir       string
+	importPaths     []string
 	constants       []Object
 	symbolTable     *SymbolTable
 	scopes          []compilationScope
 	scopeIndex      int
 	modules         *ModuleMap
 	compiledModules map[string]*CompiledFunction
+	moduleExports   map[string][]string
+	moduleCache     string
+	moduleSources   map[string]map[string]string
+	sources         map[string]string
+	exports         []*parser.Ident
+	exportValue     bool
+	exportKeys      []string
 	allowFileImport bool
+	typeChecks      bool
+	optimize        bool
+	moduleAliases   map[string]string
+	funcDocs        map[*parser.FuncLit]funcDoc
+	inlineCaches    int
 	loops           []*loop
 	loopIndex       int
 	trace           io.Writer
@@ -90,9 +101,12 @@ func NewCompiler(file *parser.SourceFile, symbolTable *SymbolTable, constants []
 		scopes:          []compilationScope{mainScope},
 		scopeIndex:      0,
 		loopIndex:       -1,
+		optimize:        true,
 		trace:           trace,
 		modules:         modules,
 		compiledModules: make(map[string]*CompiledFunction),
+		moduleExports:   make(map[string][]string),
+		moduleSources:   make(map[string]map[string]string),
 	}
 }
 
@@ -107,13 +121,29 @@ func (c *Compiler) Compile(node parser.Node) error {
 		}
 	}
 
+	if c.optimize {
+		if expr, ok := node.(parser.Expr); ok {
+			if o, ok := c.foldConstant(expr); ok {
+				c.emitConstant(node, o)
+				return nil
+			}
+		}
+	}
+
 	switch node := node.(type) {
 	case *parser.File:
+		if c.optimize {
+			c.moduleAliases = c.findModuleAliases(node)
+		}
 		for _, stmt := range node.Stmts {
 			if err := c.Compile(stmt); err != nil {
 				return err
 			}
 		}
+		c.widenJumps()
+		if c.optimize && c.parent == nil {
+			c.optimizeInstructions()
+		}
 	case *parser.ExprStmt:
 		if err := c.Compile(node.Expr); err != nil {
 			return err
@@ -260,6 +290,11 @@ func (c *Compiler) Compile(node parser.Node) error {
 				return err
 			}
 		}
+		if c.optimize {
+			if cond, ok := c.constantValue(node.Cond); ok {
+				return c.compileConstantIf(node, !cond.IsFalsy())
+			}
+		}
 		if err := c.Compile(node.Cond); err != nil {
 			return err
 		}
@@ -312,6 +347,7 @@ func (c *Compiler) Compile(node parser.Node) error {
 				node.Token.String()))
 		}
 	case *parser.FuncStmt:	
+		c.documentStmt(node, nil)
 		err := c.compileAssign(node, []parser.Expr{node.Ident}, []parser.Expr{node.Expr}, token.Define)
 		if err != nil {
 			return err
@@ -333,6 +369,7 @@ func (c *Compiler) Compile(node parser.Node) error {
 		}
 	case *parser.AssignStmt:
 		// fmt.Printf("%+v\n", node.LHS)
+		c.documentStmt(node, nil)
 		err := c.compileAssign(node, node.LHS, node.RHS, node.Token)
 		if err != nil {
 			return err
@@ -354,32 +391,40 @@ func (c *Compiler) Compile(node parser.Node) error {
 				c.emit(node, parser.OpGetFree, symbol.Index)
 		}
 	case *parser.ArrayLit:
-		for _, elem := range node.Elements {
-			if err := c.Compile(elem); err != nil {
-				return err
-			}
-		}
-		c.emit(node, parser.OpArray, len(node.Elements))
+		return c.compileLiteral(node, len(node.Elements), 1,
+			parser.OpArray, parser.OpArrayAppend, func(i int) error {
+				return c.Compile(node.Elements[i])
+			})
 	case *parser.MapLit:
-		for _, elt := range node.Elements {
-			// key
-			if len(elt.Key) > MaxStringLen {
-				return c.error(node, ErrStringLimit)
-			}
-			c.emit(node, parser.OpConstant,
-				c.addConstant(&String{Value: elt.Key}))
+		return c.compileLiteral(node, len(node.Elements), 2,
+			parser.OpMap, parser.OpMapInsert, func(i int) error {
+				elt := node.Elements[i]
+				// key
+				if len(elt.Key) > MaxStringLen {
+					return c.error(node, ErrStringLimit)
+				}
+				c.emit(node, parser.OpConstant,
+					c.addConstant(&String{Value: elt.Key}))
 
-			// value
-			if err := c.Compile(elt.Value); err != nil {
-				return err
-			}
-		}
-		c.emit(node, parser.OpMap, len(node.Elements)*2)
+				// value
+				c.documentFunc(elt.Key, elt.Value, elt.Doc)
+				return c.Compile(elt.Value)
+			})
 
 	case *parser.SelectorExpr: // selector on RHS side
 		if err := c.Compile(node.Expr); err != nil {
 			return err
 		}
+		if sel, ok := node.Sel.(*parser.StringLit); ok {
+			cidx := c.addConstant(&String{Value: sel.Value})
+			if slot := c.addInlineCache(); slot >= 0 && cidx <= 0xFFFF {
+				c.emit(node, parser.OpSelector, cidx, slot)
+				return nil
+			}
+			c.emit(node, parser.OpConstant, cidx)
+			c.emit(node, parser.OpIndex)
+			return nil
+		}
 		if err := c.Compile(node.Sel); err != nil {
 			return err
 		}
@@ -421,6 +466,12 @@ func (c *Compiler) Compile(node parser.Node) error {
 			s.LocalAssigned = true
 		}
 
+		if c.typeChecks {
+			if err := c.compileParamChecks(node.Type.Params); err != nil {
+				return err
+			}
+		}
+
 		if err := c.Compile(node.Body); err != nil {
 			return err
 		}
@@ -489,9 +540,14 @@ func (c *Compiler) Compile(node parser.Node) error {
 			Instructions:  instructions,
 			NumLocals:     numLocals,
 			NumParameters: len(node.Type.Params.List),
+			Params:        paramNames(node.Type.Params),
 			VarArgs:       node.Type.Params.VarArgs,
 			SourceMap:     sourceMap,
 		}
+		if doc, ok := c.funcDocs[node]; ok {
+			compiledFunction.Usage = doc.usage
+			compiledFunction.Doc = doc.doc
+		}
 		if len(freeSymbols) > 0 {
 			c.emit(node, parser.OpClosure,
 				c.addConstant(compiledFunction), len(freeSymbols))
@@ -506,6 +562,11 @@ func (c *Compiler) Compile(node parser.Node) error {
 
 		if node.Result == nil {
 			c.emit(node, parser.OpReturn, 0)
+		} else if call, ok := node.Result.(*parser.CallExpr); ok {
+			// calls in tail position reuse the frame of the function
+			if err := c.compileCall(call, parser.OpTailCall); err != nil {
+				return err
+			}
 		} else {
 			if err := c.Compile(node.Result); err != nil {
 				return err
@@ -513,114 +574,59 @@ func (c *Compiler) Compile(node parser.Node) error {
 			c.emit(node, parser.OpReturn, 1)
 		}
 	case *parser.CallExpr:
-		if err := c.Compile(node.Func); err != nil {
+		return c.compileCall(node, parser.OpCall)
+	case *parser.EmbedExpr:
+		if err := c.compileEmbed(node); err != nil {
 			return err
 		}
-		for _, arg := range node.Args {
-			if err := c.Compile(arg); err != nil {
-				return err
-			}
-		}
-		ellipsis := 0
-		if node.Ellipsis.IsValid() {
-			ellipsis = 1
-		}
-		c.emit(node, parser.OpCall, len(node.Args), ellipsis)
-	case *parser.EmbedExpr:
-		src, err := filepath.Abs(filepath.Join(c.importDir, node.FileSrc))
-		if err != nil {
-			return c.errorf(node, "embeding file path error: %s", err.Error())
+	case *parser.ImportExpr:
+		if _, _, err := c.compileImport(node); err != nil {
+			return err
 		}
-		var data []byte
-		data, err = ioutil.ReadFile(src)
+	case *parser.FromImportStmt:
+		names, known, err := c.compileImport(node.Expr)
 		if err != nil {
-			return c.errorf(node, "embeding file \"" + src + "\" not found!")
-		}
-		c.emit(node, parser.OpConstant, c.addConstant(&String{Value: string(data)}))
-	case *parser.ImportExpr:
-		if node.ModuleName == "" {
-			return c.errorf(node, "empty module name")
+			return err
 		}
-
-		if mod := c.modules.Get(node.ModuleName); mod != nil {
-			v, err := mod.Import(node.ModuleName)
-			if err != nil {
-				return err
-			}
-
-			switch v := v.(type) {
-			case []byte: // module written in Tender
-				compiled, err := c.compileModule(node, node.ModuleName, v, false)
-				if err != nil {
-					return err
-				}
-				c.emit(node, parser.OpConstant, c.addConstant(compiled))
-				c.emit(node, parser.OpCall, 0, 0)
-			case Object: // builtin module
-				c.emit(node, parser.OpConstant, c.addConstant(v))
-				// fmt.Printf("%+v\n", v)
-			default:
-				panic(fmt.Errorf("invalid import value type: %T", v))
-			}
-		} else if c.allowFileImport {
-			var modulePath string
-			exe, _ := os.Executable()
-			exeDir := filepath.Dir(exe)
-			moduleName := node.ModuleName
-			if !strings.HasSuffix(moduleName, ".td") {
-				moduleName += ".td"
+		if known {
+			exported := make(map[string]bool, len(names))
+			for _, name := range names {
+				exported[name] = true
 			}
-			
-			if strings.HasPrefix(node.ModuleName, "@") {
-				moduleName = moduleName[1:]
-				parts := strings.SplitN(moduleName, ":", 2)
-				if len(parts) != 2 {
-					return c.errorf(node, "module file format error: %s", moduleName)
-				}
-				username := parts[0]
-				repoPath := parts[1]
-				repoPaths := strings.Split(repoPath, "/")
-				repoName := repoPaths[0]
-				if len(repoPaths) == 1 {
-					modulePath = filepath.Join(exeDir, "pkg", "@" + username, repoName, "main.td")
-				} else {
-					modulePath = filepath.Join(exeDir, "pkg", "@" + username, repoPath)
-				}
-				
-				_, err := os.Stat(modulePath)
-				if os.IsNotExist(err) {
-					if err := utils.FetchTagsFromGithub(username, repoName); err != nil {
-						if err = utils.FetchFromGithub(username, repoName); err != nil {
-							fmt.Println("Error:", err)
-						}
-					}
+			for _, name := range node.Names {
+				if !exported[name.Name.Name] {
+					return c.errorf(name.Name, "'%s' is not exported by module '%s'",
+						name.Name.Name, node.Expr.ModuleName)
 				}
-			} else {			
-				packagePath := filepath.Join(exeDir, "pkg", moduleName)	
-				_, err := os.Stat(packagePath)
-				if os.IsNotExist(err) {
-					modulePath, err = filepath.Abs(filepath.Join(c.importDir, moduleName))
-					if err != nil {
-						return c.errorf(node, "module file path error: %s", err.Error())
-					}
-				} else {
-					modulePath = packagePath
-				}
-			}
-			
-			moduleSrc, err := ioutil.ReadFile(modulePath)
-			if err != nil {
-				return c.errorf(node, "module file read error: %s", err.Error())
 			}
+		}
 
-			compiled, err := c.compileModule(node, modulePath, moduleSrc, true)
+		// the module is stored in a hidden variable and each name is
+		// declared with a selector on it
+		module := &parser.Ident{
+			Name:    "(from " + node.Expr.ModuleName + ")",
+			NamePos: node.FromPos,
+		}
+		symbol := c.symbolTable.Define(module.Name)
+		if symbol.Scope == ScopeGlobal {
+			c.emit(node, parser.OpSetGlobal, symbol.Index)
+		} else {
+			c.emit(node, parser.OpDefineLocal, symbol.Index)
+			symbol.LocalAssigned = true
+		}
+		for _, name := range node.Names {
+			sel := &parser.SelectorExpr{
+				Expr: module,
+				Sel: &parser.StringLit{
+					Value:    name.Name.Name,
+					ValuePos: name.Name.NamePos,
+				},
+			}
+			err := c.compileAssign(name.Var(), []parser.Expr{name.Var()},
+				[]parser.Expr{sel}, token.Define)
 			if err != nil {
 				return err
 			}
-			c.emit(node, parser.OpConstant, c.addConstant(compiled))
-			c.emit(node, parser.OpCall, 0, 0)
-		} else {
-			return c.errorf(node, "module '%s' not found", node.ModuleName)
 		}
 	case *parser.ExportStmt:
 		// export statement must be in top-level scope
@@ -632,11 +638,43 @@ func (c *Compiler) Compile(node parser.Node) error {
 		if c.parent == nil {
 			break
 		}
+		if len(c.exports) > 0 {
+			return c.errorf(node, "export value not allowed in a module with named exports")
+		}
+		c.exportValue = true
+		if m, ok := node.Result.(*parser.MapLit); ok {
+			c.exportKeys = make([]string, len(m.Elements))
+			for i, elt := range m.Elements {
+				c.exportKeys[i] = elt.Key
+			}
+		}
+		c.documentFunc("", node.Result, node.Doc)
 		if err := c.Compile(node.Result); err != nil {
 			return err
 		}
 		c.emit(node, parser.OpImmutable)
 		c.emit(node, parser.OpReturn, 1)
+	case *parser.ExportDeclStmt:
+		// export declarations must be in top-level scope
+		if c.scopeIndex != 0 {
+			return c.errorf(node, "export not allowed inside function")
+		}
+		if c.symbolTable.block {
+			return c.errorf(node, "export not allowed inside block")
+		}
+		c.documentStmt(node.Decl, node.Doc)
+		if err := c.Compile(node.Decl); err != nil {
+			return err
+		}
+
+		// the declaration is compiled as usual in non-module code
+		if c.parent == nil {
+			break
+		}
+		if c.exportValue {
+			return c.errorf(node, "named export not allowed after export value")
+		}
+		c.exports = append(c.exports, node.Names()...)
 	case *parser.ErrorExpr:
 		if err := c.Compile(node.Expr); err != nil {
 			return err
@@ -648,6 +686,18 @@ func (c *Compiler) Compile(node parser.Node) error {
 		}
 		c.emit(node, parser.OpImmutable)
 	case *parser.CondExpr:
+		if c.optimize {
+			if cond, ok := c.constantValue(node.Cond); ok {
+				taken, skipped := node.True, node.False
+				if cond.IsFalsy() {
+					taken, skipped = node.False, node.True
+				}
+				if err := c.compileUnreachable(skipped); err != nil {
+					return err
+				}
+				return c.Compile(taken)
+			}
+		}
 		if err := c.Compile(node.Cond); err != nil {
 			return err
 		}
@@ -683,7 +733,9 @@ func (c *Compiler) Bytecode() *Bytecode {
 			Instructions: append(c.currentInstructions(), parser.OpSuspend),
 			SourceMap:    c.currentSourceMap(),
 		},
-		Constants: c.constants,
+		Constants:    c.constants,
+		InlineCaches: c.inlineCaches,
+		NumGlobals:   c.symbolTable.MaxSymbols() + 1,
 	}
 }
 
@@ -693,11 +745,31 @@ func (c *Compiler) EnableFileImport(enable bool) {
 	c.allowFileImport = enable
 }
 
+// EnableTypeChecks enables or disables runtime checks of annotated function
+// parameter types. Type annotations are ignored by default.
+func (c *Compiler) EnableTypeChecks(enable bool) {
+	c.typeChecks = enable
+}
+
+// EnableOptimizer enables or disables constant folding and peephole
+// optimization. The optimizer is enabled by default.
+func (c *Compiler) EnableOptimizer(enable bool) {
+	c.optimize = enable
+}
+
 // SetImportDir sets the initial import directory path for file imports.
 func (c *Compiler) SetImportDir(dir string) {
 	c.importDir = dir
 }
 
+// SetImportPaths sets the directories searched for file imports. They are
+// searched after the import directory and the vendor directory of the
+// project, and before the directories of the TENDER_PATH environment
+// variable.
+func (c *Compiler) SetImportPaths(paths ...string) {
+	c.importPaths = paths
+}
+
 func (c *Compiler) compileAssign(node parser.Node, lhs, rhs []parser.Expr, op token.Token) error {
 	numLHS, numRHS := len(lhs), len(rhs)
 	if numLHS > 1 || numRHS > 1 {
@@ -724,6 +796,10 @@ func (c *Compiler) compileAssign(node parser.Node, lhs, rhs []parser.Expr, op to
 		if !exists {
 			return c.errorf(node, "unresolved reference '%s'", ident)
 		}
+		if symbol.Scope == ScopeBuiltin {
+			return c.errorf(node, "cannot assign to builtin function '%s'",
+				ident)
+		}
 	}
 
 	// +=, -=, *=, /=
@@ -804,6 +880,55 @@ func (c *Compiler) compileAssign(node parser.Node, lhs, rhs []parser.Expr, op to
 	return nil
 }
 
+// compileParamChecks emits a type check for every annotated parameter of the
+// current function. Variadic parameters are only checked statically.
+func (c *Compiler) compileParamChecks(params *parser.IdentList) error {
+	for i, p := range params.List {
+		if p.Type == nil || (params.VarArgs && i == len(params.List)-1) {
+			continue
+		}
+		for _, name := range p.Type.Names {
+			if !IsKnownType(name) {
+				return c.errorf(p.Type, "unknown type '%s'", name)
+			}
+		}
+		c.emit(p, parser.OpCheckType, i,
+			c.addConstant(&String{Value: p.Name}),
+			c.addConstant(&String{Value: p.Type.String()}))
+	}
+	return nil
+}
+
+// paramNames returns the names of the parameters of a function.
+func paramNames(params *parser.IdentList) []string {
+	names := make([]string, len(params.List))
+	for i, p := range params.List {
+		names[i] = p.Name
+	}
+	return names
+}
+
+// compileConstantIf compiles an if statement whose condition is a constant.
+// The branch that is never taken is checked but not emitted.
+func (c *Compiler) compileConstantIf(node *parser.IfStmt, taken bool) error {
+	if !taken {
+		if err := c.compileUnreachable(node.Body); err != nil {
+			return err
+		}
+		if node.Else != nil {
+			return c.Compile(node.Else)
+		}
+		return nil
+	}
+	if err := c.Compile(node.Body); err != nil {
+		return err
+	}
+	if node.Else != nil {
+		return c.compileUnreachable(node.Else)
+	}
+	return nil
+}
+
 func (c *Compiler) compileLogical(node *parser.BinaryExpr) error {
 	// left side term
 	if err := c.Compile(node.LHS); err != nil {
@@ -845,8 +970,15 @@ func (c *Compiler) compileForStmt(stmt *parser.ForStmt) error {
 
 	// condition expression
 	postCondPos := -1
-	if stmt.Cond != nil {
-		if err := c.Compile(stmt.Cond); err != nil {
+	cond := stmt.Cond
+	if c.optimize && cond != nil {
+		// a constant true condition needs no check
+		if v, ok := c.constantValue(cond); ok && !v.IsFalsy() {
+			cond = nil
+		}
+	}
+	if cond != nil {
+		if err := c.Compile(cond); err != nil {
 			return err
 		}
 		// condition jump position
@@ -1015,6 +1147,146 @@ func (c *Compiler) checkCyclicImports(
 	return nil
 }
 
+// compileImport compiles an import expression and returns the names exported
+// by the module. known is false if the names are not known at compile time,
+// e.g. for a module exporting a value other than a map literal.
+func (c *Compiler) compileImport(node *parser.ImportExpr) (names []string, known bool, err error) {
+	if node.ModuleName == "" {
+		return nil, false, c.errorf(node, "empty module name")
+	}
+
+	if mod := c.modules.Get(node.ModuleName); mod != nil {
+		v, err := mod.Import(node.ModuleName)
+		if err != nil {
+			return nil, false, err
+		}
+
+		switch v := v.(type) {
+		case []byte: // module written in Tender
+			compiled, err := c.compileModule(node, node.ModuleName, v, false)
+			if err != nil {
+				return nil, false, err
+			}
+			c.emit(node, parser.OpConstant, c.addConstant(compiled))
+			c.emit(node, parser.OpCall, 0, 0)
+			names, known = c.loadModuleExports(node.ModuleName)
+		case Object: // builtin module
+			c.emit(node, parser.OpConstant, c.addConstant(v))
+			if m, ok := v.(*ImmutableMap); ok {
+				for name := range m.Value {
+					names = append(names, name)
+				}
+				known = true
+			}
+		default:
+			panic(fmt.Errorf("invalid import value type: %T", v))
+		}
+	} else if c.allowFileImport {
+		modulePath, err := c.resolveModule(node.ModuleName)
+		if err != nil {
+			return nil, false, c.errorf(node, "%s", err.Error())
+		}
+		moduleSrc, err := ioutil.ReadFile(modulePath)
+		if err != nil {
+			return nil, false, c.errorf(node, "module file read error: %s", err.Error())
+		}
+
+		compiled, err := c.compileModule(node, modulePath, moduleSrc, true)
+		if err != nil {
+			return nil, false, err
+		}
+		c.emit(node, parser.OpConstant, c.addConstant(compiled))
+		c.emit(node, parser.OpCall, 0, 0)
+		names, known = c.loadModuleExports(modulePath)
+	} else {
+		return nil, false, c.errorf(node, "module '%s' not found", node.ModuleName)
+	}
+	return names, known, nil
+}
+
+// compileExports returns the map of the named exports of a module at the end
+// of the module function.
+func (c *Compiler) compileExports() error {
+	if len(c.exports) == 0 {
+		return nil
+	}
+	last := c.exports[len(c.exports)-1]
+	err := c.compileLiteral(last, len(c.exports), 2,
+		parser.OpMap, parser.OpMapInsert, func(i int) error {
+			ident := c.exports[i]
+			c.emit(ident, parser.OpConstant, c.addConstant(&String{Value: ident.Name}))
+			return c.Compile(ident)
+		})
+	if err != nil {
+		return err
+	}
+	c.emit(last, parser.OpImmutable)
+	c.emit(last, parser.OpReturn, 1)
+	return nil
+}
+
+// literalChunkSize is the maximum number of elements of an array or map
+// literal that are pushed on the stack at once.
+const literalChunkSize = 1024
+
+// compileLiteral compiles an array or map literal of n elements, each of
+// which compileElem pushes as size values. Large literals are created from
+// their first chunk of elements with op, and the remaining chunks are added
+// with appendOp, so they do not need a stack as large as the literal.
+func (c *Compiler) compileLiteral(
+	node parser.Node,
+	n, size int,
+	op, appendOp parser.Opcode,
+	compileElem func(i int) error,
+) error {
+	for start := 0; start == 0 || start < n; start += literalChunkSize {
+		end := start + literalChunkSize
+		if end > n {
+			end = n
+		}
+		for i := start; i < end; i++ {
+			if err := compileElem(i); err != nil {
+				return err
+			}
+		}
+		if start == 0 {
+			c.emit(node, op, end*size)
+		} else {
+			c.emit(node, appendOp, (end-start)*size)
+		}
+	}
+	return nil
+}
+
+// exportNames returns the names exported by the module compiled by c, and
+// whether they are known.
+func (c *Compiler) exportNames() ([]string, bool) {
+	if c.exportValue {
+		return c.exportKeys, c.exportKeys != nil
+	}
+	names := make([]string, len(c.exports))
+	for i, ident := range c.exports {
+		names[i] = ident.Name
+	}
+	return names, true
+}
+
+func (c *Compiler) loadModuleExports(modulePath string) ([]string, bool) {
+	if c.parent != nil {
+		return c.parent.loadModuleExports(modulePath)
+	}
+	names, ok := c.moduleExports[modulePath]
+	return names, ok
+}
+
+func (c *Compiler) storeModuleExports(modulePath string, names []string) {
+	if c.parent != nil {
+		c.parent.storeModuleExports(modulePath, names)
+		return
+	}
+	c.moduleExports[modulePath] = names
+}
+
 func (c *Compiler) compileModule(node parser.Node, modulePath string, src []byte, isFile bool) (*CompiledFunction, error) {
 	if err := c.checkCyclicImports(node, modulePath); err != nil {
 		return nil, err
@@ -1022,11 +1294,18 @@ func (c *Compiler) compileModule(node parser.Node, modulePath string, src []byte
 
 	compiledModule, exists := c.loadCompiledModule(modulePath)
 	if exists {
+		c.addSources(c.root().moduleSources[modulePath])
 		return compiledModule, nil
 	}
+	if isFile {
+		if compiledModule, ok := c.loadCachedModule(modulePath, src); ok {
+			c.storeCompiledModule(modulePath, compiledModule)
+			return compiledModule, nil
+		}
+	}
 
 	modFile := c.file.Set().AddFile(modulePath, -1, len(src))
-	p := parser.NewParser(modFile, src, nil)
+	p := parser.NewParserWithMode(modFile, src, nil, parser.ParseComments)
 	file, err := p.ParseFile()
 	if err != nil {
 		return nil, err
@@ -1046,12 +1325,25 @@ func (c *Compiler) compileModule(node parser.Node, modulePath string, src []byte
 	if err := moduleCompiler.Compile(file); err != nil {
 		return nil, err
 	}
+	if err := moduleCompiler.compileExports(); err != nil {
+		return nil, err
+	}
+	if names, known := moduleCompiler.exportNames(); known {
+		c.storeModuleExports(modulePath, names)
+	}
 
 	// code optimization
 	moduleCompiler.optimizeFunc(node)
 	compiledFunc := moduleCompiler.Bytecode().MainFunction
 	compiledFunc.NumLocals = symbolTable.MaxSymbols()
 	c.storeCompiledModule(modulePath, compiledFunc)
+
+	moduleCompiler.addSource(modulePath, src)
+	c.root().moduleSources[modulePath] = moduleCompiler.sources
+	c.addSources(moduleCompiler.sources)
+	if isFile && c.moduleCache != "" {
+		c.storeCachedModule(modulePath, src, compiledFunc, moduleCompiler.sources)
+	}
 	return compiledFunc, nil
 }
 
@@ -1136,8 +1428,11 @@ func (c *Compiler) fork(file *parser.SourceFile, modulePath string, symbolTable
 	child.modulePath = modulePath // module file path
 	child.parent = c              // parent to set to current compiler
 	child.allowFileImport = c.allowFileImport
+	child.typeChecks = c.typeChecks
+	child.optimize = c.optimize
 	child.importDir = c.importDir
-	if isFile && c.importDir != "" {
+	child.moduleCache = c.moduleCache
+	if isFile {
 		child.importDir = filepath.Dir(modulePath)
 	}
 	return child
@@ -1171,6 +1466,19 @@ func (c *Compiler) addConstant(o Object) int {
 	return len(c.constants) - 1
 }
 
+// addInlineCache returns the index of a new selector inline cache, or -1 if
+// the operand of OpSelector cannot hold more.
+func (c *Compiler) addInlineCache() int {
+	if c.parent != nil {
+		return c.parent.addInlineCache()
+	}
+	if c.inlineCaches > 0xFFFF {
+		return -1
+	}
+	c.inlineCaches++
+	return c.inlineCaches - 1
+}
+
 func (c *Compiler) addInstruction(b []byte) int {
 	posNewIns := len(c.currentInstructions())
 	c.scopes[c.scopeIndex].Instructions = append(
@@ -1189,10 +1497,84 @@ func (c *Compiler) replaceInstruction(pos int, inst []byte) {
 
 func (c *Compiler) changeOperand(opPos int, operand ...int) {
 	op := c.currentInstructions()[opPos]
+	if _, ok := parser.WideOpcodes[op]; ok && jumpOperand(op) == 0 {
+		// the jump is widened when the function is complete, as it changes
+		// the position of the instructions after it
+		scope := &c.scopes[c.scopeIndex]
+		if operand[0] > 0xFFFF {
+			if scope.farJumps == nil {
+				scope.farJumps = make(map[int]int)
+			}
+			scope.farJumps[opPos] = operand[0]
+			return
+		}
+		delete(scope.farJumps, opPos)
+	}
 	inst := MakeInstruction(op, operand...)
 	c.replaceInstruction(opPos, inst)
 }
 
+// widenJumps replaces all jumps of the current function with wide jumps if
+// the target of any of them does not fit in 2 bytes.
+func (c *Compiler) widenJumps() {
+	scope := &c.scopes[c.scopeIndex]
+	if len(scope.farJumps) == 0 {
+		return
+	}
+
+	var newInsts []byte
+	posMap := make(map[int]int) // old position to new position
+	iterateInstructions(scope.Instructions,
+		func(pos int, opcode parser.Opcode, operands []int) bool {
+			posMap[pos] = len(newInsts)
+			if wide, ok := parser.WideOpcodes[opcode]; ok &&
+				jumpOperand(opcode) == 0 {
+				if dst, ok := scope.farJumps[pos]; ok {
+					operands[0] = dst
+				}
+				opcode = wide
+			}
+			newInsts = append(newInsts,
+				MakeInstruction(opcode, operands...)...)
+			return true
+		})
+	posMap[len(scope.Instructions)] = len(newInsts)
+
+	iterateInstructions(newInsts,
+		func(pos int, opcode parser.Opcode, operands []int) bool {
+			if n := jumpOperand(opcode); n >= 0 {
+				operands[n] = posMap[operands[n]]
+				copy(newInsts[pos:], MakeInstruction(opcode, operands...))
+			}
+			return true
+		})
+
+	newSourceMap := make(map[int]parser.Pos)
+	for pos, srcPos := range scope.SourceMap {
+		newSourceMap[posMap[pos]] = srcPos
+	}
+	scope.Instructions = newInsts
+	scope.SourceMap = newSourceMap
+	scope.farJumps = nil
+}
+
+func (c *Compiler) compileCall(node *parser.CallExpr, op parser.Opcode) error {
+	if err := c.Compile(node.Func); err != nil {
+		return err
+	}
+	for _, arg := range node.Args {
+		if err := c.Compile(arg); err != nil {
+			return err
+		}
+	}
+	ellipsis := 0
+	if node.Ellipsis.IsValid() {
+		ellipsis = 1
+	}
+	c.emit(node, op, len(node.Args), ellipsis)
+	return nil
+}
+
 // optimizeFunc performs some code-level optimization for the current function
 // instructions. It also removes unreachable (dead code) instructions and adds
 // "returns" instruction if needed.
@@ -1201,14 +1583,17 @@ func (c *Compiler) optimizeFunc(node parser.Node) {
 	// or instructions between RETURN and jump target position
 	// are considered as unreachable.
 
+	c.widenJumps()
+	if c.optimize {
+		c.optimizeInstructions()
+	}
+
 	// pass 1. identify all jump destinations
 	dsts := make(map[int]bool)
 	iterateInstructions(c.scopes[c.scopeIndex].Instructions,
 		func(pos int, opcode parser.Opcode, operands []int) bool {
-			switch opcode {
-			case parser.OpJump, parser.OpJumpFalsy,
-				parser.OpAndJump, parser.OpOrJump:
-				dsts[operands[0]] = true
+			if n := jumpOperand(opcode); n >= 0 {
+				dsts[operands[n]] = true
 			}
 			return true
 		})
@@ -1221,7 +1606,7 @@ func (c *Compiler) optimizeFunc(node parser.Node) {
 	iterateInstructions(c.scopes[c.scopeIndex].Instructions,
 		func(pos int, opcode parser.Opcode, operands []int) bool {
 			switch {
-			case opcode == parser.OpReturn:
+			case opcode == parser.OpReturn || opcode == parser.OpTailCall:
 				if deadCode {
 					return true
 				}
@@ -1245,27 +1630,24 @@ func (c *Compiler) optimizeFunc(node parser.Node) {
 	newEndPost := len(newInsts)
 	iterateInstructions(newInsts,
 		func(pos int, opcode parser.Opcode, operands []int) bool {
-			switch opcode {
-			case parser.OpJump, parser.OpJumpFalsy, parser.OpAndJump,
-				parser.OpOrJump:
-				newDst, ok := posMap[operands[0]]
+			if n := jumpOperand(opcode); n >= 0 {
+				newDst, ok := posMap[operands[n]]
 				if ok {
-					copy(newInsts[pos:],
-						MakeInstruction(opcode, newDst))
-				} else if endPos == operands[0] {
+					operands[n] = newDst
+				} else if endPos == operands[n] {
 					// there's a jump instruction that jumps to the end of
 					// function compiler should append "return".
-					copy(newInsts[pos:],
-						MakeInstruction(opcode, newEndPost))
+					operands[n] = newEndPost
 					appendReturn = true
 				} else {
 					panic(fmt.Errorf("invalid jump position: %d", newDst))
 				}
+				copy(newInsts[pos:], MakeInstruction(opcode, operands...))
 			}
 			lastOp = opcode
 			return true
 		})
-	if lastOp != parser.OpReturn {
+	if lastOp != parser.OpReturn && lastOp != parser.OpTailCall {
 		appendReturn = true
 	}
 
@@ -1296,6 +1678,9 @@ func (c *Compiler) emit(
 		filePos = node.Pos()
 	}
 
+	if wide, ok := parser.WideOpcodes[opcode]; ok && operands[0] > 0xFFFF {
+		opcode = wide
+	}
 	inst := MakeInstruction(opcode, operands...)
 	pos := c.addInstruction(inst)
 	c.scopes[c.scopeIndex].SourceMap[pos] = filePos
@@ -1339,6 +1724,20 @@ func resolveAssignLHS(
 	return
 }
 
+// jumpOperand returns the index of the jump position operand of a jump
+// instruction, or -1 if op is not a jump.
+func jumpOperand(op parser.Opcode) int {
+	switch op {
+	case parser.OpJump, parser.OpJumpFalsy, parser.OpAndJump,
+		parser.OpOrJump, parser.OpJumpW, parser.OpJumpFalsyW,
+		parser.OpAndJumpW, parser.OpOrJumpW:
+		return 0
+	case parser.OpCompareJump:
+		return 1
+	}
+	return -1
+}
+
 func iterateInstructions(
 	b []byte,
 	fn func(pos int, opcode parser.Opcode, operands []int) bool,
@@ -0,0 +1,231 @@
+package tender
+
+import (
+	"bytes"
+	"flag"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/2dprototype/tender/parser"
+)
+
+var updateGolden = flag.Bool("update", false,
+	"rewrite the golden files of the conformance tests")
+
+// conformanceDir holds the conformance cases. Each case is a source file
+// name.td with the expected standard output in name.out and, if the case
+// fails to compile or run, the expected error in name.err.
+const conformanceDir = "testdata/conformance"
+
+// conformanceModes are the compiler and VM settings every case is run with.
+// All of them must give the same output.
+var conformanceModes = []struct {
+	name      string
+	optimize  bool
+	registers bool
+}{
+	{"stack", true, false},
+	{"stack-O0", false, false},
+	{"registers", true, true},
+}
+
+// compileConformance compiles a case the way the tender command does.
+func compileConformance(
+	path string,
+	optimize, registers bool,
+) (*Bytecode, error) {
+	src, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	fileSet := parser.NewFileSet()
+	srcFile := fileSet.AddFile(filepath.Base(path), -1, len(src))
+	file, err := parser.NewParserWithMode(srcFile, src, nil, parser.ParseComments).ParseFile()
+	if err != nil {
+		return nil, err
+	}
+	c := NewCompiler(srcFile, nil, nil, nil, nil)
+	c.EnableFileImport(true)
+	c.EnableTypeChecks(true)
+	c.EnableOptimizer(optimize)
+	c.SetImportDir(filepath.Dir(path))
+	if err := c.Compile(file); err != nil {
+		return nil, err
+	}
+	bytecode := c.Bytecode()
+	bytecode.RemoveDuplicates()
+	if registers {
+		bytecode.TranslateRegisters()
+	}
+	return bytecode, nil
+}
+
+// captureStdout runs fn with os.Stdout redirected and returns what it
+// wrote. The print builtins write to os.Stdout directly.
+func captureStdout(t *testing.T, fn func()) string {
+	t.Helper()
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	done := make(chan string)
+	go func() {
+		var buf bytes.Buffer
+		_, _ = io.Copy(&buf, r)
+		done <- buf.String()
+	}()
+	stdout := os.Stdout
+	os.Stdout = w
+	defer func() {
+		os.Stdout = stdout
+	}()
+	fn()
+	os.Stdout = stdout
+	_ = w.Close()
+	return <-done
+}
+
+// runConformance compiles and runs a case and returns its output and error
+// text. Absolute paths in errors are made relative to the case directory.
+func runConformance(
+	t *testing.T,
+	path string,
+	optimize, registers bool,
+) (bytecode *Bytecode, out, errText string) {
+	var err error
+	out = captureStdout(t, func() {
+		bytecode, err = compileConformance(path, optimize, registers)
+		if err == nil {
+			err = NewVM(bytecode, nil, -1).Run()
+		}
+	})
+	if err != nil {
+		dir, _ := filepath.Abs(filepath.Dir(path))
+		errText = strings.Replace(err.Error(), dir+string(filepath.Separator),
+			"", -1) + "\n"
+	}
+	return
+}
+
+func conformanceCases(t *testing.T) []string {
+	t.Helper()
+	cases, err := filepath.Glob(filepath.Join(conformanceDir, "*.td"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cases) == 0 {
+		t.Fatal("no conformance cases found")
+	}
+	return cases
+}
+
+func readGolden(path string) string {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return ""
+	}
+	return string(data)
+}
+
+func writeGolden(t *testing.T, path, content string) {
+	t.Helper()
+	if content == "" {
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			t.Fatal(err)
+		}
+		return
+	}
+	if err := ioutil.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestConformance(t *testing.T) {
+	for _, path := range conformanceCases(t) {
+		base := strings.TrimSuffix(path, ".td")
+		t.Run(filepath.Base(base), func(t *testing.T) {
+			for _, mode := range conformanceModes {
+				_, out, errText := runConformance(t, path, mode.optimize,
+					mode.registers)
+				if *updateGolden && mode == conformanceModes[0] {
+					writeGolden(t, base+".out", out)
+					writeGolden(t, base+".err", errText)
+				}
+				if want := readGolden(base + ".out"); out != want {
+					t.Errorf("%s: output\n%s\nexpected\n%s", mode.name, out, want)
+				}
+				if want := readGolden(base + ".err"); errText != want {
+					t.Errorf("%s: error\n%s\nexpected\n%s",
+						mode.name, errText, want)
+				}
+			}
+		})
+	}
+}
+
+// collectOpcodes adds the opcodes of fn to used.
+func collectOpcodes(fn *CompiledFunction, used map[parser.Opcode]bool) {
+	insts := fn.Instructions
+	for i := 0; i < len(insts); {
+		op := insts[i]
+		used[op] = true
+		_, read := parser.ReadOperands(parser.OpcodeOperands[op], insts[i+1:])
+		i += 1 + read
+	}
+}
+
+// TestConformanceCoverage checks that the conformance cases use every
+// opcode and call every builtin function.
+func TestConformanceCoverage(t *testing.T) {
+	used := make(map[parser.Opcode]bool)
+	var corpus strings.Builder
+	for _, path := range conformanceCases(t) {
+		src, err := ioutil.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		corpus.Write(src)
+		for _, mode := range conformanceModes {
+			bytecode, err := compileConformance(path, mode.optimize,
+				mode.registers)
+			if err != nil {
+				continue
+			}
+			collectOpcodes(bytecode.MainFunction, used)
+			for _, cn := range bytecode.Constants {
+				if fn, ok := cn.(*CompiledFunction); ok {
+					collectOpcodes(fn, used)
+				}
+			}
+		}
+	}
+
+	// wide opcodes need more than 65535 constants or globals, or very long
+	// jumps, and the append opcodes literals of more than literalChunkSize
+	// elements; TestWideOperands covers them
+	wide := map[parser.Opcode]bool{
+		parser.OpArrayAppend: true,
+		parser.OpMapInsert:   true,
+	}
+	for _, op := range parser.WideOpcodes {
+		wide[op] = true
+	}
+	for op, name := range parser.OpcodeNames {
+		if name != "" && !wide[parser.Opcode(op)] &&
+			!used[parser.Opcode(op)] {
+			t.Errorf("opcode %s (%d) not covered", name, op)
+		}
+	}
+
+	for _, fn := range builtinFuncs {
+		call := regexp.MustCompile(`(^|[^\w.])` + fn.Name + `[ (]`)
+		if !call.MatchString(corpus.String()) {
+			t.Errorf("builtin function %s not covered", fn.Name)
+		}
+	}
+}
@@ -0,0 +1,266 @@
+// Package deps manages the packages a project imports with
+// `import "@user:repo"`. The packages and their versions are declared in a
+// tender.mod manifest, and the content hash of each installed version is
+// recorded in a tender.lock file which is verified before the package is
+// compiled.
+package deps
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+const (
+	// ManifestFile is the name of the file declaring the dependencies of a
+	// project.
+	ManifestFile = "tender.mod"
+
+	// LockFile is the name of the file recording the content hashes of the
+	// dependencies of a project.
+	LockFile = "tender.lock"
+
+	// VendorDir is the name of the directory of a project containing copies
+	// of its packages. It is also searched for other imported modules.
+	VendorDir = "vendor"
+)
+
+// Dependency is a package required by a project.
+type Dependency struct {
+	// Path is the package path in the form "@user:repo".
+	Path string
+	// Version is the tag or branch of the repository.
+	Version string
+	// Hash is the content hash of the installed package. It is only set for
+	// the entries of a lockfile.
+	Hash string
+}
+
+// Manifest is the content of a tender.mod file.
+//
+//	// comments
+//	require @user:repo v1.2.0
+type Manifest struct {
+	Requires []*Dependency
+}
+
+// Lock is the content of a tender.lock file.
+//
+//	@user:repo v1.2.0 sha256:...
+type Lock struct {
+	Entries []*Dependency
+}
+
+// ParsePath splits a package import path of the form "@user:repo[/path]"
+// into its user, repository and the path of the file inside the repository.
+func ParsePath(path string) (user, repo, file string, err error) {
+	if !strings.HasPrefix(path, "@") {
+		return "", "", "", fmt.Errorf("invalid package path '%s': missing '@'", path)
+	}
+	parts := strings.SplitN(path[1:], ":", 2)
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return "", "", "", fmt.Errorf("invalid package path '%s': expected @user:repo", path)
+	}
+	user = parts[0]
+	repo = parts[1]
+	if n := strings.IndexByte(repo, '/'); n >= 0 {
+		repo, file = repo[:n], repo[n+1:]
+	}
+	if !validName(user) || !validName(repo) {
+		return "", "", "", fmt.Errorf("invalid package path '%s'", path)
+	}
+	return user, repo, file, nil
+}
+
+// PackagePath returns the path of the package containing the file imported
+// with path, i.e. "@user:repo" for "@user:repo/lib/util".
+func PackagePath(path string) (string, error) {
+	user, repo, _, err := ParsePath(path)
+	if err != nil {
+		return "", err
+	}
+	return "@" + user + ":" + repo, nil
+}
+
+func validName(s string) bool {
+	if s == "" || s == "." || s == ".." {
+		return false
+	}
+	for _, r := range s {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+		case r == '-' || r == '_' || r == '.':
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// ParseManifest parses the content of a tender.mod file.
+func ParseManifest(data []byte) (*Manifest, error) {
+	m := &Manifest{}
+	err := parseLines(ManifestFile, data, func(fields []string) error {
+		if fields[0] != "require" {
+			return fmt.Errorf("unknown directive '%s'", fields[0])
+		}
+		if len(fields) != 3 {
+			return fmt.Errorf("usage: require @user:repo version")
+		}
+		dep, err := newDependency(fields[1], fields[2])
+		if err != nil {
+			return err
+		}
+		if m.Get(dep.Path) != nil {
+			return fmt.Errorf("duplicate requirement '%s'", dep.Path)
+		}
+		m.Requires = append(m.Requires, dep)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// Get returns the requirement of the package path or nil if the package is
+// not required.
+func (m *Manifest) Get(path string) *Dependency {
+	return find(m.Requires, path)
+}
+
+// Set adds the requirement of the package path or changes its version.
+func (m *Manifest) Set(path, version string) {
+	m.Requires = set(m.Requires, &Dependency{Path: path, Version: version})
+}
+
+// Remove removes the requirement of the package path and reports whether it
+// was required.
+func (m *Manifest) Remove(path string) bool {
+	var ok bool
+	m.Requires, ok = remove(m.Requires, path)
+	return ok
+}
+
+// Bytes returns the content of the tender.mod file.
+func (m *Manifest) Bytes() []byte {
+	var buf bytes.Buffer
+	for _, dep := range m.Requires {
+		fmt.Fprintf(&buf, "require %s %s\n", dep.Path, dep.Version)
+	}
+	return buf.Bytes()
+}
+
+// ParseLock parses the content of a tender.lock file.
+func ParseLock(data []byte) (*Lock, error) {
+	l := &Lock{}
+	err := parseLines(LockFile, data, func(fields []string) error {
+		if len(fields) != 3 {
+			return fmt.Errorf("expected @user:repo version hash")
+		}
+		dep, err := newDependency(fields[0], fields[1])
+		if err != nil {
+			return err
+		}
+		if !strings.HasPrefix(fields[2], hashPrefix) {
+			return fmt.Errorf("invalid hash '%s'", fields[2])
+		}
+		dep.Hash = fields[2]
+		if l.Get(dep.Path) != nil {
+			return fmt.Errorf("duplicate entry '%s'", dep.Path)
+		}
+		l.Entries = append(l.Entries, dep)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return l, nil
+}
+
+// Get returns the entry of the package path or nil if it is not locked.
+func (l *Lock) Get(path string) *Dependency {
+	return find(l.Entries, path)
+}
+
+// Set adds the entry of the package or replaces it.
+func (l *Lock) Set(dep *Dependency) {
+	l.Entries = set(l.Entries, dep)
+}
+
+// Remove removes the entry of the package path.
+func (l *Lock) Remove(path string) {
+	l.Entries, _ = remove(l.Entries, path)
+}
+
+// Bytes returns the content of the tender.lock file.
+func (l *Lock) Bytes() []byte {
+	var buf bytes.Buffer
+	for _, dep := range l.Entries {
+		fmt.Fprintf(&buf, "%s %s %s\n", dep.Path, dep.Version, dep.Hash)
+	}
+	return buf.Bytes()
+}
+
+func newDependency(path, version string) (*Dependency, error) {
+	_, _, file, err := ParsePath(path)
+	if err != nil {
+		return nil, err
+	}
+	if file != "" {
+		return nil, fmt.Errorf("invalid package path '%s': unexpected '/%s'", path, file)
+	}
+	if !validName(version) {
+		return nil, fmt.Errorf("invalid version '%s'", version)
+	}
+	return &Dependency{Path: path, Version: version}, nil
+}
+
+// parseLines calls fn with the fields of each line of data, skipping blank
+// lines and comments.
+func parseLines(name string, data []byte, fn func(fields []string) error) error {
+	s := bufio.NewScanner(bytes.NewReader(data))
+	for line := 1; s.Scan(); line++ {
+		text := s.Text()
+		if n := strings.Index(text, "//"); n >= 0 {
+			text = text[:n]
+		}
+		fields := strings.Fields(text)
+		if len(fields) == 0 {
+			continue
+		}
+		if err := fn(fields); err != nil {
+			return fmt.Errorf("%s:%d: %s", name, line, err.Error())
+		}
+	}
+	return s.Err()
+}
+
+func find(deps []*Dependency, path string) *Dependency {
+	for _, dep := range deps {
+		if dep.Path == path {
+			return dep
+		}
+	}
+	return nil
+}
+
+func set(deps []*Dependency, dep *Dependency) []*Dependency {
+	deps, _ = remove(deps, dep.Path)
+	deps = append(deps, dep)
+	sort.Slice(deps, func(i, j int) bool {
+		return deps[i].Path < deps[j].Path
+	})
+	return deps
+}
+
+func remove(deps []*Dependency, path string) ([]*Dependency, bool) {
+	for i, dep := range deps {
+		if dep.Path == path {
+			return append(deps[:i:i], deps[i+1:]...), true
+		}
+	}
+	return deps, false
+}
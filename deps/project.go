@@ -0,0 +1,349 @@
+package deps
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+const hashPrefix = "sha256:"
+
+// PkgDir is the directory packages are installed into. Each version of a
+// package is installed into {PkgDir}/@user/repo@version. It defaults to the
+// pkg directory next to the executable.
+var PkgDir = defaultPkgDir()
+
+func defaultPkgDir() string {
+	exe, _ := os.Executable()
+	return filepath.Join(filepath.Dir(exe), "pkg")
+}
+
+// Project is a directory with a tender.mod file.
+type Project struct {
+	Dir      string
+	Manifest *Manifest
+	Lock     *Lock
+}
+
+// FindProject returns the project of the tender.mod file in dir or its
+// closest parent directory. It returns nil if there is no tender.mod file.
+func FindProject(dir string) (*Project, error) {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, err
+	}
+	for {
+		if _, err := os.Stat(filepath.Join(dir, ManifestFile)); err == nil {
+			return LoadProject(dir)
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return nil, nil
+		}
+		dir = parent
+	}
+}
+
+// LoadProject reads the tender.mod and tender.lock files in dir. Missing
+// files are treated as empty.
+func LoadProject(dir string) (*Project, error) {
+	p := &Project{Dir: dir, Manifest: &Manifest{}, Lock: &Lock{}}
+	data, err := ioutil.ReadFile(filepath.Join(dir, ManifestFile))
+	if err == nil {
+		if p.Manifest, err = ParseManifest(data); err != nil {
+			return nil, err
+		}
+	} else if !os.IsNotExist(err) {
+		return nil, err
+	}
+	data, err = ioutil.ReadFile(filepath.Join(dir, LockFile))
+	if err == nil {
+		if p.Lock, err = ParseLock(data); err != nil {
+			return nil, err
+		}
+	} else if !os.IsNotExist(err) {
+		return nil, err
+	}
+	return p, nil
+}
+
+// Save writes the tender.mod and tender.lock files of the project.
+func (p *Project) Save() error {
+	err := ioutil.WriteFile(filepath.Join(p.Dir, ManifestFile),
+		p.Manifest.Bytes(), 0644)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(filepath.Join(p.Dir, LockFile), p.Lock.Bytes(),
+		0644)
+}
+
+// Add requires the package path at version, or at its newest version if
+// version is empty, installs it and locks its content hash.
+func (p *Project) Add(src Source, path, version string) (*Dependency, error) {
+	user, repo, file, err := ParsePath(path)
+	if err != nil {
+		return nil, err
+	}
+	if file != "" {
+		return nil, fmt.Errorf("invalid package path '%s': unexpected '/%s'", path, file)
+	}
+	if version == "" {
+		versions, err := src.Versions(user, repo)
+		if err != nil {
+			return nil, err
+		}
+		version = versions[0]
+	}
+	dep, err := newDependency(path, version)
+	if err != nil {
+		return nil, err
+	}
+	if dep.Hash, err = Install(src, dep); err != nil {
+		return nil, err
+	}
+	if locked := p.Lock.Get(path); locked != nil && locked.Version == dep.Version {
+		if err := checkHash(dep.Hash, locked, InstallDir(dep)); err != nil {
+			return nil, err
+		}
+	}
+	p.Manifest.Set(dep.Path, dep.Version)
+	p.Lock.Set(dep)
+	return dep, nil
+}
+
+// Remove removes the requirement of the package path.
+func (p *Project) Remove(path string) error {
+	if !p.Manifest.Remove(path) {
+		return fmt.Errorf("package '%s' is not required", path)
+	}
+	p.Lock.Remove(path)
+	return nil
+}
+
+// Update changes the required packages, or all packages if paths is empty,
+// to their newest versions. It returns the updated dependencies.
+func (p *Project) Update(src Source, paths ...string) ([]*Dependency, error) {
+	if len(paths) == 0 {
+		for _, dep := range p.Manifest.Requires {
+			paths = append(paths, dep.Path)
+		}
+	}
+	var updated []*Dependency
+	for _, path := range paths {
+		old := p.Manifest.Get(path)
+		if old == nil {
+			return updated, fmt.Errorf("package '%s' is not required", path)
+		}
+		locked := p.Lock.Get(path)
+		dep, err := p.Add(src, path, "")
+		if err != nil {
+			return updated, err
+		}
+		if locked == nil || locked.Version != dep.Version || locked.Hash != dep.Hash {
+			updated = append(updated, dep)
+		}
+	}
+	return updated, nil
+}
+
+// Verify installs the required packages that are not installed and checks
+// that the content of every package, or of its vendored copy, matches the
+// hash in the lockfile.
+func (p *Project) Verify(src Source) error {
+	for _, req := range p.Manifest.Requires {
+		if _, err := p.install(src, req.Path); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ModuleFile returns the path of the module file imported with name, e.g.
+// "@user:repo" or "@user:repo/lib/util". The package must be required in
+// tender.mod. It is installed from src if needed, and its content is
+// verified against the hash in tender.lock.
+func (p *Project) ModuleFile(src Source, name string) (string, error) {
+	path, err := PackagePath(name)
+	if err != nil {
+		return "", err
+	}
+	_, _, file, _ := ParsePath(name)
+	dir, err := p.install(src, path)
+	if err != nil {
+		return "", err
+	}
+	if file == "" {
+		return filepath.Join(dir, "main.td"), nil
+	}
+	if !strings.HasSuffix(file, ".td") {
+		file += ".td"
+	}
+	return filepath.Join(dir, filepath.FromSlash(file)), nil
+}
+
+// VendorDir returns the directory of the copy of the package in the vendor
+// directory of the project.
+func (p *Project) VendorDir(path string) string {
+	user, repo, _, _ := ParsePath(path)
+	return filepath.Join(p.Dir, VendorDir, "@"+user, repo)
+}
+
+// Vendor copies the required packages into the vendor directory of the
+// project, replacing previous copies. Vendored packages are imported
+// instead of the installed packages, and are verified against the lockfile
+// as well.
+func (p *Project) Vendor(src Source) error {
+	for _, req := range p.Manifest.Requires {
+		locked, err := p.locked(req.Path)
+		if err != nil {
+			return err
+		}
+		hash, err := Install(src, locked)
+		if err != nil {
+			return err
+		}
+		if err := checkHash(hash, locked, InstallDir(locked)); err != nil {
+			return err
+		}
+		dir := p.VendorDir(req.Path)
+		if err := os.RemoveAll(dir); err != nil {
+			return err
+		}
+		if err := copyDir(InstallDir(locked), dir); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (p *Project) locked(path string) (*Dependency, error) {
+	req := p.Manifest.Get(path)
+	if req == nil {
+		return nil, fmt.Errorf("package '%s' is not required in %s", path,
+			filepath.Join(p.Dir, ManifestFile))
+	}
+	locked := p.Lock.Get(path)
+	if locked == nil || locked.Version != req.Version {
+		return nil, fmt.Errorf("package '%s' %s is missing from %s; run 'tender pkg update %s'",
+			path, req.Version, LockFile, path)
+	}
+	return locked, nil
+}
+
+func (p *Project) install(src Source, path string) (string, error) {
+	locked, err := p.locked(path)
+	if err != nil {
+		return "", err
+	}
+	if dir := p.VendorDir(path); isDir(dir) {
+		hash, err := HashDir(dir)
+		if err != nil {
+			return "", err
+		}
+		if err := checkHash(hash, locked, dir); err != nil {
+			return "", err
+		}
+		return dir, nil
+	}
+	hash, err := Install(src, locked)
+	if err != nil {
+		return "", err
+	}
+	if err := checkHash(hash, locked, InstallDir(locked)); err != nil {
+		return "", err
+	}
+	return InstallDir(locked), nil
+}
+
+func checkHash(hash string, locked *Dependency, dir string) error {
+	if hash != locked.Hash {
+		return fmt.Errorf("package '%s' %s: checksum mismatch\n\tinstalled: %s\n\t%s: %s\n\t(%s)",
+			locked.Path, locked.Version, hash, LockFile, locked.Hash, dir)
+	}
+	return nil
+}
+
+func isDir(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && info.IsDir()
+}
+
+// InstallDir returns the directory the version of the package is installed
+// into.
+func InstallDir(dep *Dependency) string {
+	user, repo, _, _ := ParsePath(dep.Path)
+	return filepath.Join(PkgDir, "@"+user, repo+"@"+dep.Version)
+}
+
+// Install downloads the version of the package from src unless it is
+// already installed, and returns the hash of its content.
+func Install(src Source, dep *Dependency) (string, error) {
+	dir := InstallDir(dep)
+	if _, err := os.Stat(dir); err == nil {
+		return HashDir(dir)
+	}
+	user, repo, _, err := ParsePath(dep.Path)
+	if err != nil {
+		return "", err
+	}
+	if err := os.MkdirAll(filepath.Dir(dir), 0755); err != nil {
+		return "", err
+	}
+	tmp, err := ioutil.TempDir(filepath.Dir(dir), repo+".tmp")
+	if err != nil {
+		return "", err
+	}
+	defer os.RemoveAll(tmp)
+	if err := src.Fetch(user, repo, dep.Version, tmp); err != nil {
+		return "", err
+	}
+	if err := os.Chmod(tmp, 0755); err != nil {
+		return "", err
+	}
+	if err := os.Rename(tmp, dir); err != nil {
+		// the package may have been installed by another process
+		if _, statErr := os.Stat(dir); statErr != nil {
+			return "", err
+		}
+	}
+	return HashDir(dir)
+}
+
+// HashDir returns the hash of the files in dir. It is the SHA-256 of a list
+// of the SHA-256 and the slash-separated path of each file, sorted by path.
+func HashDir(dir string) (string, error) {
+	var files []string
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.Mode().IsRegular() {
+			rel, err := filepath.Rel(dir, path)
+			if err != nil {
+				return err
+			}
+			files = append(files, filepath.ToSlash(rel))
+		}
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+	sort.Strings(files)
+	var list strings.Builder
+	for _, file := range files {
+		data, err := ioutil.ReadFile(filepath.Join(dir, filepath.FromSlash(file)))
+		if err != nil {
+			return "", err
+		}
+		fmt.Fprintf(&list, "%x  %s\n", sha256.Sum256(data), file)
+	}
+	sum := sha256.Sum256([]byte(list.String()))
+	return hashPrefix + hex.EncodeToString(sum[:]), nil
+}
@@ -0,0 +1,280 @@
+package deps
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Source is where packages are downloaded from.
+type Source interface {
+	// Versions returns the versions of the repository, newest first.
+	Versions(user, repo string) ([]string, error)
+
+	// Fetch downloads a version of the repository and writes its files into
+	// dir.
+	Fetch(user, repo, version, dir string) error
+}
+
+// DefaultSource returns the source packages are downloaded from. It is
+// GitHub unless the TENDER_REGISTRY environment variable is set to a local
+// directory (see DirSource) or to the URL of a server with the same API as
+// GitHub (see GithubSource).
+func DefaultSource() Source {
+	registry := os.Getenv("TENDER_REGISTRY")
+	switch {
+	case registry == "":
+		return &GithubSource{}
+	case strings.HasPrefix(registry, "http://"), strings.HasPrefix(registry, "https://"):
+		registry = strings.TrimSuffix(registry, "/")
+		return &GithubSource{API: registry, Archive: registry}
+	default:
+		return &DirSource{Root: registry}
+	}
+}
+
+// GithubSource downloads the tags and branches of GitHub repositories.
+type GithubSource struct {
+	// API is the URL of the GitHub API. It defaults to https://api.github.com.
+	API string
+	// Archive is the URL the archives of repositories are downloaded from.
+	// It defaults to https://github.com.
+	Archive string
+	// Client is the HTTP client. It defaults to http.DefaultClient.
+	Client *http.Client
+}
+
+func (s *GithubSource) get(url string) ([]byte, error) {
+	client := s.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Get(url)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%s: status code %d", url, resp.StatusCode)
+	}
+	return ioutil.ReadAll(resp.Body)
+}
+
+// Versions returns the tags of the repository, newest first. A repository
+// without tags has the single version "main", its main branch.
+func (s *GithubSource) Versions(user, repo string) ([]string, error) {
+	api := s.API
+	if api == "" {
+		api = "https://api.github.com"
+	}
+	data, err := s.get(fmt.Sprintf("%s/repos/%s/%s/tags", api, user, repo))
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch tags: %w", err)
+	}
+	var tags []struct{ Name string }
+	if err := json.Unmarshal(data, &tags); err != nil {
+		return nil, fmt.Errorf("failed to parse tags: %w", err)
+	}
+	if len(tags) == 0 {
+		return []string{"main"}, nil
+	}
+	versions := make([]string, len(tags))
+	for i, tag := range tags {
+		versions[i] = tag.Name
+	}
+	SortVersions(versions)
+	return versions, nil
+}
+
+// Fetch downloads the archive of the tag or branch and extracts it into dir.
+func (s *GithubSource) Fetch(user, repo, version, dir string) error {
+	archive := s.Archive
+	if archive == "" {
+		archive = "https://github.com"
+	}
+	var data []byte
+	var err error
+	for _, ref := range []string{"tags", "heads"} {
+		data, err = s.get(fmt.Sprintf("%s/%s/%s/archive/refs/%s/%s.zip",
+			archive, user, repo, ref, version))
+		if err == nil {
+			break
+		}
+	}
+	if err != nil {
+		return fmt.Errorf("failed to download @%s:%s %s: %w", user, repo,
+			version, err)
+	}
+	return unzip(data, dir)
+}
+
+// unzip extracts a repository archive into dir, stripping the top-level
+// directory of the archive.
+func unzip(data []byte, dir string) error {
+	r, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return fmt.Errorf("failed to open archive: %w", err)
+	}
+	for _, file := range r.File {
+		parts := strings.SplitN(file.Name, "/", 2)
+		if len(parts) < 2 || parts[1] == "" {
+			continue
+		}
+		name := filepath.FromSlash(parts[1])
+		if !filepath.IsLocal(name) {
+			return fmt.Errorf("invalid file path in archive: %s", file.Name)
+		}
+		path := filepath.Join(dir, name)
+		if file.FileInfo().IsDir() {
+			if err := os.MkdirAll(path, 0755); err != nil {
+				return err
+			}
+			continue
+		}
+		if err := extract(file, path); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func extract(file *zip.File, path string) error {
+	rc, err := file.Open()
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	out, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, rc); err != nil {
+		_ = out.Close()
+		return err
+	}
+	return out.Close()
+}
+
+// DirSource is a registry in a local directory. Each version of a package is
+// a directory named {Root}/@user/repo/{version}.
+type DirSource struct {
+	Root string
+}
+
+// Versions returns the version directories of the repository, newest first.
+func (s *DirSource) Versions(user, repo string) ([]string, error) {
+	entries, err := ioutil.ReadDir(filepath.Join(s.Root, "@"+user, repo))
+	if err != nil {
+		return nil, fmt.Errorf("package @%s:%s not found in %s", user, repo,
+			s.Root)
+	}
+	var versions []string
+	for _, entry := range entries {
+		if entry.IsDir() {
+			versions = append(versions, entry.Name())
+		}
+	}
+	if len(versions) == 0 {
+		return nil, fmt.Errorf("package @%s:%s has no versions", user, repo)
+	}
+	SortVersions(versions)
+	return versions, nil
+}
+
+// Fetch copies the version directory into dir.
+func (s *DirSource) Fetch(user, repo, version, dir string) error {
+	src := filepath.Join(s.Root, "@"+user, repo, version)
+	if _, err := os.Stat(src); err != nil {
+		return fmt.Errorf("package @%s:%s has no version %s", user, repo,
+			version)
+	}
+	return copyDir(src, dir)
+}
+
+// copyDir copies the files of the directory src into dir.
+func copyDir(src, dir string) error {
+	return filepath.Walk(src, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(dir, rel)
+		if info.IsDir() {
+			return os.MkdirAll(target, 0755)
+		}
+		data, err := ioutil.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		return ioutil.WriteFile(target, data, 0644)
+	})
+}
+
+// SortVersions sorts versions newest first. Versions of the form v1.2.3 are
+// compared by their numbers and come before other versions, e.g. branches,
+// which are sorted by name.
+func SortVersions(versions []string) {
+	sort.SliceStable(versions, func(i, j int) bool {
+		return compareVersions(versions[i], versions[j]) > 0
+	})
+}
+
+func compareVersions(a, b string) int {
+	na, oka := versionNumbers(a)
+	nb, okb := versionNumbers(b)
+	switch {
+	case oka && !okb:
+		return 1
+	case !oka && okb:
+		return -1
+	case !oka && !okb:
+		return strings.Compare(b, a)
+	}
+	for i := 0; i < len(na) || i < len(nb); i++ {
+		var x, y int
+		if i < len(na) {
+			x = na[i]
+		}
+		if i < len(nb) {
+			y = nb[i]
+		}
+		if x != y {
+			if x > y {
+				return 1
+			}
+			return -1
+		}
+	}
+	return strings.Compare(b, a)
+}
+
+func versionNumbers(v string) ([]int, bool) {
+	v = strings.TrimPrefix(v, "v")
+	if n := strings.IndexAny(v, "-+"); n >= 0 {
+		v = v[:n]
+	}
+	var nums []int
+	for _, part := range strings.Split(v, ".") {
+		n, err := strconv.Atoi(part)
+		if err != nil || n < 0 {
+			return nil, false
+		}
+		nums = append(nums, n)
+	}
+	return nums, true
+}
@@ -0,0 +1,306 @@
+package tender
+
+import (
+	"archive/zip"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/2dprototype/tender/deps"
+)
+
+// writeFiles writes files, given as slash-separated paths relative to dir,
+// and their content.
+func writeFiles(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// setupRegistry creates a local registry of packages and installs packages
+// into a temporary directory.
+func setupRegistry(t *testing.T) *deps.DirSource {
+	t.Helper()
+	root := t.TempDir()
+	writeFiles(t, root, map[string]string{
+		"@alice/greet/v1.0.0/main.td":     `export { hi: fn(n) { return "hi " + n } }`,
+		"@alice/greet/v1.2.0/main.td":     `export { hi: fn(n) { return "hello " + n } }`,
+		"@alice/greet/v1.2.0/lib/x.td":    `export 42`,
+		"@alice/greet/v1.10.0-rc/main.td": `export { hi: fn(n) { return "hey " + n } }`,
+		"@alice/greet/dev/main.td":        `export { hi: fn(n) { return "dev " + n } }`,
+	})
+	pkgDir := deps.PkgDir
+	deps.PkgDir = t.TempDir()
+	t.Cleanup(func() { deps.PkgDir = pkgDir })
+	t.Setenv("TENDER_REGISTRY", root)
+	return &deps.DirSource{Root: root}
+}
+
+func runProject(dir, src string) (string, error) {
+	s := NewScript([]byte(src))
+	s.EnableFileImport(true)
+	if err := s.SetImportDir(dir); err != nil {
+		return "", err
+	}
+	c, err := s.Run()
+	if err != nil {
+		return "", err
+	}
+	return c.Get("out").String(), nil
+}
+
+func TestManifest(t *testing.T) {
+	m, err := deps.ParseManifest([]byte(`
+// dependencies
+require @b:repo v1.0.0
+require @a:repo main // branch
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	m.Set("@c:x", "v2")
+	m.Set("@b:repo", "v1.1.0")
+	expected := "require @a:repo main\nrequire @b:repo v1.1.0\nrequire @c:x v2\n"
+	if got := string(m.Bytes()); got != expected {
+		t.Errorf("manifest:\n%s\nexpected:\n%s", got, expected)
+	}
+	m2, err := deps.ParseManifest(m.Bytes())
+	if err != nil || !reflect.DeepEqual(m, m2) {
+		t.Errorf("manifest does not round-trip: %v", err)
+	}
+
+	l, err := deps.ParseLock([]byte("@a:repo main sha256:00\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if dep := l.Get("@a:repo"); dep == nil || dep.Hash != "sha256:00" {
+		t.Errorf("lock entry: %+v", dep)
+	}
+
+	for src, expected := range map[string]string{
+		"require @a:b":                     "tender.mod:1: usage: require @user:repo version",
+		"replace @a:b v1":                  "tender.mod:1: unknown directive 'replace'",
+		"\nrequire a:b v1":                 "tender.mod:2: invalid package path 'a:b': missing '@'",
+		"require @a:b/c v1":                "tender.mod:1: invalid package path '@a:b/c': unexpected '/c'",
+		"require @a:b ../v1":               "tender.mod:1: invalid version '../v1'",
+		"require @a:b v1\nrequire @a:b v2": "tender.mod:2: duplicate requirement '@a:b'",
+	} {
+		if _, err := deps.ParseManifest([]byte(src)); err == nil || err.Error() != expected {
+			t.Errorf("%q: error %v, expected %s", src, err, expected)
+		}
+	}
+	if _, err := deps.ParseLock([]byte("@a:b v1 md5:00")); err == nil {
+		t.Error("expected an error for an invalid hash")
+	}
+
+	versions := []string{"main", "v1.2.0", "v1.10.0", "v1.9", "dev", "v2.0.0-rc1"}
+	deps.SortVersions(versions)
+	expectedVersions := []string{"v2.0.0-rc1", "v1.10.0", "v1.9", "v1.2.0", "dev", "main"}
+	if !reflect.DeepEqual(versions, expectedVersions) {
+		t.Errorf("versions %v, expected %v", versions, expectedVersions)
+	}
+}
+
+func TestProjectImport(t *testing.T) {
+	src := setupRegistry(t)
+	dir := t.TempDir()
+	sub := filepath.Join(dir, "cmd")
+	if err := os.Mkdir(sub, 0755); err != nil {
+		t.Fatal(err)
+	}
+	writeFiles(t, dir, map[string]string{deps.ManifestFile: ""})
+	script := `out := import("@alice:greet").hi("bob")`
+
+	// the package must be required in tender.mod
+	_, err := runProject(sub, script)
+	if err == nil || !strings.Contains(err.Error(), "package '@alice:greet' is not required") {
+		t.Fatalf("error %v, expected a missing requirement", err)
+	}
+
+	p, err := deps.FindProject(sub)
+	if err != nil || p == nil || p.Dir != dir {
+		t.Fatalf("project %+v, error %v", p, err)
+	}
+	dep, err := p.Add(src, "@alice:greet", "v1.0.0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := p.Save(); err != nil {
+		t.Fatal(err)
+	}
+	if out, err := runProject(sub, script); err != nil || out != "hi bob" {
+		t.Fatalf("out %s, error %v", out, err)
+	}
+
+	// a version that is not locked is rejected
+	p.Manifest.Set("@alice:greet", "v1.2.0")
+	if err := p.Save(); err != nil {
+		t.Fatal(err)
+	}
+	_, err = runProject(sub, script)
+	if err == nil || !strings.Contains(err.Error(), "missing from tender.lock") {
+		t.Fatalf("error %v, expected a missing lock entry", err)
+	}
+
+	// update moves to the newest tagged version
+	updated, err := p.Update(src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(updated) != 1 || updated[0].Version != "v1.10.0-rc" {
+		t.Fatalf("updated %+v", updated)
+	}
+	if _, err := p.Add(src, "@alice:greet", "v1.2.0"); err != nil {
+		t.Fatal(err)
+	}
+	if err := p.Save(); err != nil {
+		t.Fatal(err)
+	}
+	out, err := runProject(sub, `out := [import("@alice:greet").hi("bob"), import("@alice:greet/lib/x")]`)
+	if err != nil || out != `["hello bob", 42]` {
+		t.Fatalf("out %s, error %v", out, err)
+	}
+	lock, _ := ioutil.ReadFile(filepath.Join(dir, deps.LockFile))
+	if !strings.HasPrefix(string(lock), "@alice:greet v1.2.0 sha256:") {
+		t.Errorf("lockfile: %s", lock)
+	}
+
+	// modified packages fail verification
+	writeFiles(t, deps.InstallDir(p.Lock.Get("@alice:greet")), map[string]string{
+		"main.td": `export { hi: fn(n) { return "pwned" } }`,
+	})
+	_, err = runProject(sub, script)
+	if err == nil || !strings.Contains(err.Error(), "checksum mismatch") {
+		t.Fatalf("error %v, expected a checksum mismatch", err)
+	}
+	if err := p.Verify(src); err == nil {
+		t.Error("expected a verification error")
+	}
+
+	// vendored copies are imported instead and verified as well
+	if err := os.RemoveAll(deps.InstallDir(p.Lock.Get("@alice:greet"))); err != nil {
+		t.Fatal(err)
+	}
+	if err := p.Vendor(src); err != nil {
+		t.Fatal(err)
+	}
+	vendored := p.VendorDir("@alice:greet")
+	if vendored != filepath.Join(dir, "vendor", "@alice", "greet") {
+		t.Errorf("vendor directory %s", vendored)
+	}
+	if err := os.RemoveAll(deps.InstallDir(p.Lock.Get("@alice:greet"))); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("TENDER_REGISTRY", filepath.Join(dir, "offline"))
+	if out, err := runProject(sub, script); err != nil || out != "hello bob" {
+		t.Fatalf("out %s, error %v", out, err)
+	}
+	writeFiles(t, vendored, map[string]string{"lib/x.td": `export 0`})
+	_, err = runProject(sub, script)
+	if err == nil || !strings.Contains(err.Error(), "checksum mismatch") {
+		t.Fatalf("error %v, expected a checksum mismatch", err)
+	}
+	if err := os.RemoveAll(filepath.Join(dir, "vendor")); err != nil {
+		t.Fatal(err)
+	}
+
+	// a lockfile entry for a version is not replaced by different content
+	if err := os.RemoveAll(deps.InstallDir(dep)); err != nil {
+		t.Fatal(err)
+	}
+	writeFiles(t, src.Root, map[string]string{"@alice/greet/v1.0.0/main.td": "export 1"})
+	p.Lock.Set(dep)
+	if _, err := p.Add(src, "@alice:greet", "v1.0.0"); err == nil ||
+		!strings.Contains(err.Error(), "checksum mismatch") {
+		t.Errorf("error %v, expected a checksum mismatch", err)
+	}
+
+	if err := p.Remove("@alice:greet"); err != nil {
+		t.Fatal(err)
+	}
+	if err := p.Remove("@alice:greet"); err == nil {
+		t.Error("expected an error removing a package that is not required")
+	}
+	if len(p.Manifest.Requires) != 0 || len(p.Lock.Entries) != 0 {
+		t.Errorf("package not removed: %+v %+v", p.Manifest, p.Lock)
+	}
+}
+
+func TestGithubSource(t *testing.T) {
+	archive := func(files map[string]string) []byte {
+		var buf strings.Builder
+		w := zip.NewWriter(&buf)
+		for name, content := range files {
+			f, _ := w.Create(name)
+			_, _ = f.Write([]byte(content))
+		}
+		_ = w.Close()
+		return []byte(buf.String())
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/repos/bob/lib/tags":
+			fmt.Fprint(w, `[{"name": "v0.9.0"}, {"name": "v1.0.0"}]`)
+		case "/repos/bob/new/tags":
+			fmt.Fprint(w, `[]`)
+		case "/bob/lib/archive/refs/tags/v1.0.0.zip":
+			_, _ = w.Write(archive(map[string]string{
+				"lib-1.0.0/main.td":     "export 1",
+				"lib-1.0.0/sub/util.td": "export 2",
+			}))
+		case "/bob/new/archive/refs/heads/main.zip":
+			_, _ = w.Write(archive(map[string]string{"new-main/main.td": "export 3"}))
+		case "/bob/evil/archive/refs/tags/v1.zip":
+			_, _ = w.Write(archive(map[string]string{"evil-1/../../x.td": "export 4"}))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+	src := &deps.GithubSource{API: server.URL, Archive: server.URL}
+
+	versions, err := src.Versions("bob", "lib")
+	if err != nil || !reflect.DeepEqual(versions, []string{"v1.0.0", "v0.9.0"}) {
+		t.Errorf("versions %v, error %v", versions, err)
+	}
+	if versions, _ := src.Versions("bob", "new"); !reflect.DeepEqual(versions, []string{"main"}) {
+		t.Errorf("versions %v, expected [main]", versions)
+	}
+	if _, err := src.Versions("bob", "missing"); err == nil {
+		t.Error("expected an error for a missing repository")
+	}
+
+	dir := t.TempDir()
+	if err := src.Fetch("bob", "lib", "v1.0.0", dir); err != nil {
+		t.Fatal(err)
+	}
+	data, err := ioutil.ReadFile(filepath.Join(dir, "sub", "util.td"))
+	if err != nil || string(data) != "export 2" {
+		t.Errorf("util.td: %q, error %v", data, err)
+	}
+	if err := src.Fetch("bob", "new", "main", t.TempDir()); err != nil {
+		t.Errorf("fetching a branch: %v", err)
+	}
+	if err := src.Fetch("bob", "evil", "v1", t.TempDir()); err == nil {
+		t.Error("expected an error for an archive with paths outside the directory")
+	}
+
+	// the TENDER_REGISTRY environment variable selects the source
+	t.Setenv("TENDER_REGISTRY", server.URL+"/")
+	if s, ok := deps.DefaultSource().(*deps.GithubSource); !ok || s.API != server.URL {
+		t.Errorf("source %#v", deps.DefaultSource())
+	}
+}
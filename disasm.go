@@ -0,0 +1,139 @@
+package tender
+
+import (
+	"fmt"
+
+	"github.com/2dprototype/tender/parser"
+	"github.com/2dprototype/tender/token"
+)
+
+// DisasmInstruction is a decoded bytecode instruction.
+type DisasmInstruction struct {
+	Offset   int    `json:"offset"`
+	Opcode   string `json:"opcode"`
+	Operands []int  `json:"operands"`
+	Comment  string `json:"comment,omitempty"` // resolved constant, builtin or operator
+	Target   int    `json:"target"`            // jump target; or -1 if not a jump
+	Source   string `json:"source,omitempty"`  // source position from the SourceMap
+	Line     int    `json:"line,omitempty"`
+}
+
+// DisasmFunction is a disassembled compiled function.
+type DisasmFunction struct {
+	Name          string              `json:"name"`
+	Constant      int                 `json:"constant"` // constant index; or -1 for the main function
+	NumLocals     int                 `json:"num_locals"`
+	NumParameters int                 `json:"num_parameters"`
+	VarArgs       bool                `json:"var_args"`
+	Instructions  []DisasmInstruction `json:"instructions"`
+}
+
+// String returns the instruction in the same form as FormatInstructions,
+// followed by the resolved comment if any.
+func (i DisasmInstruction) String() string {
+	s := fmt.Sprintf("%04d %-7s", i.Offset, i.Opcode)
+	for _, o := range i.Operands {
+		s += fmt.Sprintf(" %-5d", o)
+	}
+	if i.Comment != "" {
+		s += " ; " + i.Comment
+	}
+	return s
+}
+
+// Disassemble decodes the main function and every compiled function found in
+// the constants.
+func (b *Bytecode) Disassemble() []*DisasmFunction {
+	fns := []*DisasmFunction{b.disassemble("main", -1, b.MainFunction)}
+	for cidx, cn := range b.Constants {
+		if fn, ok := cn.(*CompiledFunction); ok {
+			fns = append(fns, b.disassemble(
+				fmt.Sprintf("fn[%d]", cidx), cidx, fn))
+		}
+	}
+	return fns
+}
+
+func (b *Bytecode) disassemble(
+	name string,
+	cidx int,
+	fn *CompiledFunction,
+) *DisasmFunction {
+	out := &DisasmFunction{
+		Name:          name,
+		Constant:      cidx,
+		NumLocals:     fn.NumLocals,
+		NumParameters: fn.NumParameters,
+		VarArgs:       fn.VarArgs,
+	}
+
+	ins := fn.Instructions
+	i := 0
+	for i < len(ins) {
+		op := ins[i]
+		operands, read := parser.ReadOperands(parser.OpcodeOperands[op], ins[i+1:])
+		d := DisasmInstruction{
+			Offset:   i,
+			Opcode:   parser.OpcodeNames[op],
+			Operands: operands,
+			Target:   -1,
+		}
+
+		switch op {
+		case parser.OpConstant, parser.OpClosure, parser.OpSelector,
+			parser.OpConstantW, parser.OpClosureW:
+			d.Comment = b.describeConstant(operands[0])
+		case parser.OpCheckType:
+			d.Comment = b.describeConstant(operands[1]) + ": " +
+				b.describeConstant(operands[2])
+		case parser.OpGetBuiltin:
+			if operands[0] < len(builtinFuncs) {
+				d.Comment = builtinFuncs[operands[0]].Name
+			}
+		case parser.OpBinaryOp:
+			d.Comment = token.Token(operands[0]).String()
+		case parser.OpLocalBinaryOp:
+			d.Comment = token.Token(operands[2]).String() + " " +
+				b.describeConstant(operands[1])
+		case parser.OpCompareJump:
+			d.Comment = token.Token(operands[0]).String() +
+				fmt.Sprintf(" -> %04d", operands[1])
+		case parser.OpJump, parser.OpJumpFalsy, parser.OpAndJump,
+			parser.OpOrJump:
+			d.Comment = fmt.Sprintf("-> %04d", operands[0])
+		}
+		if n := jumpOperand(op); n >= 0 {
+			d.Target = operands[n]
+		}
+
+		if pos, ok := fn.SourceMap[i]; ok && b.FileSet != nil {
+			p := b.FileSet.Position(pos)
+			if p.IsValid() {
+				d.Source = p.String()
+				d.Line = p.Line
+			}
+		}
+
+		out.Instructions = append(out.Instructions, d)
+		i += 1 + read
+	}
+	return out
+}
+
+func (b *Bytecode) describeConstant(cidx int) string {
+	if cidx < 0 || cidx >= len(b.Constants) {
+		return fmt.Sprintf("<invalid constant %d>", cidx)
+	}
+	switch cn := b.Constants[cidx].(type) {
+	case *CompiledFunction:
+		return fmt.Sprintf("fn[%d]", cidx)
+	case *ImmutableMap:
+		return fmt.Sprintf("module{%d}", len(cn.Value))
+	default:
+		s := cn.String()
+		if len(s) > 48 {
+			s = s[:45] + "..."
+		}
+		return s
+	}
+}
@@ -0,0 +1,315 @@
+package tender
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/2dprototype/tender/parser"
+)
+
+// ModuleDoc is the documentation of a module.
+type ModuleDoc struct {
+	Name    string
+	Doc     string
+	Usage   string // usage of a module exporting a function
+	Members []*MemberDoc
+}
+
+// MemberDoc is the documentation of a function or value exported by a module.
+type MemberDoc struct {
+	Name  string
+	Usage string // usage of a function, such as "add(a, b)"
+	Value string // source or string of a literal value
+	Type  string // type name of a value of a builtin module
+	Doc   string
+}
+
+// funcDoc is the usage and the doc comment of a function literal.
+type funcDoc struct {
+	usage string
+	doc   string
+}
+
+// documentStmt records the doc comment of a function declared by the
+// statement, so that it is kept in the compiled function.
+func (c *Compiler) documentStmt(stmt parser.Stmt, doc *parser.CommentGroup) {
+	switch stmt := stmt.(type) {
+	case *parser.FuncStmt:
+		if stmt.Doc != nil {
+			doc = stmt.Doc
+		}
+		c.documentFunc(stmt.Ident.Name, stmt.Expr, doc)
+	case *parser.AssignStmt:
+		if stmt.Doc != nil {
+			doc = stmt.Doc
+		}
+		if len(stmt.LHS) == 1 && len(stmt.RHS) == 1 {
+			if ident, ok := stmt.LHS[0].(*parser.Ident); ok {
+				c.documentFunc(ident.Name, stmt.RHS[0], doc)
+			}
+		}
+	}
+}
+
+// documentFunc records the doc comment of a function literal with its usage.
+func (c *Compiler) documentFunc(name string, expr parser.Expr, doc *parser.CommentGroup) {
+	fn, ok := expr.(*parser.FuncLit)
+	if !ok || doc == nil {
+		return
+	}
+	if c.funcDocs == nil {
+		c.funcDocs = make(map[*parser.FuncLit]funcDoc)
+	}
+	c.funcDocs[fn] = funcDoc{usage: funcUsage(name, fn), doc: doc.Text()}
+}
+
+// funcUsage returns the usage of a function literal, such as "add(a, b)".
+func funcUsage(name string, fn *parser.FuncLit) string {
+	if name == "" {
+		name = "fn"
+	}
+	return name + strings.TrimPrefix(fn.Type.String(), "fn")
+}
+
+// SourceModuleDoc returns the documentation of a source module: the doc
+// comments of its exported functions and values, and the first comment of the
+// file if a blank line separates it from the first statement.
+func SourceModuleDoc(name string, src []byte) (*ModuleDoc, error) {
+	fileSet := parser.NewFileSet()
+	srcFile := fileSet.AddFile(name, -1, len(src))
+	p := parser.NewParserWithMode(srcFile, src, nil, parser.ParseComments)
+	file, err := p.ParseFile()
+	if err != nil {
+		return nil, err
+	}
+
+	mod := &ModuleDoc{Name: name}
+	if len(file.Comments) > 0 {
+		first := file.Comments[0]
+		line := func(pos parser.Pos) int { return srcFile.Position(pos).Line }
+		if len(file.Stmts) == 0 || line(first.End())+1 < line(file.Stmts[0].Pos()) {
+			mod.Doc = first.Text()
+		}
+	}
+
+	// top-level declarations, to document the identifiers of an exported map
+	decls := make(map[string]*MemberDoc)
+	declare := func(stmt parser.Stmt, doc *parser.CommentGroup) []*MemberDoc {
+		var members []*MemberDoc
+		switch stmt := stmt.(type) {
+		case *parser.FuncStmt:
+			if stmt.Doc != nil {
+				doc = stmt.Doc
+			}
+			members = append(members, &MemberDoc{
+				Name:  stmt.Ident.Name,
+				Usage: funcUsage(stmt.Ident.Name, stmt.Expr),
+				Doc:   doc.Text(),
+			})
+		case *parser.AssignStmt:
+			if stmt.Doc != nil {
+				doc = stmt.Doc
+			}
+			for i, lhs := range stmt.LHS {
+				ident, ok := lhs.(*parser.Ident)
+				if !ok {
+					continue
+				}
+				member := &MemberDoc{Name: ident.Name, Doc: doc.Text()}
+				if len(stmt.RHS) == len(stmt.LHS) {
+					member.Usage, member.Value = exprDoc(ident.Name, stmt.RHS[i])
+				}
+				members = append(members, member)
+			}
+		}
+		for _, member := range members {
+			decls[member.Name] = member
+		}
+		return members
+	}
+
+	for _, stmt := range file.Stmts {
+		switch stmt := stmt.(type) {
+		case *parser.FuncStmt, *parser.AssignStmt:
+			declare(stmt, nil)
+		case *parser.ExportDeclStmt:
+			mod.Members = append(mod.Members, declare(stmt.Decl, stmt.Doc)...)
+		case *parser.ExportStmt:
+			switch result := stmt.Result.(type) {
+			case *parser.MapLit:
+				for _, elt := range result.Elements {
+					member := &MemberDoc{Name: elt.Key, Doc: elt.Doc.Text()}
+					if ident, ok := elt.Value.(*parser.Ident); ok && decls[ident.Name] != nil {
+						decl := decls[ident.Name]
+						member.Value = decl.Value
+						if decl.Usage != "" {
+							member.Usage = elt.Key + strings.TrimPrefix(decl.Usage, ident.Name)
+						}
+						if member.Doc == "" {
+							member.Doc = decl.Doc
+						}
+					} else {
+						member.Usage, member.Value = exprDoc(elt.Key, elt.Value)
+					}
+					mod.Members = append(mod.Members, member)
+				}
+			default:
+				var value string
+				mod.Usage, value = exprDoc("", result)
+				if ident, ok := result.(*parser.Ident); ok && decls[ident.Name] != nil {
+					decl := decls[ident.Name]
+					mod.Usage, value = decl.Usage, decl.Value
+					if mod.Doc == "" {
+						mod.Doc = decl.Doc
+					}
+				}
+				if mod.Usage == "" && value != "" {
+					mod.Usage = value
+				}
+				if doc := stmt.Doc.Text(); doc != "" {
+					mod.Doc = doc
+				}
+			}
+		}
+	}
+	return mod, nil
+}
+
+// exprDoc returns the usage of a function literal, or the source of a literal
+// value.
+func exprDoc(name string, expr parser.Expr) (usage, value string) {
+	switch expr := expr.(type) {
+	case *parser.FuncLit:
+		return funcUsage(name, expr), ""
+	case *parser.IntLit, *parser.FloatLit, *parser.StringLit, *parser.CharLit,
+		*parser.BoolLit, *parser.NullLit:
+		return "", expr.String()
+	case *parser.UnaryExpr:
+		if _, ok := expr.Expr.(*parser.IntLit); ok {
+			return "", expr.String()
+		}
+		if _, ok := expr.Expr.(*parser.FloatLit); ok {
+			return "", expr.String()
+		}
+	}
+	return "", ""
+}
+
+// BuiltinModuleDoc returns the documentation of a builtin module from the
+// usages and docs of its functions, and the literal values or type names of
+// its other members.
+func BuiltinModuleDoc(name, doc string, attrs map[string]Object) *ModuleDoc {
+	mod := &ModuleDoc{Name: name, Doc: doc}
+	for _, key := range sortedKeys(attrs) {
+		if key == "__module_name__" {
+			continue
+		}
+		usage, doc := FunctionDoc(attrs[key])
+		member := &MemberDoc{Name: key, Usage: usage, Doc: doc}
+		switch value := attrs[key].(type) {
+		case *Int, *Float, *String, *Char, *Bool:
+			member.Value = value.String()
+		default:
+			if usage == "" {
+				member.Type = value.TypeName()
+			}
+		}
+		mod.Members = append(mod.Members, member)
+	}
+	return mod
+}
+
+// FunctionDoc returns the usage and the documentation of a builtin, Go or
+// compiled function.
+func FunctionDoc(o Object) (usage, doc string) {
+	switch o := o.(type) {
+	case *BuiltinFunction:
+		return o.Usage, o.Doc
+	case *UserFunction:
+		return o.Usage, o.Doc
+	case *CompiledFunction:
+		return o.Usage, o.Doc
+	}
+	return "", ""
+}
+
+// helpText returns the documentation of a function or a module printed by the
+// help builtin function.
+func helpText(o Object) string {
+	var sb strings.Builder
+	var attrs map[string]Object
+	switch o := o.(type) {
+	case *ImmutableMap:
+		attrs = o.Value
+	case *Map:
+		attrs = o.Value
+	default:
+		usage, doc := FunctionDoc(o)
+		if usage == "" {
+			if name := functionName(o); name != "" {
+				usage = name + "(...)"
+			} else {
+				usage = o.TypeName()
+			}
+		}
+		sb.WriteString(usage + "\n")
+		if doc == "" {
+			doc = "no documentation"
+		}
+		sb.WriteString(indent(doc, "    ") + "\n")
+		return sb.String()
+	}
+
+	if name, ok := attrs["__module_name__"].(*String); ok {
+		sb.WriteString("module " + name.Value + "\n\n")
+	}
+	for _, key := range sortedKeys(attrs) {
+		if key == "__module_name__" {
+			continue
+		}
+		usage, doc := FunctionDoc(attrs[key])
+		if usage == "" {
+			fmt.Fprintf(&sb, "%s %s\n", key, attrs[key].TypeName())
+		} else {
+			sb.WriteString(key + strings.TrimPrefix(usage, usageName(usage)) + "\n")
+		}
+		if doc != "" {
+			sb.WriteString(indent(doc, "    ") + "\n")
+		}
+	}
+	return sb.String()
+}
+
+// functionName returns the name of a builtin or Go function.
+func functionName(o Object) string {
+	switch o := o.(type) {
+	case *BuiltinFunction:
+		return o.Name
+	case *UserFunction:
+		return o.Name
+	}
+	return ""
+}
+
+// usageName returns the function name of a usage, such as "add" for
+// "add(a, b)".
+func usageName(usage string) string {
+	if i := strings.IndexByte(usage, '('); i >= 0 {
+		return usage[:i]
+	}
+	return usage
+}
+
+func indent(s, prefix string) string {
+	return prefix + strings.ReplaceAll(s, "\n", "\n"+prefix)
+}
+
+func sortedKeys(m map[string]Object) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
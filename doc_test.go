@@ -0,0 +1,147 @@
+package tender
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/2dprototype/tender/parser"
+)
+
+func TestDocComments(t *testing.T) {
+	src := `// Package geo has shapes.
+
+// area returns the area of a rectangle.
+export fn area(w, h) {
+	return w * h // trailing comment
+}
+
+/*
+ * Unit is the length of a side.
+ */
+export unit := 1
+
+// perimeter of a rectangle.
+perimeter := fn(w, h) { return 2 * (w + h) }
+
+// not a doc comment
+
+square := fn(s) { return area(s, s) }`
+
+	fileSet := parser.NewFileSet()
+	srcFile := fileSet.AddFile("geo.td", -1, len(src))
+	file, err := parser.NewParserWithMode(srcFile, []byte(src), nil,
+		parser.ParseComments).ParseFile()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(file.Comments) != 6 {
+		t.Errorf("%d comment groups, expected 6", len(file.Comments))
+	}
+	docs := []string{"area returns the area of a rectangle.",
+		"Unit is the length of a side.", "perimeter of a rectangle.", ""}
+	i := 0
+	for _, stmt := range file.Stmts {
+		var doc *parser.CommentGroup
+		switch stmt := stmt.(type) {
+		case *parser.ExportDeclStmt:
+			doc = stmt.Doc
+		case *parser.AssignStmt:
+			doc = stmt.Doc
+		default:
+			continue
+		}
+		if doc.Text() != docs[i] {
+			t.Errorf("statement %d: doc %q, expected %q", i, doc.Text(), docs[i])
+		}
+		i++
+	}
+
+	mod, err := SourceModuleDoc("geo.td", []byte(src))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if mod.Doc != "Package geo has shapes." || len(mod.Members) != 2 {
+		t.Fatalf("module doc %q with %d members", mod.Doc, len(mod.Members))
+	}
+	if m := mod.Members[0]; m.Usage != "area(w, h)" || m.Doc != docs[0] {
+		t.Errorf("area: %+v", m)
+	}
+	if m := mod.Members[1]; m.Value != "1" || m.Doc != docs[1] {
+		t.Errorf("unit: %+v", m)
+	}
+
+	// the identifiers of an exported map are documented by their declarations
+	mod, err = SourceModuleDoc("m.td", []byte(`
+// twice doubles x.
+twice := fn(x) { return x * 2 }
+export {
+	double: twice,
+	// zero is nothing.
+	zero: 0
+}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(mod.Members) != 2 || mod.Members[0].Usage != "double(x)" ||
+		mod.Members[0].Doc != "twice doubles x." || mod.Members[1].Doc != "zero is nothing." {
+		t.Errorf("map members: %+v %+v", mod.Members[0], mod.Members[1])
+	}
+}
+
+func TestCommentGroupText(t *testing.T) {
+	tests := []struct {
+		comments []string
+		expected string
+	}{
+		{[]string{"// one line"}, "one line"},
+		{[]string{"// first", "//   indented", "//"}, "first\n  indented"},
+		{[]string{"/* one line */"}, "one line"},
+		{[]string{"/* multi\n   line doc */"}, "multi\nline doc"},
+		{[]string{"/*\n    indented\n      more\n\n    end\n*/"}, "indented\n  more\n\nend"},
+		{[]string{"/*\n\tdoc\n\t\tcode\n*/"}, "doc\n\tcode"},
+		{[]string{"/*\n * star\n *   nested\n */"}, "star\n  nested"},
+		{[]string{"/*  first\n  second */", "// line"}, "first\nsecond\nline"},
+	}
+	for _, tc := range tests {
+		g := &parser.CommentGroup{}
+		for _, text := range tc.comments {
+			g.List = append(g.List, &parser.Comment{Text: text})
+		}
+		if got := g.Text(); got != tc.expected {
+			t.Errorf("%q: text %q, expected %q", tc.comments, got, tc.expected)
+		}
+	}
+}
+
+func TestCompiledFunctionDoc(t *testing.T) {
+	c, err := NewScript([]byte(`
+// add returns the sum of a and b.
+add := fn(a, b) { return a + b }
+out := add(1, 2)`)).Compile()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	if err := c.bytecode.Encode(&buf); err != nil {
+		t.Fatal(err)
+	}
+	decoded := &Bytecode{}
+	if err := decoded.Decode(&buf, nil); err != nil {
+		t.Fatal(err)
+	}
+	var fn *CompiledFunction
+	for _, cn := range decoded.Constants {
+		if f, ok := cn.(*CompiledFunction); ok {
+			fn = f
+		}
+	}
+	if fn == nil {
+		t.Fatal("no function in decoded constants")
+	}
+	if fn.Usage != "add(a, b)" || fn.Doc != "add returns the sum of a and b." {
+		t.Errorf("usage %q, doc %q", fn.Usage, fn.Doc)
+	}
+	if text := helpText(fn); text != "add(a, b)\n    add returns the sum of a and b.\n" {
+		t.Errorf("help %q", text)
+	}
+}
@@ -0,0 +1,178 @@
+package tender
+
+import (
+	"fmt"
+	"io/fs"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/2dprototype/tender/parser"
+)
+
+// compileEmbed compiles an embed expression into a constant holding the
+// content of the embedded files, relative to the directory of the file:
+//
+//	embed("file")           the file as a string
+//	embed bytes("file")     the file as bytes
+//	embed("assets/*.png")   a map of the matching files to their bytes
+//	embed dir("assets")     a map of the files in the directory to their bytes
+//	embed fs("assets")      a read-only file system of the files in the
+//	                        directory, or of the files matching a pattern
+//
+// Files of directories are named by their slash-separated path relative to
+// the directory, and files matching a pattern by their path relative to the
+// directory of the file. Files and directories starting with "." are not
+// embedded from directories. A path with pattern characters is a pattern
+// only if no file or directory has the path itself.
+//
+// Bytes are copied each time the expression is evaluated, so that changing
+// them does not change the embedded files.
+func (c *Compiler) compileEmbed(node *parser.EmbedExpr) error {
+	src, err := filepath.Abs(filepath.Join(c.importDir, node.FileSrc))
+	if err != nil {
+		return c.errorf(node, "embeding file path error: %s", err.Error())
+	}
+	isPattern := false
+	if embedMayBePattern(node) {
+		_, err := os.Stat(src)
+		isPattern = os.IsNotExist(err)
+	}
+
+	if node.Mode == "" && !isPattern || node.Mode == parser.EmbedBytes {
+		data, err := ioutil.ReadFile(src)
+		if err != nil {
+			return c.errorf(node, "embeding file \""+src+"\" not found!")
+		}
+		c.addSource(src, data)
+		if node.Mode == parser.EmbedBytes {
+			c.emitCopy(node, &Bytes{Value: data})
+		} else {
+			c.emit(node, parser.OpConstant, c.addConstant(&String{Value: string(data)}))
+		}
+		return nil
+	}
+
+	isDir := node.Mode == parser.EmbedDir || node.Mode == parser.EmbedFS && !isPattern
+	paths, err := embedList(src, isDir)
+	if err != nil {
+		return c.errorf(node, "%s", err.Error())
+	}
+	base := c.importDir
+	if isDir {
+		base = src
+	}
+	files := make(map[string][]byte, len(paths))
+	for _, path := range paths {
+		data, err := ioutil.ReadFile(path)
+		if err != nil {
+			return c.errorf(node, "embeding file \""+path+"\" not found!")
+		}
+		c.addSource(path, data)
+		name, err := filepath.Rel(base, path)
+		if err != nil {
+			return c.errorf(node, "embeding file path error: %s", err.Error())
+		}
+		files[filepath.ToSlash(name)] = data
+	}
+	c.addSource(embedListSource(src, isDir), []byte(strings.Join(paths, "\n")))
+
+	if node.Mode == parser.EmbedFS {
+		c.emit(node, parser.OpConstant, c.addConstant(NewFS(files)))
+		return nil
+	}
+	m := make(map[string]Object, len(files))
+	for name, data := range files {
+		m[name] = &Bytes{Value: data}
+	}
+	c.emitCopy(node, &ImmutableMap{Value: m})
+	return nil
+}
+
+// embedMayBePattern returns true if the path of an embed expression is a
+// pattern when no file has the path.
+func embedMayBePattern(node *parser.EmbedExpr) bool {
+	return (node.Mode == "" || node.Mode == parser.EmbedFS) &&
+		strings.ContainsAny(node.FileSrc, "*?[")
+}
+
+// emitCopy emits a call of the copy builtin function with the constant o,
+// which evaluates to a copy of o.
+func (c *Compiler) emitCopy(node parser.Node, o Object) {
+	for idx, fn := range builtinFuncs {
+		if fn.Name == "copy" {
+			c.emit(node, parser.OpGetBuiltin, idx)
+			break
+		}
+	}
+	c.emit(node, parser.OpConstant, c.addConstant(o))
+	c.emit(node, parser.OpCall, 1, 0)
+}
+
+// embedList returns the sorted paths of the files in the directory src, or
+// of the files matching the pattern src.
+func embedList(src string, isDir bool) ([]string, error) {
+	var paths []string
+	if !isDir {
+		matches, err := filepath.Glob(src)
+		if err != nil {
+			return nil, fmt.Errorf("invalid embed pattern \"%s\": %s", src, err.Error())
+		}
+		for _, path := range matches {
+			if info, err := os.Stat(path); err == nil && info.Mode().IsRegular() {
+				paths = append(paths, path)
+			}
+		}
+		if len(paths) == 0 {
+			return nil, fmt.Errorf("no files match embed pattern \"%s\"", src)
+		}
+		sort.Strings(paths)
+		return paths, nil
+	}
+
+	if info, err := os.Stat(src); err != nil || !info.IsDir() {
+		return nil, fmt.Errorf("embeding directory \"%s\" not found!", src)
+	}
+	err := filepath.WalkDir(src, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if path != src && strings.HasPrefix(d.Name(), ".") {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if d.Type().IsRegular() {
+			paths = append(paths, path)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(paths)
+	return paths, nil
+}
+
+// embedListSource returns the name of the source recording the files of an
+// embedded directory or pattern, so that the compiled modules embedding them
+// are not used from the module cache when files are added or removed.
+func embedListSource(src string, isDir bool) string {
+	if isDir {
+		return "embed:dir:" + src
+	}
+	return "embed:glob:" + src
+}
+
+// splitEmbedListSource returns the kind, "dir" or "glob", and the path of a
+// source name returned by embedListSource.
+func splitEmbedListSource(name string) (kind, src string) {
+	parts := strings.SplitN(strings.TrimPrefix(name, "embed:"), ":", 2)
+	if len(parts) != 2 {
+		return "", ""
+	}
+	return parts[0], parts[1]
+}
@@ -3,28 +3,31 @@ package main
 import (
 	"bytes"
 	_ "embed"
+	"fmt"
+	"os"
 
 	"github.com/2dprototype/tender"
 	"github.com/2dprototype/tender/stdlib"
 )
 
+// Runner for a compiled source.tdo embedded at build time. "tender build"
+// produces the same kind of executable without the Go toolchain.
+
 //go:embed source.tdo
 var inputData []byte
 
 func main() {
-	
 	modules := stdlib.GetModuleMap(stdlib.AllModuleNames()...)
 	bytecode := &tender.Bytecode{}
 	err := bytecode.Decode(bytes.NewReader(inputData), modules)
-	if err != nil {
-		return
+	if err == nil {
+		err = bytecode.Verify()
 	}
-	machine := tender.NewVM(bytecode, nil, -1)
-	err = machine.Run()
-	
 	if err == nil {
-		return
+		err = tender.NewVM(bytecode, nil, -1).Run()
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err.Error())
+		os.Exit(1)
 	}
-	
 }
-
@@ -0,0 +1,141 @@
+package tender
+
+import (
+	"bytes"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestEmbed(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"text.txt":         "text",
+		"assets/a.png":     "A",
+		"assets/b.png":     "B",
+		"assets/c.txt":     "C",
+		"assets/sub/d.txt": "D",
+		"assets/.hidden":   "H",
+		"raw/[1].txt":      "one",
+		"raw/1.txt":        "not this one",
+		"lib/mod.td":       `export embed fs("../assets")`,
+	})
+
+	compile := func(src string) (*Compiled, error) {
+		keys := `keys := fn(m) { ks := []; for k, _ in m { ks = append(ks, k) }; return sort(ks) };`
+		s := NewScript([]byte(keys + src))
+		s.EnableFileImport(true)
+		if err := s.SetImportDir(dir); err != nil {
+			return nil, err
+		}
+		return s.Compile()
+	}
+
+	tests := []struct {
+		src      string
+		expected string
+	}{
+		{`out := embed("text.txt")`, `text`},
+		{`out := embed "text.txt"`, `text`},
+		{`out := string(embed bytes("text.txt"))`, `text`},
+		{`out := typeof(embed bytes "text.txt")`, `bytes`},
+		{`out := keys(embed("assets/*.png"))`, `["assets/a.png", "assets/b.png"]`},
+		{`out := string(embed("assets/*.png")["assets/b.png"])`, `B`},
+		{`out := keys(embed dir("assets"))`, `["a.png", "b.png", "c.txt", "sub/d.txt"]`},
+		{`out := string(embed dir "assets"["sub/d.txt"])`, `D`},
+		{`out := embed fs("assets").files`, `["a.png", "b.png", "c.txt", "sub/d.txt"]`},
+		{`out := embed fs("assets/*.txt").files`, `["assets/c.txt"]`},
+		{`out := string(embed fs("assets").read_file("./sub/../a.png"))`, `A`},
+		{`out := is_error(embed fs("assets").read_file("missing"))`, `true`},
+		{`out := embed fs("assets").exists("sub")`, `true`},
+		{`out := embed fs("assets").stat("sub").is_dir`, `true`},
+		{`out := embed fs("assets").glob("*.png")`, `["a.png", "b.png"]`},
+		{`out := []; for e in embed fs("assets").read_dir(".") { out = append(out, e.name) }`,
+			`["a.png", "b.png", "c.txt", "sub"]`},
+		{`out := import("lib/mod").files`, `["a.png", "b.png", "c.txt", "sub/d.txt"]`},
+		// a path with pattern characters is a pattern only if no file has it
+		{`out := embed("raw/[1].txt")`, `one`},
+		{`out := keys(embed("raw/[1]*.txt"))`, `["raw/1.txt"]`},
+		// embedded bytes are copied each time they are evaluated
+		{`f := fn() { return embed bytes("text.txt") }; b := f(); b[0] = 88; out := string(f())`, `text`},
+		{`f := fn() { return embed dir("assets") }; m := f(); m["a.png"][0] = 88; out := string(f()["a.png"])`, `A`},
+		{`f := fn() { return embed("assets/*.png") }; m := f(); m["assets/a.png"][0] = 88; out := string(f()["assets/a.png"])`, `A`},
+	}
+	for _, tc := range tests {
+		c, err := compile(tc.src)
+		if err != nil {
+			t.Errorf("%s: %v", tc.src, err)
+			continue
+		}
+		if err := c.Run(); err != nil {
+			t.Errorf("%s: %v", tc.src, err)
+			continue
+		}
+		if got := c.Get("out").String(); got != tc.expected {
+			t.Errorf("%s: out = %s, expected %s", tc.src, got, tc.expected)
+		}
+	}
+
+	errorTests := []struct {
+		src      string
+		expected string
+	}{
+		{`out := embed("missing.txt")`, "not found"},
+		{`out := embed("assets/*.gif")`, "no files match embed pattern"},
+		{`out := embed dir("text.txt")`, "embeding directory"},
+		{`out := embed text("text.txt")`, "unknown embed mode 'text'"},
+		{`out := embed(1)`, "expected file path"},
+	}
+	for _, tc := range errorTests {
+		_, err := compile(tc.src)
+		if err == nil || !strings.Contains(err.Error(), tc.expected) {
+			t.Errorf("%s: error %v, expected %s", tc.src, err, tc.expected)
+		}
+	}
+
+	// file systems are stored in bytecode
+	c, err := compile(`out := embed fs("assets")`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	if err := c.bytecode.Encode(&buf); err != nil {
+		t.Fatal(err)
+	}
+	decoded := &Bytecode{}
+	if err := decoded.Decode(&buf, nil); err != nil {
+		t.Fatal(err)
+	}
+	var fsys *FS
+	for _, cn := range decoded.Constants {
+		if f, ok := ToFS(cn); ok {
+			fsys = f
+		}
+	}
+	if fsys == nil {
+		t.Fatal("no file system in decoded constants")
+	}
+	if data, err := fsys.ReadFile("sub/d.txt"); err != nil || string(data) != "D" {
+		t.Errorf("decoded file system: %q, %v", data, err)
+	}
+
+	// adding a file to an embedded directory invalidates cached modules
+	s := func() string {
+		s := NewScript([]byte(`out := import("lib/mod").files`))
+		s.EnableFileImport(true)
+		s.SetModuleCache(filepath.Join(dir, "cache"))
+		if err := s.SetImportDir(dir); err != nil {
+			t.Fatal(err)
+		}
+		c, err := s.Run()
+		if err != nil {
+			t.Fatal(err)
+		}
+		return c.Get("out").String()
+	}
+	s()
+	writeFiles(t, dir, map[string]string{"assets/e.txt": "E"})
+	if out := s(); !strings.Contains(out, "e.txt") {
+		t.Errorf("cached module: %s, expected e.txt", out)
+	}
+}
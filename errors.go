@@ -58,6 +58,10 @@ var (
 
 	// ErrVMAborted is an error to denote the VM was forcibly terminated without proper exit.
 	ErrVMAborted = errors.New("virtual machine aborted")
+
+	// ErrInvalidBytecode is an error where compiled bytecode is malformed or
+	// was produced by an incompatible tender build.
+	ErrInvalidBytecode = errors.New("invalid bytecode")
 	
 	ErrByteValueOutOfRange = errors.New("index out of range")
 	ErrInvalidValueType = errors.New("invalid value type")
@@ -0,0 +1,195 @@
+package tender
+
+import (
+	"io/fs"
+	"path"
+	"sort"
+	"strings"
+)
+
+// FS is a read-only file system of embedded files, created with
+// `embed fs "assets"`. Names are slash-separated paths relative to the root
+// of the file system, such as "images/logo.png".
+type FS struct {
+	Files map[string][]byte
+}
+
+// fsMethods is the method table of file systems.
+var fsMethods *MethodTable
+
+func init() {
+	fsMethods = NewMethodTable("fs", map[string]MethodFunc{
+		"read_file": fsMethod(func(fsys *FS, name string) (Object, error) {
+			data, err := fsys.ReadFile(name)
+			if err != nil {
+				return wrapError(err), nil
+			}
+			// the files are shared by all the copies of the constant
+			return &Bytes{Value: append([]byte{}, data...)}, nil
+		}),
+		"read_dir": fsMethod(func(fsys *FS, name string) (Object, error) {
+			names, err := fsys.ReadDir(name)
+			if err != nil {
+				return wrapError(err), nil
+			}
+			entries := make([]Object, len(names))
+			for i, entry := range names {
+				entries[i] = fsys.fileInfo(path.Join(fsCleanPath(name), entry))
+			}
+			return &Array{Value: entries}, nil
+		}),
+		"stat": fsMethod(func(fsys *FS, name string) (Object, error) {
+			if _, _, err := fsys.Stat(name); err != nil {
+				return wrapError(err), nil
+			}
+			return fsys.fileInfo(fsCleanPath(name)), nil
+		}),
+		"exists": fsMethod(func(fsys *FS, name string) (Object, error) {
+			if _, _, err := fsys.Stat(name); err != nil {
+				return FalseValue, nil
+			}
+			return TrueValue, nil
+		}),
+		"glob": fsMethod(func(fsys *FS, pattern string) (Object, error) {
+			names, err := fsys.Glob(pattern)
+			if err != nil {
+				return wrapError(err), nil
+			}
+			return &Array{Value: stringObjects(names)}, nil
+		}),
+	}, map[string]PropertyFunc{
+		"files": func(recv interface{}) Object {
+			return &ImmutableArray{Value: stringObjects(recv.(*FS).Names())}
+		},
+	})
+}
+
+// fsMethod returns a method of file systems taking a path argument.
+func fsMethod(fn func(fsys *FS, name string) (Object, error)) MethodFunc {
+	return func(recv interface{}, args ...Object) (Object, error) {
+		if len(args) != 1 {
+			return nil, ErrWrongNumArguments
+		}
+		name, ok := ToString(args[0])
+		if !ok {
+			return nil, ErrInvalidArgumentType{
+				Name:     "path",
+				Expected: "string(compatible)",
+				Found:    args[0].TypeName(),
+			}
+		}
+		return fn(recv.(*FS), name)
+	}
+}
+
+// NewFS returns a file system object of the files.
+func NewFS(files map[string][]byte) *MethodObject {
+	return fsMethods.New(&FS{Files: files})
+}
+
+// ToFS returns the file system of an object created by NewFS.
+func ToFS(o Object) (*FS, bool) {
+	if m, ok := o.(*MethodObject); ok && m.Methods == fsMethods {
+		fsys, ok := m.Value.(*FS)
+		return fsys, ok
+	}
+	return nil, false
+}
+
+// fsCleanPath returns the name of a path in a file system, with "" for the
+// root directory.
+func fsCleanPath(name string) string {
+	return strings.TrimPrefix(path.Clean("/"+name), "/")
+}
+
+// Names returns the sorted names of the files.
+func (fsys *FS) Names() []string {
+	names := make([]string, 0, len(fsys.Files))
+	for name := range fsys.Files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ReadFile returns the content of the named file. It must not be modified.
+func (fsys *FS) ReadFile(name string) ([]byte, error) {
+	data, ok := fsys.Files[fsCleanPath(name)]
+	if !ok {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrNotExist}
+	}
+	return data, nil
+}
+
+// Stat returns the size of the named file, or whether it is a directory.
+func (fsys *FS) Stat(name string) (size int, isDir bool, err error) {
+	clean := fsCleanPath(name)
+	if data, ok := fsys.Files[clean]; ok {
+		return len(data), false, nil
+	}
+	for file := range fsys.Files {
+		if clean == "" || strings.HasPrefix(file, clean+"/") {
+			return 0, true, nil
+		}
+	}
+	return 0, false, &fs.PathError{Op: "stat", Path: name, Err: fs.ErrNotExist}
+}
+
+// ReadDir returns the sorted names of the files and directories in the named
+// directory.
+func (fsys *FS) ReadDir(name string) ([]string, error) {
+	dir := fsCleanPath(name)
+	if dir != "" {
+		dir += "/"
+	}
+	seen := make(map[string]bool)
+	var names []string
+	for file := range fsys.Files {
+		if !strings.HasPrefix(file, dir) {
+			continue
+		}
+		entry := strings.SplitN(file[len(dir):], "/", 2)[0]
+		if !seen[entry] {
+			seen[entry] = true
+			names = append(names, entry)
+		}
+	}
+	if names == nil {
+		return nil, &fs.PathError{Op: "readdir", Path: name, Err: fs.ErrNotExist}
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// Glob returns the sorted names of the files matching the pattern, with the
+// syntax of path.Match.
+func (fsys *FS) Glob(pattern string) ([]string, error) {
+	if _, err := path.Match(pattern, ""); err != nil {
+		return nil, err
+	}
+	var names []string
+	for _, name := range fsys.Names() {
+		if ok, _ := path.Match(pattern, name); ok {
+			names = append(names, name)
+		}
+	}
+	return names, nil
+}
+
+// fileInfo returns the name, size and kind of the file or directory.
+func (fsys *FS) fileInfo(name string) Object {
+	size, isDir, _ := fsys.Stat(name)
+	return &ImmutableMap{Value: map[string]Object{
+		"name":   &String{Value: path.Base(name)},
+		"size":   &Int{Value: int64(size)},
+		"is_dir": FromBool(isDir),
+	}}
+}
+
+func stringObjects(values []string) []Object {
+	objs := make([]Object, len(values))
+	for i, v := range values {
+		objs[i] = &String{Value: v}
+	}
+	return objs
+}
@@ -0,0 +1,87 @@
+package tender
+
+import (
+	"bytes"
+	"hash/crc32"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/2dprototype/tender/parser"
+)
+
+// FuzzParse checks that the parser and the compiler return errors instead
+// of panicking on malformed source.
+func FuzzParse(f *testing.F) {
+	for _, src := range regVMTests {
+		f.Add([]byte(src))
+	}
+	cases, _ := filepath.Glob(filepath.Join(conformanceDir, "*.td"))
+	for _, path := range cases {
+		if src, err := ioutil.ReadFile(path); err == nil {
+			f.Add(src)
+		}
+	}
+	f.Fuzz(func(t *testing.T, src []byte) {
+		fileSet := parser.NewFileSet()
+		srcFile := fileSet.AddFile("fuzz", -1, len(src))
+		file, err := parser.NewParser(srcFile, src, nil).ParseFile()
+		if err != nil {
+			return
+		}
+		c := NewCompiler(srcFile, nil, nil, nil, nil)
+		c.EnableTypeChecks(true)
+		if err := c.Compile(file); err != nil {
+			return
+		}
+		c.Bytecode().RemoveDuplicates()
+	})
+}
+
+// encodePayload returns the encoded bytecode of src without its header, or
+// nil if src does not compile.
+func encodePayload(f *testing.F, src string) []byte {
+	c, err := NewScript([]byte(src)).Compile()
+	if err != nil {
+		return nil
+	}
+	var buf bytes.Buffer
+	if err := c.bytecode.Encode(&buf); err != nil {
+		f.Fatal(err)
+	}
+	if _, err := ReadBytecodeHeader(&buf); err != nil {
+		f.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// FuzzBytecodeDecode checks that decoding and verifying corrupt bytecode
+// returns errors instead of panicking. The fuzzer mutates the payload; the
+// header is rebuilt with a matching checksum so the payload gets decoded.
+func FuzzBytecodeDecode(f *testing.F) {
+	for _, src := range regVMTests {
+		if payload := encodePayload(f, src); payload != nil {
+			f.Add(payload)
+		}
+	}
+	f.Fuzz(func(t *testing.T, payload []byte) {
+		header := BytecodeHeader{
+			FormatVersion: BytecodeFormatVersion,
+			Version:       Version,
+			BuiltinsHash:  BuiltinsHash(),
+			PayloadSize:   uint32(len(payload)),
+			Checksum:      crc32.ChecksumIEEE(payload),
+		}
+		var buf bytes.Buffer
+		if err := header.write(&buf); err != nil {
+			t.Fatal(err)
+		}
+		buf.Write(payload)
+
+		b := &Bytecode{}
+		if err := b.Decode(&buf, nil); err != nil {
+			return
+		}
+		_ = b.Verify()
+	})
+}
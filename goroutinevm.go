@@ -1,7 +1,7 @@
 package tender
 
 import (
-	"fmt"
+	"errors"
 	"runtime/debug"
 	"sync/atomic"
 	"time"
@@ -61,12 +61,11 @@ func builtinGovm(args ...Object) (Object, error) {
 		waitChan: make(chan ret, 1),
 	}
 	
-	var callers []frame
+	spawn := vm.spawnStack()
 	cfn, compiled := fn.(*CompiledFunction)
 	if compiled {
 	gvm.VM = vm.ShallowClone()
-	} else {
-		callers = vm.callers()
+	gvm.VM.spawnFrames = spawn
 	}
 	
 	if err := vm.addChild(gvm.VM); err != nil {
@@ -77,10 +76,11 @@ func builtinGovm(args ...Object) (Object, error) {
 		var err error
 		defer func() {
 			if perr := recover(); perr != nil {
-				if callers == nil {
-					panic("callers not saved")
-				}
-				err = fmt.Errorf("\nRuntime Panic: %v%s\n%s", perr, vm.callStack(callers), debug.Stack())
+				err = ErrPanic{perr, debug.Stack()}
+			}
+			if err != nil && !compiled && !errors.Is(err, ErrVMAborted) {
+				// errors of Go functions get the call stack of go
+				err = &RuntimeError{Err: err, Frames: spawn, fileSet: vm.fileSet}
 			}
 			if err != nil {
 				vm.addError(err)
@@ -106,13 +106,23 @@ func builtinGovm(args ...Object) (Object, error) {
 		}()
 		
 		obj := map[string]Object{
-			"result": &BuiltinFunction{Value: gvm.getRet},
-			"wait":   &BuiltinFunction{Value: gvm.waitTimeout},
+			"result": &BuiltinFunction{Value: gvm.getRet, NeedVMObj: true},
+			"wait":   &BuiltinFunction{Value: gvm.waitTimeout, NeedVMObj: true},
 			"abort":  &BuiltinFunction{Value: gvm.abort},
 		}
 		return &Map{Value: obj}, nil
 }
 
+// spawnStack returns the call stack of the call of go starting a goroutine,
+// which continues the call stack of the goroutine.
+func (v *VM) spawnStack() []StackFrame {
+	stack := v.stackFrames(v.callers())
+	if len(stack) > 0 {
+		stack[0].Spawn = true
+	}
+	return stack
+}
+
 // Triggers the termination process of the current VM and all its descendant VMs.
 func builtinAbort(args ...Object) (Object, error) {
 	vm := args[0].(*VMObj).Value
@@ -148,6 +158,8 @@ func (gvm *goroutineVM) wait(seconds int64) bool {
 	// Returns true if the goroutineVM exited(successfully or not) within the timeout.
 // Waits forever if the optional timeout not specified, or timeout < 0.
 func (gvm *goroutineVM) waitTimeout(args ...Object) (Object, error) {
+	vm := args[0].(*VMObj).Value
+	args = args[1:] // the first arg is VMObj inserted by VM
 	if len(args) > 1 {
 		return nil, ErrWrongNumArguments
 	}
@@ -165,11 +177,20 @@ func (gvm *goroutineVM) waitTimeout(args ...Object) (Object, error) {
 	}
 	
 	if gvm.wait(int64(timeOut)) {
+		gvm.waited(vm)
 		return TrueValue, nil
 	}
 	return FalseValue, nil
 }
 
+// waited records the call stack of vm waiting for the goroutineVM if it
+// failed, which the error report of vm shows.
+func (gvm *goroutineVM) waited(vm *VM) {
+	if gvm.ret.err != nil && vm.waitFrames == nil {
+		vm.waitFrames = vm.stackFrames(vm.callers())
+	}
+}
+
 // Triggers the termination process of the goroutineVM and all its descendant VMs.
 func (gvm *goroutineVM) abort(args ...Object) (Object, error) {
 	if len(args) != 0 {
@@ -184,11 +205,14 @@ func (gvm *goroutineVM) abort(args ...Object) (Object, error) {
 // Waits the goroutineVM to complete, return Error object if any runtime error occurred
 // during the execution, otherwise return the result value of fn(arg1, arg2, ...)
 func (gvm *goroutineVM) getRet(args ...Object) (Object, error) {
+	vm := args[0].(*VMObj).Value
+	args = args[1:] // the first arg is VMObj inserted by VM
 	if len(args) != 0 {
 		return nil, ErrWrongNumArguments
 	}
 	
 	gvm.wait(-1)
+	gvm.waited(vm)
 	if gvm.ret.err != nil {
 		return &Error{Value: &String{Value: gvm.ret.err.Error()}}, nil
 	}
@@ -289,12 +313,11 @@ func WrapFuncCall(vm *VM, args ...Object) (Object, error) {
 		waitChan: make(chan ret, 1),
 	}
 	
-	var callers []frame
+	spawn := vm.spawnStack()
 	cfn, compiled := fn.(*CompiledFunction)
 	if compiled {
 	gvm.VM = vm.ShallowClone()
-	} else {
-		callers = vm.callers()
+	gvm.VM.spawnFrames = spawn
 	}
 	
 	if err := vm.addChild(gvm.VM); err != nil {
@@ -305,10 +328,10 @@ func WrapFuncCall(vm *VM, args ...Object) (Object, error) {
 	var err error
 	defer func() {
 		if perr := recover(); perr != nil {
-			if callers == nil {
-				panic("callers not saved")
-			}
-			err = fmt.Errorf("\nRuntime Panic: %v%s\n%s", perr, vm.callStack(callers), debug.Stack())
+			err = ErrPanic{perr, debug.Stack()}
+		}
+		if err != nil && !compiled && !errors.Is(err, ErrVMAborted) {
+			err = &RuntimeError{Err: err, Frames: spawn, fileSet: vm.fileSet}
 		}
 		if err != nil {
 			vm.addError(err)
@@ -0,0 +1,68 @@
+package tender
+
+// selectorCache is the inline cache of one selector expression. It holds the
+// result of the selector for the last object it was evaluated on, which is
+// valid as long as the object cannot change: immutable maps and method
+// objects, except for the fields of Go structs bound by Bind. For method
+// objects it also remembers the index of the method in the method table,
+// which is shared by all objects of the type.
+type selectorCache struct {
+	key   int // constant index of the selector name
+	recv  Object
+	value Object
+	table *MethodTable
+	index int
+}
+
+// selector evaluates left.name, where name is the string constant cidx,
+// using the inline cache slot.
+func (v *VM) selector(left Object, cidx, slot int) (Object, error) {
+	if slot >= len(v.caches) {
+		// bytecode created without inline caches
+		return left.IndexGet(v.constants[cidx])
+	}
+	c := &v.caches[slot]
+	if c.recv == left && c.key == cidx {
+		return c.value, nil
+	}
+
+	switch left := left.(type) {
+	case *ImmutableMap:
+		name, ok := v.constants[cidx].(*String)
+		if !ok {
+			return nil, ErrInvalidIndexType
+		}
+		val, ok := left.Value[name.Value]
+		if !ok || val == nil {
+			val = NullValue
+		}
+		*c = selectorCache{key: cidx, recv: left, value: val}
+		return val, nil
+	case *MethodObject:
+		i := c.index
+		if c.table != left.Methods || c.key != cidx {
+			name, ok := v.constants[cidx].(*String)
+			if !ok {
+				return nil, ErrInvalidIndexType
+			}
+			if i = left.Methods.lookup(name.Value); i < 0 {
+				return NullValue, nil
+			}
+		}
+		val := left.Methods.get(left, i)
+		if left.Methods.isField(i) {
+			// the value of a field can change, so only its index is cached
+			*c = selectorCache{key: cidx, table: left.Methods, index: i}
+			return val, nil
+		}
+		*c = selectorCache{
+			key:   cidx,
+			recv:  left,
+			value: val,
+			table: left.Methods,
+			index: i,
+		}
+		return val, nil
+	}
+	return left.IndexGet(v.constants[cidx])
+}
@@ -0,0 +1,99 @@
+package tender
+
+import (
+	"fmt"
+	"testing"
+)
+
+type testCounter struct {
+	n int64
+}
+
+var testCounterMethods = NewMethodTable("counter", map[string]MethodFunc{
+	"inc": func(recv interface{}, args ...Object) (Object, error) {
+		c := recv.(*testCounter)
+		c.n++
+		return &Int{Value: c.n}, nil
+	},
+	"get": func(recv interface{}, args ...Object) (Object, error) {
+		return &Int{Value: recv.(*testCounter).n}, nil
+	},
+}, map[string]PropertyFunc{
+	"kind": func(recv interface{}) Object {
+		return &String{Value: "counter"}
+	},
+})
+
+func TestSelectorInlineCache(t *testing.T) {
+	tests := []struct {
+		src      string
+		expected string
+	}{
+		{`out := []; for m in [{a: 1}, immutable({a: 2}), {b: 3}, immutable({a: 4})] { out = append(out, m.a) }`,
+			`[1, 2, null, 4]`},
+		{`m := immutable({a: 1, b: 2}); out := []; for i := 0; i < 3; i++ { out = append(out, m.a, m.b) }`,
+			`[1, 2, 1, 2, 1, 2]`},
+		{`m := {a: 1}; out := []; for i := 0; i < 3; i++ { out = append(out, m.a); m.a = i * 10 }`,
+			`[1, 0, 10]`},
+		{`f := fn(x) { return x.inc() }; f(c1); f(c1); f(c2); out := [c1.get(), c2.get(), c1.kind, c2.nope]`,
+			`[2, 1, "counter", null]`},
+		{`out := []; for o in [c1, immutable({inc: fn() { return "map" }}), c2] { out = append(out, o.inc()) }`,
+			`[1, "map", 1]`},
+	}
+	for _, tc := range tests {
+		s := NewScript([]byte(tc.src))
+		_ = s.Add("c1", testCounterMethods.New(&testCounter{}))
+		_ = s.Add("c2", testCounterMethods.New(&testCounter{}))
+		c, err := s.Run()
+		if err != nil {
+			t.Errorf("%s: %v", tc.src, err)
+			continue
+		}
+		out := c.Get("out").Object()
+		if got := out.String(); got != tc.expected {
+			t.Errorf("%s: out = %s, expected %s", tc.src, got, tc.expected)
+		}
+	}
+}
+
+func TestSelectorErrors(t *testing.T) {
+	for _, src := range []string{`x := 1; y := x.a`, `x := true; y := x.a`} {
+		_, err := NewScript([]byte(src)).Run()
+		want := fmt.Sprint(err)
+		if err == nil {
+			t.Errorf("%s: expected an error", src)
+			continue
+		}
+		// same error without inline caches
+		s := NewScript([]byte(src))
+		c, cerr := s.Compile()
+		if cerr != nil {
+			t.Fatal(cerr)
+		}
+		c.bytecode.InlineCaches = 0
+		if got := fmt.Sprint(c.Run()); got != want {
+			t.Errorf("%s: error %s, expected %s", src, got, want)
+		}
+	}
+}
+
+func TestMethodTable(t *testing.T) {
+	names := testCounterMethods.Names()
+	if fmt.Sprint(names) != "[get inc kind]" {
+		t.Errorf("Names() = %v", names)
+	}
+	o := testCounterMethods.New(&testCounter{})
+	if o.TypeName() != "counter" {
+		t.Errorf("TypeName() = %s", o.TypeName())
+	}
+	m, err := o.IndexGet(&String{Value: "inc"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res, _ := m.Call(); res.String() != "1" {
+		t.Errorf("inc() = %s", res)
+	}
+	if _, err := o.IndexGet(&Int{Value: 1}); err != ErrInvalidIndexType {
+		t.Errorf("IndexGet(1) error = %v", err)
+	}
+}
@@ -28,6 +28,12 @@ func MakeInstruction(opcode parser.Opcode, operands ...int) []byte {
 			n := uint16(o)
 			instruction[offset] = byte(n >> 8)
 			instruction[offset+1] = byte(n)
+		case 4:
+			n := uint32(o)
+			instruction[offset] = byte(n >> 24)
+			instruction[offset+1] = byte(n >> 16)
+			instruction[offset+2] = byte(n >> 8)
+			instruction[offset+3] = byte(n)
 		}
 		offset += width
 	}
@@ -54,6 +60,10 @@ func FormatInstructions(b []byte, posOffset int) []string {
 			out = append(out, fmt.Sprintf("%04d %-7s %-5d %-5d",
 				posOffset+i, parser.OpcodeNames[b[i]],
 				operands[0], operands[1]))
+		case 3:
+			out = append(out, fmt.Sprintf("%04d %-7s %-5d %-5d %-5d",
+				posOffset+i, parser.OpcodeNames[b[i]],
+				operands[0], operands[1], operands[2]))
 		}
 		i += 1 + read
 	}
@@ -0,0 +1,231 @@
+package tender
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+)
+
+// MethodFunc is a method of a Go-defined object type. recv is the Value of
+// the MethodObject the method is called on.
+type MethodFunc func(recv interface{}, args ...Object) (Object, error)
+
+// PropertyFunc returns the value of a property of a Go-defined object type.
+// The value must not change for the lifetime of the object, as it may be
+// cached.
+type PropertyFunc func(recv interface{}) Object
+
+type methodEntry struct {
+	name     string
+	method   MethodFunc
+	property PropertyFunc
+	// set assigns the field of a table created by Bind; its value is read
+	// with property and is not cached, as it can change
+	set func(recv interface{}, value Object) error
+}
+
+// MethodTable holds the methods and properties of a Go-defined object type.
+// A table is created once for each type and shared by all its objects, so
+// that creating an object does not build a map of functions.
+type MethodTable struct {
+	typeName string
+	entries  []methodEntry // sorted by name
+	index    map[string]int
+}
+
+// NewMethodTable creates a MethodTable for the type with the given methods
+// and properties. properties can be nil.
+func NewMethodTable(
+	typeName string,
+	methods map[string]MethodFunc,
+	properties map[string]PropertyFunc,
+) *MethodTable {
+	entries := make([]methodEntry, 0, len(methods)+len(properties))
+	for name, fn := range methods {
+		entries = append(entries, methodEntry{name: name, method: fn})
+	}
+	for name, fn := range properties {
+		entries = append(entries, methodEntry{name: name, property: fn})
+	}
+	return newMethodTable(typeName, entries)
+}
+
+func newMethodTable(typeName string, entries []methodEntry) *MethodTable {
+	t := &MethodTable{
+		typeName: typeName,
+		entries:  entries,
+		index:    make(map[string]int, len(entries)),
+	}
+	sort.Slice(t.entries, func(i, j int) bool {
+		return t.entries[i].name < t.entries[j].name
+	})
+	for i, e := range t.entries {
+		t.index[e.name] = i
+	}
+	return t
+}
+
+// TypeName returns the name of the type of the table.
+func (t *MethodTable) TypeName() string {
+	return t.typeName
+}
+
+// Names returns the sorted names of the methods and properties.
+func (t *MethodTable) Names() []string {
+	names := make([]string, len(t.entries))
+	for i, e := range t.entries {
+		names[i] = e.name
+	}
+	return names
+}
+
+// New returns an object of the table type wrapping value.
+func (t *MethodTable) New(value interface{}) *MethodObject {
+	return &MethodObject{Methods: t, Value: value}
+}
+
+// lookup returns the index of the named entry or -1.
+func (t *MethodTable) lookup(name string) int {
+	if i, ok := t.index[name]; ok {
+		return i
+	}
+	return -1
+}
+
+// isField returns true if entry i is a field, whose value must not be
+// cached.
+func (t *MethodTable) isField(i int) bool {
+	return t.entries[i].set != nil
+}
+
+// get returns the bound method or the property value of entry i for o.
+func (t *MethodTable) get(o *MethodObject, i int) Object {
+	e := &t.entries[i]
+	if e.property != nil {
+		v := e.property(o.Value)
+		if v == nil {
+			return NullValue
+		}
+		return v
+	}
+	return &BoundMethod{Name: e.name, Recv: o, Fn: e.method}
+}
+
+// MethodObject is an object of a Go-defined type whose methods and
+// properties are defined by a MethodTable. Selectors on a MethodObject are
+// inline cached by the VM.
+type MethodObject struct {
+	ObjectImpl
+	Methods *MethodTable
+	Value   interface{}
+}
+
+// TypeName returns the name of the type.
+func (o *MethodObject) TypeName() string {
+	return o.Methods.typeName
+}
+
+func (o *MethodObject) String() string {
+	if s, ok := o.Value.(fmt.Stringer); ok {
+		return s.String()
+	}
+	return "<" + o.Methods.typeName + ">"
+}
+
+// Copy returns the object itself, as the wrapped Go value is shared.
+func (o *MethodObject) Copy() Object {
+	return o
+}
+
+// IsFalsy returns false.
+func (o *MethodObject) IsFalsy() bool {
+	return false
+}
+
+// Equals returns true if x is an object of the same type wrapping the same
+// value.
+func (o *MethodObject) Equals(x Object) bool {
+	m, ok := x.(*MethodObject)
+	if !ok || m.Methods != o.Methods {
+		return false
+	}
+	return o == m || reflect.TypeOf(o.Value).Comparable() && o.Value == m.Value
+}
+
+// IndexGet returns the method or property with the name of the index.
+func (o *MethodObject) IndexGet(index Object) (Object, error) {
+	name, ok := index.(*String)
+	if !ok {
+		return nil, ErrInvalidIndexType
+	}
+	i := o.Methods.lookup(name.Value)
+	if i < 0 {
+		return NullValue, nil
+	}
+	return o.Methods.get(o, i), nil
+}
+
+// IndexSet assigns value to the field with the name of the index.
+func (o *MethodObject) IndexSet(index, value Object) error {
+	name, ok := index.(*String)
+	if !ok {
+		return ErrInvalidIndexType
+	}
+	i := o.Methods.lookup(name.Value)
+	if i < 0 || !o.Methods.isField(i) {
+		return fmt.Errorf("%s has no field '%s'", o.Methods.typeName, name.Value)
+	}
+	return o.Methods.entries[i].set(o.Value, value)
+}
+
+// CanIterate returns true.
+func (o *MethodObject) CanIterate() bool {
+	return true
+}
+
+// Iterate returns an iterator over the methods and properties.
+func (o *MethodObject) Iterate() Iterator {
+	m := make(map[string]Object, len(o.Methods.entries))
+	for i, e := range o.Methods.entries {
+		m[e.name] = o.Methods.get(o, i)
+	}
+	keys := o.Methods.Names()
+	return &MapIterator{v: m, k: keys, l: len(keys)}
+}
+
+// BoundMethod is a method of a MethodTable bound to an object.
+type BoundMethod struct {
+	ObjectImpl
+	Name string
+	Recv *MethodObject
+	Fn   MethodFunc
+}
+
+// TypeName returns the name of the type.
+func (o *BoundMethod) TypeName() string {
+	return "user-function:" + o.Name
+}
+
+func (o *BoundMethod) String() string {
+	return "<user-function>"
+}
+
+// Copy returns the method itself.
+func (o *BoundMethod) Copy() Object {
+	return o
+}
+
+// Equals returns false.
+func (o *BoundMethod) Equals(_ Object) bool {
+	return false
+}
+
+// Call invokes the method on its object.
+func (o *BoundMethod) Call(args ...Object) (Object, error) {
+	return o.Fn(o.Recv.Value, args...)
+}
+
+// CanCall returns true.
+func (o *BoundMethod) CanCall() bool {
+	return true
+}
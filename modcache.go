@@ -0,0 +1,491 @@
+package tender
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/2dprototype/tender/parser"
+)
+
+// moduleCacheExt is the file extension of the entries of a module cache.
+const moduleCacheExt = ".tdc"
+
+// DefaultModuleCacheDir returns the directory compiled modules are cached in:
+// the TENDER_CACHE environment variable, or the "tender" directory in the
+// user cache directory. It returns an empty string if TENDER_CACHE is "off".
+func DefaultModuleCacheDir() (string, error) {
+	if dir := os.Getenv("TENDER_CACHE"); dir != "" {
+		if dir == "off" {
+			return "", nil
+		}
+		return filepath.Abs(dir)
+	}
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "tender"), nil
+}
+
+// CleanModuleCache removes the compiled modules cached in dir.
+func CleanModuleCache(dir string) error {
+	entries, err := ioutil.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		if strings.HasSuffix(entry.Name(), moduleCacheExt) ||
+			strings.HasSuffix(entry.Name(), moduleCacheExt+".tmp") {
+			if err := os.Remove(filepath.Join(dir, entry.Name())); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// SetModuleCache enables caching the compiled file modules in dir, so that
+// other processes importing them do not compile them again. An entry is only
+// used if the module file, the files it embeds and the modules it imports are
+// unchanged, and it was compiled by the same version of tender with the same
+// builtin functions and options. An empty dir disables the cache, which is
+// the default.
+func (c *Compiler) SetModuleCache(dir string) {
+	c.moduleCache = dir
+}
+
+// root returns the compiler of the main file.
+func (c *Compiler) root() *Compiler {
+	for c.parent != nil {
+		c = c.parent
+	}
+	return c
+}
+
+// addSource records that the code compiled by c depends on the content of
+// a file or of a source module, identified by its absolute path or its name.
+func (c *Compiler) addSource(name string, data []byte) {
+	if c.sources == nil {
+		c.sources = make(map[string]string)
+	}
+	sum := sha256.Sum256(data)
+	c.sources[name] = hex.EncodeToString(sum[:])
+}
+
+// addSources records the sources of an imported module.
+func (c *Compiler) addSources(sources map[string]string) {
+	if c.sources == nil {
+		c.sources = make(map[string]string)
+	}
+	for name, hash := range sources {
+		c.sources[name] = hash
+	}
+}
+
+// Sources returns the sorted absolute paths of the files the compiled code
+// depends on: the module files it imports and the files it embeds, directly or
+// through the imported modules, and the directories of embedded directories
+// and patterns. The file given to the compiler is not included.
+func (c *Compiler) Sources() []string {
+	seen := make(map[string]bool)
+	var paths []string
+	for name := range c.sources {
+		if strings.HasPrefix(name, "embed:") {
+			kind, src := splitEmbedListSource(name)
+			if kind == "glob" {
+				src = filepath.Dir(src)
+			}
+			name = src
+		} else if !filepath.IsAbs(name) {
+			continue // source module
+		}
+		if !seen[name] {
+			seen[name] = true
+			paths = append(paths, name)
+		}
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+// moduleCachePath returns the path of the cache entry of a module.
+func (c *Compiler) moduleCachePath(modulePath string, src []byte) string {
+	h := sha256.New()
+	srcHash := sha256.Sum256(src)
+	fields := []string{
+		modulePath,
+		hex.EncodeToString(srcHash[:]),
+		Version,
+		fmt.Sprintf("%016x", BuiltinsHash()),
+		fmt.Sprintf("optimize=%t typechecks=%t", c.optimize, c.typeChecks),
+	}
+	// modules imported by name depend on the search paths
+	fields = append(fields, c.root().searchPaths()...)
+	for _, field := range fields {
+		_, _ = h.Write([]byte(field))
+		_, _ = h.Write([]byte{0})
+	}
+	return filepath.Join(c.moduleCache, hex.EncodeToString(h.Sum(nil))+moduleCacheExt)
+}
+
+// loadCachedModule returns the compiled module of the module file from the
+// module cache, with its constants added to the constants of the compiler.
+func (c *Compiler) loadCachedModule(modulePath string, src []byte) (*CompiledFunction, bool) {
+	if c.moduleCache == "" || c.trace != nil {
+		return nil, false
+	}
+	data, err := ioutil.ReadFile(c.moduleCachePath(modulePath, src))
+	if err != nil || len(data) < 4 {
+		return nil, false
+	}
+
+	// the sources and exports of the module precede its bytecode
+	size := int(binary.BigEndian.Uint32(data))
+	if size > len(data)-4 {
+		return nil, false
+	}
+	dec := &bytecodeDecoder{data: data[4 : 4+size], modules: c.modules}
+	sources, exports, known, err := decodeModuleInfo(dec)
+	if err != nil || !c.validSources(sources) {
+		return nil, false
+	}
+	bytecode := &Bytecode{}
+	if err := bytecode.Decode(bytes.NewReader(data[4+size:]), c.modules); err != nil {
+		return nil, false
+	}
+
+	// narrow operands must be able to hold the new indexes
+	root := c.root()
+	if len(root.constants)+len(bytecode.Constants) > 0x10000 ||
+		root.inlineCaches+bytecode.InlineCaches > 0x10000 {
+		return nil, false
+	}
+
+	// add the source files to the file set of the compiler
+	fileSet := c.file.Set()
+	files := make(map[*parser.SourceFile]*parser.SourceFile)
+	for _, f := range bytecode.FileSet.Files {
+		file := fileSet.AddFile(f.Name, -1, f.Size)
+		file.Lines = f.Lines
+		files[f] = file
+	}
+	fns := []*CompiledFunction{bytecode.MainFunction}
+	for _, cn := range bytecode.Constants {
+		if fn, ok := cn.(*CompiledFunction); ok {
+			fns = append(fns, fn)
+		}
+	}
+	for _, fn := range fns {
+		for ip, pos := range fn.SourceMap {
+			if f := bytecode.FileSet.File(pos); f != nil {
+				fn.SourceMap[ip] = parser.Pos(int(pos) - f.Base + files[f].Base)
+			}
+		}
+	}
+
+	constIndexes := make(map[int]int, len(bytecode.Constants))
+	for i, cn := range bytecode.Constants {
+		constIndexes[i] = c.addConstant(cn)
+	}
+	cacheIndexes := make(map[int]int, bytecode.InlineCaches)
+	for i := 0; i < bytecode.InlineCaches; i++ {
+		cacheIndexes[i] = c.addInlineCache()
+	}
+	for _, fn := range fns {
+		updateConstIndexes(fn.Instructions, constIndexes)
+		updateInlineCaches(fn.Instructions, cacheIndexes)
+	}
+
+	if known {
+		c.storeModuleExports(modulePath, exports)
+	}
+	c.root().moduleSources[modulePath] = sources
+	c.addSources(sources)
+	return bytecode.MainFunction, true
+}
+
+// validSources returns whether the sources recorded in a cache entry are
+// unchanged.
+func (c *Compiler) validSources(sources map[string]string) bool {
+	for name, hash := range sources {
+		var data []byte
+		if strings.HasPrefix(name, "embed:") {
+			kind, src := splitEmbedListSource(name)
+			paths, err := embedList(src, kind == "dir")
+			if err != nil {
+				return false
+			}
+			data = []byte(strings.Join(paths, "\n"))
+		} else if filepath.IsAbs(name) {
+			var err error
+			if data, err = ioutil.ReadFile(name); err != nil {
+				return false
+			}
+		} else {
+			mod := c.modules.Get(name)
+			if mod == nil {
+				return false
+			}
+			v, err := mod.Import(name)
+			if err != nil {
+				return false
+			}
+			if data, _ = v.([]byte); data == nil {
+				return false
+			}
+		}
+		if sum := sha256.Sum256(data); hex.EncodeToString(sum[:]) != hash {
+			return false
+		}
+	}
+	return true
+}
+
+// storeCachedModule writes the compiled module of the module file into the
+// module cache. The module function and the constants it uses are copied,
+// with the constants and inline caches numbered from zero and the source
+// positions relative to the files of the module. Errors are ignored, and the
+// module is compiled again the next time.
+func (c *Compiler) storeCachedModule(
+	modulePath string,
+	src []byte,
+	fn *CompiledFunction,
+	sources map[string]string,
+) {
+	if c.moduleCache == "" || c.trace != nil {
+		return
+	}
+	root := c.root()
+
+	// collect the constants used by the module
+	var constants []Object
+	constIndexes := make(map[int]int)
+	var collect func(insts []byte)
+	collect = func(insts []byte) {
+		iterateConstIndexes(insts, func(idx int) {
+			if _, ok := constIndexes[idx]; ok {
+				return
+			}
+			constIndexes[idx] = len(constants)
+			cn := root.constants[idx]
+			constants = append(constants, cn)
+			if fn, ok := cn.(*CompiledFunction); ok {
+				collect(fn.Instructions)
+			}
+		})
+	}
+	collect(fn.Instructions)
+
+	// copy the functions
+	main := copyCompiledFunction(fn)
+	fns := []*CompiledFunction{main}
+	for i, cn := range constants {
+		if fn, ok := cn.(*CompiledFunction); ok {
+			fn = copyCompiledFunction(fn)
+			constants[i] = fn
+			fns = append(fns, fn)
+		}
+	}
+
+	// files of the source positions, in the order of the file set
+	fileSet := c.file.Set()
+	used := make(map[*parser.SourceFile]bool)
+	for _, fn := range fns {
+		for _, pos := range fn.SourceMap {
+			if f := fileSet.File(pos); f != nil {
+				used[f] = true
+			}
+		}
+	}
+	moduleFileSet := parser.NewFileSet()
+	files := make(map[*parser.SourceFile]*parser.SourceFile)
+	for _, f := range fileSet.Files {
+		if used[f] {
+			file := moduleFileSet.AddFile(f.Name, -1, f.Size)
+			file.Lines = append([]int(nil), f.Lines...)
+			files[f] = file
+		}
+	}
+
+	cacheIndexes := make(map[int]int)
+	for _, fn := range fns {
+		for ip, pos := range fn.SourceMap {
+			if f := fileSet.File(pos); f != nil {
+				fn.SourceMap[ip] = parser.Pos(int(pos) - f.Base + files[f].Base)
+			}
+		}
+		updateConstIndexes(fn.Instructions, constIndexes)
+		iterateInlineCaches(fn.Instructions, func(idx int) {
+			if _, ok := cacheIndexes[idx]; !ok {
+				cacheIndexes[idx] = len(cacheIndexes)
+			}
+		})
+		updateInlineCaches(fn.Instructions, cacheIndexes)
+	}
+
+	bytecode := &Bytecode{
+		FileSet:      moduleFileSet,
+		MainFunction: main,
+		Constants:    constants,
+		InlineCaches: len(cacheIndexes),
+	}
+	var buf bytes.Buffer
+	enc := newBytecodeEncoder()
+	names, known := c.loadModuleExports(modulePath)
+	encodeModuleInfo(enc, sources, names, known)
+	info := enc.payload()
+	buf.Write(binary.BigEndian.AppendUint32(nil, uint32(len(info))))
+	buf.Write(info)
+	if err := bytecode.Encode(&buf); err != nil {
+		return
+	}
+
+	// write the entry atomically, as other processes may read it
+	path := c.moduleCachePath(modulePath, src)
+	if err := os.MkdirAll(c.moduleCache, 0755); err != nil {
+		return
+	}
+	tmp, err := ioutil.TempFile(c.moduleCache, filepath.Base(path)+".*"+moduleCacheExt+".tmp")
+	if err != nil {
+		return
+	}
+	_, err = tmp.Write(buf.Bytes())
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), path)
+	}
+	if err != nil {
+		_ = os.Remove(tmp.Name())
+	}
+}
+
+func encodeModuleInfo(
+	enc *bytecodeEncoder,
+	sources map[string]string,
+	exports []string,
+	known bool,
+) {
+	names := make([]string, 0, len(sources))
+	for name := range sources {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	enc.uvarint(uint64(len(names)))
+	for _, name := range names {
+		enc.string(name)
+		enc.string(sources[name])
+	}
+	if !known {
+		enc.uvarint(0)
+		return
+	}
+	enc.uvarint(uint64(len(exports)) + 1)
+	for _, name := range exports {
+		enc.string(name)
+	}
+}
+
+func decodeModuleInfo(dec *bytecodeDecoder) (
+	sources map[string]string,
+	exports []string,
+	known bool,
+	err error,
+) {
+	if err = dec.stringTable(); err != nil {
+		return
+	}
+	n, err := dec.int(len(dec.data))
+	if err != nil {
+		return
+	}
+	sources = make(map[string]string, n)
+	for i := 0; i < n; i++ {
+		var name, hash string
+		if name, err = dec.string(); err != nil {
+			return
+		}
+		if hash, err = dec.string(); err != nil {
+			return
+		}
+		sources[name] = hash
+	}
+	if n, err = dec.int(len(dec.data)); err != nil || n == 0 {
+		return
+	}
+	known = true
+	exports = make([]string, n-1)
+	for i := range exports {
+		if exports[i], err = dec.string(); err != nil {
+			return
+		}
+	}
+	return
+}
+
+func copyCompiledFunction(fn *CompiledFunction) *CompiledFunction {
+	sourceMap := make(map[int]parser.Pos, len(fn.SourceMap))
+	for ip, pos := range fn.SourceMap {
+		sourceMap[ip] = pos
+	}
+	return &CompiledFunction{
+		Instructions:  append([]byte(nil), fn.Instructions...),
+		NumLocals:     fn.NumLocals,
+		NumParameters: fn.NumParameters,
+		VarArgs:       fn.VarArgs,
+		SourceMap:     sourceMap,
+		Params:        fn.Params,
+		Usage:         fn.Usage,
+		Doc:           fn.Doc,
+	}
+}
+
+// iterateConstIndexes calls fn with the constant indexes of the
+// instructions.
+func iterateConstIndexes(insts []byte, fn func(idx int)) {
+	for i := 0; i < len(insts); {
+		op := insts[i]
+		operands, read := parser.ReadOperands(parser.OpcodeOperands[op], insts[i+1:])
+		for _, n := range constOperands(op) {
+			fn(operands[n])
+		}
+		i += 1 + read
+	}
+}
+
+// iterateInlineCaches calls fn with the inline cache indexes of the
+// instructions.
+func iterateInlineCaches(insts []byte, fn func(idx int)) {
+	for i := 0; i < len(insts); {
+		op := insts[i]
+		operands, read := parser.ReadOperands(parser.OpcodeOperands[op], insts[i+1:])
+		if op == parser.OpSelector {
+			fn(operands[1])
+		}
+		i += 1 + read
+	}
+}
+
+// updateInlineCaches replaces the inline cache indexes of the instructions.
+func updateInlineCaches(insts []byte, indexMap map[int]int) {
+	for i := 0; i < len(insts); {
+		op := insts[i]
+		operands, read := parser.ReadOperands(parser.OpcodeOperands[op], insts[i+1:])
+		if op == parser.OpSelector {
+			operands[1] = indexMap[operands[1]]
+			copy(insts[i:], MakeInstruction(op, operands...))
+		}
+		i += 1 + read
+	}
+}
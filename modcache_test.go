@@ -0,0 +1,161 @@
+package tender
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/2dprototype/tender/parser"
+)
+
+func TestModuleCache(t *testing.T) {
+	dir := t.TempDir()
+	cache := filepath.Join(t.TempDir(), "cache")
+	writeFiles(t, dir, map[string]string{
+		"lib/a.td": `
+b := import("./b")
+data := embed("data.txt")
+export fn f(x) {
+	m := {k: x}
+	return b.name + " " + data + " " + m.k
+}
+export fn fail() {
+	n := 1
+	return n()
+}`,
+		"lib/b.td":     `export {name: "b"}`,
+		"lib/data.txt": "data",
+	})
+
+	run := func(src string) (string, error) {
+		s := NewScript([]byte(src))
+		s.EnableFileImport(true)
+		s.SetModuleCache(cache)
+		if err := s.SetImportDir(dir); err != nil {
+			return "", err
+		}
+		c, err := s.Run()
+		if err != nil {
+			return "", err
+		}
+		return c.Get("out").String(), nil
+	}
+	entries := func() map[string]time.Time {
+		files, err := ioutil.ReadDir(cache)
+		if err != nil {
+			t.Fatal(err)
+		}
+		modTimes := make(map[string]time.Time)
+		for _, f := range files {
+			modTimes[f.Name()] = f.ModTime()
+		}
+		return modTimes
+	}
+
+	src := `from "lib/a" import f; out := f("x")`
+	out, err := run(src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out != "b data x" {
+		t.Fatalf("out = %s, expected b data x", out)
+	}
+	_, failErr := run(`a := import("lib/a"); a.fail()`)
+	if failErr == nil {
+		t.Fatal("expected runtime error")
+	}
+
+	// entries are not written again when they are used
+	old := time.Now().Add(-time.Hour).Truncate(time.Second)
+	cached := entries()
+	if len(cached) != 2 {
+		t.Fatalf("%d cache entries, expected 2", len(cached))
+	}
+	for name := range cached {
+		if err := os.Chtimes(filepath.Join(cache, name), old, old); err != nil {
+			t.Fatal(err)
+		}
+	}
+	out, err = run(`s := "more constants"; x := [1.5, 'c', s]; ` + src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out != "b data x" {
+		t.Errorf("cached: out = %s, expected b data x", out)
+	}
+	_, err = run(`a := import("lib/a"); a.fail()`)
+	if err == nil || err.Error() != failErr.Error() {
+		t.Errorf("cached: error %v, expected %v", err, failErr)
+	}
+	_, err = run(`from "lib/a" import g`)
+	if err == nil || !strings.Contains(err.Error(), "'g' is not exported by module") {
+		t.Errorf("cached: error %v, expected not exported", err)
+	}
+	for name, modTime := range entries() {
+		if !modTime.Equal(old) {
+			t.Errorf("cache entry %s was written again", name)
+		}
+	}
+
+	// changes of embedded files and imported modules invalidate entries
+	writeFiles(t, dir, map[string]string{"lib/data.txt": "changed"})
+	if out, err = run(src); err != nil || out != "b changed x" {
+		t.Errorf("embed changed: out = %s, %v, expected b changed x", out, err)
+	}
+	writeFiles(t, dir, map[string]string{"lib/b.td": `export {name: "c"}`})
+	if out, err = run(src); err != nil || out != "c changed x" {
+		t.Errorf("import changed: out = %s, %v, expected c changed x", out, err)
+	}
+
+	if err := CleanModuleCache(cache); err != nil {
+		t.Fatal(err)
+	}
+	if n := len(entries()); n != 0 {
+		t.Errorf("%d cache entries after clean, expected 0", n)
+	}
+}
+
+func TestCompilerSources(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"lib/a.td":      `b := import("./b"); export embed("data.txt")`,
+		"lib/b.td":      `export embed fs("assets")`,
+		"lib/data.txt":  "data",
+		"lib/assets/x":  "x",
+		"images/a.png":  "a",
+		"images/b.txt":  "b",
+		"unused/mod.td": `export 1`,
+	})
+
+	src := []byte(`a := import("lib/a"); t := import("times"); p := embed("images/*.png")`)
+	fileSet := parser.NewFileSet()
+	srcFile := fileSet.AddFile("main.td", -1, len(src))
+	file, err := parser.NewParser(srcFile, src, nil).ParseFile()
+	if err != nil {
+		t.Fatal(err)
+	}
+	modules := NewModuleMap()
+	modules.AddSourceModule("times", []byte(`export {}`))
+	c := NewCompiler(srcFile, nil, nil, modules, nil)
+	c.EnableFileImport(true)
+	c.SetImportDir(dir)
+	if err := c.Compile(file); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []string{
+		filepath.Join(dir, "images"),
+		filepath.Join(dir, "images", "a.png"),
+		filepath.Join(dir, "lib", "a.td"),
+		filepath.Join(dir, "lib", "assets"),
+		filepath.Join(dir, "lib", "assets", "x"),
+		filepath.Join(dir, "lib", "b.td"),
+		filepath.Join(dir, "lib", "data.txt"),
+	}
+	if got := c.Sources(); strings.Join(got, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("sources: %v, expected %v", got, expected)
+	}
+}
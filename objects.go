@@ -430,6 +430,8 @@ type BuiltinFunction struct {
 	Name      string
 	Value     CallableFunc
 	NeedVMObj bool
+	Usage     string // optional usage, such as "len(x) => int"
+	Doc       string // optional description
 }
 
 // TypeName returns the name of the type.
@@ -443,7 +445,12 @@ func (o *BuiltinFunction) String() string {
 
 // Copy returns a copy of the type.
 func (o *BuiltinFunction) Copy() Object {
-	return &BuiltinFunction{Value: o.Value, NeedVMObj: o.NeedVMObj}
+	return &BuiltinFunction{
+		Value:     o.Value,
+		NeedVMObj: o.NeedVMObj,
+		Usage:     o.Usage,
+		Doc:       o.Doc,
+	}
 }
 
 // Equals returns true if the value of the type is equal to the value of
@@ -718,6 +725,10 @@ type CompiledFunction struct {
 	VarArgs       bool
 	SourceMap     map[int]parser.Pos
 	Free          []*ObjectPtr
+	Params        []string     // names of the parameters, used in error reports
+	Usage         string       // usage of a documented function, such as "add(a, b)"
+	Doc           string       // doc comment of the function
+	reg           *regFunction // register VM translation
 }
 
 // TypeName returns the name of the type.
@@ -738,6 +749,10 @@ func (o *CompiledFunction) Copy() Object {
 		VarArgs:       o.VarArgs,
 		SourceMap:     o.SourceMap,
 		Free:          append([]*ObjectPtr{}, o.Free...), // DO NOT Copy() of elements; these are variable pointers
+		Params:        o.Params,
+		Usage:         o.Usage,
+		Doc:           o.Doc,
+		reg:           o.reg,
 	}
 }
 
@@ -2181,6 +2196,8 @@ type UserFunction struct {
 	Name       string
 	Value      CallableFunc
 	EncodingID string
+	Usage      string // optional usage, such as "join(arr [string], sep string) => string"
+	Doc        string // optional description
 }
 
 // TypeName returns the name of the type.
@@ -2194,7 +2211,7 @@ func (o *UserFunction) String() string {
 
 // Copy returns a copy of the type.
 func (o *UserFunction) Copy() Object {
-	return &UserFunction{Value: o.Value}
+	return &UserFunction{Value: o.Value, Usage: o.Usage, Doc: o.Doc}
 }
 
 // Equals returns true if the value of the type is equal to the value of
@@ -0,0 +1,541 @@
+package tender
+
+import (
+	"github.com/2dprototype/tender/parser"
+	"github.com/2dprototype/tender/token"
+)
+
+// foldConstant evaluates operator, conditional and module selector
+// expressions whose operands are all constants. Literals are not folded as
+// they compile to a single instruction anyway.
+func (c *Compiler) foldConstant(expr parser.Expr) (Object, bool) {
+	switch expr.(type) {
+	case *parser.BinaryExpr, *parser.UnaryExpr, *parser.ParenExpr,
+		*parser.CondExpr, *parser.SelectorExpr:
+		return c.constantValue(expr)
+	}
+	return nil, false
+}
+
+// constantValue returns the value of expr if it can be computed at compile
+// time. Expressions that would fail at runtime are never folded, so that the
+// error is still reported when the code runs.
+func (c *Compiler) constantValue(expr parser.Expr) (res Object, ok bool) {
+	defer func() {
+		if r := recover(); r != nil {
+			res, ok = nil, false
+		}
+	}()
+
+	switch expr := expr.(type) {
+	case *parser.IntLit:
+		return &Int{Value: expr.Value}, true
+	case *parser.FloatLit:
+		return &Float{Value: expr.Value}, true
+	case *parser.StringLit:
+		res = &String{Value: expr.Value}
+	case *parser.CharLit:
+		return &Char{Value: expr.Value}, true
+	case *parser.BoolLit:
+		if expr.Value {
+			return TrueValue, true
+		}
+		return FalseValue, true
+	case *parser.NullLit:
+		return NullValue, true
+	case *parser.ParenExpr:
+		return c.constantValue(expr.Expr)
+	case *parser.UnaryExpr:
+		x, ok := c.constantValue(expr.Expr)
+		if !ok {
+			return nil, false
+		}
+		switch expr.Token {
+		case token.Not:
+			if x.IsFalsy() {
+				return TrueValue, true
+			}
+			return FalseValue, true
+		case token.Add:
+			return x, true
+		case token.Sub:
+			switch x := x.(type) {
+			case *Int:
+				return &Int{Value: -x.Value}, true
+			case *Float:
+				return &Float{Value: -x.Value}, true
+			}
+		case token.Xor:
+			if x, ok := x.(*Int); ok {
+				return &Int{Value: ^x.Value}, true
+			}
+		}
+		return nil, false
+	case *parser.BinaryExpr:
+		lhs, ok := c.constantValue(expr.LHS)
+		if !ok {
+			return nil, false
+		}
+		rhs, ok := c.constantValue(expr.RHS)
+		if !ok {
+			return nil, false
+		}
+		var err error
+		switch expr.Token {
+		case token.LAnd:
+			if lhs.IsFalsy() {
+				return lhs, true
+			}
+			return rhs, true
+		case token.LOr:
+			if lhs.IsFalsy() {
+				return rhs, true
+			}
+			return lhs, true
+		case token.Equal:
+			if lhs.Equals(rhs) {
+				return TrueValue, true
+			}
+			return FalseValue, true
+		case token.NotEqual:
+			if lhs.Equals(rhs) {
+				return FalseValue, true
+			}
+			return TrueValue, true
+		case token.Less:
+			res, err = rhs.BinaryOp(token.Greater, lhs)
+		case token.LessEq:
+			res, err = rhs.BinaryOp(token.GreaterEq, lhs)
+		default:
+			res, err = lhs.BinaryOp(expr.Token, rhs)
+		}
+		if err != nil {
+			return nil, false
+		}
+	case *parser.CondExpr:
+		cond, ok := c.constantValue(expr.Cond)
+		if !ok {
+			return nil, false
+		}
+		// both branches must be constant, so that errors in the branch
+		// that is not taken are still reported
+		t, ok := c.constantValue(expr.True)
+		if !ok {
+			return nil, false
+		}
+		f, ok := c.constantValue(expr.False)
+		if !ok {
+			return nil, false
+		}
+		if cond.IsFalsy() {
+			return f, true
+		}
+		return t, true
+	case *parser.SelectorExpr:
+		ident, ok := expr.Expr.(*parser.Ident)
+		if !ok {
+			return nil, false
+		}
+		sel, ok := expr.Sel.(*parser.StringLit)
+		if !ok {
+			return nil, false
+		}
+		modName, ok := c.moduleAliases[ident.Name]
+		if !ok {
+			return nil, false
+		}
+		mod, ok := c.modules.Get(modName).(*BuiltinModule)
+		if !ok {
+			return nil, false
+		}
+		res = mod.Attrs[sel.Value]
+	default:
+		return nil, false
+	}
+
+	// only immutable values can be shared as constants
+	switch res := res.(type) {
+	case *Int, *Float, *Char, *Bool, *Null:
+		return res, true
+	case *String:
+		return res, len(res.Value) <= MaxStringLen
+	}
+	return nil, false
+}
+
+// emitConstant emits the instruction that pushes the constant o.
+func (c *Compiler) emitConstant(node parser.Node, o Object) {
+	switch o := o.(type) {
+	case *Bool:
+		if o.IsFalsy() {
+			c.emit(node, parser.OpFalse)
+		} else {
+			c.emit(node, parser.OpTrue)
+		}
+	case *Null:
+		c.emit(node, parser.OpNull)
+	default:
+		c.emit(node, parser.OpConstant, c.addConstant(o))
+	}
+}
+
+// compileUnreachable compiles code that can never run, such as the branch of
+// an if statement with a constant condition. Compile errors are reported as
+// usual, but the emitted instructions are discarded.
+func (c *Compiler) compileUnreachable(node parser.Node) error {
+	start := len(c.currentInstructions())
+	loop := c.currentLoop()
+	var numBreaks, numContinues int
+	if loop != nil {
+		numBreaks, numContinues = len(loop.Breaks), len(loop.Continues)
+	}
+
+	if err := c.Compile(node); err != nil {
+		return err
+	}
+
+	scope := &c.scopes[c.scopeIndex]
+	scope.Instructions = scope.Instructions[:start]
+	for pos := range scope.SourceMap {
+		if pos >= start {
+			delete(scope.SourceMap, pos)
+		}
+	}
+	for pos := range scope.farJumps {
+		if pos >= start {
+			delete(scope.farJumps, pos)
+		}
+	}
+	if loop != nil {
+		loop.Breaks = loop.Breaks[:numBreaks]
+		loop.Continues = loop.Continues[:numContinues]
+	}
+	return nil
+}
+
+// findModuleAliases returns the top-level variables of file that hold a
+// builtin module and are never assigned or redeclared anywhere in the file.
+// Selectors on these variables may be folded into constants.
+func (c *Compiler) findModuleAliases(file *parser.File) map[string]string {
+	imports := make(map[string]string)
+	for _, stmt := range file.Stmts {
+		var ident *parser.Ident
+		var expr parser.Expr
+		switch stmt := stmt.(type) {
+		case *parser.ImportStmt:
+			ident, expr = stmt.Ident, stmt.Expr
+		case *parser.AssignStmt:
+			if stmt.Token != token.Define || len(stmt.LHS) != 1 ||
+				len(stmt.RHS) != 1 {
+				continue
+			}
+			ident, _ = stmt.LHS[0].(*parser.Ident)
+			expr = stmt.RHS[0]
+		}
+		imp, ok := expr.(*parser.ImportExpr)
+		if ident == nil || !ok {
+			continue
+		}
+		if _, ok := c.modules.Get(imp.ModuleName).(*BuiltinModule); ok {
+			imports[ident.Name] = imp.ModuleName
+		}
+	}
+	if len(imports) == 0 {
+		return nil
+	}
+
+	// count every declaration of and assignment to each name
+	counts := make(map[string]int)
+	define := func(expr parser.Expr) {
+		name, _ := resolveAssignLHS(expr)
+		counts[name]++
+	}
+	walkAST(file, func(node parser.Node) {
+		switch node := node.(type) {
+		case *parser.AssignStmt:
+			for _, lhs := range node.LHS {
+				define(lhs)
+			}
+		case *parser.IncDecStmt:
+			define(node.Expr)
+		case *parser.ImportStmt:
+			define(node.Ident)
+		case *parser.FromImportStmt:
+			for _, name := range node.Names {
+				define(name.Var())
+			}
+		case *parser.FuncStmt:
+			define(node.Ident)
+		case *parser.FuncType:
+			for _, p := range node.Params.List {
+				define(p)
+			}
+		case *parser.ForInStmt:
+			if node.Key != nil {
+				define(node.Key)
+			}
+			if node.Value != nil {
+				define(node.Value)
+			}
+		}
+	})
+	for name := range imports {
+		if counts[name] != 1 {
+			delete(imports, name)
+		}
+	}
+	return imports
+}
+
+// walkAST calls fn for node and all of its descendants.
+func walkAST(node parser.Node, fn func(parser.Node)) {
+	if node == nil {
+		return
+	}
+	fn(node)
+	walkList := func(nodes ...parser.Node) {
+		for _, n := range nodes {
+			if n != nil {
+				walkAST(n, fn)
+			}
+		}
+	}
+	switch node := node.(type) {
+	case *parser.File:
+		for _, stmt := range node.Stmts {
+			walkAST(stmt, fn)
+		}
+	case *parser.BlockStmt:
+		for _, stmt := range node.Stmts {
+			walkAST(stmt, fn)
+		}
+	case *parser.AssignStmt:
+		for _, e := range node.LHS {
+			walkAST(e, fn)
+		}
+		for _, e := range node.RHS {
+			walkAST(e, fn)
+		}
+	case *parser.ExprStmt:
+		walkAST(node.Expr, fn)
+	case *parser.IncDecStmt:
+		walkAST(node.Expr, fn)
+	case *parser.ReturnStmt:
+		if node.Result != nil {
+			walkAST(node.Result, fn)
+		}
+	case *parser.ExportStmt:
+		walkAST(node.Result, fn)
+	case *parser.ExportDeclStmt:
+		walkAST(node.Decl, fn)
+	case *parser.FuncStmt:
+		walkAST(node.Expr, fn)
+	case *parser.IfStmt:
+		if node.Init != nil {
+			walkAST(node.Init, fn)
+		}
+		walkAST(node.Cond, fn)
+		walkAST(node.Body, fn)
+		if node.Else != nil {
+			walkAST(node.Else, fn)
+		}
+	case *parser.ForStmt:
+		if node.Init != nil {
+			walkAST(node.Init, fn)
+		}
+		if node.Cond != nil {
+			walkAST(node.Cond, fn)
+		}
+		if node.Post != nil {
+			walkAST(node.Post, fn)
+		}
+		walkAST(node.Body, fn)
+	case *parser.ForInStmt:
+		walkAST(node.Iterable, fn)
+		walkAST(node.Body, fn)
+	case *parser.FuncLit:
+		walkAST(node.Type, fn)
+		walkAST(node.Body, fn)
+	case *parser.ArrayLit:
+		for _, e := range node.Elements {
+			walkAST(e, fn)
+		}
+	case *parser.MapLit:
+		for _, e := range node.Elements {
+			walkAST(e.Value, fn)
+		}
+	case *parser.BinaryExpr:
+		walkList(node.LHS, node.RHS)
+	case *parser.UnaryExpr:
+		walkAST(node.Expr, fn)
+	case *parser.ParenExpr:
+		walkAST(node.Expr, fn)
+	case *parser.CondExpr:
+		walkList(node.Cond, node.True, node.False)
+	case *parser.CallExpr:
+		walkAST(node.Func, fn)
+		for _, e := range node.Args {
+			walkAST(e, fn)
+		}
+	case *parser.IndexExpr:
+		walkList(node.Expr, node.Index)
+	case *parser.SliceExpr:
+		walkAST(node.Expr, fn)
+		if node.Low != nil {
+			walkAST(node.Low, fn)
+		}
+		if node.High != nil {
+			walkAST(node.High, fn)
+		}
+	case *parser.SelectorExpr:
+		walkList(node.Expr, node.Sel)
+	case *parser.ErrorExpr:
+		walkAST(node.Expr, fn)
+	case *parser.ImmutableExpr:
+		walkAST(node.Expr, fn)
+	}
+}
+
+// optimizeInstructions removes stores to local variables that are never
+// read, and pure instructions whose result is popped right away.
+func (c *Compiler) optimizeInstructions() {
+	type instruction struct {
+		pos      int
+		opcode   parser.Opcode
+		operands []int
+	}
+	var insts []*instruction
+	dsts := make(map[int]bool)
+	usedLocals := make(map[int]bool)
+	iterateInstructions(c.scopes[c.scopeIndex].Instructions,
+		func(pos int, opcode parser.Opcode, operands []int) bool {
+			insts = append(insts, &instruction{pos, opcode, operands})
+			if n := jumpOperand(opcode); n >= 0 {
+				dsts[operands[n]] = true
+			}
+			switch opcode {
+			case parser.OpGetLocal, parser.OpGetLocalPtr,
+				parser.OpSetSelLocal, parser.OpCheckType:
+				usedLocals[operands[0]] = true
+			}
+			return true
+		})
+
+	// pass 1. stores to unused locals only need to pop the value
+	for _, inst := range insts {
+		switch inst.opcode {
+		case parser.OpDefineLocal, parser.OpSetLocal:
+			if !usedLocals[inst.operands[0]] {
+				inst.opcode, inst.operands = parser.OpPop, nil
+			}
+		}
+	}
+
+	// pass 2. remove pure instructions followed by a pop
+	removed := make(map[int]bool)
+	for i := 0; i+1 < len(insts); i++ {
+		next := insts[i+1]
+		if next.opcode != parser.OpPop || dsts[next.pos] {
+			continue
+		}
+		switch insts[i].opcode {
+		case parser.OpConstant, parser.OpTrue, parser.OpFalse,
+			parser.OpNull, parser.OpGetGlobal, parser.OpGetLocal,
+			parser.OpGetFree, parser.OpGetBuiltin:
+			removed[i], removed[i+1] = true, true
+			i++
+		}
+	}
+
+	// pass 3. fuse common instruction sequences; the fused instruction keeps
+	// the source position of the last instruction, where errors happen
+	var live []int
+	for i := range insts {
+		if !removed[i] {
+			live = append(live, i)
+		}
+	}
+	srcPos := make(map[int]int) // instruction to instruction of source position
+	for k := 0; k < len(live); k++ {
+		i := live[k]
+		if k+2 < len(live) &&
+			insts[i].opcode == parser.OpGetLocal &&
+			insts[live[k+1]].opcode == parser.OpConstant &&
+			insts[live[k+2]].opcode == parser.OpBinaryOp &&
+			!dsts[insts[live[k+1]].pos] && !dsts[insts[live[k+2]].pos] {
+			// GETL a; CONST c; BINARYOP op => LOCALOP a c op
+			insts[i].opcode = parser.OpLocalBinaryOp
+			insts[i].operands = []int{insts[i].operands[0],
+				insts[live[k+1]].operands[0], insts[live[k+2]].operands[0]}
+			removed[live[k+1]], removed[live[k+2]] = true, true
+			srcPos[i] = live[k+2]
+			k += 2
+			continue
+		}
+		if k+1 < len(live) &&
+			insts[live[k+1]].opcode == parser.OpJumpFalsy &&
+			!dsts[insts[live[k+1]].pos] {
+			tok := token.Illegal
+			switch insts[i].opcode {
+			case parser.OpEqual:
+				tok = token.Equal
+			case parser.OpNotEqual:
+				tok = token.NotEqual
+			case parser.OpBinaryOp:
+				switch t := token.Token(insts[i].operands[0]); t {
+				case token.Less, token.Greater, token.LessEq,
+					token.GreaterEq:
+					tok = t
+				}
+			}
+			if tok != token.Illegal {
+				// compare; JMPF pos => CMPJMP op pos
+				insts[i].opcode = parser.OpCompareJump
+				insts[i].operands = []int{int(tok),
+					insts[live[k+1]].operands[0]}
+				removed[live[k+1]] = true
+				k++
+			}
+		}
+	}
+
+	// pass 4. rebuild instructions; jumps to removed instructions go to the
+	// next remaining instruction
+	var newInsts []byte
+	posMap := make(map[int]int)
+	for i, inst := range insts {
+		posMap[inst.pos] = len(newInsts)
+		if !removed[i] {
+			newInsts = append(newInsts,
+				MakeInstruction(inst.opcode, inst.operands...)...)
+		}
+	}
+	posMap[len(c.scopes[c.scopeIndex].Instructions)] = len(newInsts)
+	iterateInstructions(newInsts,
+		func(pos int, opcode parser.Opcode, operands []int) bool {
+			if n := jumpOperand(opcode); n >= 0 {
+				operands[n] = posMap[operands[n]]
+				copy(newInsts[pos:], MakeInstruction(opcode, operands...))
+			}
+			return true
+		})
+
+	sourceMap := c.scopes[c.scopeIndex].SourceMap
+	newSourceMap := make(map[int]parser.Pos)
+	for i, inst := range insts {
+		if removed[i] {
+			continue
+		}
+		pos, ok := sourceMap[inst.pos]
+		if j, fused := srcPos[i]; fused {
+			if p, ok2 := sourceMap[insts[j].pos]; ok2 {
+				pos, ok = p, true
+			}
+		}
+		if ok {
+			newSourceMap[posMap[inst.pos]] = pos
+		}
+	}
+	c.scopes[c.scopeIndex].Instructions = newInsts
+	c.scopes[c.scopeIndex].SourceMap = newSourceMap
+}
@@ -0,0 +1,118 @@
+package tender
+
+import (
+	"fmt"
+	"testing"
+)
+
+func runWithOptimizer(
+	t *testing.T,
+	src string,
+	optimize bool,
+) (*Compiled, string, error) {
+	t.Helper()
+	modules := NewModuleMap()
+	modules.AddBuiltinModule("math", map[string]Object{
+		"pi": &Float{Value: 3.141592653589793},
+	})
+	s := NewScript([]byte(src))
+	s.SetImports(modules)
+	s.EnableOptimizer(optimize)
+	c, err := s.Compile()
+	if err != nil {
+		return nil, "", err
+	}
+	err = c.Run()
+	return c, fmt.Sprintf("%v", c.Get("out").Value()), err
+}
+
+func TestOptimizerIdenticalBehavior(t *testing.T) {
+	tests := []string{
+		`out := 1 + 2 * 3 - 4 / 2`,
+		`out := "a" + "b" + string(1 + 2)`,
+		`out := [1 < 2 && "x" != "y", 3 <= 2 || false, !true, -(2.5 * 2)]`,
+		`out := 1 == 1 ? "yes" : "no"`,
+		`out := 1 > 2 ? undefined_var : 5`,
+		`math := import("math"); out := 2 * math.pi / 180`,
+		`math := import("math"); math = {pi: 3}; out := math.pi * 2`,
+		`math := import("math"); f := fn(math) { return math.pi }; out := f({pi: 1})`,
+		`out := 0; if 2 > 1 { out = 1 } else { out = 2 }`,
+		`out := 0; if 1 > 2 { out = 1 } else if true { out = 3 }`,
+		`out := 0; for true { out++; if out == 5 { break } }`,
+		`out := 0; for 1 > 2 { out++ }`,
+		`f := fn() { a := 5; b := 1; 1 + 2; "s"; return b }; out := f()`,
+		`f := fn(x) { y := x * 2; return fn() { return y } }; out := f(4)()`,
+		`out := [1, 2, 3][1:] + [4 & 6, 5 | 2, 1 << 3, 9 >> 1, 5 ^ 3, 7 &^ 2]`,
+		`out := 7 / 2 + 7.0 / 2 + 7 % 3`,
+		`f := fn(n) { s := 0; for i := 0; i < n; i++ { if i % 3 == 0 { s += i * 2 } }; return s }; out := f(100)`,
+		`f := fn(n) { s := 0.5; for i := n; i >= 0; i-- { if i != 2 { s += i / 2.0 } }; return s }; out := f(10)`,
+		`f := fn(a) { return a + 1 }; out := [f(1), f(1.5), f('a'), f(1 << 40)]`,
+		`f := fn(a) { return a + 1 }; out := f("x")`,
+		`f := fn(a, b) { if a < b { return a }; return b }; out := f("a", 1)`,
+	}
+	for _, src := range tests {
+		_, want, wantErr := runWithOptimizer(t, src, false)
+		_, got, gotErr := runWithOptimizer(t, src, true)
+		if fmt.Sprint(wantErr) != fmt.Sprint(gotErr) {
+			t.Errorf("%s: error %v, expected %v", src, gotErr, wantErr)
+			continue
+		}
+		if got != want {
+			t.Errorf("%s: out = %s, expected %s", src, got, want)
+		}
+	}
+}
+
+func TestOptimizerKeepsErrors(t *testing.T) {
+	tests := []string{
+		`out := "a" - 1`,
+		`out := 1 + true`,
+		`out := 0; if false { out = undefined_var }`,
+	}
+	for _, src := range tests {
+		_, _, wantErr := runWithOptimizer(t, src, false)
+		_, _, gotErr := runWithOptimizer(t, src, true)
+		if wantErr == nil {
+			t.Errorf("%s: expected an error", src)
+			continue
+		}
+		if fmt.Sprint(wantErr) != fmt.Sprint(gotErr) {
+			t.Errorf("%s: error %v, expected %v", src, gotErr, wantErr)
+		}
+	}
+}
+
+func TestOptimizerReducesInstructions(t *testing.T) {
+	tests := []string{
+		`out := 1 + 2 * 3`,
+		`out := "a" + "b" + "c"`,
+		`math := import("math"); out := 2 * math.pi / 180`,
+		`out := 0; if 1 > 2 { out = 1 } else { out = 2 }`,
+		`f := fn() { a := 1; 1 + 2; return 3 }; out := f()`,
+	}
+	for _, src := range tests {
+		plain, _, err := runWithOptimizer(t, src, false)
+		if err != nil {
+			t.Fatalf("%s: %v", src, err)
+		}
+		optimized, _, err := runWithOptimizer(t, src, true)
+		if err != nil {
+			t.Fatalf("%s: %v", src, err)
+		}
+		n := instructionCount(plain.bytecode)
+		m := instructionCount(optimized.bytecode)
+		if m >= n {
+			t.Errorf("%s: %d instructions, expected fewer than %d", src, m, n)
+		}
+	}
+}
+
+func instructionCount(b *Bytecode) int {
+	n := len(b.MainFunction.Instructions)
+	for _, cn := range b.Constants {
+		if fn, ok := cn.(*CompiledFunction); ok {
+			n += len(fn.Instructions)
+		}
+	}
+	return n
+}
@@ -59,11 +59,120 @@ func (n *IdentList) NumFields() int {
 func (n *IdentList) String() string {
 	var list []string
 	for i, e := range n.List {
+		param := e.String()
+		if e.Type != nil {
+			param += ": " + e.Type.String()
+		}
 		if n.VarArgs && i == len(n.List)-1 {
-			list = append(list, "..."+e.String())
+			list = append(list, "..."+param)
 		} else {
-			list = append(list, e.String())
+			list = append(list, param)
 		}
 	}
 	return "(" + strings.Join(list, ", ") + ")"
 }
+
+// Comment represents a single //-style or /*-style comment.
+type Comment struct {
+	Slash Pos    // position of "/" starting the comment
+	Text  string // comment text, including "//" or "/*" and "*/"
+}
+
+// Pos returns the position of first character belonging to the node.
+func (c *Comment) Pos() Pos {
+	return c.Slash
+}
+
+// End returns the position of first character immediately after the node.
+func (c *Comment) End() Pos {
+	return Pos(int(c.Slash) + len(c.Text))
+}
+
+func (c *Comment) String() string {
+	return c.Text
+}
+
+// CommentGroup represents a sequence of comments with no other tokens and no
+// empty lines between them.
+type CommentGroup struct {
+	List []*Comment
+}
+
+// Pos returns the position of first character belonging to the node.
+func (g *CommentGroup) Pos() Pos {
+	return g.List[0].Pos()
+}
+
+// End returns the position of first character immediately after the node.
+func (g *CommentGroup) End() Pos {
+	return g.List[len(g.List)-1].End()
+}
+
+func (g *CommentGroup) String() string {
+	var list []string
+	for _, c := range g.List {
+		list = append(list, c.Text)
+	}
+	return strings.Join(list, "\n")
+}
+
+// Text returns the text of the comments without the comment markers, the
+// first space of //-style comment lines, and leading and trailing empty lines.
+// It returns "" if g is nil.
+func (g *CommentGroup) Text() string {
+	if g == nil {
+		return ""
+	}
+	var lines []string
+	for _, c := range g.List {
+		text := c.Text
+		if strings.HasPrefix(text, "//") {
+			text = strings.TrimPrefix(text[2:], " ")
+			lines = append(lines, strings.TrimRight(text, " \t"))
+			continue
+		}
+		text = strings.TrimSuffix(strings.TrimPrefix(text, "/*"), "*/")
+		block := strings.Split(text, "\n")
+		for i, line := range block {
+			line = strings.TrimRight(line, " \t")
+			// strip the " * " prefix of aligned block comments
+			if trimmed := strings.TrimLeft(line, " \t"); strings.HasPrefix(trimmed, "*") {
+				line = strings.TrimPrefix(trimmed[1:], " ")
+			}
+			block[i] = line
+		}
+		// the text following "/*" is not indented like the other lines
+		block[0] = strings.TrimLeft(block[0], " \t")
+		lines = append(lines, unindent(block[:1], block[1:])...)
+	}
+	for len(lines) > 0 && strings.TrimSpace(lines[0]) == "" {
+		lines = lines[1:]
+	}
+	for len(lines) > 0 && strings.TrimSpace(lines[len(lines)-1]) == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return strings.Join(lines, "\n")
+}
+
+// unindent appends lines to dst without the indentation common to the
+// non-blank lines.
+func unindent(dst, lines []string) []string {
+	var prefix string
+	first := true
+	for _, line := range lines {
+		if line == "" {
+			continue
+		}
+		indent := line[:len(line)-len(strings.TrimLeft(line, " \t"))]
+		if first {
+			prefix, first = indent, false
+		}
+		for !strings.HasPrefix(indent, prefix) {
+			prefix = prefix[:len(prefix)-1]
+		}
+	}
+	for _, line := range lines {
+		dst = append(dst, strings.TrimPrefix(line, prefix))
+	}
+	return dst
+}
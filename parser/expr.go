@@ -303,13 +303,14 @@ func (e *FuncLit) End() Pos {
 }
 
 func (e *FuncLit) String() string {
-	return "fn" + e.Type.Params.String() + " " + e.Body.String()
+	return e.Type.String() + " " + e.Body.String()
 }
 
 // FuncType represents a function type definition.
 type FuncType struct {
 	FuncPos Pos
 	Params  *IdentList
+	Result  *TypeExpr // optional result type annotation; or nil
 }
 
 func (e *FuncType) exprNode() {}
@@ -321,10 +322,16 @@ func (e *FuncType) Pos() Pos {
 
 // End returns the position of first character immediately after the node.
 func (e *FuncType) End() Pos {
+	if e.Result != nil {
+		return e.Result.End()
+	}
 	return e.Params.End()
 }
 
 func (e *FuncType) String() string {
+	if e.Result != nil {
+		return "fn" + e.Params.String() + " -> " + e.Result.String()
+	}
 	return "fn" + e.Params.String()
 }
 
@@ -332,6 +339,7 @@ func (e *FuncType) String() string {
 type Ident struct {
 	Name    string
 	NamePos Pos
+	Type    *TypeExpr // optional type annotation; or nil
 }
 
 func (e *Ident) exprNode() {}
@@ -353,6 +361,29 @@ func (e *Ident) String() string {
 	return nullRep
 }
 
+// TypeExpr represents a type annotation such as "int" or "int|float". Type
+// annotations are ignored by the VM unless runtime type checks are enabled.
+type TypeExpr struct {
+	Names   []string
+	TypePos Pos
+}
+
+func (e *TypeExpr) exprNode() {}
+
+// Pos returns the position of first character belonging to the node.
+func (e *TypeExpr) Pos() Pos {
+	return e.TypePos
+}
+
+// End returns the position of first character immediately after the node.
+func (e *TypeExpr) End() Pos {
+	return Pos(int(e.TypePos) + len(e.String()))
+}
+
+func (e *TypeExpr) String() string {
+	return strings.Join(e.Names, "|")
+}
+
 // ImmutableExpr represents an immutable expression
 type ImmutableExpr struct {
 	Expr     Expr
@@ -379,10 +410,21 @@ func (e *ImmutableExpr) String() string {
 
 
 
+// embed modes
+const (
+	EmbedBytes = "bytes" // file content as bytes
+	EmbedDir   = "dir"   // map of the files in a directory
+	EmbedFS    = "fs"    // read-only file system
+)
+
+// EmbedExpr represents an embed expression. FileSrc is a file, a directory
+// or a glob pattern, depending on Mode.
 type EmbedExpr struct {
-	FileSrc    string
-	Token      token.Token
-	TokenPos   Pos
+	FileSrc  string
+	Mode     string
+	Token    token.Token
+	TokenPos Pos
+	EndPos   Pos
 }
 
 func (e *EmbedExpr) exprNode() {}
@@ -394,12 +436,14 @@ func (e *EmbedExpr) Pos() Pos {
 
 // End returns the position of first character immediately after the node.
 func (e *EmbedExpr) End() Pos {
-	// import("moduleName")
-	return Pos(int(e.TokenPos) + 10 + len(e.FileSrc))
+	return e.EndPos
 }
 
 func (e *EmbedExpr) String() string {
-	return `embed("` + e.FileSrc + `")"`
+	if e.Mode != "" {
+		return "embed " + e.Mode + `("` + e.FileSrc + `")`
+	}
+	return `embed("` + e.FileSrc + `")`
 }
 
 // ImportExpr represents an import expression
@@ -506,6 +550,7 @@ type MapElementLit struct {
 	KeyPos   Pos
 	ColonPos Pos
 	Value    Expr
+	Doc      *CommentGroup // doc comment; or nil
 }
 
 func (e *MapElementLit) exprNode() {}
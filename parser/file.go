@@ -8,6 +8,7 @@ import (
 type File struct {
 	InputFile *SourceFile
 	Stmts     []Stmt
+	Comments  []*CommentGroup // comments of the file if parsed with ParseComments
 }
 
 // Pos returns the position of first character belonging to the node.
@@ -47,6 +47,24 @@ const (
 	OpIteratorValue               // Iterator value
 	OpBinaryOp                    // Binary operation
 	OpSuspend                     // Suspend VM
+	OpCheckType                   // Check type of local variable
+	OpLocalBinaryOp               // Binary operation of local and constant
+	OpCompareJump                 // Compare and jump if false
+	OpSelector                    // Selector with inline cache
+	OpTailCall                    // Call function in tail position
+	OpConstantW                   // Load constant (wide)
+	OpGetGlobalW                  // Get global variable (wide)
+	OpSetGlobalW                  // Set global variable (wide)
+	OpSetSelGlobalW               // Set global variable using selectors (wide)
+	OpArrayW                      // Array object (wide)
+	OpMapW                        // Map object (wide)
+	OpClosureW                    // Push closure (wide)
+	OpJumpW                       // Jump (wide)
+	OpJumpFalsyW                  // Jump if falsy (wide)
+	OpAndJumpW                    // Logical AND jump (wide)
+	OpOrJumpW                     // Logical OR jump (wide)
+	OpArrayAppend                 // Append elements to array
+	OpMapInsert                   // Insert elements into map
 )
 
 // OpcodeNames are string representation of opcodes.
@@ -93,6 +111,24 @@ var OpcodeNames = [...]string{
 	OpIteratorValue: "ITVAL",
 	OpBinaryOp:      "BINARYOP",
 	OpSuspend:       "SUSPEND",
+	OpCheckType:     "TYPECHK",
+	OpLocalBinaryOp: "LOCALOP",
+	OpCompareJump:   "CMPJMP",
+	OpSelector:      "SELECTOR",
+	OpTailCall:      "TAILCALL",
+	OpConstantW:     "CONSTW",
+	OpGetGlobalW:    "GETGW",
+	OpSetGlobalW:    "SETGW",
+	OpSetSelGlobalW: "SETSGW",
+	OpArrayW:        "ARRW",
+	OpMapW:          "MAPW",
+	OpClosureW:      "CLOSUREW",
+	OpJumpW:         "JMPW",
+	OpJumpFalsyW:    "JMPFW",
+	OpAndJumpW:      "ANDJMPW",
+	OpOrJumpW:       "ORJMPW",
+	OpArrayAppend:   "ARRAPPEND",
+	OpMapInsert:     "MAPINSERT",
 }
 
 // OpcodeOperands is the number of operands.
@@ -139,6 +175,40 @@ var OpcodeOperands = [...][]int{
 	OpIteratorValue: {},
 	OpBinaryOp:      {1},
 	OpSuspend:       {},
+	OpCheckType:     {1, 4, 4},
+	OpLocalBinaryOp: {1, 2, 1},
+	OpCompareJump:   {1, 2},
+	OpSelector:      {2, 2},
+	OpTailCall:      {1, 1},
+	OpConstantW:     {4},
+	OpGetGlobalW:    {4},
+	OpSetGlobalW:    {4},
+	OpSetSelGlobalW: {4, 1},
+	OpArrayW:        {4},
+	OpMapW:          {4},
+	OpClosureW:      {4, 1},
+	OpJumpW:         {4},
+	OpJumpFalsyW:    {4},
+	OpAndJumpW:      {4},
+	OpOrJumpW:       {4},
+	OpArrayAppend:   {2},
+	OpMapInsert:     {2},
+}
+
+// WideOpcodes maps opcodes with a 2-byte first operand to their variants
+// with a 4-byte operand.
+var WideOpcodes = map[Opcode]Opcode{
+	OpConstant:     OpConstantW,
+	OpGetGlobal:    OpGetGlobalW,
+	OpSetGlobal:    OpSetGlobalW,
+	OpSetSelGlobal: OpSetSelGlobalW,
+	OpArray:        OpArrayW,
+	OpMap:          OpMapW,
+	OpClosure:      OpClosureW,
+	OpJump:         OpJumpW,
+	OpJumpFalsy:    OpJumpFalsyW,
+	OpAndJump:      OpAndJumpW,
+	OpOrJump:       OpOrJumpW,
 }
 
 // ReadOperands reads operands from the bytecode.
@@ -149,6 +219,10 @@ func ReadOperands(numOperands []int, ins []byte) (operands []int, offset int) {
 			operands = append(operands, int(ins[offset]))
 		case 2:
 			operands = append(operands, int(ins[offset+1])|int(ins[offset])<<8)
+		case 4:
+			operands = append(operands, int(ins[offset+3])|
+				int(ins[offset+2])<<8|int(ins[offset+1])<<16|
+				int(ins[offset])<<24)
 		}
 		offset += width
 	}
@@ -94,6 +94,16 @@ func (p ErrorList) Err() error {
 	return p
 }
 
+// Mode represents a parser mode.
+type Mode int
+
+// List of parser modes.
+const (
+	// ParseComments keeps the comments in the AST, and sets the doc comments
+	// of declarations, exports and map elements.
+	ParseComments Mode = 1 << iota
+)
+
 // Parser parses the tender source files. It's based on Go's parser
 // implementation.
 type Parser struct {
@@ -109,19 +119,34 @@ type Parser struct {
 	trace     bool
 	indent    int
 	traceOut  io.Writer
+
+	mode        Mode
+	comments    []*CommentGroup
+	leadComment *CommentGroup // comment group just before the current token
 }
 
 // NewParser creates a Parser.
 func NewParser(file *SourceFile, src []byte, trace io.Writer) *Parser {
+	return NewParserWithMode(file, src, trace, 0)
+}
+
+// NewParserWithMode creates a Parser with the parser mode.
+func NewParserWithMode(file *SourceFile, src []byte, trace io.Writer, mode Mode) *Parser {
+	var scanMode ScanMode
+	if mode&ParseComments != 0 {
+		scanMode = ScanComments
+	}
 	p := &Parser{
 		file:     file,
 		trace:    trace != nil,
 		traceOut: trace,
+		mode:     mode,
 	}
+	file.Source = src
 	p.scanner = NewScanner(p.file, src,
 		func(pos SourceFilePos, msg string) {
 			p.errors.Add(pos, msg)
-		}, 0)
+		}, scanMode)
 	p.next()
 	return p
 }
@@ -156,6 +181,7 @@ func (p *Parser) ParseFile() (file *File, err error) {
 	file = &File{
 		InputFile: p.file,
 		Stmts:     stmts,
+		Comments:  p.comments,
 	}
 	return
 }
@@ -574,12 +600,67 @@ func (p *Parser) parseImportStmt() Stmt {
 	}
 }
 
+func (p *Parser) parseFromImportStmt() Stmt {
+	if p.trace {
+		defer untracep(tracep(p, "FromImportStmt"))
+	}
+
+	pos := p.pos
+	p.next()
+
+	modulePos := p.pos
+	moduleName, _ := strconv.Unquote(p.tokenLit)
+	p.expect(token.String)
+	p.expect(token.Import)
+
+	var names []*ImportName
+	for {
+		name := &ImportName{Name: p.parseIdent()}
+		if p.token == token.As {
+			p.next()
+			name.Alias = p.parseIdent()
+		}
+		names = append(names, name)
+		if p.token != token.Comma {
+			break
+		}
+		p.next()
+	}
+	p.expectSemi()
+
+	return &FromImportStmt{
+		FromPos: pos,
+		Expr: &ImportExpr{
+			ModuleName: moduleName,
+			Token:      token.Import,
+			TokenPos:   modulePos,
+		},
+		Names: names,
+	}
+}
+
 func (p *Parser) parseEmbedExpr() Expr {
 	pos := p.pos
 	p.next()
-	p.expect(token.LParen)
+
+	// optional mode: embed bytes("file"), embed dir "assets"
+	mode := ""
+	if p.token == token.Ident {
+		mode = p.tokenLit
+		switch mode {
+		case EmbedBytes, EmbedDir, EmbedFS:
+		default:
+			p.error(p.pos, "unknown embed mode '"+mode+"'")
+		}
+		p.next()
+	}
+
+	paren := p.token == token.LParen
+	if paren {
+		p.next()
+	}
 	if p.token != token.String {
-		p.errorExpected(p.pos, "module name")
+		p.errorExpected(p.pos, "file path")
 		p.advance(stmtStart)
 		return &BadExpr{From: pos, To: p.pos}
 	}
@@ -587,13 +668,17 @@ func (p *Parser) parseEmbedExpr() Expr {
 	// file src
 	fileSrc, _ := strconv.Unquote(p.tokenLit)
 	expr := &EmbedExpr{
-		FileSrc:    fileSrc,
-		Token:      token.Embed,
-		TokenPos:   pos,
+		FileSrc:  fileSrc,
+		Mode:     mode,
+		Token:    token.Embed,
+		TokenPos: pos,
+		EndPos:   p.pos + Pos(len(p.tokenLit)),
 	}
 
 	p.next()
-	p.expect(token.RParen)
+	if paren {
+		expr.EndPos = p.expect(token.RParen) + 1
+	}
 	return expr
 }
 
@@ -644,6 +729,7 @@ func (p *Parser) parseDeclStmt() Stmt {
 		p.errorExpected(p.pos, "identifier")
 	}
 	x := p.parseExprList()
+	p.parseVarType(x)
 	if p.token == token.Semicolon {
 		return &AssignStmt{
 			LHS:      x,
@@ -712,6 +798,7 @@ func (p *Parser) parseFuncStmt() Stmt {
 	typ :=  &FuncType{
 		FuncPos: pos,
 		Params:  params,
+		Result:  p.parseResultType(),
 	}
 	
 	p.exprLevel++
@@ -797,9 +884,44 @@ func (p *Parser) parseFuncType() *FuncType {
 	return &FuncType{
 		FuncPos: pos,
 		Params:  params,
+		Result:  p.parseResultType(),
 	}
 }
 
+func (p *Parser) parseResultType() *TypeExpr {
+	if p.token != token.Arrow {
+		return nil
+	}
+	p.next()
+	return p.parseTypeExpr()
+}
+
+func (p *Parser) parseTypeExpr() *TypeExpr {
+	if p.trace {
+		defer untracep(tracep(p, "TypeExpr"))
+	}
+
+	pos := p.pos
+	var names []string
+	for {
+		switch p.token {
+		case token.Ident, token.Error, token.Null:
+			names = append(names, p.tokenLit)
+		case token.Func:
+			names = append(names, "fn")
+		default:
+			p.errorExpected(p.pos, "type name")
+			return &TypeExpr{Names: names, TypePos: pos}
+		}
+		p.next()
+		if p.token != token.Or {
+			break
+		}
+		p.next()
+	}
+	return &TypeExpr{Names: names, TypePos: pos}
+}
+
 func (p *Parser) parseBody() *BlockStmt {
 	if p.trace {
 		defer untracep(tracep(p, "Body"))
@@ -842,6 +964,16 @@ func (p *Parser) parseIdent() *Ident {
 	}
 }
 
+// parseParam parses a function parameter with an optional type annotation.
+func (p *Parser) parseParam() *Ident {
+	ident := p.parseIdent()
+	if p.token == token.Colon {
+		p.next()
+		ident.Type = p.parseTypeExpr()
+	}
+	return ident
+}
+
 func (p *Parser) parseIdentList() *IdentList {
 	if p.trace {
 		defer untracep(tracep(p, "IdentList"))
@@ -856,14 +988,14 @@ func (p *Parser) parseIdentList() *IdentList {
 			p.next()
 		}
 
-		params = append(params, p.parseIdent())
+		params = append(params, p.parseParam())
 		for !isVarArgs && p.token == token.Comma {
 			p.next()
 			if p.token == token.Ellipsis {
 				isVarArgs = true
 				p.next()
 			}
-			params = append(params, p.parseIdent())
+			params = append(params, p.parseParam())
 		}
 	}
 
@@ -880,6 +1012,15 @@ func (p *Parser) parseStmt() (stmt Stmt) {
 	if p.trace {
 		defer untracep(tracep(p, "Statement"))
 	}
+	if doc := p.leadComment; doc != nil {
+		defer func() { setDoc(stmt, doc) }()
+	}
+
+	// "from" is only a keyword at the start of a from-import statement
+	if p.token == token.Ident && p.tokenLit == "from" &&
+		p.scanner.Peek() == token.String {
+		return p.parseFromImportStmt()
+	}
 
 	switch p.token {
 		case token.Var:
@@ -939,6 +1080,20 @@ func (p *Parser) parseStmt() (stmt Stmt) {
 	}
 }
 
+// setDoc sets the doc comment of a statement that can have one.
+func setDoc(stmt Stmt, doc *CommentGroup) {
+	switch stmt := stmt.(type) {
+	case *AssignStmt:
+		stmt.Doc = doc
+	case *FuncStmt:
+		stmt.Doc = doc
+	case *ExportStmt:
+		stmt.Doc = doc
+	case *ExportDeclStmt:
+		stmt.Doc = doc
+	}
+}
+
 func (p *Parser) parseForStmt() Stmt {
 	if p.trace {
 		defer untracep(tracep(p, "ForStmt"))
@@ -1163,6 +1318,40 @@ func (p *Parser) parseExportStmt() Stmt {
 
 	pos := p.pos
 	p.expect(token.Export)
+
+	// export fn name() {}
+	if p.token == token.Func && p.scanner.Peek() == token.Ident {
+		return &ExportDeclStmt{
+			ExportPos: pos,
+			Decl:      p.parseFuncStmt(),
+		}
+	}
+
+	// export name := value
+	if p.token == token.Ident {
+		switch s := p.parseSimpleStmt(false).(type) {
+		case *AssignStmt:
+			p.expectSemi()
+			if s.Token != token.Define {
+				p.error(s.TokenPos, "expected ':=' in export declaration")
+			}
+			return &ExportDeclStmt{
+				ExportPos: pos,
+				Decl:      s,
+			}
+		case *ExprStmt:
+			p.expectSemi()
+			return &ExportStmt{
+				ExportPos: pos,
+				Result:    s.Expr,
+			}
+		default:
+			p.errorExpected(pos, "export value or declaration")
+			p.advance(stmtStart)
+			return &BadStmt{From: pos, To: p.pos}
+		}
+	}
+
 	x := p.parseExpr()
 	p.expectSemi()
 	return &ExportStmt{
@@ -1177,6 +1366,9 @@ func (p *Parser) parseSimpleStmt(forIn bool) Stmt {
 	}
 
 	x := p.parseExprList()
+	if p.parseVarType(x) && p.token != token.Define {
+		p.errorExpected(p.pos, "':='")
+	}
 
 	switch p.token {
 		case token.Assign, token.Define: // assignment statement
@@ -1254,6 +1446,21 @@ func (p *Parser) parseSimpleStmt(forIn bool) Stmt {
 	return &ExprStmt{Expr: x[0]}
 }
 
+// parseVarType parses an optional type annotation following a variable
+// declaration ("x: map := {}") and attaches it to the identifier.
+func (p *Parser) parseVarType(x []Expr) bool {
+	if p.token != token.Colon || len(x) != 1 {
+		return false
+	}
+	ident, ok := x[0].(*Ident)
+	if !ok {
+		return false
+	}
+	p.next()
+	ident.Type = p.parseTypeExpr()
+	return true
+}
+
 func (p *Parser) parseExprList() (list []Expr) {
 	if p.trace {
 		defer untracep(tracep(p, "ExpressionList"))
@@ -1273,6 +1480,7 @@ func (p *Parser) parseMapElementLit() *MapElementLit {
 	}
 
 	pos := p.pos
+	doc := p.leadComment
 	name := "_"
 	if p.token == token.Ident {
 		name = p.tokenLit
@@ -1290,6 +1498,7 @@ func (p *Parser) parseMapElementLit() *MapElementLit {
 		KeyPos:   pos,
 		ColonPos: colonPos,
 		Value:    valueExpr,
+		Doc:      doc,
 	}
 }
 
@@ -1395,6 +1604,46 @@ func (p *Parser) errorExpected(pos Pos, msg string) {
 
 
 func (p *Parser) next() {
+	p.leadComment = nil
+	prev := p.pos
+	p.next0()
+	if p.token != token.Comment {
+		return
+	}
+
+	var comment *CommentGroup
+	endline := -1
+	if prev.IsValid() && p.line(p.pos) == p.line(prev) {
+		// a comment on the line of the previous token is not a doc comment
+		p.consumeCommentGroup(0)
+	}
+	for p.token == token.Comment {
+		comment, endline = p.consumeCommentGroup(1)
+	}
+	if endline+1 == p.line(p.pos) && p.token != token.EOF {
+		p.leadComment = comment
+	}
+}
+
+func (p *Parser) line(pos Pos) int {
+	return p.file.Position(pos).Line
+}
+
+// consumeCommentGroup reads a group of comments separated by at most n
+// lines, and returns it with the line of its end.
+func (p *Parser) consumeCommentGroup(n int) (*CommentGroup, int) {
+	group := &CommentGroup{}
+	endline := p.line(p.pos)
+	for p.token == token.Comment && p.line(p.pos) <= endline+n {
+		endline = p.line(p.pos) + strings.Count(p.tokenLit, "\n")
+		group.List = append(group.List, &Comment{Slash: p.pos, Text: p.tokenLit})
+		p.next0()
+	}
+	p.comments = append(p.comments, group)
+	return group, endline
+}
+
+func (p *Parser) next0() {
 	if p.trace && p.pos.IsValid() {
 		s := p.token.String()
 		switch {
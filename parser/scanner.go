@@ -159,9 +159,14 @@ func (s *Scanner) Scan() (
 				insertSemi = true
 			}
 		case '-':
-			tok = s.switch3(token.Sub, token.SubAssign, '-', token.Dec)
-			if tok == token.Dec {
-				insertSemi = true
+			if s.ch == '>' {
+				s.next()
+				tok = token.Arrow
+			} else {
+				tok = s.switch3(token.Sub, token.SubAssign, '-', token.Dec)
+				if tok == token.Dec {
+					insertSemi = true
+				}
 			}
 		case '*':
 			tok = s.switch2(token.Mul, token.MulAssign)
@@ -272,7 +277,10 @@ func (s *Scanner) Peek() token.Token {
     savedReadOffset := s.readOffset
     savedInsertSemi := s.insertSemi
 
-    token, _, _ := s.Scan()
+    tok, _, _ := s.Scan()
+    for tok == token.Comment {
+        tok, _, _ = s.Scan()
+    }
 
     // Restore the saved state
     s.offset = savedPos
@@ -280,7 +288,7 @@ func (s *Scanner) Peek() token.Token {
     s.readOffset = savedReadOffset
     s.insertSemi = savedInsertSemi
 
-    return token
+    return tok
 }
 
 func (s *Scanner) error(offset int, msg string) {
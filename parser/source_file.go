@@ -141,6 +141,9 @@ type SourceFile struct {
 	// Lines contains the offset of the first character for each line
 	// (the first entry is always 0)
 	Lines []int
+	// Source is the source code of the file if it was parsed, to show it in
+	// error reports; or nil
+	Source []byte
 }
 
 // Set returns SourceFileSet.
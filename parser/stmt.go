@@ -18,6 +18,7 @@ type AssignStmt struct {
 	RHS      []Expr
 	Token    token.Token
 	TokenPos Pos
+	Doc      *CommentGroup // doc comment; or nil
 }
 
 func (s *AssignStmt) stmtNode() {}
@@ -153,6 +154,7 @@ func (s *EmptyStmt) String() string {
 type ExportStmt struct {
 	ExportPos Pos
 	Result    Expr
+	Doc       *CommentGroup // doc comment; or nil
 }
 
 func (s *ExportStmt) stmtNode() {}
@@ -171,6 +173,48 @@ func (s *ExportStmt) String() string {
 	return "export " + s.Result.String()
 }
 
+// ExportDeclStmt represents an export statement of a named function or a
+// variable declaration. The exported names of a module are collected into
+// its module map.
+type ExportDeclStmt struct {
+	ExportPos Pos
+	Decl      Stmt          // *FuncStmt or *AssignStmt
+	Doc       *CommentGroup // doc comment; or nil
+}
+
+func (s *ExportDeclStmt) stmtNode() {}
+
+// Pos returns the position of first character belonging to the node.
+func (s *ExportDeclStmt) Pos() Pos {
+	return s.ExportPos
+}
+
+// End returns the position of first character immediately after the node.
+func (s *ExportDeclStmt) End() Pos {
+	return s.Decl.End()
+}
+
+func (s *ExportDeclStmt) String() string {
+	return "export " + s.Decl.String()
+}
+
+// Names returns the identifiers declared by the statement.
+func (s *ExportDeclStmt) Names() []*Ident {
+	switch decl := s.Decl.(type) {
+	case *FuncStmt:
+		return []*Ident{decl.Ident}
+	case *AssignStmt:
+		var names []*Ident
+		for _, lhs := range decl.LHS {
+			if ident, ok := lhs.(*Ident); ok {
+				names = append(names, ident)
+			}
+		}
+		return names
+	}
+	return nil
+}
+
 // ExprStmt represents an expression statement.
 type ExprStmt struct {
 	Expr Expr
@@ -285,10 +329,62 @@ func (s *ImportStmt) String() string {
 	return "import \"" + s.Expr.ModuleName + "\" as " + s.Ident.Name  
 }
 
+// ImportName is a name imported by a from-import statement and the name of
+// the variable it is assigned to.
+type ImportName struct {
+	Name  *Ident
+	Alias *Ident // nil if the name is not renamed
+}
+
+// Var returns the identifier of the variable the name is assigned to.
+func (n *ImportName) Var() *Ident {
+	if n.Alias != nil {
+		return n.Alias
+	}
+	return n.Name
+}
+
+func (n *ImportName) String() string {
+	if n.Alias != nil {
+		return n.Name.Name + " as " + n.Alias.Name
+	}
+	return n.Name.Name
+}
+
+// FromImportStmt represents a from-import statement, e.g.
+// from "fs" import readfile, exists as file_exists.
+type FromImportStmt struct {
+	FromPos Pos
+	Expr    *ImportExpr
+	Names   []*ImportName
+}
+
+func (s *FromImportStmt) stmtNode() {}
+
+// Pos returns the position of first character belonging to the node.
+func (s *FromImportStmt) Pos() Pos {
+	return s.FromPos
+}
+
+// End returns the position of first character immediately after the node.
+func (s *FromImportStmt) End() Pos {
+	return s.Names[len(s.Names)-1].Var().End()
+}
+
+func (s *FromImportStmt) String() string {
+	names := make([]string, len(s.Names))
+	for i, n := range s.Names {
+		names[i] = n.String()
+	}
+	return "from \"" + s.Expr.ModuleName + "\" import " +
+		strings.Join(names, ", ")
+}
+
 // FuncStmt represents an if statement.
 type FuncStmt struct {
 	Ident    *Ident
 	Expr     *FuncLit
+	Doc      *CommentGroup // doc comment; or nil
 }
 
 func (s *FuncStmt) stmtNode() {}
@@ -304,7 +400,11 @@ func (s *FuncStmt) End() Pos {
 }
 
 func (s *FuncStmt) String() string {
-	return "fn " + s.Ident.Name + s.Expr.Type.Params.String() + " " + s.Expr.Body.String()
+	typ := s.Expr.Type.Params.String()
+	if s.Expr.Type.Result != nil {
+		typ += " -> " + s.Expr.Type.Result.String()
+	}
+	return "fn " + s.Ident.Name + typ + " " + s.Expr.Body.String()
 }
 
 
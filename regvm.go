@@ -0,0 +1,949 @@
+package tender
+
+import (
+	"fmt"
+	"sync/atomic"
+
+	"github.com/2dprototype/tender/parser"
+	"github.com/2dprototype/tender/token"
+)
+
+// The register VM is an experimental execution mode. A compiled function is
+// translated from its stack instructions to instructions that address
+// registers: the local variables and the stack slots of the function, which
+// are the same slots of the VM stack that the stack VM uses. Operands that
+// are locals or constants are read in place instead of being pushed, which
+// removes most of the push/pop traffic of simple expressions.
+//
+// Functions that use instructions the register VM does not implement, such
+// as closures capturing local variables, keep running on the stack VM. Calls
+// between the two kinds of functions work in both directions.
+
+type regOpcode = byte
+
+// register instructions. Operand a is the destination register unless noted.
+const (
+	regMove         regOpcode = iota // a = b
+	regLoadNull                      // a = null
+	regLoadTrue                      // a = true
+	regLoadFalse                     // a = false
+	regGetGlobal                     // a = globals[b]
+	regSetGlobal                     // globals[a] = b
+	regGetFree                       // a = free[b]
+	regSetFree                       // free[a] = b
+	regGetBuiltin                    // a = builtins[b]
+	regBinaryOp                      // a = b tok c
+	regEqual                         // a = b == c
+	regNotEqual                      // a = b != c
+	regNot                           // a = !b
+	regMinus                         // a = -b
+	regComplement                    // a = ^b
+	regJump                          // goto a
+	regJumpFalsy                     // if !b goto a
+	regJumpTruthy                    // if b goto a
+	regCompareJump                   // if !(b tok c) goto a
+	regArray                         // a = [b, ..., b+c-1]
+	regMap                           // a = {b: b+1, ..., b+c-2: b+c-1}
+	regArrayAppend                   // a = a + [b, ..., b+c-1]
+	regMapInsert                     // a[b] = b+1, ..., a[b+c-2] = b+c-1
+	regError                         // a = error(b)
+	regImmutable                     // a = immutable(b)
+	regIndex                         // a = b[c]
+	regSelector                      // a = b.consts[c], inline cache d
+	regSetSelLocal                   // a[b+1]...[b+c] = b
+	regSetSelGlobal                  // globals[a][b+1]...[b+c] = b
+	regSetSelFree                    // free[a][b+1]...[b+c] = b
+	regIterInit                      // a = iterator(b)
+	regIterNext                      // a = b.next()
+	regIterKey                       // a = b.key()
+	regIterValue                     // a = b.value()
+	regCall                          // a = a(a+1, ..., a+b), spread if c
+	regTailCall                      // return a(a+1, ..., a+b), spread if c
+	regReturn                        // return a
+	regReturnNull                    // return null
+	regSuspend                       // end of the main function
+)
+
+// regInstruction is an instruction of the register VM. Register operands
+// are indexes of the frame registers; negative operands -1, -2, ... are the
+// constants 0, 1, ...
+type regInstruction struct {
+	op  regOpcode
+	tok token.Token
+	a   int32
+	b   int32
+	c   int32
+	d   int32
+}
+
+// regFunction is the register translation of a CompiledFunction.
+type regFunction struct {
+	code    []regInstruction
+	pos     []parser.Pos // source position of each instruction
+	numRegs int
+}
+
+// regBridge is the function of the frames that the register VM pushes when
+// it calls a function running on the stack VM. It stops the stack VM when
+// the called function returns.
+var regBridge = &CompiledFunction{
+	Instructions: []byte{parser.OpSuspend},
+}
+
+// TranslateRegisters translates the main function and the compiled function
+// constants of the bytecode for the register VM. A VM runs the translated
+// functions on the register VM and the others on the stack VM. It must be
+// called before the bytecode is run, after RemoveDuplicates.
+func (b *Bytecode) TranslateRegisters() {
+	if b.MainFunction != nil {
+		b.MainFunction.reg = translateRegisters(b.MainFunction)
+	}
+	for _, c := range b.Constants {
+		if fn, ok := c.(*CompiledFunction); ok {
+			fn.reg = translateRegisters(fn)
+		}
+	}
+}
+
+// regEntry is a value on the stack during the translation. A value that
+// was pushed from a local variable or a constant stays virtual until it must
+// be stored in its stack slot.
+type regEntry struct {
+	kind  int // regTemp, regLocal or regConst
+	index int
+}
+
+const (
+	regTemp = iota
+	regLocal
+	regConst
+)
+
+type regTranslator struct {
+	fn        *CompiledFunction
+	code      []regInstruction
+	pos       []parser.Pos
+	stack     []regEntry
+	curPos    int  // stack instruction being translated
+	curEnd    int  // position after the stack instruction
+	producer  bool // last instruction writes the top stack slot
+	maxDepth  int
+	jumps     []int // instructions whose operand a is a stack position
+	targets   map[int]int
+	reachable bool
+}
+
+// translateRegisters returns the register translation of fn, or nil if fn
+// uses instructions that the register VM does not implement.
+func translateRegisters(fn *CompiledFunction) *regFunction {
+	depths, ok := regStackDepths(fn.Instructions)
+	if !ok {
+		return nil
+	}
+	t := &regTranslator{
+		fn:        fn,
+		targets:   make(map[int]int),
+		reachable: true,
+	}
+	dsts := make(map[int]bool)
+	iterateInstructions(fn.Instructions,
+		func(pos int, op parser.Opcode, operands []int) bool {
+			if n := jumpOperand(op); n >= 0 {
+				dsts[operands[n]] = true
+			}
+			return true
+		})
+
+	ok = true
+	iterateInstructions(fn.Instructions,
+		func(pos int, op parser.Opcode, operands []int) bool {
+			depth, reached := depths[pos]
+			if !reached {
+				return true // dead code
+			}
+			t.curPos = pos
+			t.curEnd = pos + 1 + sumWidths(parser.OpcodeOperands[op])
+			if dsts[pos] {
+				if t.reachable {
+					t.flush(0, 0)
+				}
+				t.stack = t.stack[:0]
+				for i := 0; i < depth; i++ {
+					t.stack = append(t.stack, regEntry{kind: regTemp})
+				}
+				t.producer = false
+			}
+			t.targets[pos] = len(t.code)
+			t.reachable = true
+			ok = t.translate(op, operands)
+			if len(t.stack) > t.maxDepth {
+				t.maxDepth = len(t.stack)
+			}
+			return ok
+		})
+	if !ok {
+		return nil
+	}
+	for _, i := range t.jumps {
+		t.code[i].a = int32(t.targets[int(t.code[i].a)])
+	}
+	return &regFunction{
+		code:    t.code,
+		pos:     t.pos,
+		numRegs: fn.NumLocals + t.maxDepth + 1,
+	}
+}
+
+// regStackDepths returns the stack depth before each reachable instruction,
+// or false if an instruction is not supported or the depths at a jump target
+// do not agree.
+func regStackDepths(insts []byte) (map[int]int, bool) {
+	depths := map[int]int{0: 0}
+	work := []int{0}
+	for len(work) > 0 {
+		pos := work[len(work)-1]
+		work = work[:len(work)-1]
+		if pos >= len(insts) {
+			return nil, false
+		}
+		op := insts[pos]
+		operands, read := parser.ReadOperands(parser.OpcodeOperands[op],
+			insts[pos+1:])
+		next := pos + 1 + read
+		pop, push, ok := regStackEffect(op, operands)
+		if !ok {
+			return nil, false
+		}
+		depth := depths[pos]
+		if depth < pop {
+			return nil, false
+		}
+		succ := func(p, d int) bool {
+			if old, seen := depths[p]; seen {
+				return old == d
+			}
+			depths[p] = d
+			work = append(work, p)
+			return true
+		}
+		after := depth - pop + push
+		switch op {
+		case parser.OpJump, parser.OpJumpW:
+			ok = succ(operands[0], after)
+		case parser.OpAndJump, parser.OpOrJump, parser.OpAndJumpW,
+			parser.OpOrJumpW:
+			// the value stays on the stack when jumping
+			ok = succ(operands[0], depth) && succ(next, after)
+		case parser.OpReturn, parser.OpTailCall, parser.OpSuspend:
+		default:
+			if n := jumpOperand(op); n >= 0 {
+				ok = succ(operands[n], after)
+			}
+			ok = ok && succ(next, after)
+		}
+		if !ok {
+			return nil, false
+		}
+	}
+	return depths, true
+}
+
+// regStackEffect returns the number of values an instruction pops and
+// pushes, or false if the register VM does not implement it.
+func regStackEffect(op parser.Opcode, operands []int) (int, int, bool) {
+	switch op {
+	case parser.OpConstant, parser.OpConstantW, parser.OpNull,
+		parser.OpTrue, parser.OpFalse, parser.OpLocalBinaryOp,
+		parser.OpGetGlobal, parser.OpGetGlobalW, parser.OpGetLocal,
+		parser.OpGetBuiltin, parser.OpGetFree:
+		return 0, 1, true
+	case parser.OpBinaryOp, parser.OpEqual, parser.OpNotEqual,
+		parser.OpIndex:
+		return 2, 1, true
+	case parser.OpCompareJump:
+		return 2, 0, true
+	case parser.OpLNot, parser.OpBComplement, parser.OpMinus,
+		parser.OpError, parser.OpImmutable, parser.OpSelector,
+		parser.OpIteratorInit, parser.OpIteratorNext,
+		parser.OpIteratorKey, parser.OpIteratorValue:
+		return 1, 1, true
+	case parser.OpPop, parser.OpJumpFalsy, parser.OpJumpFalsyW,
+		parser.OpSetGlobal, parser.OpSetGlobalW, parser.OpDefineLocal,
+		parser.OpSetLocal, parser.OpSetFree, parser.OpAndJump,
+		parser.OpOrJump, parser.OpAndJumpW, parser.OpOrJumpW:
+		return 1, 0, true
+	case parser.OpJump, parser.OpJumpW, parser.OpSuspend:
+		return 0, 0, true
+	case parser.OpArray, parser.OpArrayW, parser.OpMap, parser.OpMapW:
+		return operands[0], 1, true
+	case parser.OpArrayAppend, parser.OpMapInsert:
+		return operands[0] + 1, 1, true
+	case parser.OpSetSelGlobal, parser.OpSetSelGlobalW,
+		parser.OpSetSelLocal, parser.OpSetSelFree:
+		return operands[1] + 1, 0, true
+	case parser.OpCall:
+		return operands[0] + 1, 1, true
+	case parser.OpTailCall:
+		return operands[0] + 1, 0, true
+	case parser.OpReturn:
+		return operands[0], 0, true
+	}
+	return 0, 0, false
+}
+
+// slot returns the register of stack slot i.
+func (t *regTranslator) slot(i int) int {
+	return t.fn.NumLocals + i
+}
+
+// operand returns the operand of stack entry i.
+func (t *regTranslator) operand(i int) int {
+	e := t.stack[i]
+	switch e.kind {
+	case regLocal:
+		return e.index
+	case regConst:
+		return ^e.index
+	}
+	return t.slot(i)
+}
+
+func (t *regTranslator) emit(op regOpcode, a, b, c int) int {
+	t.code = append(t.code, regInstruction{
+		op: op, a: int32(a), b: int32(b), c: int32(c),
+	})
+	// the stack VM reports errors at the byte before the last byte of the
+	// instruction
+	t.pos = append(t.pos, t.fn.SourcePos(t.curEnd-2))
+	t.producer = false
+	return len(t.code) - 1
+}
+
+func (t *regTranslator) emitJump(op regOpcode, target, b, c int) {
+	t.jumps = append(t.jumps, t.emit(op, target, b, c))
+}
+
+// push emits an instruction that writes a new top stack slot.
+func (t *regTranslator) push(op regOpcode, b, c int) *regInstruction {
+	t.stack = append(t.stack, regEntry{kind: regTemp})
+	t.emit(op, t.slot(len(t.stack)-1), b, c)
+	t.producer = true
+	return &t.code[len(t.code)-1]
+}
+
+func (t *regTranslator) pop(n int) {
+	t.stack = t.stack[:len(t.stack)-n]
+}
+
+// materialize stores the virtual stack entry i in its slot.
+func (t *regTranslator) materialize(i int) {
+	switch e := t.stack[i]; e.kind {
+	case regLocal, regConst:
+		t.emit(regMove, t.slot(i), t.operand(i), 0)
+		t.stack[i] = regEntry{kind: regTemp}
+	}
+}
+
+// flush stores the stack entries from index from up to the top n entries
+// in their slots.
+func (t *regTranslator) flush(from, n int) {
+	for i := from; i < len(t.stack)-n; i++ {
+		t.materialize(i)
+	}
+}
+
+var regUnaryOps = map[parser.Opcode]regOpcode{
+	parser.OpLNot:          regNot,
+	parser.OpMinus:         regMinus,
+	parser.OpBComplement:   regComplement,
+	parser.OpError:         regError,
+	parser.OpImmutable:     regImmutable,
+	parser.OpIteratorInit:  regIterInit,
+	parser.OpIteratorNext:  regIterNext,
+	parser.OpIteratorKey:   regIterKey,
+	parser.OpIteratorValue: regIterValue,
+}
+
+func (t *regTranslator) translate(op parser.Opcode, operands []int) bool {
+	top := len(t.stack) - 1
+	switch op {
+	case parser.OpConstant, parser.OpConstantW:
+		t.stack = append(t.stack, regEntry{kind: regConst, index: operands[0]})
+	case parser.OpGetLocal:
+		t.stack = append(t.stack, regEntry{kind: regLocal, index: operands[0]})
+	case parser.OpNull:
+		t.push(regLoadNull, 0, 0)
+	case parser.OpTrue:
+		t.push(regLoadTrue, 0, 0)
+	case parser.OpFalse:
+		t.push(regLoadFalse, 0, 0)
+	case parser.OpGetGlobal, parser.OpGetGlobalW:
+		t.push(regGetGlobal, operands[0], 0)
+	case parser.OpGetFree:
+		t.push(regGetFree, operands[0], 0)
+	case parser.OpGetBuiltin:
+		t.push(regGetBuiltin, operands[0], 0)
+	case parser.OpLocalBinaryOp:
+		in := t.push(regBinaryOp, operands[0], ^operands[1])
+		in.tok = token.Token(operands[2])
+	case parser.OpBinaryOp, parser.OpEqual, parser.OpNotEqual, parser.OpIndex:
+		l, r := t.operand(top-1), t.operand(top)
+		t.pop(2)
+		var in *regInstruction
+		switch op {
+		case parser.OpBinaryOp:
+			in = t.push(regBinaryOp, l, r)
+			in.tok = token.Token(operands[0])
+		case parser.OpEqual:
+			t.push(regEqual, l, r)
+		case parser.OpNotEqual:
+			t.push(regNotEqual, l, r)
+		default:
+			t.push(regIndex, l, r)
+		}
+	case parser.OpLNot, parser.OpMinus, parser.OpBComplement, parser.OpError,
+		parser.OpImmutable, parser.OpIteratorInit, parser.OpIteratorNext,
+		parser.OpIteratorKey, parser.OpIteratorValue:
+		x := t.operand(top)
+		t.pop(1)
+		t.push(regUnaryOps[op], x, 0)
+	case parser.OpSelector:
+		x := t.operand(top)
+		t.pop(1)
+		in := t.push(regSelector, x, operands[0])
+		in.d = int32(operands[1])
+	case parser.OpPop:
+		t.pop(1)
+	case parser.OpDefineLocal, parser.OpSetLocal:
+		t.setLocal(operands[0])
+	case parser.OpSetGlobal, parser.OpSetGlobalW:
+		t.emit(regSetGlobal, operands[0], t.operand(top), 0)
+		t.pop(1)
+	case parser.OpSetFree:
+		t.emit(regSetFree, operands[0], t.operand(top), 0)
+		t.pop(1)
+	case parser.OpSetSelLocal, parser.OpSetSelGlobal, parser.OpSetSelGlobalW,
+		parser.OpSetSelFree:
+		n := operands[1]
+		start := len(t.stack) - n - 1
+		t.flush(start, 0)
+		var sop regOpcode
+		switch op {
+		case parser.OpSetSelLocal:
+			sop = regSetSelLocal
+		case parser.OpSetSelFree:
+			sop = regSetSelFree
+		default:
+			sop = regSetSelGlobal
+		}
+		t.emit(sop, operands[0], t.slot(start), n)
+		t.pop(n + 1)
+	case parser.OpArray, parser.OpArrayW, parser.OpMap, parser.OpMapW:
+		n := operands[0]
+		start := len(t.stack) - n
+		t.flush(start, 0)
+		t.pop(n)
+		if op == parser.OpArray || op == parser.OpArrayW {
+			t.push(regArray, t.slot(start), n)
+		} else {
+			t.push(regMap, t.slot(start), n)
+		}
+	case parser.OpArrayAppend, parser.OpMapInsert:
+		n := operands[0]
+		start := len(t.stack) - n
+		t.flush(start-1, 0)
+		aop := regArrayAppend
+		if op == parser.OpMapInsert {
+			aop = regMapInsert
+		}
+		t.emit(aop, t.slot(start-1), t.slot(start), n)
+		t.pop(n)
+	case parser.OpJump, parser.OpJumpW:
+		t.flush(0, 0)
+		t.emitJump(regJump, operands[0], 0, 0)
+		t.reachable = false
+	case parser.OpJumpFalsy, parser.OpJumpFalsyW:
+		t.flush(0, 1)
+		x := t.operand(top)
+		t.pop(1)
+		t.emitJump(regJumpFalsy, operands[0], x, 0)
+	case parser.OpAndJump, parser.OpAndJumpW, parser.OpOrJump,
+		parser.OpOrJumpW:
+		t.flush(0, 0)
+		jop := regJumpFalsy
+		if op == parser.OpOrJump || op == parser.OpOrJumpW {
+			jop = regJumpTruthy
+		}
+		t.emitJump(jop, operands[0], t.slot(top), 0)
+		t.pop(1)
+	case parser.OpCompareJump:
+		t.flush(0, 2)
+		l, r := t.operand(top-1), t.operand(top)
+		t.pop(2)
+		t.emitJump(regCompareJump, operands[1], l, r)
+		t.code[len(t.code)-1].tok = token.Token(operands[0])
+	case parser.OpCall, parser.OpTailCall:
+		n := operands[0]
+		start := len(t.stack) - n - 1
+		t.flush(start, 0)
+		t.pop(n + 1)
+		if op == parser.OpTailCall {
+			t.emit(regTailCall, t.slot(start), n, operands[1])
+			t.reachable = false
+			break
+		}
+		t.emit(regCall, t.slot(start), n, operands[1])
+		t.stack = append(t.stack, regEntry{kind: regTemp})
+	case parser.OpReturn:
+		if operands[0] == 1 {
+			t.emit(regReturn, t.operand(top), 0, 0)
+			t.pop(1)
+		} else {
+			t.emit(regReturnNull, 0, 0, 0)
+		}
+		t.reachable = false
+	case parser.OpSuspend:
+		t.emit(regSuspend, 0, 0, 0)
+		t.reachable = false
+	default:
+		return false
+	}
+	return true
+}
+
+// setLocal translates a store of the top stack entry in local variable i.
+func (t *regTranslator) setLocal(i int) {
+	top := len(t.stack) - 1
+	e := t.stack[top]
+	producer := t.producer && e.kind == regTemp
+	t.pop(1)
+	// entries that read the old value must not see the new one
+	for j, o := range t.stack {
+		if o.kind == regLocal && o.index == i {
+			t.materialize(j)
+			producer = false
+		}
+	}
+	if producer {
+		// write the result directly into the local variable
+		t.code[len(t.code)-1].a = int32(i)
+		t.producer = false
+		return
+	}
+	if e.kind == regLocal && e.index == i {
+		return
+	}
+	x := t.slot(top)
+	switch e.kind {
+	case regLocal:
+		x = e.index
+	case regConst:
+		x = ^e.index
+	}
+	t.emit(regMove, i, x, 0)
+}
+
+// regOperand returns the value of register or constant operand x.
+func regOperand(regs, consts []Object, x int32) Object {
+	if x >= 0 {
+		return regs[x]
+	}
+	return consts[^x]
+}
+
+// runRegisters runs fn, translated for the register VM, in the current
+// frame whose registers start at base. It returns the result of fn, or false
+// if the VM stopped with an error or was aborted.
+func (v *VM) runRegisters(fn *CompiledFunction, base int) (Object, bool) {
+	rf := fn.reg
+	code := rf.code
+	consts := v.constants
+	if v.sp = base + rf.numRegs; v.sp >= len(v.stack) {
+		if v.checkGrowStack(0); v.err != nil {
+			return nil, false
+		}
+	}
+	regs := v.stack[base:]
+	free := fn.Free
+	pc := 0
+	for {
+		in := &code[pc]
+		pc++
+		switch in.op {
+		case regMove:
+			regs[in.a] = regOperand(regs, consts, in.b)
+		case regBinaryOp:
+			res, ok := v.binaryOp(in.tok, regOperand(regs, consts, in.b),
+				regOperand(regs, consts, in.c))
+			if !ok {
+				v.ip = pc
+				return nil, false
+			}
+			regs[in.a] = res
+		case regCompareJump:
+			left := regOperand(regs, consts, in.b)
+			right := regOperand(regs, consts, in.c)
+			var truthy bool
+			if l, ok := left.(*Int); ok {
+				if r, ok := right.(*Int); ok {
+					switch in.tok {
+					case token.Less:
+						truthy = l.Value < r.Value
+					case token.LessEq:
+						truthy = l.Value <= r.Value
+					case token.Greater:
+						truthy = l.Value > r.Value
+					case token.GreaterEq:
+						truthy = l.Value >= r.Value
+					case token.Equal:
+						truthy = l.Value == r.Value
+					case token.NotEqual:
+						truthy = l.Value != r.Value
+					}
+					goto compared
+				}
+			}
+			switch in.tok {
+			case token.Equal:
+				truthy = left.Equals(right)
+			case token.NotEqual:
+				truthy = !left.Equals(right)
+			default:
+				res, ok := v.binaryOp(in.tok, left, right)
+				if !ok {
+					v.ip = pc
+					return nil, false
+				}
+				truthy = !res.IsFalsy()
+			}
+		compared:
+			if !truthy {
+				if int(in.a) < pc &&
+					atomic.LoadInt64(&v.aborting) != 0 {
+					return nil, false
+				}
+				pc = int(in.a)
+			}
+		case regJump:
+			if int(in.a) < pc && atomic.LoadInt64(&v.aborting) != 0 {
+				return nil, false
+			}
+			pc = int(in.a)
+		case regJumpFalsy:
+			if regOperand(regs, consts, in.b).IsFalsy() {
+				pc = int(in.a)
+			}
+		case regJumpTruthy:
+			if !regOperand(regs, consts, in.b).IsFalsy() {
+				pc = int(in.a)
+			}
+		case regLoadNull:
+			regs[in.a] = NullValue
+		case regLoadTrue:
+			regs[in.a] = TrueValue
+		case regLoadFalse:
+			regs[in.a] = FalseValue
+		case regGetGlobal:
+			regs[in.a] = v.globals[in.b]
+		case regSetGlobal:
+			v.globals[in.a] = regOperand(regs, consts, in.b)
+		case regGetFree:
+			regs[in.a] = *free[in.b].Value
+		case regSetFree:
+			*free[in.a].Value = regOperand(regs, consts, in.b)
+		case regGetBuiltin:
+			regs[in.a] = builtinFuncs[in.b]
+		case regEqual:
+			regs[in.a] = boolValue(regOperand(regs, consts, in.b).Equals(
+				regOperand(regs, consts, in.c)))
+		case regNotEqual:
+			regs[in.a] = boolValue(!regOperand(regs, consts, in.b).Equals(
+				regOperand(regs, consts, in.c)))
+		case regNot:
+			regs[in.a] = boolValue(regOperand(regs, consts, in.b).IsFalsy())
+		case regMinus, regComplement:
+			operand := regOperand(regs, consts, in.b)
+			var res Object
+			switch x := operand.(type) {
+			case *Int:
+				if in.op == regMinus {
+					res = newInt(-x.Value)
+				} else {
+					res = &Int{Value: ^x.Value}
+				}
+			case *Float:
+				if in.op == regMinus {
+					res = &Float{Value: -x.Value}
+				}
+			}
+			if res == nil {
+				op := "-"
+				if in.op == regComplement {
+					op = "^"
+				}
+				v.err = fmt.Errorf("invalid operation: %s%s", op,
+					operand.TypeName())
+				v.ip = pc
+				return nil, false
+			}
+			if !v.countAlloc() {
+				v.ip = pc
+				return nil, false
+			}
+			regs[in.a] = res
+		case regIndex:
+			index := regOperand(regs, consts, in.c)
+			val, err := regOperand(regs, consts, in.b).IndexGet(index)
+			if err != nil {
+				v.err = indexGetError(index, err)
+				v.ip = pc
+				return nil, false
+			}
+			if val == nil {
+				val = NullValue
+			}
+			regs[in.a] = val
+		case regSelector:
+			val, err := v.selector(regOperand(regs, consts, in.b),
+				int(in.c), int(in.d))
+			if err != nil {
+				v.err = indexGetError(v.constants[in.c], err)
+				v.ip = pc
+				return nil, false
+			}
+			if val == nil {
+				val = NullValue
+			}
+			regs[in.a] = val
+		case regArray:
+			elements := make([]Object, in.c)
+			copy(elements, regs[in.b:in.b+in.c])
+			if !v.countAlloc() {
+				v.ip = pc
+				return nil, false
+			}
+			regs[in.a] = &Array{Value: elements}
+		case regMap:
+			kv := make(map[string]Object)
+			for i := in.b; i < in.b+in.c; i += 2 {
+				kv[regs[i].(*String).Value] = regs[i+1]
+			}
+			if !v.countAlloc() {
+				v.ip = pc
+				return nil, false
+			}
+			regs[in.a] = &Map{Value: kv}
+		case regArrayAppend:
+			arr := regs[in.a].(*Array)
+			arr.Value = append(arr.Value, regs[in.b:in.b+in.c]...)
+		case regMapInsert:
+			kv := regs[in.a].(*Map).Value
+			for i := in.b; i < in.b+in.c; i += 2 {
+				kv[regs[i].(*String).Value] = regs[i+1]
+			}
+		case regError:
+			if !v.countAlloc() {
+				v.ip = pc
+				return nil, false
+			}
+			regs[in.a] = &Error{Value: regOperand(regs, consts, in.b)}
+		case regImmutable:
+			val := regOperand(regs, consts, in.b)
+			switch x := val.(type) {
+			case *Array:
+				val = &ImmutableArray{Value: x.Value}
+			case *Map:
+				val = &ImmutableMap{Value: x.Value}
+			default:
+				regs[in.a] = val
+				continue
+			}
+			if !v.countAlloc() {
+				v.ip = pc
+				return nil, false
+			}
+			regs[in.a] = val
+		case regSetSelLocal, regSetSelGlobal, regSetSelFree:
+			selectors := make([]Object, in.c)
+			copy(selectors, regs[in.b+1:in.b+1+in.c])
+			var dst Object
+			switch in.op {
+			case regSetSelLocal:
+				dst = regs[in.a]
+			case regSetSelGlobal:
+				dst = v.globals[in.a]
+			default:
+				dst = *free[in.a].Value
+			}
+			if e := indexAssign(dst, regs[in.b], selectors); e != nil {
+				v.err = e
+				v.ip = pc
+				return nil, false
+			}
+		case regIterInit:
+			dst := regOperand(regs, consts, in.b)
+			if !dst.CanIterate() {
+				v.err = fmt.Errorf("not iterable: %s", dst.TypeName())
+				v.ip = pc
+				return nil, false
+			}
+			if !v.countAlloc() {
+				v.ip = pc
+				return nil, false
+			}
+			regs[in.a] = dst.Iterate()
+		case regIterNext:
+			regs[in.a] = boolValue(regs[in.b].(Iterator).Next())
+		case regIterKey:
+			regs[in.a] = regs[in.b].(Iterator).Key()
+		case regIterValue:
+			regs[in.a] = regs[in.b].(Iterator).Value()
+		case regCall, regTailCall:
+			if atomic.LoadInt64(&v.aborting) != 0 {
+				return nil, false
+			}
+			callBase := base + int(in.a)
+			v.sp = callBase + 1 + int(in.b)
+			numArgs, ok := v.spreadArgs(int(in.b), in.c == 1)
+			if !ok {
+				v.ip = pc
+				return nil, false
+			}
+			value := v.stack[callBase]
+			callee, ok := value.(*CompiledFunction)
+			if !ok {
+				if !value.CanCall() {
+					v.err = fmt.Errorf("not callable: %s", value.TypeName())
+					v.ip = pc
+					return nil, false
+				}
+				ret, ok := v.callNative(value, numArgs)
+				if !ok {
+					v.ip = pc
+					return nil, false
+				}
+				if in.op == regTailCall {
+					return ret, true
+				}
+				regs = v.stack[base:]
+				regs[in.a] = ret
+				v.sp = base + rf.numRegs
+				continue
+			}
+			if numArgs, ok = v.prepareArgs(callee, numArgs); !ok {
+				v.ip = pc
+				return nil, false
+			}
+
+			if in.op == regTailCall && callee.reg != nil {
+				// reuse the current frame
+				copy(v.stack[base:], v.stack[v.sp-numArgs:v.sp])
+				v.curFrame.fn = callee
+				v.curFrame.freeVars = callee.Free
+				v.curFrame.elided++
+				fn, rf, code, free, pc = callee, callee.reg, callee.reg.code,
+					callee.Free, 0
+				if v.sp = base + rf.numRegs; v.sp >= len(v.stack) {
+					if v.checkGrowStack(0); v.err != nil {
+						return nil, false
+					}
+				}
+				regs = v.stack[base:]
+				continue
+			}
+
+			ret, ok := v.callCompiled(callee, numArgs, pc-1)
+			if !ok {
+				return nil, false
+			}
+			if in.op == regTailCall {
+				return ret, true
+			}
+			regs = v.stack[base:]
+			regs[in.a] = ret
+			v.sp = base + rf.numRegs
+		case regReturn:
+			return regOperand(regs, consts, in.a), true
+		case regReturnNull:
+			return NullValue, true
+		case regSuspend:
+			return NullValue, true
+		}
+	}
+}
+
+// callCompiled calls callee, whose arguments are at the top of the stack,
+// from the register function of the current frame at instruction pc.
+func (v *VM) callCompiled(
+	callee *CompiledFunction,
+	numArgs, pc int,
+) (Object, bool) {
+	need := 1
+	if callee.reg == nil {
+		need = 2
+	}
+	if v.framesIndex+need > v.MaxFrames {
+		v.err = ErrStackOverflow
+		v.ip = pc + 1
+		return nil, false
+	}
+	caller := v.curFrame
+	caller.ip = pc
+	if callee.reg == nil {
+		// the callee returns to a bridge frame that stops the stack VM
+		v.pushFrame(regBridge, -1)
+	}
+	f := v.pushFrame(callee, v.sp-numArgs)
+	if callee.reg != nil {
+		ret, ok := v.runRegisters(callee, f.basePointer)
+		if !ok {
+			return nil, false
+		}
+		v.framesIndex--
+		v.curFrame = caller
+		return ret, true
+	}
+
+	v.curInsts = callee.Instructions
+	v.ip = -1
+	v.sp = f.basePointer + callee.NumLocals
+	if v.sp >= len(v.stack) {
+		if v.checkGrowStack(0); v.err != nil {
+			return nil, false
+		}
+	}
+	v.run()
+	if v.err != nil || atomic.LoadInt64(&v.aborting) != 0 {
+		return nil, false
+	}
+	v.framesIndex-- // bridge frame
+	v.curFrame = caller
+	return v.stack[v.sp-1], true
+}
+
+// pushFrame pushes a frame for fn whose locals start at basePointer.
+func (v *VM) pushFrame(fn *CompiledFunction, basePointer int) *frame {
+	if v.framesIndex >= len(v.frames) {
+		v.frames = append(v.frames, &frame{})
+	}
+	f := v.frames[v.framesIndex]
+	f.fn = fn
+	f.freeVars = fn.Free
+	f.basePointer = basePointer
+	f.elided = 0
+	f.ip = -1
+	v.framesIndex++
+	v.curFrame = f
+	return f
+}
+
+// countAlloc counts an allocated object and returns false if the VM exceeds
+// its allocation limit.
+func (v *VM) countAlloc() bool {
+	v.allocs--
+	if v.allocs == 0 {
+		v.err = ErrObjectAllocLimit
+		return false
+	}
+	return true
+}
@@ -0,0 +1,202 @@
+package tender
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// regVMTests are run on both VMs, which must give the same results and
+// errors.
+var regVMTests = []string{
+	`out := 1 + 2 * 3 - 4 / 2`,
+	`out := "a" + "b" + string(1 + 2)`,
+	`out := [1 < 2 && "x" != "y", 3 <= 2 || false, !true, -(2.5 * 2), ^5]`,
+	`a := 3; out := a > 2 ? "yes" : "no"`,
+	`f := fn(n) { if n < 2 { return n }; return f(n - 1) + f(n - 2) }; out := f(15)`,
+	`f := fn(n) { s := 0; for i := 0; i < n; i++ { if i % 3 == 0 { s += i * 2 } }; return s }; out := f(100)`,
+	`f := fn(n) { s := 0.5; for i := n; i >= 0; i-- { if i != 2 { s += i / 2.0 } }; return s }; out := f(10)`,
+	`f := fn(a, b) { t := a; a = b; b = t; return [a, b] }; out := f(1, 2)`,
+	`f := fn(x) { x = x + 1; y := x; x = 10; return [x, y] }; out := f(1)`,
+	`f := fn(a, b) { return [a == b, a != b, a < b || b < a] }; out := [f(1, 1), f("a", "b")]`,
+	`f := fn(a, b) { return a && b || !a }; out := [f(true, false), f(false, 1), f(1, 2)]`,
+	`f := fn(m) { s := 0; for k, v in m { s += v }; return s }; out := f({a: 1, b: 2, c: 3})`,
+	`f := fn(a) { r := []; for i, x in a { r = append(r, i * x) }; return r }; out := f([3, 4, 5])`,
+	`f := fn(n) { s := ""; for i := 0; i < n; i++ { s += string(i) }; return s }; out := f(12)`,
+	`f := fn() { m := {}; for i := 0; i < 5; i++ { m["k" + string(i)] = i * i }; return m }; out := f()`,
+	`f := fn() { a := [1, 2, [3, 4]]; a[0] = 9; a[2][1] = 7; return a }; out := f()`,
+	`g := [0, 0]; f := fn() { g[1] = 5; return g }; out := f()`,
+	`f := fn(m) { return [m.a, m["b"], m.c] }; out := f(immutable({a: 1, b: 2}))`,
+	`f := fn(...a) { return len(a) }; out := [f(), f(1, 2), f([1, 2]...)]`,
+	`f := fn(a, ...b) { return [a, b] }; g := fn(x) { return f(x, x, x) }; out := g(1)`,
+	`f := fn(x) { return error(x) }; out := [string(f("e")), is_error(f(1))]`,
+	`x := 1; f := fn() { x = x + 1; return x }; f(); out := f()`,
+	`f := fn(x) { y := x * 2; return fn() { return y } }; out := f(4)()`,
+	`f := fn(x) { g := fn(y) { return x + y }; return g(2) }; out := f(1)`,
+	`f := fn(x) { return x + 1 }; g := fn(h, x) { return h(h(x)) }; out := g(f, 1)`,
+	`f := fn(n, acc) { if n == 0 { return acc }; return f(n - 1, acc + 1) }; out := f(50000, 0)`,
+	`f := fn(n) { if n == 0 { return string(n) }; return f(n - 1) }; out := f(1000)`,
+	`f := fn(n) { if n == 0 { return 0 }; return 1 + f(n - 1) }; out := f(5000)`,
+	`f := fn(a) { return a[1:] }; out := f([1, 2, 3])`,
+	`f := fn() { return }; out := f()`,
+	`f := fn(x) { if x { return 1 } else { return 2 } }; out := [f(true), f(0)]`,
+	`f := fn(n) { for { n--; if n < 3 { break } }; return n }; out := f(10)`,
+	`f := fn(n) { s := 0; for i := 0; i < n; i++ { if i == 3 { continue }; s += i }; return s }; out := f(6)`,
+	`f := fn() { return math.pi * 2 }; out := f()`,
+	`out := 0; for i := 0; i < 10; i++ { out += i }`,
+	`f := fn(a) { return a + 1 }; out := f("x")`,
+	`f := fn(a, b) { if a < b { return a }; return b }; out := f("a", 1)`,
+	`f := fn(a) { return a.b.c }; out := f({b: 1})`,
+	`f := fn(a) { return a() }; out := f(1)`,
+	`f := fn(a) { return -a }; out := f("x")`,
+	`f := fn(a, b) { return a }; out := f(1)`,
+	`f := fn(a) { for x in a {} }; out := f(1)`,
+	`f := fn(n) { return f(n + 1) + 1 }; out := f(0)`,
+	`g := fn(x) { return x.y.z }; f := fn(x) { return g(x) + 1 }; out := f({})`,
+}
+
+func runRegVM(
+	t *testing.T,
+	src string,
+	registers bool,
+) (*Compiled, string, error) {
+	t.Helper()
+	modules := NewModuleMap()
+	modules.AddBuiltinModule("math", map[string]Object{
+		"pi": &Float{Value: 3.141592653589793},
+	})
+	s := NewScript([]byte(`math := import("math");` + src))
+	s.SetImports(modules)
+	s.EnableRegisterVM(registers)
+	c, err := s.Compile()
+	if err != nil {
+		return nil, "", err
+	}
+	err = c.Run()
+	return c, fmt.Sprintf("%v", c.Get("out").Value()), err
+}
+
+func TestRegisterVM(t *testing.T) {
+	for _, src := range regVMTests {
+		_, want, wantErr := runRegVM(t, src, false)
+		_, got, gotErr := runRegVM(t, src, true)
+		if fmt.Sprint(wantErr) != fmt.Sprint(gotErr) {
+			t.Errorf("%s: error %v, expected %v", src, gotErr, wantErr)
+			continue
+		}
+		if got != want {
+			t.Errorf("%s: out = %s, expected %s", src, got, want)
+		}
+	}
+}
+
+func TestRegisterVMTranslation(t *testing.T) {
+	c, _, err := runRegVM(t, `
+f := fn(n) { if n < 2 { return n }; return f(n - 1) + f(n - 2) }
+g := fn(x) { y := x; return fn() { return y } }
+out := f(10)`, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var translated, stack int
+	for _, cn := range c.bytecode.Constants {
+		if fn, ok := cn.(*CompiledFunction); ok {
+			if fn.reg != nil {
+				translated++
+			} else {
+				stack++
+			}
+		}
+	}
+	// g captures a local variable and runs on the stack VM
+	if translated != 2 || stack != 1 {
+		t.Errorf("%d functions translated, %d not, expected 2 and 1",
+			translated, stack)
+	}
+	if c.bytecode.MainFunction.reg == nil {
+		t.Error("main function not translated")
+	}
+
+	// large literals are built in chunks, which the register VM runs too
+	c, out, err := runRegVM(t, `f := fn(x) { a := [x, `+bigArray(3000)[1:]+`
+	m := {x: x, `+bigMap(3000)+`}; return [len(a), a[3000], len(m), m.k2999] }
+out := f(1)`, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out != `[3001 s2999 3001 2999]` {
+		t.Errorf("out = %s", out)
+	}
+	for _, cn := range c.bytecode.Constants {
+		if fn, ok := cn.(*CompiledFunction); ok && fn.reg == nil {
+			t.Error("function with large literals not translated")
+		}
+	}
+}
+
+func TestRegisterVMStackTrace(t *testing.T) {
+	src := `
+g := fn(x) { return x + "s" }
+f := fn(x) { y := 1; return [g(x)] }
+out := f(1)`
+	_, _, want := runRegVM(t, src, false)
+	_, _, got := runRegVM(t, src, true)
+	if want == nil || strings.Count(want.Error(), "\n\tat ") != 3 {
+		t.Fatalf("unexpected stack VM error %v", want)
+	}
+	if got == nil || got.Error() != want.Error() {
+		t.Errorf("error %v, expected %v", got, want)
+	}
+}
+
+var regVMBenchmarks = map[string]string{
+	"fib": `fib := fn(n) { if n < 2 { return n }; return fib(n - 1) + fib(n - 2) }
+out := fib(22)`,
+	"pixels": `f := fn(w, h) {
+	img := []
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			v := (x * 3 + y * 5) % 256
+			if v > 127 { v = 255 - v }
+			img = append(img, v)
+		}
+	}
+	return len(img)
+}
+out := f(200, 100)`,
+	"strings": `f := fn(n) { s := ""; for i := 0; i < n; i++ { s += "ab" + string(i % 10) }; return len(s) }
+out := f(3000)`,
+	"maps": `f := fn(n) {
+	m := {}
+	for i := 0; i < n; i++ { m["k" + string(i % 100)] = i }
+	s := 0
+	for k, v in m { s += v }
+	return s
+}
+out := f(10000)`,
+}
+
+func BenchmarkRegisterVM(b *testing.B) {
+	for name, src := range regVMBenchmarks {
+		for _, registers := range []bool{false, true} {
+			mode := "stack"
+			if registers {
+				mode = "registers"
+			}
+			b.Run(name+"/"+mode, func(b *testing.B) {
+				s := NewScript([]byte(src))
+				s.EnableRegisterVM(registers)
+				c, err := s.Compile()
+				if err != nil {
+					b.Fatal(err)
+				}
+				b.ResetTimer()
+				for i := 0; i < b.N; i++ {
+					if err := c.Run(); err != nil {
+						b.Fatal(err)
+					}
+				}
+			})
+		}
+	}
+}
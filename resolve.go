@@ -0,0 +1,125 @@
+package tender
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/2dprototype/tender/deps"
+	"github.com/2dprototype/tender/utils"
+)
+
+// resolveModule returns the absolute path of the module file imported with
+// name.
+//
+// Names starting with "./" or "../" are relative to the directory of the
+// importing file. Packages "@user:repo[/path]" are looked up in the vendor
+// directory of the project and then in the installed packages. Other names
+// are searched for in the import directory, the vendor directory of the
+// project, the import paths, the directories of the TENDER_PATH environment
+// variable, the pkg directory next to the executable and finally the
+// directory of the importing file. A name is either a file, with an optional
+// ".td" extension, or a directory with a "main.td" file.
+func (c *Compiler) resolveModule(name string) (string, error) {
+	if strings.HasPrefix(name, "@") {
+		return c.resolvePackage(name)
+	}
+	if filepath.IsAbs(name) {
+		if path, ok := moduleFile(name); ok {
+			return path, nil
+		}
+		return "", fmt.Errorf("module '%s' not found", name)
+	}
+	if strings.HasPrefix(name, "./") || strings.HasPrefix(name, "../") {
+		if path, ok := moduleFile(filepath.Join(c.importDir, name)); ok {
+			return filepath.Abs(path)
+		}
+		return "", fmt.Errorf("module '%s' not found", name)
+	}
+	for _, dir := range c.searchPaths() {
+		if path, ok := moduleFile(filepath.Join(dir, name)); ok {
+			return filepath.Abs(path)
+		}
+	}
+	return "", fmt.Errorf("module '%s' not found", name)
+}
+
+// searchPaths returns the directories searched for modules imported by
+// name, in order.
+func (c *Compiler) searchPaths() []string {
+	root := c.root()
+	paths := []string{root.importDir}
+	if dir, err := projectDir(root.importDir); err == nil {
+		paths = append(paths, filepath.Join(dir, deps.VendorDir))
+	}
+	paths = append(paths, root.importPaths...)
+	paths = append(paths, filepath.SplitList(os.Getenv("TENDER_PATH"))...)
+	paths = append(paths, deps.PkgDir)
+	if c.importDir != root.importDir {
+		paths = append(paths, c.importDir)
+	}
+	return paths
+}
+
+// resolvePackage returns the path of a module file of a package. The
+// packages of a project are required in its tender.mod file and verified
+// against its tender.lock file. Outside of projects, the newest version of
+// the package is downloaded into the pkg directory.
+func (c *Compiler) resolvePackage(name string) (string, error) {
+	project, err := deps.FindProject(c.importDir)
+	if err != nil {
+		return "", err
+	}
+	if project != nil {
+		return project.ModuleFile(deps.DefaultSource(), name)
+	}
+
+	user, repo, file, err := deps.ParsePath(name)
+	if err != nil {
+		return "", err
+	}
+	dir := filepath.Join(deps.PkgDir, "@"+user, repo)
+	if _, err := os.Stat(dir); os.IsNotExist(err) {
+		if err := utils.FetchTagsFromGithub(user, repo); err != nil {
+			if err = utils.FetchFromGithub(user, repo); err != nil {
+				return "", err
+			}
+		}
+	}
+	if file == "" {
+		file = "main"
+	}
+	if path, ok := moduleFile(filepath.Join(dir, filepath.FromSlash(file))); ok {
+		return path, nil
+	}
+	return "", fmt.Errorf("module '%s' not found", name)
+}
+
+// projectDir returns the directory of the tender.mod file in dir or its
+// closest parent, or dir itself if there is none.
+func projectDir(dir string) (string, error) {
+	project, err := deps.FindProject(dir)
+	if err != nil {
+		return "", err
+	}
+	if project != nil {
+		return project.Dir, nil
+	}
+	return filepath.Abs(dir)
+}
+
+// moduleFile returns the module file of path: path itself or path with the
+// ".td" extension, or the "main.td" file if path is a directory.
+func moduleFile(path string) (string, bool) {
+	candidates := []string{path + ".td", filepath.Join(path, "main.td")}
+	if strings.HasSuffix(path, ".td") {
+		candidates = []string{path}
+	}
+	for _, file := range candidates {
+		if info, err := os.Stat(file); err == nil && info.Mode().IsRegular() {
+			return file, true
+		}
+	}
+	return "", false
+}
@@ -0,0 +1,178 @@
+package tender
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/2dprototype/tender/deps"
+)
+
+func TestResolveModule(t *testing.T) {
+	pkgDir := deps.PkgDir
+	deps.PkgDir = t.TempDir()
+	defer func() { deps.PkgDir = pkgDir }()
+
+	dir := t.TempDir()
+	paths := t.TempDir()
+	env := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"app/main.td":           `export "app"`,
+		"app/lib/util.td":       `export "util " + import("./helper") + " " + import("../shared/x")`,
+		"app/lib/helper.td":     `export "helper"`,
+		"app/shared/x/main.td":  `export "x"`,
+		"app/vendor/vlib.td":    `export "vendored"`,
+		"app/vendor/both.td":    `export "vendor"`,
+		"app/nested/mod.td":     `export import("sibling") + " " + import("lib/helper")`,
+		"app/nested/sibling.td": `export "sibling"`,
+		"app/dup.td":            `export "local"`,
+	})
+	writeFiles(t, paths, map[string]string{
+		"both.td":  `export "paths"`,
+		"found.td": `export "paths"`,
+		"dup.td":   `export "paths"`,
+	})
+	writeFiles(t, env, map[string]string{
+		"found.td":       `export "env"`,
+		"envlib/main.td": `export "env"`,
+	})
+	writeFiles(t, deps.PkgDir, map[string]string{
+		"envlib.td":  `export "pkg"`,
+		"pkgonly.td": `export "pkg"`,
+	})
+	t.Setenv("TENDER_PATH", env+string(filepath.ListSeparator)+filepath.Join(env, "missing"))
+
+	run := func(src string) (string, error) {
+		s := NewScript([]byte(src))
+		s.EnableFileImport(true)
+		if err := s.SetImportDir(filepath.Join(dir, "app")); err != nil {
+			return "", err
+		}
+		if err := s.SetImportPaths(paths); err != nil {
+			return "", err
+		}
+		c, err := s.Run()
+		if err != nil {
+			return "", err
+		}
+		return c.Get("out").String(), nil
+	}
+
+	tests := []struct {
+		name     string
+		expected string
+	}{
+		{"lib/util", "util helper x"},      // relative imports of the importing file
+		{"./lib/util.td", "util helper x"}, // explicit extension
+		{"shared/x", "x"},                  // directory with main.td
+		{"vlib", "vendored"},               // vendor directory
+		{"both", "vendor"},                 // vendor before import paths
+		{"dup", "local"},                   // import directory first
+		{"found", "paths"},                 // import paths before TENDER_PATH
+		{"envlib", "env"},                  // TENDER_PATH before pkg directory
+		{"pkgonly", "pkg"},                 // pkg directory
+		{"nested/mod", "sibling helper"},   // importing file's directory last
+		{filepath.Join(dir, "app", "lib", "helper"), "helper"},
+	}
+	for _, tc := range tests {
+		out, err := run(`out := import("` + filepath.ToSlash(tc.name) + `")`)
+		if err != nil {
+			t.Errorf("%s: %v", tc.name, err)
+			continue
+		}
+		if out != tc.expected {
+			t.Errorf("%s: %s, expected %s", tc.name, out, tc.expected)
+		}
+	}
+
+	for _, name := range []string{"missing", "./vlib", "../app/missing", "lib"} {
+		_, err := run(`out := import("` + name + `")`)
+		if err == nil || !strings.Contains(err.Error(), "module '"+name+"' not found") {
+			t.Errorf("%s: error %v, expected not found", name, err)
+		}
+	}
+
+	// a project's vendor directory is next to its tender.mod file
+	writeFiles(t, dir, map[string]string{
+		"app/tender.mod":        "",
+		"app/cmd/tool/main.td":  "",
+		"app/vendor/projlib.td": `export "project"`,
+	})
+	s := NewScript([]byte(`out := import("projlib")`))
+	s.EnableFileImport(true)
+	if err := s.SetImportDir(filepath.Join(dir, "app", "cmd", "tool")); err != nil {
+		t.Fatal(err)
+	}
+	c, err := s.Run()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out := c.Get("out").String(); out != "project" {
+		t.Errorf("projlib: %s, expected project", out)
+	}
+}
+
+func TestFromImport(t *testing.T) {
+	modules := NewModuleMap()
+	modules.AddBuiltinModule("text", map[string]Object{
+		"upper": &UserFunction{Name: "upper", Value: func(args ...Object) (Object, error) {
+			s, _ := ToString(args[0])
+			return &String{Value: strings.ToUpper(s)}, nil
+		}},
+		"sep": &String{Value: "-"},
+	})
+	modules.AddSourceModule("src", []byte(`export fn twice(x) { return x * 2 }; export k := 1`))
+	modules.AddSourceModule("value", []byte(`v := {a: 1}; export v`))
+
+	run := func(src string) (Object, error) {
+		s := NewScript([]byte(src))
+		s.SetImports(modules)
+		c, err := s.Run()
+		if err != nil {
+			return nil, err
+		}
+		return c.Get("out").Object(), nil
+	}
+
+	tests := []struct {
+		src      string
+		expected string
+	}{
+		{`from "text" import upper, sep as s; out := upper("a") + s`, `"A-"`},
+		{`from "src" import twice, k; out := twice(k)`, `2`},
+		{`f := fn() { from "src" import twice as t; return t(4) }; out := f()`, `8`},
+		{`from "value" import a; out := a`, `1`},
+		{`from "value" import b; out := b`, `null`},
+		{`export x := 2; export fn y() { return x }; out := y()`, `2`},
+	}
+	for _, tc := range tests {
+		out, err := run(tc.src)
+		if err != nil {
+			t.Errorf("%s: %v", tc.src, err)
+			continue
+		}
+		if got := out.String(); got != tc.expected {
+			t.Errorf("%s: out = %s, expected %s", tc.src, got, tc.expected)
+		}
+	}
+
+	errorTests := []struct {
+		src      string
+		expected string
+	}{
+		{`from "text" import lower`, "'lower' is not exported by module 'text'"},
+		{`from "src" import twice, thrice`, "'thrice' is not exported by module 'src'"},
+		{`from "missing" import a`, "module 'missing' not found"},
+		{`from "text" import upper; upper := 1`, "'upper' redeclared in this block"},
+		{`from "text" import`, "expected 'IDENT'"},
+		{`export x = 1`, "expected ':=' in export declaration"},
+		{`if true { export x := 1 }`, "export not allowed inside block"},
+		{`f := fn() { export fn g() {} }`, "export not allowed inside function"},
+	}
+	for _, tc := range errorTests {
+		_, err := run(tc.src)
+		if err == nil || !strings.Contains(err.Error(), tc.expected) {
+			t.Errorf("%s: error %v, expected %s", tc.src, err, tc.expected)
+		}
+	}
+}
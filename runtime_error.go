@@ -0,0 +1,269 @@
+package tender
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+
+	"github.com/2dprototype/tender/parser"
+)
+
+// RuntimeError is an error that stopped a VM, with the call stack where it
+// happened. The call stack of an error in a goroutine started by the go
+// builtin function continues with the call stack that started it.
+type RuntimeError struct {
+	Err        error        // error of the VM; or nil if only goroutines failed
+	Frames     []StackFrame // call stack, innermost frame first; or of the wait for a goroutine
+	Args       []Argument   // arguments of the innermost function
+	Goroutines []error      // errors of the goroutines started by the VM
+
+	fileSet *parser.SourceFileSet
+}
+
+// StackFrame is a function call in the call stack of a runtime error.
+type StackFrame struct {
+	Pos    parser.SourceFilePos
+	Elided int  // number of frames replaced by tail calls
+	Spawn  bool // call of go starting the goroutine of the previous frames
+
+	pos parser.Pos
+}
+
+// Argument is an argument of the innermost function of a runtime error.
+type Argument struct {
+	Name  string
+	Value Object
+}
+
+func (e *RuntimeError) Error() string {
+	var sb strings.Builder
+	if e.Err != nil {
+		var p ErrPanic
+		if errors.As(e.Err, &p) {
+			fmt.Fprintf(&sb, "\nRuntime Panic: %v%s\n%s", p.perr,
+				formatStack(e.Frames), p.stack)
+		} else {
+			fmt.Fprintf(&sb, "\nRuntime Error: %s%s", e.Err.Error(),
+				formatStack(e.Frames))
+		}
+		if len(e.Goroutines) > 0 {
+			sb.WriteString("\n")
+		}
+	}
+	for _, err := range e.Goroutines {
+		fmt.Fprintf(&sb, "%v\n", err)
+	}
+	return sb.String()
+}
+
+// Unwrap returns the error of the VM.
+func (e *RuntimeError) Unwrap() error {
+	return e.Err
+}
+
+// Report returns a report of the error showing the source line where it
+// happened, the arguments of the function and the call stack, followed by
+// the reports of the errors of the goroutines.
+func (e *RuntimeError) Report() string {
+	var sb strings.Builder
+	if e.Err != nil {
+		var p ErrPanic
+		if errors.As(e.Err, &p) {
+			fmt.Fprintf(&sb, "Runtime Panic: %v", p.perr)
+		} else {
+			fmt.Fprintf(&sb, "Runtime Error: %s", e.Err.Error())
+		}
+		if len(e.Frames) > 0 {
+			pos := e.Frames[0].Pos
+			fmt.Fprintf(&sb, "\n  --> %s", pos)
+			if line, ok := e.sourceLine(e.Frames[0]); ok {
+				num := fmt.Sprintf("%d", pos.Line)
+				margin := strings.Repeat(" ", len(num))
+				fmt.Fprintf(&sb, "\n %s |\n %s | %s\n %s | %s^", margin, num,
+					line, margin, caretIndent(line, pos.Column))
+			}
+		}
+		if len(e.Args) > 0 {
+			args := make([]string, len(e.Args))
+			for i, arg := range e.Args {
+				args[i] = arg.Name + " = " + arg.Value.String()
+			}
+			fmt.Fprintf(&sb, "\n   = arguments: %s", strings.Join(args, ", "))
+		}
+		sb.WriteString(formatStack(e.Frames))
+		if errors.As(e.Err, &p) {
+			fmt.Fprintf(&sb, "\n%s", p.stack)
+		}
+	}
+	for i, err := range e.Goroutines {
+		if i > 0 || e.Err != nil {
+			sb.WriteString("\n\n")
+		}
+		var rerr *RuntimeError
+		if errors.As(err, &rerr) {
+			sb.WriteString(strings.TrimRight(rerr.Report(), "\n"))
+		} else {
+			sb.WriteString(strings.TrimSpace(err.Error()))
+		}
+	}
+	return sb.String()
+}
+
+// MarshalJSON returns the error as a JSON object, with the source line where
+// it happened, the call stack, the arguments of the function and the errors of
+// the goroutines.
+func (e *RuntimeError) MarshalJSON() ([]byte, error) {
+	type jsonFrame struct {
+		File   string `json:"file"`
+		Line   int    `json:"line"`
+		Column int    `json:"column"`
+		Elided int    `json:"elided,omitempty"`
+		Spawn  bool   `json:"spawn,omitempty"`
+	}
+	type jsonArgument struct {
+		Name  string `json:"name"`
+		Type  string `json:"type"`
+		Value string `json:"value"`
+	}
+	type jsonError struct {
+		Kind       string            `json:"kind"`
+		Message    string            `json:"message,omitempty"`
+		Source     string            `json:"source,omitempty"`
+		Stack      []jsonFrame       `json:"stack"`
+		Arguments  []jsonArgument    `json:"arguments,omitempty"`
+		Goroutines []json.RawMessage `json:"goroutines,omitempty"`
+	}
+
+	out := jsonError{Kind: "runtime", Stack: []jsonFrame{}}
+	if e.Err != nil {
+		var p ErrPanic
+		if errors.As(e.Err, &p) {
+			out.Kind = "panic"
+			out.Message = fmt.Sprint(p.perr)
+		} else {
+			out.Message = e.Err.Error()
+		}
+	} else if len(e.Goroutines) > 0 {
+		out.Message = goroutineMessage(e.Goroutines[0])
+		if n := len(e.Goroutines) - 1; n > 0 {
+			out.Message += fmt.Sprintf(" (and %d more goroutine errors)", n)
+		}
+	}
+	if len(e.Frames) > 0 {
+		out.Source, _ = e.sourceLine(e.Frames[0])
+	}
+	for _, f := range e.Frames {
+		out.Stack = append(out.Stack, jsonFrame{
+			File:   f.Pos.Filename,
+			Line:   f.Pos.Line,
+			Column: f.Pos.Column,
+			Elided: f.Elided,
+			Spawn:  f.Spawn,
+		})
+	}
+	for _, arg := range e.Args {
+		out.Arguments = append(out.Arguments, jsonArgument{
+			Name:  arg.Name,
+			Type:  arg.Value.TypeName(),
+			Value: arg.Value.String(),
+		})
+	}
+	for _, err := range e.Goroutines {
+		var data []byte
+		var rerr *RuntimeError
+		if errors.As(err, &rerr) {
+			data, err = json.Marshal(rerr)
+		} else {
+			data, err = json.Marshal(jsonError{
+				Kind:    "runtime",
+				Message: strings.TrimSpace(err.Error()),
+				Stack:   []jsonFrame{},
+			})
+		}
+		if err != nil {
+			return nil, err
+		}
+		out.Goroutines = append(out.Goroutines, data)
+	}
+	return json.Marshal(out)
+}
+
+// goroutineMessage returns the message of the error of a goroutine.
+func goroutineMessage(err error) string {
+	var rerr *RuntimeError
+	if errors.As(err, &rerr) && rerr.Err != nil {
+		err = rerr.Err
+	}
+	msg := strings.TrimSpace(err.Error())
+	var p ErrPanic
+	if errors.As(err, &p) {
+		msg = fmt.Sprint(p.perr)
+	}
+	return "goroutine: " + msg
+}
+
+// sourceLine returns the source line of a frame, from the source of the
+// parsed file or from the file of an absolute path, such as a cached module.
+func (e *RuntimeError) sourceLine(f StackFrame) (string, bool) {
+	if !f.Pos.IsValid() || e.fileSet == nil {
+		return "", false
+	}
+	var src []byte
+	if file := e.fileSet.File(f.pos); file != nil {
+		src = file.Source
+	}
+	if src == nil {
+		if !filepath.IsAbs(f.Pos.Filename) {
+			return "", false
+		}
+		data, err := ioutil.ReadFile(f.Pos.Filename)
+		if err != nil {
+			return "", false
+		}
+		src = data
+	}
+	lines := strings.Split(string(src), "\n")
+	if f.Pos.Line > len(lines) {
+		return "", false
+	}
+	return strings.TrimRight(lines[f.Pos.Line-1], "\r"), true
+}
+
+// caretIndent returns the indentation of a caret under the column of the
+// line, keeping its tabs so that the caret is aligned.
+func caretIndent(line string, column int) string {
+	if column-1 < len(line) {
+		line = line[:column-1]
+	}
+	var sb strings.Builder
+	for _, c := range line {
+		if c == '\t' {
+			sb.WriteRune('\t')
+		} else {
+			sb.WriteRune(' ')
+		}
+	}
+	return sb.String()
+}
+
+// formatStack formats the frames of a call stack, one per line.
+func formatStack(frames []StackFrame) string {
+	var sb strings.Builder
+	for _, f := range frames {
+		if f.Spawn {
+			fmt.Fprintf(&sb, "\n\tstarted by go at %s", f.Pos)
+		} else {
+			fmt.Fprintf(&sb, "\n\tat %s", f.Pos)
+		}
+		if f.Elided == 1 {
+			sb.WriteString("\n\t... 1 frame elided (tail call)")
+		} else if f.Elided > 1 {
+			fmt.Fprintf(&sb, "\n\t... %d frames elided (tail calls)",
+				f.Elided)
+		}
+	}
+	return sb.String()
+}
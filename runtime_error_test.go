@@ -0,0 +1,124 @@
+package tender
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestRuntimeError(t *testing.T) {
+	run := func(src string) *RuntimeError {
+		t.Helper()
+		_, err := NewScript([]byte(src)).Run()
+		var rerr *RuntimeError
+		if !errors.As(err, &rerr) {
+			t.Fatalf("error %v, expected a runtime error", err)
+		}
+		return rerr
+	}
+
+	rerr := run(`
+add := fn(a, b) {
+	x := 1
+	return a + b
+}
+out := add(1, "s")`)
+	if !strings.HasPrefix(rerr.Error(), "\nRuntime Error: invalid operation: int + string\n\tat (main):4:9") {
+		t.Errorf("error %q", rerr.Error())
+	}
+	if len(rerr.Frames) != 2 || rerr.Frames[1].Pos.Line != 6 {
+		t.Errorf("frames %+v", rerr.Frames)
+	}
+	if len(rerr.Args) != 2 || rerr.Args[0].Name != "a" || rerr.Args[1].Value.String() != `"s"` {
+		t.Errorf("arguments %+v", rerr.Args)
+	}
+	report := rerr.Report()
+	for _, expected := range []string{
+		"  --> (main):4:9\n",
+		" 4 | \treturn a + b\n   | \t       ^\n",
+		"   = arguments: a = 1, b = \"s\"\n",
+	} {
+		if !strings.Contains(report, expected) {
+			t.Errorf("report\n%s\ndoes not contain\n%s", report, expected)
+		}
+	}
+
+	// parameters captured by closures are shown with their values
+	rerr = run(`
+f := fn(a, b) {
+	g := fn() { return a }
+	return g() + b
+}
+out := f(1, "s")`)
+	if len(rerr.Args) != 2 || rerr.Args[0].Value.String() != "1" || rerr.Args[1].Value.String() != `"s"` {
+		t.Errorf("arguments %+v", rerr.Args)
+	}
+	if report := rerr.Report(); !strings.Contains(report, "   = arguments: a = 1, b = \"s\"\n") {
+		t.Errorf("report\n%s\ndoes not show the captured argument", report)
+	}
+	data, err := json.Marshal(rerr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), `{"name":"a","type":"int","value":"1"}`) {
+		t.Errorf("json %s", data)
+	}
+
+	// goroutine errors continue with the call stack of go
+	rerr = run(`
+work := fn(v) { return v.x }
+start := fn() { return go(work, 1) }
+start().wait()`)
+	if rerr.Err != nil || len(rerr.Goroutines) != 1 {
+		t.Fatalf("error %v with goroutine errors %v", rerr.Err, rerr.Goroutines)
+	}
+	var gerr *RuntimeError
+	if !errors.As(rerr.Goroutines[0], &gerr) {
+		t.Fatalf("goroutine error %v", rerr.Goroutines[0])
+	}
+	var lines []int
+	for _, f := range gerr.Frames {
+		lines = append(lines, f.Pos.Line)
+	}
+	if len(gerr.Frames) != 3 || !gerr.Frames[1].Spawn || gerr.Frames[2].Spawn ||
+		lines[0] != 2 || lines[1] != 3 || lines[2] != 4 {
+		t.Errorf("goroutine frames %+v", gerr.Frames)
+	}
+	if !strings.Contains(gerr.Error(), "\n\tstarted by go at (main):3:24") {
+		t.Errorf("goroutine error %q", gerr.Error())
+	}
+
+	data, err = json.Marshal(rerr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var decoded struct {
+		Message    string
+		Source     string
+		Stack      []struct{ Line, Column int }
+		Goroutines []struct {
+			Kind      string
+			Message   string
+			Source    string
+			Stack     []struct{ Line int }
+			Arguments []struct{ Name, Type, Value string }
+		}
+	}
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if len(decoded.Goroutines) != 1 {
+		t.Fatalf("json %s", data)
+	}
+	// the main stack is the call of wait
+	if !strings.HasPrefix(decoded.Message, "goroutine: ") || decoded.Source != "start().wait()" ||
+		len(decoded.Stack) != 1 || decoded.Stack[0].Line != 4 || decoded.Stack[0].Column != 1 {
+		t.Errorf("json %s", data)
+	}
+	g := decoded.Goroutines[0]
+	if g.Kind != "runtime" || g.Source != "work := fn(v) { return v.x }" ||
+		len(g.Stack) != 3 || len(g.Arguments) != 1 || g.Arguments[0].Type != "int" {
+		t.Errorf("json %s", data)
+	}
+}
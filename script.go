@@ -16,8 +16,16 @@ type Script struct {
 	input            []byte
 	maxAllocs        int64
 	maxConstObjects  int
+	maxGlobals       int
+	maxStackSize     int
+	maxFrames        int
 	enableFileImport bool
+	typeChecks       bool
+	noOptimize       bool
+	registerVM       bool
 	importDir        string
+	importPaths      []string
+	moduleCache      string
 }
 
 // NewScript creates a Script instance with an input script.
@@ -27,6 +35,9 @@ func NewScript(input []byte) *Script {
 		input:           input,
 		maxAllocs:       -1,
 		maxConstObjects: -1,
+		maxGlobals:      GlobalsSize,
+		maxStackSize:    StackSize,
+		maxFrames:       MaxFrames,
 	}
 }
 
@@ -68,6 +79,28 @@ func (s *Script) SetImportDir(dir string) error {
 	return nil
 }
 
+// SetImportPaths sets the directories searched for modules imported by name.
+// They are searched after the import directory and the vendor directory of
+// the project, and before the directories of the TENDER_PATH environment
+// variable.
+func (s *Script) SetImportPaths(paths ...string) error {
+	s.importPaths = make([]string, len(paths))
+	for i, path := range paths {
+		path, err := filepath.Abs(path)
+		if err != nil {
+			return err
+		}
+		s.importPaths[i] = path
+	}
+	return nil
+}
+
+// SetModuleCache sets the directory compiled file modules are cached in
+// across processes. The cache is disabled by default.
+func (s *Script) SetModuleCache(dir string) {
+	s.moduleCache = dir
+}
+
 // SetMaxAllocs sets the maximum number of objects allocations during the run
 // time. Compiled script will return ErrObjectAllocLimit error if it
 // exceeds this limit.
@@ -81,12 +114,51 @@ func (s *Script) SetMaxConstObjects(n int) {
 	s.maxConstObjects = n
 }
 
+// SetMaxGlobals sets the maximum number of global variables. Compile returns
+// an error if the script defines more. The default is GlobalsSize.
+func (s *Script) SetMaxGlobals(n int) {
+	s.maxGlobals = n
+}
+
+// SetMaxStackSize sets the maximum size of the stack of the VM. Compiled
+// script will return ErrStackOverflow error if it exceeds this limit. The
+// default is StackSize.
+func (s *Script) SetMaxStackSize(n int) {
+	s.maxStackSize = n
+}
+
+// SetMaxFrames sets the maximum depth of function calls. Compiled script will
+// return ErrStackOverflow error if it exceeds this limit. The default is
+// MaxFrames.
+func (s *Script) SetMaxFrames(n int) {
+	s.maxFrames = n
+}
+
 // EnableFileImport enables or disables module loading from local files. Local
 // file modules are disabled by default.
 func (s *Script) EnableFileImport(enable bool) {
 	s.enableFileImport = enable
 }
 
+// EnableTypeChecks enables or disables runtime checks of annotated function
+// parameter types. Type annotations are ignored by default.
+func (s *Script) EnableTypeChecks(enable bool) {
+	s.typeChecks = enable
+}
+
+// EnableOptimizer enables or disables constant folding and peephole
+// optimization. The optimizer is enabled by default.
+func (s *Script) EnableOptimizer(enable bool) {
+	s.noOptimize = !enable
+}
+
+// EnableRegisterVM enables or disables the experimental register VM. The
+// functions it can run are translated to register instructions, and the
+// others run on the stack VM. The register VM is disabled by default.
+func (s *Script) EnableRegisterVM(enable bool) {
+	s.registerVM = enable
+}
+
 // Compile compiles the script with all the defined variables, and, returns
 // Compiled object.
 func (s *Script) Compile() (*Compiled, error) {
@@ -97,7 +169,7 @@ func (s *Script) Compile() (*Compiled, error) {
 
 	fileSet := parser.NewFileSet()
 	srcFile := fileSet.AddFile("(main)", -1, len(s.input))
-	p := parser.NewParser(srcFile, s.input, nil)
+	p := parser.NewParserWithMode(srcFile, s.input, nil, parser.ParseComments)
 	file, err := p.ParseFile()
 	if err != nil {
 		return nil, err
@@ -105,13 +177,21 @@ func (s *Script) Compile() (*Compiled, error) {
 
 	c := NewCompiler(srcFile, symbolTable, nil, s.modules, nil)
 	c.EnableFileImport(s.enableFileImport)
+	c.EnableTypeChecks(s.typeChecks)
+	c.EnableOptimizer(!s.noOptimize)
 	c.SetImportDir(s.importDir)
+	c.SetImportPaths(s.importPaths...)
+	c.SetModuleCache(s.moduleCache)
 	if err := c.Compile(file); err != nil {
 		return nil, err
 	}
 
-	// reduce globals size
-	globals = globals[:symbolTable.MaxSymbols()+1]
+	// check the globals limit
+	numGlobals := symbolTable.MaxSymbols() + 1
+	if numGlobals > s.maxGlobals {
+		return nil, fmt.Errorf("exceeding globals limit: %d", numGlobals)
+	}
+	globals = append(globals, make([]Object, numGlobals-len(globals))...)
 
 	// global symbol names to indexes
 	globalIndexes := make(map[string]int, len(globals))
@@ -133,11 +213,16 @@ func (s *Script) Compile() (*Compiled, error) {
 			return nil, fmt.Errorf("exceeding constant objects limit: %d", cnt)
 		}
 	}
+	if s.registerVM {
+		bytecode.TranslateRegisters()
+	}
 	return &Compiled{
 		globalIndexes: globalIndexes,
 		bytecode:      bytecode,
 		globals:       globals,
 		maxAllocs:     s.maxAllocs,
+		maxStackSize:  s.maxStackSize,
+		maxFrames:     s.maxFrames,
 	}, nil
 }
 
@@ -179,7 +264,7 @@ func (s *Script) prepCompile() (
 		symbolTable.DefineBuiltin(idx, fn.Name)
 	}
 
-	globals = make([]Object, GlobalsSize)
+	globals = make([]Object, len(names))
 
 	for idx, name := range names {
 		symbol := symbolTable.Define(name)
@@ -199,16 +284,24 @@ type Compiled struct {
 	bytecode      *Bytecode
 	globals       []Object
 	maxAllocs     int64
+	maxStackSize  int
+	maxFrames     int
 	lock          sync.RWMutex
 }
 
+func (c *Compiled) newVM() *VM {
+	v := NewVM(c.bytecode, c.globals, c.maxAllocs)
+	v.MaxStackSize = c.maxStackSize
+	v.MaxFrames = c.maxFrames
+	return v
+}
+
 // Run executes the compiled script in the virtual machine.
 func (c *Compiled) Run() error {
 	c.lock.Lock()
 	defer c.lock.Unlock()
 
-	v := NewVM(c.bytecode, c.globals, c.maxAllocs)
-	return v.Run()
+	return c.newVM().Run()
 }
 
 // RunContext is like Run but includes a context.
@@ -216,7 +309,7 @@ func (c *Compiled) RunContext(ctx context.Context) (err error) {
 	c.lock.Lock()
 	defer c.lock.Unlock()
 
-	v := NewVM(c.bytecode, c.globals, c.maxAllocs)
+	v := c.newVM()
 	ch := make(chan error, 1)
 	go func() {
 		ch <- v.Run()
@@ -243,6 +336,8 @@ func (c *Compiled) Clone() *Compiled {
 		bytecode:      c.bytecode,
 		globals:       make([]Object, len(c.globals)),
 		maxAllocs:     c.maxAllocs,
+		maxStackSize:  c.maxStackSize,
+		maxFrames:     c.maxFrames,
 	}
 	// copy global objects
 	for idx, g := range c.globals {
@@ -324,3 +419,126 @@ func (c *Compiled) Set(name string, value interface{}) error {
 	c.globals[idx] = obj
 	return nil
 }
+
+// Call calls the script function stored in the global variable name with
+// the arguments converted by FromInterface and returns its result converted
+// by ToInterface. The script must have been run to define the function.
+// Like Run, the call can change the global variables of c.
+func (c *Compiled) Call(name string, args ...interface{}) (interface{}, error) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	fn, err := c.function(name)
+	if err != nil {
+		return nil, err
+	}
+	return callFunction(c.newVM(), fn, args)
+}
+
+// Callable returns a handle to the script function stored in the global
+// variable name. The script must have been run to define the function.
+func (c *Compiled) Callable(name string) (*Callable, error) {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+
+	fn, err := c.function(name)
+	if err != nil {
+		return nil, err
+	}
+	return &Callable{compiled: c, fn: fn}, nil
+}
+
+func (c *Compiled) function(name string) (*CompiledFunction, error) {
+	idx, ok := c.globalIndexes[name]
+	if !ok || c.globals[idx] == nil {
+		return nil, fmt.Errorf("'%s' is not defined", name)
+	}
+	fn, ok := c.globals[idx].(*CompiledFunction)
+	if !ok {
+		return nil, fmt.Errorf("'%s' is not a function: %s", name,
+			c.globals[idx].TypeName())
+	}
+	return fn, nil
+}
+
+// Callable is a handle to a function defined by a compiled script. It can
+// be called any number of times and by multiple goroutines at once: every
+// call runs on a clone of the script with a copy of the variables the
+// function captured, so changes the function makes to global and captured
+// variables are not kept. The objects the variables refer to, such as arrays
+// and maps, and the variables captured by other closures the function calls
+// are shared by the calls, so a function called concurrently must not change
+// them.
+type Callable struct {
+	compiled *Compiled
+	fn       *CompiledFunction
+}
+
+// Call calls the function with the arguments converted by FromInterface and
+// returns its result converted by ToInterface.
+func (f *Callable) Call(args ...interface{}) (interface{}, error) {
+	return callFunction(f.compiled.Clone().newVM(), f.function(), args)
+}
+
+// CallContext is like Call but aborts the function when ctx is done.
+func (f *Callable) CallContext(
+	ctx context.Context,
+	args ...interface{},
+) (res interface{}, err error) {
+	v := f.compiled.Clone().newVM()
+	fn := f.function()
+	type result struct {
+		res interface{}
+		err error
+	}
+	ch := make(chan result, 1)
+	go func() {
+		res, err := callFunction(v, fn, args)
+		ch <- result{res, err}
+	}()
+
+	select {
+	case <-ctx.Done():
+		v.Abort()
+		<-ch
+		err = ctx.Err()
+	case r := <-ch:
+		res, err = r.res, r.err
+	}
+	return
+}
+
+// function returns the function for a call, with copies of the cells of its
+// free variables.
+func (f *Callable) function() *CompiledFunction {
+	if len(f.fn.Free) == 0 {
+		return f.fn
+	}
+	fn := *f.fn
+	fn.Free = make([]*ObjectPtr, len(f.fn.Free))
+	for i, free := range f.fn.Free {
+		value := *free.Value
+		fn.Free[i] = &ObjectPtr{Value: &value}
+	}
+	return &fn
+}
+
+func callFunction(
+	v *VM,
+	fn *CompiledFunction,
+	args []interface{},
+) (interface{}, error) {
+	objs := make([]Object, len(args))
+	for i, arg := range args {
+		obj, err := FromInterface(arg)
+		if err != nil {
+			return nil, err
+		}
+		objs[i] = obj
+	}
+	res, err := v.RunCompiled(fn, objs...)
+	if err != nil {
+		return nil, err
+	}
+	return ToInterface(res), nil
+}
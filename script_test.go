@@ -0,0 +1,193 @@
+package tender
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+)
+
+func runScript(t *testing.T, src string, registers bool) *Compiled {
+	t.Helper()
+	s := NewScript([]byte(src))
+	s.EnableRegisterVM(registers)
+	c, err := s.Run()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return c
+}
+
+func TestCompiledCall(t *testing.T) {
+	src := `
+count := 0
+add := fn(a, b) { count++; return a + b }
+pair := fn(x, ...rest) { return {x: x, rest: rest} }
+scale := fn(k) { return fn(v) { return v * k } }
+triple := scale(3)
+fail := fn() { return 1 + "a" }
+nargs := fn(...args) { return len(args) }
+notfn := 5`
+	for _, registers := range []bool{false, true} {
+		c := runScript(t, src, registers)
+		tests := []struct {
+			name     string
+			args     []interface{}
+			expected string
+		}{
+			{"add", []interface{}{1, 2}, "3"},
+			{"add", []interface{}{"a", "b"}, "ab"},
+			{"pair", []interface{}{1.5, true, nil}, "map[rest:[true <nil>] x:1.5]"},
+			{"triple", []interface{}{int64(7)}, "21"},
+		}
+		for _, tc := range tests {
+			res, err := c.Call(tc.name, tc.args...)
+			if err != nil {
+				t.Errorf("%s%v: %v", tc.name, tc.args, err)
+				continue
+			}
+			if got := fmt.Sprint(res); got != tc.expected {
+				t.Errorf("%s%v = %s, expected %s", tc.name, tc.args, got,
+					tc.expected)
+			}
+		}
+		// calls through Compiled keep changes to globals
+		if got := c.Get("count").Int(); got != 2 {
+			t.Errorf("count = %d, expected 2", got)
+		}
+
+		for name, expected := range map[string]string{
+			"missing": "'missing' is not defined",
+			"notfn":   "'notfn' is not a function: int",
+		} {
+			if _, err := c.Call(name); err == nil || err.Error() != expected {
+				t.Errorf("%s: error %v, expected %s", name, err, expected)
+			}
+		}
+		if _, err := c.Call("fail"); err == nil {
+			t.Error("fail: expected a runtime error")
+		}
+		if _, err := c.Call("add", 1); err == nil {
+			t.Error("add(1): expected a wrong number of arguments error")
+		}
+
+		// the arguments do not fit the initial stack
+		args := make([]interface{}, maxRunCompiledArgs+1)
+		for _, n := range []int{100, maxRunCompiledArgs} {
+			if res, err := c.Call("nargs", args[:n]...); err != nil || res != int64(n) {
+				t.Errorf("nargs with %d arguments = %v, %v", n, res, err)
+			}
+		}
+		if _, err := c.Call("nargs", args...); !errors.Is(err, ErrWrongNumArguments) {
+			t.Errorf("nargs with %d arguments: error %v, expected %v",
+				len(args), err, ErrWrongNumArguments)
+		}
+	}
+}
+
+func TestCallable(t *testing.T) {
+	c := runScript(t, `
+calls := 0
+fib := fn(n) { calls++; if n < 2 { return n }; return fib(n - 1) + fib(n - 2) }
+wait := fn() { for {} }
+next := fn() { n := 0; return fn() { n++; return n } }()`, false)
+	fib, err := c.Callable("fib")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 16)
+	for i := 0; i < 16; i++ {
+		wg.Add(1)
+		go func(n int) {
+			defer wg.Done()
+			res, err := fib.Call(n)
+			if err != nil {
+				errs <- err
+				return
+			}
+			var a, b int64 = 0, 1
+			for j := 0; j < n; j++ {
+				a, b = b, a+b
+			}
+			if res != a {
+				errs <- fmt.Errorf("fib(%d) = %v, expected %d", n, res, a)
+			}
+		}(i)
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Error(err)
+	}
+	// calls through a Callable run on clones of the script
+	if got := c.Get("calls").Int(); got != 0 {
+		t.Errorf("calls = %d, expected 0", got)
+	}
+
+	// and with copies of the variables the function captured
+	next, err := c.Callable("next")
+	if err != nil {
+		t.Fatal(err)
+	}
+	wg = sync.WaitGroup{}
+	for i := 0; i < 16; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if res, err := next.Call(); err != nil || res != int64(1) {
+				t.Errorf("next() = %v, %v, expected 1", res, err)
+			}
+		}()
+	}
+	wg.Wait()
+	if res, err := c.Call("next"); err != nil || res != int64(1) {
+		t.Errorf("next() = %v, %v, expected 1", res, err)
+	}
+
+	wait, err := c.Callable("wait")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(),
+		10*time.Millisecond)
+	defer cancel()
+	if _, err := wait.CallContext(ctx); err != context.DeadlineExceeded {
+		t.Errorf("error %v, expected %v", err, context.DeadlineExceeded)
+	}
+
+	if _, err := c.Callable("calls"); err == nil {
+		t.Error("expected an error for a non-function global")
+	}
+}
+
+func BenchmarkCallable(b *testing.B) {
+	s := NewScript([]byte(`
+add := fn(a, b) { return a + b }
+scale := fn(k) { return fn(v) { return v * k } }(3)`))
+	c, err := s.Run()
+	if err != nil {
+		b.Fatal(err)
+	}
+	for _, name := range []string{"add", "scale"} {
+		fn, err := c.Callable(name)
+		if err != nil {
+			b.Fatal(err)
+		}
+		args := []interface{}{2, 3}[:fn.fn.NumParameters]
+		b.Run(name, func(b *testing.B) {
+			b.ReportAllocs()
+			b.RunParallel(func(pb *testing.PB) {
+				for pb.Next() {
+					if _, err := fn.Call(args...); err != nil {
+						b.Error(err)
+						return
+					}
+				}
+			})
+		})
+	}
+}
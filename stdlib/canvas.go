@@ -30,247 +30,171 @@ func ggNewContext(args ...tender.Object) (ret tender.Object, err error) {
 	return makeGGContext(dc), nil
 }
 
-func makeGGContext(ctx *gg.Context) *tender.ImmutableMap {
-	return &tender.ImmutableMap{
-		Value: map[string]tender.Object{
-			"drawimage": &tender.UserFunction{
-				Value: func(args ...tender.Object) (tender.Object, error) {
-					if len(args) != 3 {
-						return nil, tender.ErrWrongNumArguments
-					}
-					imageBytes, _ := tender.ToByteSlice(args[0])
-					ix, _ := tender.ToInt(args[1])
-					iy, _ := tender.ToInt(args[2])
-					img, _, err := image.Decode(bytes.NewReader(imageBytes))
-					if err != nil {
-						return wrapError(err), nil
-					}
-					ctx.DrawImage(img, ix, iy)
-					return nil, nil
-				},
-			},	
-			"drawimage_anchored": &tender.UserFunction{
-				Value: func(args ...tender.Object) (tender.Object, error) {
-					if len(args) != 5 {
-						return nil, tender.ErrWrongNumArguments
-					}
-					imageBytes, _ := tender.ToByteSlice(args[0])
-					ix, _ := tender.ToInt(args[1])
-					iy, _ := tender.ToInt(args[2])	
-					fx, _ := tender.ToFloat64(args[3])
-					fy, _ := tender.ToFloat64(args[4])
-					img, _, err := image.Decode(bytes.NewReader(imageBytes))
-					if err != nil {
-						return wrapError(err), nil
-					}
-					ctx.DrawImageAnchored(img, ix, iy, fx, fy)
-					return nil, nil
-				},
-			},	
-			"save_png": &tender.UserFunction{
-				Value: FuncASRE(ctx.SavePNG),
-			},	
-			"point": &tender.UserFunction{
-				Value: FuncAFFFR(ctx.DrawPoint),
-			},	
-			"line": &tender.UserFunction{
-				Value: FuncAFFFFR(ctx.DrawLine),
-			},	
-			"rect": &tender.UserFunction{
-				Value: FuncAFFFFR(ctx.DrawRectangle),
-			},
-			"polygon": &tender.UserFunction{
-				Value: func(args ...tender.Object) (tender.Object, error) {
-					if len(args) != 5 {
-						return nil, tender.ErrWrongNumArguments
-					}
-					i0, _ := tender.ToInt(args[0])
-					f1, _ := tender.ToFloat64(args[1])
-					f2, _ := tender.ToFloat64(args[2])
-					f3, _ := tender.ToFloat64(args[3])
-					f4, _ := tender.ToFloat64(args[4])
-					ctx.DrawRegularPolygon(i0, f1, f2, f3, f4)
-					return nil, nil
-				},
-			},	
-			"roundrect": &tender.UserFunction{
-				Value: FuncAFFFFFR(ctx.DrawRoundedRectangle),
-			},
-			"circle": &tender.UserFunction{
-				Value: FuncAFFFR(ctx.DrawCircle),
-			},	
-			"arc": &tender.UserFunction{
-				Value: FuncAFFFFFR(ctx.DrawArc),
-			},
-			"ellipse": &tender.UserFunction{
-				Value: FuncAFFFFR(ctx.DrawEllipse),
-			},
-			"ellipsearc": &tender.UserFunction{
-				Value: FuncAFFFFFFR(ctx.DrawEllipticalArc),
-			},
-			"set_pixel": &tender.UserFunction{
-				Name:  "set_pixel",
-				Value: FuncAIIR(ctx.SetPixel),
-			},	
-			"rgb": &tender.UserFunction{
-				Value: FuncAFFFR(ctx.SetRGB),
-			},
-			"rgba": &tender.UserFunction{
-				Value: FuncAFFFFR(ctx.SetRGBA),
-			},	
-			"rgba255": &tender.UserFunction{
-				Value: FuncAIIIIR(ctx.SetRGBA255),
-			},	
-			"rgb255": &tender.UserFunction{
-				Value: FuncAIIIR(ctx.SetRGB255),
-			},
-			"hex": &tender.UserFunction{
-				Value: FuncASR(ctx.SetHexColor),
-			},
-			"linewidth": &tender.UserFunction{
-				Value: FuncAFR(ctx.SetLineWidth),
-			},	
-			"dashoffset": &tender.UserFunction{
-				Value: FuncAFR(ctx.SetDashOffset),
-			},
-			"dash": &tender.UserFunction{
-				Value: func(args ...tender.Object) (tender.Object, error) {
-					if len(args) < 1 {
-						return nil, tender.ErrWrongNumArguments
-					}
-					elements := make([]float64, len(args))
-					for i, arg := range args {
-						s, _ := tender.ToFloat64(arg)
-						elements[i] = s
-					}
-					ctx.SetDash(elements...)
-					return &tender.Null{}, nil
-				},
-			},	
-			"move_to": &tender.UserFunction{
-				Value: FuncAFFR(ctx.MoveTo),
-			},	
-			"line_to": &tender.UserFunction{
-				Value: FuncAFFR(ctx.LineTo),
-			},	
-			"quadratic_to": &tender.UserFunction{
-				Value: FuncAFFFFR(ctx.QuadraticTo),
-			},	
-			"cubic_to": &tender.UserFunction{
-				Value: FuncAFFFFFFR(ctx.CubicTo),
-			},
-			"closepath": &tender.UserFunction{
-				Value: FuncAR(ctx.ClosePath),
-			},	
-			"clearpath": &tender.UserFunction{
-				Value: FuncAR(ctx.ClearPath),
-			},	
-			"newsubpath": &tender.UserFunction{
-				Value: FuncAR(ctx.NewSubPath),
-			},	
-			"clear": &tender.UserFunction{
-				Value: FuncAR(ctx.Clear),
-			},
-			"stroke": &tender.UserFunction{
-				Value: FuncAR(ctx.Stroke),
-			},	
-			"fill": &tender.UserFunction{
-				Value: FuncAR(ctx.Fill),
-			},		
-			"stroke_preserve": &tender.UserFunction{
-				Value: FuncAR(ctx.StrokePreserve),
-			},	
-			"fill_preserve": &tender.UserFunction{
-				Value: FuncAR(ctx.FillPreserve),
-			},	
-			"text": &tender.UserFunction{
-				Value: FuncASFFR(ctx.DrawString),
-			},	
-			"text_anchored": &tender.UserFunction{
-				Value: FuncASFFFFR(ctx.DrawStringAnchored),
-			},	
-			"measure_text": &tender.UserFunction{
-				Value: FuncASRFF(ctx.MeasureString),
-			},	
-			"measure_multiline_text": &tender.UserFunction{
-				Value: FuncASFRFF(ctx.MeasureMultilineString),
-			},	
-			"load_fontface": &tender.UserFunction{
-				Value: FuncASFRE(ctx.LoadFontFace),
-			},	
-			"fontface": &tender.UserFunction{
-				Value: FuncAYFRE(ctx.FontFace),
-			},	
-			"fontheight": &tender.UserFunction{
-				Value: FuncARF(ctx.FontHeight),
-			},	
-			"identity": &tender.UserFunction{
-				Name:  "identity",
-				Value: FuncAR(ctx.Identity),
-			},	
-			"translate": &tender.UserFunction{
-				Value: FuncAFFR(ctx.Translate),
-			},	
-			"scale": &tender.UserFunction{
-				Value: FuncAFFR(ctx.Scale),
-			},	
-			"rotate": &tender.UserFunction{
-				Value: FuncAFR(ctx.Rotate),
-			},	
-			"shear": &tender.UserFunction{
-				Value: FuncAFFR(ctx.Shear),
-			},
-			"scaleabout": &tender.UserFunction{
-				Value: FuncAFFFFR(ctx.ScaleAbout),
-			},	
-			"rotateabout": &tender.UserFunction{
-				Value: FuncAFFFR(ctx.RotateAbout),
-			},
-			"shearabout": &tender.UserFunction{
-				Value: FuncAFFFFR(ctx.ShearAbout),
-			},	
-			"transform_point": &tender.UserFunction{
-				Value: FuncAFFRFF(ctx.TransformPoint),
-			},
-			"invertmask": &tender.UserFunction{
-				Value: FuncAR(ctx.InvertMask),
-			},	
-			"inverty": &tender.UserFunction{
-				Value: FuncAR(ctx.InvertY),
-			},	
-			"push": &tender.UserFunction{
-				Value: FuncAR(ctx.Push),
-			},	
-			"pop": &tender.UserFunction{
-				Value: FuncAR(ctx.Pop),
-			},	
-			"clip": &tender.UserFunction{
-				Value: FuncAR(ctx.Clip),
-			},		
-			"clip_preserve": &tender.UserFunction{
-				Value: FuncAR(ctx.ClipPreserve),
-			},	
-			"resetclip": &tender.UserFunction{
-				Value: FuncAR(ctx.ResetClip),
-			},
-			"height": &tender.UserFunction{
-				Value: FuncARI(ctx.Height),
-			},	
-			"width": &tender.UserFunction{
-				Value: FuncARI(ctx.Width),
-			},	
-			"wordwrap": &tender.UserFunction{
-				Value: FuncASFRSs(ctx.WordWrap),
-			},
-			"image": &tender.UserFunction{
-				Value: func(args ...tender.Object) (tender.Object, error) {
-					if len(args) != 0 {
-						return nil, tender.ErrWrongNumArguments
-					}
-					return makeImage(ctx.Image()), nil
-				},
-			},	
-		},
+// ggContextMethods is the method table of canvas contexts.
+var ggContextMethods *tender.MethodTable
+
+func init() {
+	ggContextMethods = tender.NewMethodTable("canvas-context", map[string]tender.MethodFunc{
+		"drawimage": ggMethod(func(ctx *gg.Context, args ...tender.Object) (tender.Object, error) {
+			if len(args) != 3 {
+				return nil, tender.ErrWrongNumArguments
+			}
+			imageBytes, _ := tender.ToByteSlice(args[0])
+			ix, _ := tender.ToInt(args[1])
+			iy, _ := tender.ToInt(args[2])
+			img, _, err := image.Decode(bytes.NewReader(imageBytes))
+			if err != nil {
+				return wrapError(err), nil
+			}
+			ctx.DrawImage(img, ix, iy)
+			return nil, nil
+		}),	
+		"drawimage_anchored": ggMethod(func(ctx *gg.Context, args ...tender.Object) (tender.Object, error) {
+			if len(args) != 5 {
+				return nil, tender.ErrWrongNumArguments
+			}
+			imageBytes, _ := tender.ToByteSlice(args[0])
+			ix, _ := tender.ToInt(args[1])
+			iy, _ := tender.ToInt(args[2])	
+			fx, _ := tender.ToFloat64(args[3])
+			fy, _ := tender.ToFloat64(args[4])
+			img, _, err := image.Decode(bytes.NewReader(imageBytes))
+			if err != nil {
+				return wrapError(err), nil
+			}
+			ctx.DrawImageAnchored(img, ix, iy, fx, fy)
+			return nil, nil
+		}),	
+		"save_png": MethodASRE((*gg.Context).SavePNG),	
+		"point": MethodAFFFR((*gg.Context).DrawPoint),	
+		"line": MethodAFFFFR((*gg.Context).DrawLine),	
+		"rect": MethodAFFFFR((*gg.Context).DrawRectangle),
+		"polygon": ggMethod(func(ctx *gg.Context, args ...tender.Object) (tender.Object, error) {
+			if len(args) != 5 {
+				return nil, tender.ErrWrongNumArguments
+			}
+			i0, _ := tender.ToInt(args[0])
+			f1, _ := tender.ToFloat64(args[1])
+			f2, _ := tender.ToFloat64(args[2])
+			f3, _ := tender.ToFloat64(args[3])
+			f4, _ := tender.ToFloat64(args[4])
+			ctx.DrawRegularPolygon(i0, f1, f2, f3, f4)
+			return nil, nil
+		}),	
+		"roundrect": MethodAFFFFFR((*gg.Context).DrawRoundedRectangle),
+		"circle": MethodAFFFR((*gg.Context).DrawCircle),	
+		"arc": MethodAFFFFFR((*gg.Context).DrawArc),
+		"ellipse": MethodAFFFFR((*gg.Context).DrawEllipse),
+		"ellipsearc": MethodAFFFFFFR((*gg.Context).DrawEllipticalArc),
+		"set_pixel": MethodAIIR((*gg.Context).SetPixel),	
+		"rgb": MethodAFFFR((*gg.Context).SetRGB),
+		"rgba": MethodAFFFFR((*gg.Context).SetRGBA),	
+		"rgba255": MethodAIIIIR((*gg.Context).SetRGBA255),	
+		"rgb255": MethodAIIIR((*gg.Context).SetRGB255),
+		"hex": MethodASR((*gg.Context).SetHexColor),
+		"linewidth": MethodAFR((*gg.Context).SetLineWidth),	
+		"dashoffset": MethodAFR((*gg.Context).SetDashOffset),
+		"dash": ggMethod(func(ctx *gg.Context, args ...tender.Object) (tender.Object, error) {
+			if len(args) < 1 {
+				return nil, tender.ErrWrongNumArguments
+			}
+			elements := make([]float64, len(args))
+			for i, arg := range args {
+				s, _ := tender.ToFloat64(arg)
+				elements[i] = s
+			}
+			ctx.SetDash(elements...)
+			return &tender.Null{}, nil
+		}),	
+		"move_to": MethodAFFR((*gg.Context).MoveTo),	
+		"line_to": MethodAFFR((*gg.Context).LineTo),	
+		"quadratic_to": MethodAFFFFR((*gg.Context).QuadraticTo),	
+		"cubic_to": MethodAFFFFFFR((*gg.Context).CubicTo),
+		"closepath": MethodAR((*gg.Context).ClosePath),	
+		"clearpath": MethodAR((*gg.Context).ClearPath),	
+		"newsubpath": MethodAR((*gg.Context).NewSubPath),	
+		"clear": MethodAR((*gg.Context).Clear),
+		"stroke": MethodAR((*gg.Context).Stroke),	
+		"fill": MethodAR((*gg.Context).Fill),		
+		"stroke_preserve": MethodAR((*gg.Context).StrokePreserve),	
+		"fill_preserve": MethodAR((*gg.Context).FillPreserve),	
+		"text": MethodASFFR((*gg.Context).DrawString),	
+		"text_anchored": MethodASFFFFR((*gg.Context).DrawStringAnchored),	
+		"measure_text": MethodASRFF((*gg.Context).MeasureString),	
+		"measure_multiline_text": MethodASFRFF((*gg.Context).MeasureMultilineString),	
+		"load_fontface": ggMethod(ggLoadFontFace),	
+		"fontface": MethodAYFRE((*gg.Context).FontFace),	
+		"fontheight": MethodARF((*gg.Context).FontHeight),	
+		"identity": MethodAR((*gg.Context).Identity),	
+		"translate": MethodAFFR((*gg.Context).Translate),	
+		"scale": MethodAFFR((*gg.Context).Scale),	
+		"rotate": MethodAFR((*gg.Context).Rotate),	
+		"shear": MethodAFFR((*gg.Context).Shear),
+		"scaleabout": MethodAFFFFR((*gg.Context).ScaleAbout),	
+		"rotateabout": MethodAFFFR((*gg.Context).RotateAbout),
+		"shearabout": MethodAFFFFR((*gg.Context).ShearAbout),	
+		"transform_point": MethodAFFRFF((*gg.Context).TransformPoint),
+		"invertmask": MethodAR((*gg.Context).InvertMask),	
+		"inverty": MethodAR((*gg.Context).InvertY),	
+		"push": MethodAR((*gg.Context).Push),	
+		"pop": MethodAR((*gg.Context).Pop),	
+		"clip": MethodAR((*gg.Context).Clip),		
+		"clip_preserve": MethodAR((*gg.Context).ClipPreserve),	
+		"resetclip": MethodAR((*gg.Context).ResetClip),
+		"height": MethodARI((*gg.Context).Height),	
+		"width": MethodARI((*gg.Context).Width),	
+		"wordwrap": MethodASFRSs((*gg.Context).WordWrap),
+		"image": ggMethod(func(ctx *gg.Context, args ...tender.Object) (tender.Object, error) {
+			if len(args) != 0 {
+				return nil, tender.ErrWrongNumArguments
+			}
+			return makeImage(ctx.Image()), nil
+		}),
+	}, nil)
+}
+
+// ggLoadFontFace is the load_fontface method, which loads a font file from
+// the disk or from an embedded file system.
+func ggLoadFontFace(ctx *gg.Context, args ...tender.Object) (tender.Object, error) {
+	if len(args) != 2 && len(args) != 3 {
+		return nil, tender.ErrWrongNumArguments
+	}
+	path, ok := tender.ToString(args[0])
+	if !ok {
+		return nil, tender.ErrInvalidArgumentType{
+			Name:     "first",
+			Expected: "string(compatible)",
+			Found:    args[0].TypeName(),
+		}
+	}
+	points, ok := tender.ToFloat64(args[1])
+	if !ok {
+		return nil, tender.ErrInvalidArgumentType{
+			Name:     "second",
+			Expected: "float(compatible)",
+			Found:    args[1].TypeName(),
+		}
+	}
+	fsys, err := fsArg(args, 2)
+	if err != nil {
+		return nil, err
 	}
+	data, err := readFile(fsys, path)
+	if err != nil {
+		return wrapError(err), nil
+	}
+	return wrapError(ctx.FontFace(data, points)), nil
+}
+
+// ggMethod returns a method of canvas contexts from a function that takes
+// the context with the arguments of the call.
+func ggMethod(fn func(ctx *gg.Context, args ...tender.Object) (tender.Object, error)) tender.MethodFunc {
+	return func(recv interface{}, args ...tender.Object) (tender.Object, error) {
+		return fn(recv.(*gg.Context), args...)
+	}
+}
+
+func makeGGContext(ctx *gg.Context) tender.Object {
+	return ggContextMethods.New(ctx)
 }
 
@@ -0,0 +1,429 @@
+package stdlib
+
+import (
+	"fmt"
+
+	"github.com/2dprototype/tender"
+)
+
+// ModuleDocs are the descriptions of the builtin modules, used by "tender doc".
+var ModuleDocs = map[string]string{
+	"base64":    "The `base64` module provides functions for base64 encoding and decoding.",
+	"bufio":     "The `bufio` module provides functions for buffered I/O operations, particularly useful for reading input from standard input (stdin).",
+	"canvas":    "The `canvas` module provides functionalities for creating and manipulating graphical elements.",
+	"cmplx":     "The `cmplx` module provides a suite of functions for performing operations on complex numbers. These functions allow you to create, manipulate, and compute advanced mathematical expressions using complex arithmetic.",
+	"colors":    "The `colors` module provides functionality for printing colored and styled text to the terminal. It includes utilities for writing to standard output and error, as well as applying rich text styles.",
+	"crypto":    "The `crypto` module provides comprehensive cryptographic functionalities including hashing, encryption/decryption, digital signatures, key generation, and secure random number generation.",
+	"csv":       "The `csv` module provides functions for encoding and decoding CSV data.",
+	"fmt":       "The `fmt` module provides functions for formatted I/O operations similar to those in the standard Go `fmt` package.",
+	"gob":       "The `gob` module provides functions for encoding values into bytes and decoding them with the Go gob format.",
+	"gzip":      "The `gzip` module provides functions for compressing and decompressing data using the gzip compression format.",
+	"hex":       "The `hex` module provides functions for encoding and decoding data using hexadecimal representation, as well as for generating a hex dump of the data.",
+	"http":      "The `http` module provides functionalities for making HTTP requests. This module supports various HTTP methods including GET, POST, PUT, DELETE, PATCH, OPTIONS, HEAD, and TRACE.",
+	"image":     "The `image` module provides comprehensive functionalities for working with images, including loading, decoding, creating new images, applying filters, and encoding images into various formats.",
+	"io":        "The `io` module provides functions for reading from and writing to files.",
+	"json":      "The `json` module provides functionalities for playing with json!",
+	"math":      "The `math` module provides mathematical functions and constants for performing various calculations.",
+	"net":       "The `net` module provides functionalities for network communication, including DNS lookup, TCP and UDP address resolution, and various methods for establishing network connections.",
+	"os":        "The `os` module provides functions for operating system functionality.",
+	"path":      "The `path` module provides functions for manipulating file paths and performing operations related to file paths.",
+	"rand":      "The `rand` module provides functions for generating random numbers and sequences, seeding the random number generator, and creating custom random number generators.",
+	"strings":   "The `strings` module provides functionalities for manipulating strings.",
+	"tar":       "The `tar` module provides functions for creating and reading TAR archives.",
+	"times":     "The `times` module provides functions and constants for working with time-related operations.",
+	"websocket": "The `websocket` module provides functionalities for establishing and managing WebSocket connections using the `gorilla/websocket` library.",
+	"xml":       "The `xml` module provides comprehensive functionality for parsing, generating, and manipulating XML data. It supports both document-style and data-oriented XML processing with flexible object mapping.",
+	"zip":       "The `zip` module provides functions for creating and reading ZIP archives.",
+}
+
+// funcDocs are the usages and descriptions of the functions of the builtin
+// modules. They are attached to the functions by init, and shown by "tender
+// doc" and the help builtin function. Optional parameters are in brackets,
+// and the usages must match the arities of the functions and their
+// ModuleSignatures, which TestFuncDocs checks.
+var funcDocs = map[string]map[string][2]string{
+	"base64": {
+		"decode":         {"decode(s)", "Returns the bytes represented by the base64 string s."},
+		"encode":         {"encode(src)", "Returns the base64 encoding of src."},
+		"raw_decode":     {"raw_decode(s)", "Returns the bytes represented by the base64 string s which omits the padding."},
+		"raw_encode":     {"raw_encode(src)", "Returns the base64 encoding of src but omits the padding."},
+		"raw_url_decode": {"raw_url_decode(s)", "Returns the bytes represented by the url-base64 string s which omits the padding."},
+		"raw_url_encode": {"raw_url_encode(src)", "Returns the url-base64 encoding of src but omits the padding."},
+		"url_decode":     {"url_decode(s)", "Returns the bytes represented by the url-base64 string s."},
+		"url_encode":     {"url_encode(src)", "Returns the url-base64 encoding of src."},
+	},
+	"bufio": {
+		"readbytes":  {"readbytes(num_bytes)", "Reads a specified number of bytes from standard input."},
+		"readline":   {"readline()", "Reads a line of text from standard input until a newline character (`\"\\n\"`) is encountered."},
+		"readstring": {"readstring(delimiter)", "Reads a string from standard input until the specified delimiter character is encountered."},
+	},
+	"canvas": {
+		"degrees":     {"degrees(radians)", "Converts radians to degrees."},
+		"load_image":  {"load_image(path[, fs])", "Loads an image from the specified file path, or from the file system `fs` created with `embed fs(...)` if given."},
+		"new_context": {"new_context(width, height)", "Creates a new canvas context with the specified width and height."},
+		"radians":     {"radians(degrees)", "Converts degrees to radians."},
+	},
+	"cmplx": {
+		"abs":   {"abs(c)", "Returns the modulus (absolute value) of the complex number `c`."},
+		"acos":  {"acos(c)", "Returns the arc cosine (inverse cosine) of the complex number `c`."},
+		"acosh": {"acosh(c)", "Returns the hyperbolic arc cosine of the complex number `c`."},
+		"arg":   {"arg(c)", "Returns the phase (argument) of the complex number `c` in radians."},
+		"asin":  {"asin(c)", "Returns the arc sine (inverse sine) of the complex number `c`."},
+		"asinh": {"asinh(c)", "Returns the hyperbolic arc sine of the complex number `c`."},
+		"atan":  {"atan(c)", "Returns the arc tangent (inverse tangent) of the complex number `c`."},
+		"atanh": {"atanh(c)", "Returns the hyperbolic arc tangent of the complex number `c`."},
+		"conj":  {"conj(c)", "Returns the complex conjugate of the complex number `c`."},
+		"cos":   {"cos(c)", "Returns the cosine of the complex number `c`."},
+		"cosh":  {"cosh(c)", "Returns the hyperbolic cosine of the complex number `c`."},
+		"cot":   {"cot(c)", "Returns the cotangent of the complex number `c` (defined as 1/tan(c))."},
+		"exp":   {"exp(c)", "Returns the exponential function (e^c) of the complex number `c`."},
+		"inf":   {"inf()", "Returns an infinite complex number."},
+		"isinf": {"isinf(c)", "Reports whether the complex number `c` is infinite; returns `true` if it is, otherwise `false`."},
+		"isnan": {"isnan(c)", "Reports whether the complex number `c` is NaN (not a number); returns `true` if it is, otherwise `false`."},
+		"log":   {"log(c)", "Returns the natural logarithm of the complex number `c`."},
+		"log10": {"log10(c)", "Returns the base-10 logarithm of the complex number `c`."},
+		"nan":   {"nan()", "Returns a complex number representing NaN."},
+		"new":   {"new(real, imag)", "Creates a new complex number from the given real and imaginary parts."},
+		"phase": {"phase(c)", "Alias for `arg(c)`; returns the phase (argument) of the complex number `c`."},
+		"polar": {"polar(c)", "Returns the polar coordinates of the complex number `c` as a map with keys: - `r`: The modulus (absolute value) - `theta`: The angle in radians."},
+		"pow":   {"pow(x, y)", "Returns the result of raising the complex number `x` to the power of complex number `y`."},
+		"rect":  {"rect(r, theta)", "Returns the complex number corresponding to the given polar coordinates `r` and `theta`."},
+		"sin":   {"sin(c)", "Returns the sine of the complex number `c`."},
+		"sinh":  {"sinh(c)", "Returns the hyperbolic sine of the complex number `c`."},
+		"sqrt":  {"sqrt(c)", "Returns the square root of the complex number `c`."},
+		"tan":   {"tan(c)", "Returns the tangent of the complex number `c`."},
+		"tanh":  {"tanh(c)", "Returns the hyperbolic tangent of the complex number `c`."},
+	},
+	"colors": {
+		"stderr": {"stderr() => IOWriter", "Returns an `IOWriter` that writes to **standard error** (`stderr`) with color support."},
+		"stdout": {"stdout() => IOWriter", "Returns an `IOWriter` that writes to **standard output** (`stdout`) with color support."},
+		"style":  {"style(text: string, ...props: map) => string", "Applies styles to a string and returns a formatted string."},
+	},
+	"crypto": {
+		"bcrypt":                {"bcrypt(password, cost)", "Hashes password using bcrypt."},
+		"blake2b_256":           {"blake2b_256(input)", "Generates a BLAKE2b-256 hash for the given input."},
+		"blake2b_512":           {"blake2b_512(input)", "Generates a BLAKE2b-512 hash for the given input."},
+		"constant_time_compare": {"constant_time_compare(a, b)", "Compares two values in constant time to prevent timing attacks."},
+		"md5":                   {"md5(input)", "Generates an MD5 hash for the given input."},
+		"pbkdf2":                {"pbkdf2(password, salt, iterations, key_len, hash_func)", "Derives key using PBKDF2."},
+		"scrypt":                {"scrypt(password, salt, key_len, N, r, p)", "Derives key using scrypt."},
+		"sha1":                  {"sha1(input)", "Generates a SHA-1 hash for the given input."},
+		"sha224":                {"sha224(input)", "Generates a SHA-224 hash for the given input."},
+		"sha256":                {"sha256(input)", "Generates a SHA-256 hash for the given input."},
+		"sha384":                {"sha384(input)", "Generates a SHA-384 hash for the given input."},
+		"sha3_224":              {"sha3_224(input)", "Generates a SHA3-224 hash for the given input."},
+		"sha3_256":              {"sha3_256(input)", "Generates a SHA3-256 hash for the given input."},
+		"sha3_384":              {"sha3_384(input)", "Generates a SHA3-384 hash for the given input."},
+		"sha3_512":              {"sha3_512(input)", "Generates a SHA3-512 hash for the given input."},
+		"sha512":                {"sha512(input)", "Generates a SHA-512 hash for the given input."},
+	},
+	"csv": {
+		"decode": {"decode(s string) => [[string]]/error", "Parses the CSV string s and returns its records as arrays of fields."},
+		"encode": {"encode(records [[string]]) => string/error", "Returns the CSV string of the records, which are arrays of fields."},
+	},
+	"fmt": {
+		"fprint":   {"fprint(IOWriter, args...)", "Prints the arguments to standard output without a newline using IOWriter."},
+		"fprintln": {"fprintln(IOWriter, args...)", "Prints the arguments to standard output with a newline using IOWriter."},
+		"print":    {"print(args...)", "Prints the arguments to standard output without a newline."},
+		"printf":   {"printf(format, args...)", "Prints the formatted string to standard output."},
+		"println":  {"println(args...)", "Prints the arguments to standard output with a newline."},
+		"scanln":   {"scanln()", "Reads a line from standard input."},
+		"sprintf":  {"sprintf(format, args...)", "Returns a formatted string."},
+	},
+	"gob": {
+		"decode": {"decode(b bytes) => object", "Decodes a value encoded by `encode`."},
+		"encode": {"encode(o object) => bytes", "Encodes a value into bytes with the Go gob format."},
+	},
+	"gzip": {
+		"compress":   {"compress(data)", "Compresses the input data using gzip compression and returns the compressed data as a byte slice."},
+		"decompress": {"decompress(data)", "Decompresses the input data using gzip decompression and returns the decompressed data as a byte slice."},
+	},
+	"hex": {
+		"decode": {"decode(s)", "Returns the bytes represented by the hexadecimal string s."},
+		"dump":   {"dump(src)", "Returns a `string` containing the hex dump of the input data."},
+		"encode": {"encode(src)", "Returns the hexadecimal encoding of src."},
+	},
+	"http": {
+		"delete":  {"delete(url, [body], [headers])", "Sends an HTTP DELETE request to the specified URL."},
+		"get":     {"get(url, [body], [headers])", "Sends an HTTP GET request to the specified URL."},
+		"head":    {"head(url, [body], [headers])", "Sends an HTTP HEAD request to the specified URL."},
+		"options": {"options(url, [body], [headers])", "Sends an HTTP OPTIONS request to the specified URL."},
+		"patch":   {"patch(url, [body], [headers])", "Sends an HTTP PATCH request to the specified URL."},
+		"post":    {"post(url, [body], [headers])", "Sends an HTTP POST request to the specified URL."},
+		"put":     {"put(url, [body], [headers])", "Sends an HTTP PUT request to the specified URL."},
+		"trace":   {"trace(url, [body], [headers])", "Sends an HTTP TRACE request to the specified URL."},
+	},
+	"image": {
+		"decode": {"decode(image_data)", "Decodes image data into an image object."},
+		"load":   {"load(path[, fs])", "Loads an image from the specified file path, or from the file system `fs` created with `embed fs(...)` if given."},
+		"new":    {"new(width, height)", "Creates a new image with the specified width and height."},
+	},
+	"io": {
+		"read_all":  {"read_all(r io.reader) => bytes/error", "Reads from r until an error or EOF and returns the data it read."},
+		"read_full": {"read_full(r io.reader, buf bytes) => int/error", "Reads exactly len(buf) bytes from r into buf, and returns the number of bytes read."},
+		"readfile":  {"readfile(path)", "Reads the contents of the file specified by the `path` parameter and returns it as a string."},
+		"writefile": {"writefile(path, content[, mode])", "Writes the `content` to the file specified by the `path` parameter. The optional `mode` parameter specifies the file mode (permission and mode bits). If not provided, the default mode is `0644`."},
+	},
+	"json": {
+		"decode":      {"decode(b string/bytes) => object", "Parses the JSON string and returns an object."},
+		"encode":      {"encode(o object) => bytes", "Returns the JSON string (bytes) of the object. Unlike Go's JSON package, this function does not HTML-escape texts, but, one can use `html_escape` function if needed."},
+		"html_escape": {"html_escape(b string/bytes) => bytes", "Return an HTML-safe form of input JSON bytes string."},
+		"indent":      {"indent(b string/bytes) => bytes", "Returns an indented form of input JSON bytes string."},
+	},
+	"math": {
+		"abs":       {"abs(x)", "Returns the absolute value of x."},
+		"acos":      {"acos(x)", "Returns the arccosine of x in radians."},
+		"acosh":     {"acosh(x)", "Returns the inverse hyperbolic cosine of x."},
+		"asin":      {"asin(x)", "Returns the arcsine of x in radians."},
+		"asinh":     {"asinh(x)", "Returns the inverse hyperbolic sine of x."},
+		"atan":      {"atan(x)", "Returns the arctangent of x in radians."},
+		"atan2":     {"atan2(y, x)", "Returns the arctangent of y/x in radians, using the signs of both parameters to determine the quadrant of the result."},
+		"atanh":     {"atanh(x)", "Returns the inverse hyperbolic tangent of x."},
+		"cbrt":      {"cbrt(x)", "Returns the cube root of x."},
+		"ceil":      {"ceil(x)", "Returns the smallest integer value greater than or equal to x."},
+		"copysign":  {"copysign(x, y)", "Returns x with the sign of y."},
+		"cos":       {"cos(x)", "Returns the cosine of x (x is in radians)."},
+		"cosh":      {"cosh(x)", "Returns the hyperbolic cosine of x."},
+		"dim":       {"dim(x, y)", "Returns the maximum of x and y."},
+		"erf":       {"erf(x)", "Returns the error function of x."},
+		"erfc":      {"erfc(x)", "Returns the complementary error function of x."},
+		"exp":       {"exp(x)", "Returns e^x, where e is Euler's number."},
+		"exp2":      {"exp2(x)", "Returns 2 raised to the power of x."},
+		"expm1":     {"expm1(x)", "Returns e^x - 1."},
+		"floor":     {"floor(x)", "Returns the largest integer value less than or equal to x."},
+		"gamma":     {"gamma(x)", "Returns the gamma function of x."},
+		"hypot":     {"hypot(x, y)", "Returns sqrt(x^2 + y^2) without intermediate overflow or underflow."},
+		"ilogb":     {"ilogb(x)", "Returns the exponent of the radix representation of x."},
+		"inf":       {"inf(sign)", "Returns positive infinity if sign is positive, negative infinity if sign is negative."},
+		"is_inf":    {"is_inf(x, sign)", "Reports whether x is positive infinity or negative infinity."},
+		"is_nan":    {"is_nan(x)", "Reports whether x is NaN (not a number)."},
+		"j0":        {"j0(x)", "Returns the order-zero Bessel function of the first kind."},
+		"j1":        {"j1(x)", "Returns the order-one Bessel function of the first kind."},
+		"jn":        {"jn(n, x)", "Returns the nth order Bessel function of the first kind."},
+		"ldexp":     {"ldexp(frac, exp)", "Returns frac × 2**exp."},
+		"log":       {"log(x)", "Returns the natural logarithm of x."},
+		"log10":     {"log10(x)", "Returns the base-10 logarithm of x."},
+		"log1p":     {"log1p(x)", "Returns the natural logarithm of 1 plus x."},
+		"log2":      {"log2(x)", "Returns the base-2 logarithm of x."},
+		"logb":      {"logb(x)", "Returns the unbiased exponent of x in the IEEE 754 floating-point representation."},
+		"max":       {"max(x, y)", "Returns the larger of x or y."},
+		"min":       {"min(x, y)", "Returns the smaller of x or y."},
+		"mod":       {"mod(x, y)", "Returns the floating-point remainder of x/y."},
+		"nan":       {"nan()", "Returns an IEEE 754 \"not-a-number\" value."},
+		"nextafter": {"nextafter(x, y)", "Returns the next representable float value after x towards y."},
+		"pow":       {"pow(x, y)", "Returns x**y, the base-x exponential of y."},
+		"pow10":     {"pow10(n)", "Returns 10**n."},
+		"remainder": {"remainder(x, y)", "Returns the IEEE 754 floating-point remainder of x/y."},
+		"signbit":   {"signbit(x)", "Reports whether x is negative or negative zero."},
+		"sin":       {"sin(x)", "Returns the sine of x (x is in radians)."},
+		"sinh":      {"sinh(x)", "Returns the hyperbolic sine of x."},
+		"sqrt":      {"sqrt(x)", "Returns the square root of x."},
+		"tan":       {"tan(x)", "Returns the tangent of x (x is in radians)."},
+		"tanh":      {"tanh(x)", "Returns the hyperbolic tangent of x."},
+		"trunc":     {"trunc(x)", "Returns the integer value of x truncated towards zero."},
+		"y0":        {"y0(x)", "Returns the order-zero Bessel function of the second kind."},
+		"y1":        {"y1(x)", "Returns the order-one Bessel function of the second kind."},
+		"yn":        {"yn(n, x)", "Returns the nth order Bessel function of the second kind."},
+	},
+	"net": {
+		"dial":             {"dial(network, address)", "Establishes a generic network connection."},
+		"dialtcp":          {"dialtcp(network, address)", "Establishes a TCP network connection."},
+		"dnslookup":        {"dnslookup(host)", "Performs a DNS lookup for the given host."},
+		"resolve_tcp_addr": {"resolve_tcp_addr(network, address)", "Resolves a TCP address."},
+		"resolve_udp_addr": {"resolve_udp_addr(network, address)", "Resolves a UDP address."},
+	},
+	"os": {
+		"args":           {"args() => [string]", "Returns command-line arguments, starting with the program name."},
+		"chdir":          {"chdir(dir string) => error", "Changes the current working directory to the named directory."},
+		"chmod":          {"chmod(name string, mode int) => error", "Changes the mode of the named file to mode."},
+		"chown":          {"chown(name string, uid int, gid int) => error", "Changes the numeric uid and gid of the named file."},
+		"chtimes":        {"chtimes(name string, atime time, mtime time) => error", "Changes the access and modification times of the named file."},
+		"clearenv":       {"clearenv()", "Deletes all environment variables."},
+		"copy":           {"copy(src string, dest string) => error", "Copies the file src to dest."},
+		"create":         {"create(name string) => File/error", "Creates the named file with mode 0666 (before umask), truncating it if it already exists."},
+		"environ":        {"environ() => [string]", "Returns a copy of strings representing the environment."},
+		"exec":           {"exec(name string, args...) => Command/error", "Returns the Command to execute the named program with the given arguments."},
+		"exec_look_path": {"exec_look_path(file string) => string/error", "Searches for an executable named file in the directories named by the PATH environment variable."},
+		"executable":     {"executable() => string/error", "Returns the path name of the executable that started the current process."},
+		"exit":           {"exit(code int)", "Causes the current program to exit with the given status code."},
+		"expand_env":     {"expand_env(s string) => string", "Replaces ${var} or $var in the string according to the values of the current environment variables."},
+		"find_process":   {"find_process(pid int) => Process/error", "Looks for a running process by its pid."},
+		"getegid":        {"getegid() => int", "Returns the numeric effective group id of the caller."},
+		"getenv":         {"getenv(key string) => string", "Retrieves the value of the environment variable named by the key."},
+		"geteuid":        {"geteuid() => int", "Returns the numeric effective user id of the caller."},
+		"getgid":         {"getgid() => int", "Returns the numeric group id of the caller."},
+		"getgroups":      {"getgroups() => [int]/error", "Returns a list of the numeric ids of groups that the caller belongs to."},
+		"getpagesize":    {"getpagesize() => int", "Returns the underlying system's memory page size."},
+		"getpid":         {"getpid() => int", "Returns the process id of the caller."},
+		"getppid":        {"getppid() => int", "Returns the process id of the caller's parent."},
+		"getuid":         {"getuid() => int", "Returns the numeric user id of the caller."},
+		"getwd":          {"getwd() => string/error", "Returns a rooted path name corresponding to the current directory."},
+		"hostname":       {"hostname() => string/error", "Returns the host name reported by the kernel."},
+		"lchown":         {"lchown(name string, uid int, gid int) => error", "Changes the numeric uid and gid of the named file."},
+		"link":           {"link(oldname string, newname string) => error", "Creates newname as a hard link to the oldname file."},
+		"lookup_env":     {"lookup_env(key string) => string/false", "Retrieves the value of the environment variable named by the key."},
+		"mkdir":          {"mkdir(name string, perm int) => error", "Creates a new directory with the specified name and permission bits (before umask)."},
+		"mkdir_all":      {"mkdir_all(name string, perm int) => error", "Creates a directory named path, along with any necessary parents, and returns null, or else returns an error."},
+		"open":           {"open(name string) => File/error", "Opens the named file for reading. If successful, methods on the returned file can be used for reading; the associated file descriptor has mode O_RDONLY."},
+		"open_file":      {"open_file(name string, flag int, perm int) => File/error", "Is the generalized open call; most users will use Open or Create instead. It opens the named file with specified flag (O_RDONLY etc.) and perm (before umask), if applicable."},
+		"read_dir":       {"read_dir(dirname string) => [{name: string, size: int, mode: int, mtime: time, is_dir: bool}]/error", "Reads the directory and returns its entries sorted by file name."},
+		"read_file":      {"read_file(name string[, fs]) => bytes/error", "Reads the contents of a file into a byte array, from the file system `fs` created with `embed fs(...)` if given."},
+		"readlink":       {"readlink(name string) => string/error", "Returns the destination of the named symbolic link."},
+		"remove":         {"remove(name string) => error", "Removes the named file or (empty) directory."},
+		"remove_all":     {"remove_all(name string) => error", "Removes path and any children it contains."},
+		"rename":         {"rename(oldpath string, newpath string) => error", "Renames (moves) oldpath to newpath."},
+		"setenv":         {"setenv(key string, value string) => error", "Sets the value of the environment variable named by the key."},
+		"start_process":  {"start_process(name string, argv [string], dir string, env [string]) => Process/error", "Starts a new process with the program, arguments and attributes specified by name, argv and attr. The argv slice will become os.Args in the new process, so it normally starts with the program name."},
+		"stat":           {"stat(filename string) => FileInfo/error", "Returns a file info structure describing the file."},
+		"stderr":         {"stderr() => io.writer", "Returns a writer of the standard error."},
+		"stdin":          {"stdin() => io.reader", "Returns a reader of the standard input."},
+		"stdout":         {"stdout() => io.writer", "Returns a writer of the standard output."},
+		"symlink":        {"symlink(oldname string, newname string) => error", "Creates newname as a symbolic link to oldname."},
+		"temp_dir":       {"temp_dir() => string", "Returns the default directory to use for temporary files."},
+		"truncate":       {"truncate(name string, size int) => error", "Changes the size of the named file."},
+		"unsetenv":       {"unsetenv(key string) => error", "Unsets a single environment variable."},
+	},
+	"path": {
+		"abs":        {"abs(path)", "Returns the absolute path of the given path."},
+		"base":       {"base(path)", "Returns the last element of the path, typically the file or directory name."},
+		"clean":      {"clean(path)", "Returns the cleaned version of the path."},
+		"dir":        {"dir(path)", "Returns the directory part of the given path."},
+		"ext":        {"ext(path)", "Returns the file extension of the given path."},
+		"from_slash": {"from_slash(path)", "Converts the path to use the native operating system separator."},
+		"isabs":      {"isabs(path)", "Checks whether the given path is absolute."},
+		"join":       {"join(path1, path2, ...)", "Joins any number of path elements into a single path, separating them with the operating system-specific separator."},
+		"splitlist":  {"splitlist(paths)", "Splits the input string containing a list of paths into individual paths."},
+		"to_slash":   {"to_slash(path)", "Converts the path to use forward slashes ('/') as the separator."},
+		"vol":        {"vol(path)", "Returns the volume name of the given path."},
+		"walklist":   {"walklist(root)", "Walks the file tree rooted at the specified root path and returns a list of all visited files and directories."},
+	},
+	"rand": {
+		"exp_float":  {"exp_float() => float", "Returns an exponentially distributed float64 in the range (0, +math.MaxFloat64] with an exponential distribution whose rate parameter (lambda) is 1 and whose mean is 1/lambda (1) from the default Source."},
+		"float":      {"float() => float", "Returns, as a float64, a pseudo-random number in [0.0,1.0) from the default Source."},
+		"int":        {"int() => int", "Returns a non-negative pseudo-random 63-bit integer as an int64 from the default Source."},
+		"intn":       {"intn(n int) => int", "Returns, as an int64, a non-negative pseudo-random number in [0,n) from the default Source. It panics if n <= 0."},
+		"norm_float": {"norm_float() => float", "Returns a normally distributed float64 in the range [-math.MaxFloat64, +math.MaxFloat64] with standard normal distribution (mean = 0, stddev = 1) from the default Source."},
+		"perm":       {"perm(n int) => [int]", "Returns, as a slice of n ints, a pseudo-random permutation of the integers [0,n) from the default Source."},
+		"rand":       {"rand(src_seed int) => Rand", "Returns a new Rand that uses random values from src to generate other random values."},
+		"read":       {"read(p bytes) => int/error", "Generates len(p) random bytes from the default Source and writes them into p. It always returns len(p) and a null error."},
+		"seed":       {"seed(seed int)", "Uses the provided seed value to initialize the default Source to a deterministic state."},
+	},
+	"strings": {
+		"atoi":           {"atoi(str string) => int/error", "Returns the result of ParseInt(s, 10, 0) converted to type int."},
+		"compare":        {"compare(a string, b string) => int", "Returns an integer comparing two strings lexicographically. The result will be 0 if a==b, -1 if a < b, and +1 if a > b."},
+		"contains":       {"contains(s string, substr string) => bool", "Reports whether substr is within s."},
+		"contains_any":   {"contains_any(s string, chars string) => bool", "Reports whether any Unicode code points in chars are within s."},
+		"count":          {"count(s string, substr string) => int", "Counts the number of non-overlapping instances of substr in s."},
+		"equal_fold":     {"equal_fold(s string, t string) => bool", "Reports whether s and t, interpreted as UTF-8 strings, are equal under simple Unicode case-folding."},
+		"fields":         {"fields(s string) => [string]", "Splits the string s around each instance of one or more consecutive white space characters, as defined by unicode.IsSpace, returning a slice of substrings of s or an empty slice if s contains only white space."},
+		"format_bool":    {"format_bool(b bool) => string", "Returns \"true\" or \"false\" according to the value of b."},
+		"format_float":   {"format_float(f float, fmt char, prec int, bits int) => string", "Converts the floating-point number f to a string, according to the format fmt and precision prec."},
+		"format_int":     {"format_int(i int, base int) => string", "Returns the string representation of i in the given base, for 2 <= base <= 36. The result uses the lower-case letters 'a' to 'z' for digit values >= 10."},
+		"has_prefix":     {"has_prefix(s string, prefix string) => bool", "Tests whether the string s begins with prefix."},
+		"has_suffix":     {"has_suffix(s string, suffix string) => bool", "Tests whether the string s ends with suffix."},
+		"index":          {"index(s string, substr string) => int", "Returns the index of the first instance of substr in s, or -1 if substr is not present in s."},
+		"index_any":      {"index_any(s string, chars string) => int", "Returns the index of the first instance of any Unicode code point from chars in s, or -1 if no Unicode code point from chars is present in s."},
+		"itoa":           {"itoa(i int) => string", "Is shorthand for format_int(i, 10)."},
+		"join":           {"join(arr [string], sep string) => string", "Concatenates the elements of arr to create a single string. The separator string sep is placed between elements in the resulting string."},
+		"last_index":     {"last_index(s string, substr string) => int", "Returns the index of the last instance of substr in s, or -1 if substr is not present in s."},
+		"last_index_any": {"last_index_any(s string, chars string) => int", "Returns the index of the last instance of any Unicode code point from chars in s, or -1 if no Unicode code point from chars is present in s."},
+		"pad_left":       {"pad_left(s string, pad_len int[, pad_with string]) => string", "Returns a copy of the string s padded on the left with the contents of the string pad_with to length pad_len. If pad_with is not specified, white space is used as the default padding."},
+		"pad_right":      {"pad_right(s string, pad_len int[, pad_with string]) => string", "Returns a copy of the string s padded on the right with the contents of the string pad_with to length pad_len. If pad_with is not specified, white space is used as the default padding."},
+		"parse_bool":     {"parse_bool(s string) => bool/error", "Returns the boolean value represented by the string. It accepts 1, t, T, TRUE, true, True, 0, f, F, FALSE, false, False. Any other value returns an error."},
+		"parse_float":    {"parse_float(s string, bits int) => float/error", "Converts the string s to a floating-point number with the precision specified by bitSize: 32 for float32, or 64 for float64. When bitSize=32, the result still has type float64, but it will be convertible to float32 without changing its value."},
+		"parse_int":      {"parse_int(s string, base int, bits int) => int/error", "Interprets a string s in the given base (0, 2 to 36) and bit size (0 to 64) and returns the corresponding value i."},
+		"quote":          {"quote(s string) => string", "Returns a double-quoted Go string literal representing s. The returned string uses Go escape sequences (\\t, \\n, \\xFF, \\u0100) for control characters and non-printable characters as defined by IsPrint."},
+		"re_compile":     {"re_compile(pattern string) => Regexp/error", "Parses a regular expression and returns, if successful, a Regexp object that can be used to match against text."},
+		"re_find":        {"re_find(pattern string, text string[, count int]) => [[{text: string, begin: int, end: int}]]/null", "Returns an array holding all matches, each of which is an array of map object that contains matching text, begin and end (exclusive) index."},
+		"re_match":       {"re_match(pattern string, text string) => bool/error", "Reports whether the string s contains any match of the regular expression pattern."},
+		"re_replace":     {"re_replace(pattern string, text string, repl string) => string/error", "Returns a copy of src, replacing matches of the pattern with the replacement string repl."},
+		"re_split":       {"re_split(pattern string, text string[, count int]) => [string]/error", "Slices s into substrings separated by the expression and returns a slice of the substrings between those expression matches."},
+		"repeat":         {"repeat(s string, count int) => string", "Returns a new string consisting of count copies of the string s."},
+		"replace":        {"replace(s string, old string, new string, n int) => string", "Returns a copy of the string s with the first n non-overlapping instances of old replaced by new."},
+		"split":          {"split(s string, sep string) => [string]", "Slices s into all substrings separated by sep and returns a slice of the substrings between those separators."},
+		"split_after":    {"split_after(s string, sep string) => [string]", "Slices s into all substrings after each instance of sep and returns a slice of those substrings."},
+		"split_after_n":  {"split_after_n(s string, sep string, n int) => [string]", "Slices s into substrings after each instance of sep and returns a slice of those substrings."},
+		"split_n":        {"split_n(s string, sep string, n int) => [string]", "Slices s into substrings separated by sep and returns a slice of the substrings between those separators."},
+		"substr":         {"substr(s string, lower int[, upper int]) => string", "Returns a substring of the string s specified by the lower and upper parameters."},
+		"title":          {"title(s string) => string", "Returns a copy of the string s with all Unicode letters that begin words mapped to their title case."},
+		"to_lower":       {"to_lower(s string) => string", "Returns a copy of the string s with all Unicode letters mapped to their lower case."},
+		"to_title":       {"to_title(s string) => string", "Returns a copy of the string s with all Unicode letters mapped to their title case."},
+		"to_upper":       {"to_upper(s string) => string", "Returns a copy of the string s with all Unicode letters mapped to their upper case."},
+		"trim":           {"trim(s string, cutset string) => string", "Returns a slice of the string s with all leading and trailing Unicode code points contained in cutset removed."},
+		"trim_left":      {"trim_left(s string, cutset string) => string", "Returns a slice of the string s with all leading Unicode code points contained in cutset removed."},
+		"trim_prefix":    {"trim_prefix(s string, prefix string) => string", "Returns s without the provided leading prefix string."},
+		"trim_right":     {"trim_right(s string, cutset string) => string", "Returns a slice of the string s, with all trailing Unicode code points contained in cutset removed."},
+		"trim_space":     {"trim_space(s string) => string", "Returns a slice of the string s, with all leading and trailing white space removed, as defined by Unicode."},
+		"trim_suffix":    {"trim_suffix(s string, suffix string) => string", "Returns s without the provided trailing suffix string."},
+		"unquote":        {"unquote(s string) => string/error", "Interprets s as a single-quoted, double-quoted, or backquoted Go string literal, returning the string value that s quotes. If s is single-quoted, it is a Go character literal, and the corresponding one-character string is returned."},
+	},
+	"tar": {
+		"reader": {"reader(data)", "Returns an array containing information about each file in the TAR archive. - Each element in the array represents a file and contains the following attributes: - `name`: Name of the file. - `mode`: Permission mode of the file. - `size`: Size of the file. - `data`: Content of the file."},
+		"writer": {"writer()", "Returns a writer object for creating a new TAR archive. The writer object provides methods for adding files and closing the TAR archive. - `create(filename, content)`: Creates a new file entry in the TAR archive with the specified filename and content. - `bytes()`: Returns the byte representation of the created TAR archive. - `close()`: Closes the TAR archive writer."},
+	},
+	"times": {
+		"add":                  {"add(t time, duration int) => time", "Returns the time t+d."},
+		"add_date":             {"add_date(t time, years int, months int, days int) => time", "Returns the time corresponding to adding the given number of years, months, and days to t. For example, AddDate(-1, 2, 3) applied to January 1, 2011 returns March 4, 2010."},
+		"after":                {"after(t time, u time) => bool", "Reports whether the time instant t is after u."},
+		"before":               {"before(t time, u time) => bool", "Reports whether the time instant t is before u."},
+		"date":                 {"date(year int, month int, day int, hour int, min int, sec int, nsec int) => time", "Returns the Time corresponding to \"yyyy-mm-dd hh:mm:ss + nsec nanoseconds\". Current location is used."},
+		"duration_hours":       {"duration_hours(duration int) => float", "Returns the duration as a floating point number of hours."},
+		"duration_minutes":     {"duration_minutes(duration int) => float", "Returns the duration as a floating point number of minutes."},
+		"duration_nanoseconds": {"duration_nanoseconds(duration int) => int", "Returns the duration as an integer of nanoseconds."},
+		"duration_seconds":     {"duration_seconds(duration int) => float", "Returns the duration as a floating point number of seconds."},
+		"duration_string":      {"duration_string(duration int) => string", "Returns a string representation of duration."},
+		"is_zero":              {"is_zero(t time) => bool", "Reports whether t represents the zero time instant, January 1, year 1, 00:00:00 UTC."},
+		"month_string":         {"month_string(month int) => string", "Returns the English name of the month (\"January\", \"February\", ...)."},
+		"now":                  {"now() => time", "Returns the current local time."},
+		"parse":                {"parse(format string, s string) => time", "Parses a formatted string and returns the time value it represents. The layout defines the format by showing how the reference time, defined to be \"Mon Jan 2 15:04:05 -0700 MST 2006\" would be interpreted if it were the value; it serves as an example of the input format. The same interpretation will then be made to the input string."},
+		"parse_duration":       {"parse_duration(s string) => int", "Parses a duration string. A duration string is a possibly signed sequence of decimal numbers, each with optional fraction and a unit suffix, such as \"300ms\", \"-1.5h\" or \"2h45m\". Valid time units are \"ns\", \"us\" (or \"µs\"), \"ms\", \"s\", \"m\", \"h\"."},
+		"since":                {"since(t time) => int", "Returns the time elapsed since t."},
+		"sleep":                {"sleep(duration int)", "Pauses the current goroutine for at least the duration d. A negative or zero duration causes Sleep to return immediately."},
+		"sub":                  {"sub(t time, u time) => int", "Returns the duration t-u."},
+		"time_day":             {"time_day(t time) => int", "Returns the day of the month specified by t."},
+		"time_format":          {"time_format(t time, format) => string", "Returns a textual representation of he time value formatted according to layout, which defines the format by showing how the reference time, defined to be \"Mon Jan 2 15:04:05 -0700 MST 2006\" would be displayed if it were the value; it serves as an example of the desired output. The same display rules will then be applied to the time value."},
+		"time_hour":            {"time_hour(t time) => int", "Returns the hour within the day specified by t, in the range [0, 23]."},
+		"time_location":        {"time_location(t time) => string", "Returns the time zone name associated with t."},
+		"time_minute":          {"time_minute(t time) => int", "Returns the minute offset within the hour specified by t, in the range [0, 59]."},
+		"time_month":           {"time_month(t time) => int", "Returns the month of the year specified by t."},
+		"time_nanosecond":      {"time_nanosecond(t time) => int", "Returns the nanosecond offset within the second specified by t, in the range [0, 999999999]."},
+		"time_second":          {"time_second(t time) => int", "Returns the second offset within the minute specified by t, in the range [0, 59]."},
+		"time_string":          {"time_string(t time) => string", "Returns the time formatted using the format string \"2006-01-02 15:04:05.999999999 -0700 MST\"."},
+		"time_unix":            {"time_unix(t time) => int", "Returns t as a Unix time, the number of seconds elapsed since January 1, 1970 UTC. The result does not depend on the location associated with t."},
+		"time_unix_nano":       {"time_unix_nano(t time) => int", "Returns t as a Unix time, the number of nanoseconds elapsed since January 1, 1970 UTC. The result is null if the Unix time in nanoseconds cannot be represented by an int64 (a date before the year 1678 or after 2262). Note that this means the result of calling UnixNano on the zero Time is null. The result does not depend on the location associated with t."},
+		"time_weekday":         {"time_weekday(t time) => int", "Returns the day of the week specified by t."},
+		"time_year":            {"time_year(t time) => int", "Returns the year in which t occurs."},
+		"to_local":             {"to_local(t time) => time", "Returns t with the location set to local time."},
+		"to_utc":               {"to_utc(t time) => time", "Returns t with the location set to UTC."},
+		"unix":                 {"unix(sec int, nsec int) => time", "Returns the local Time corresponding to the given Unix time, sec seconds and nsec nanoseconds since January 1, 1970 UTC."},
+		"until":                {"until(t time) => int", "Returns the duration until t."},
+	},
+	"websocket": {
+		"dial": {"dial(url)", "Establishes a WebSocket connection to the specified URL."},
+	},
+	"xml": {
+		"decode":   {"decode(xml_string)", "Parses XML string into Tender objects using the `@` prefix for attributes and `#` key for text content."},
+		"encode":   {"encode(object)", "Converts Tender objects into XML string using the `@` prefix for attributes and `#` key for text content."},
+		"escape":   {"escape(text)", "Escapes XML special characters in a string."},
+		"unescape": {"unescape(text)", "Unescapes XML entities back to their original characters."},
+	},
+	"zip": {
+		"reader": {"reader(data)", "Returns a reader object for reading a ZIP archive from the provided byte slice. - `files`: Returns an array containing information about each file in the ZIP archive. - `name`: Name of the file. - `comment`: Comment associated with the file. - `non_utf8`: Indicates whether the file name is not encoded in UTF-8. - `creator_version`: Version of the software that created the ZIP archive. - `reader_version`: Version needed to read the ZIP archive. - `method`: Compression method used for the file. - `modified`: Last modification time of the file. - `modified_time`: Modified time in seconds since January 1, 1970 UTC. - `modified_date`: Modified date in MS-DOS date format. - `crc32`: CRC-32 checksum of the file contents. - `compressed_size`: Size of the compressed file data. - `uncompressed_size`: Size of the uncompressed file data. - `extra`: Extra data associated with the file. - `read()`: Function to read the content of the file."},
+		"writer": {"writer()", "Returns a writer object for creating a new ZIP archive. The writer object provides methods for adding files, setting comment, closing, and flushing the ZIP archive. - `create(filename, content)`: Creates a new file entry in the ZIP archive with the specified filename and content. - `bytes()`: Returns the byte representation of the created ZIP archive. - `close()`: Closes the ZIP archive writer. - `flush()`: Flushes any buffered data to the underlying writer. - `set_comment(comment)`: Sets the comment for the ZIP archive. - `set_offset(offset)`: Sets the offset for the next file entry in the ZIP archive."},
+	},
+}
+
+func init() {
+	for name, docs := range funcDocs {
+		for key, doc := range docs {
+			switch fn := BuiltinModules[name][key].(type) {
+			case *tender.UserFunction:
+				fn.Usage, fn.Doc = doc[0], doc[1]
+			case *tender.BuiltinFunction:
+				fn.Usage, fn.Doc = doc[0], doc[1]
+			default:
+				panic(fmt.Sprintf("stdlib: doc of unknown function %s.%s", name, key))
+			}
+		}
+	}
+}
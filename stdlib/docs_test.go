@@ -0,0 +1,235 @@
+package stdlib
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"reflect"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/2dprototype/tender"
+)
+
+func TestFuncDocs(t *testing.T) {
+	arities := typedefArities(t)
+	for name, mod := range BuiltinModules {
+		for member, o := range mod {
+			switch o.(type) {
+			case *tender.UserFunction, *tender.BuiltinFunction:
+			default:
+				continue
+			}
+			if _, ok := funcDocs[name][member]; !ok {
+				t.Errorf("%s.%s has no doc", name, member)
+			}
+		}
+	}
+
+	for name, docs := range funcDocs {
+		for member, doc := range docs {
+			var value tender.CallableFunc
+			switch fn := BuiltinModules[name][member].(type) {
+			case *tender.UserFunction:
+				value = fn.Value
+			case *tender.BuiltinFunction:
+				value = fn.Value
+			default:
+				t.Errorf("doc of unknown function %s.%s", name, member)
+				continue
+			}
+			params, ok := parseUsage(member, doc[0])
+			if !ok {
+				t.Errorf("%s.%s: invalid usage %q", name, member, doc[0])
+				continue
+			}
+			if doc[1] == "" || !strings.HasSuffix(doc[1], ".") {
+				t.Errorf("%s.%s: doc is not a sentence: %q", name, member, doc[1])
+			}
+
+			// the arity of functions adapted by the typedefs is known
+			if arity, ok := arities[typedefName(value)]; ok {
+				required, variadic := 0, false
+				for _, p := range params {
+					if strings.Contains(p.name, "...") {
+						variadic = true
+					} else if !p.optional {
+						required++
+					}
+				}
+				if required != arity || variadic || len(params) != arity {
+					t.Errorf("%s.%s: usage %q does not take %d arguments",
+						name, member, doc[0], arity)
+				}
+			}
+
+			s, ok := ModuleSignatures[name][member]
+			if !ok {
+				continue
+			}
+			sig, err := tender.ParseSignature(s)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !sig.VarArgs && len(params) != len(sig.Params) {
+				t.Errorf("%s.%s: usage %q has %d parameters, signature %q has %d",
+					name, member, doc[0], len(params), s, len(sig.Params))
+				continue
+			}
+			for i, p := range params {
+				if i < len(sig.Params) && !usageTypeMatches(p.typ, sig.Params[i]) {
+					t.Errorf("%s.%s: parameter %s of usage %q has type %s, signature %q has %s",
+						name, member, p.name, doc[0], p.typ, s, sig.Params[i])
+				}
+			}
+		}
+	}
+}
+
+// typedefArities returns the number of arguments of the functions adapted by
+// the Func typedefs, by their names.
+func typedefArities(t *testing.T) map[string]int {
+	file, err := parser.ParseFile(token.NewFileSet(), "func_typedefs.go", nil, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	arities := make(map[string]int)
+	for _, decl := range file.Decls {
+		decl, ok := decl.(*ast.FuncDecl)
+		if !ok || decl.Recv != nil || len(decl.Type.Params.List) != 1 {
+			continue
+		}
+		fn, ok := decl.Type.Params.List[0].Type.(*ast.FuncType)
+		if !ok {
+			continue
+		}
+		n := 0
+		for _, field := range fn.Params.List {
+			if _, ok := field.Type.(*ast.Ellipsis); ok {
+				n = -1
+				break
+			}
+			n += max(len(field.Names), 1)
+		}
+		if n >= 0 {
+			arities[decl.Name.Name] = n
+		}
+	}
+	return arities
+}
+
+// typedefName returns the name of the typedef, such as "FuncASRS", that
+// created the function, or "".
+func typedefName(fn tender.CallableFunc) string {
+	name := runtime.FuncForPC(reflect.ValueOf(fn).Pointer()).Name()
+	name = strings.TrimPrefix(name, "github.com/2dprototype/tender/stdlib.")
+	if n := strings.IndexByte(name, '.'); n > 0 {
+		return name[:n]
+	}
+	return ""
+}
+
+type usageParam struct {
+	name, typ string
+	optional  bool
+}
+
+// parseUsage returns the parameters of a usage such as
+// "join(arr array, sep string) => string", "put(url, [body])" or
+// "write(path, content[, mode])".
+func parseUsage(name, usage string) ([]usageParam, bool) {
+	if !strings.HasPrefix(usage, name+"(") {
+		return nil, false
+	}
+	rest := usage[len(name)+1:]
+	depth, end := 0, -1
+	for i, c := range rest {
+		switch c {
+		case '(', '[', '{':
+			depth++
+		case ']', '}':
+			depth--
+		case ')':
+			if depth == 0 && end < 0 {
+				end = i
+			}
+			depth--
+		}
+	}
+	if end < 0 {
+		return nil, false
+	}
+	if result := rest[end+1:]; result != "" {
+		if !strings.HasPrefix(result, " => ") || strings.Contains(result[4:], "=>") {
+			return nil, false
+		}
+	}
+
+	var params []usageParam
+	list := strings.ReplaceAll(rest[:end], "[, ", ", [")
+	for _, s := range splitUsageParams(list) {
+		var p usageParam
+		if strings.HasPrefix(s, "[") && strings.HasSuffix(s, "]") {
+			s, p.optional = strings.Trim(s, "[]"), true
+		}
+		s = strings.Replace(s, ": ", " ", 1)
+		p.name = s
+		if n := strings.IndexByte(s, ' '); n > 0 {
+			p.name, p.typ = s[:n], s[n+1:]
+		}
+		if p.name == "" {
+			return nil, false
+		}
+		params = append(params, p)
+	}
+	return params, true
+}
+
+// splitUsageParams splits a parameter list at the commas outside of brackets
+// and braces.
+func splitUsageParams(list string) []string {
+	var params []string
+	depth, start := 0, 0
+	for i := 0; i < len(list); i++ {
+		switch list[i] {
+		case '[', '{', '(':
+			depth++
+		case ']', '}', ')':
+			depth--
+		case ',':
+			if depth == 0 {
+				params = append(params, strings.TrimSpace(list[start:i]))
+				start = i + 1
+			}
+		}
+	}
+	if s := strings.TrimSpace(list[start:]); s != "" {
+		params = append(params, s)
+	}
+	return params
+}
+
+// usageTypeMatches returns true if the type of a parameter in a usage is one
+// of the types of the parameter in the signature.
+func usageTypeMatches(typ, sigType string) bool {
+	if typ == "" || sigType == "any" {
+		return true
+	}
+	types := strings.Split(sigType, "|")
+	for _, t := range strings.FieldsFunc(typ, func(c rune) bool { return c == '/' || c == '|' }) {
+		if strings.HasPrefix(t, "[") {
+			t = "array"
+		} else if strings.HasPrefix(t, "{") {
+			t = "map"
+		}
+		found := false
+		for _, s := range types {
+			found = found || s == t
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
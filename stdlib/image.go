@@ -54,7 +54,7 @@ func imageDecode(args ...tender.Object) (tender.Object, error) {
 }
 
 func imageLoad(args ...tender.Object) (tender.Object, error) {
-	if len(args) != 1 {
+	if len(args) != 1 && len(args) != 2 {
 		return nil, tender.ErrWrongNumArguments
 	}
 
@@ -67,13 +67,16 @@ func imageLoad(args ...tender.Object) (tender.Object, error) {
 		}
 	}
 
-	file, err := os.Open(path)
+	fsys, err := fsArg(args, 1)
+	if err != nil {
+		return nil, err
+	}
+	data, err := readFile(fsys, path)
 	if err != nil {
 		return wrapError(err), nil
 	}
-	defer file.Close()
 
-	img, _, err := image.Decode(file)
+	img, _, err := image.Decode(bytes.NewReader(data))
 	if err != nil {
 		return wrapError(err), nil
 	}
@@ -101,252 +104,244 @@ func imageNew(args ...tender.Object) (ret tender.Object, err error) {
 	return makeImage(img), nil
 }
 
-func makeImage(img image.Image) *tender.ImmutableMap {
+// imageMethods is the method table of images.
+var imageMethods *tender.MethodTable
+
+func init() {
+	imageMethods = tender.NewMethodTable("image", map[string]tender.MethodFunc{
+		"encode": imageMethod(func(img image.Image, args ...tender.Object) (tender.Object, error) {
+			if len(args) != 1 {
+				return nil, tender.ErrWrongNumArguments
+			}
+			format, ok := tender.ToString(args[0])
+			if !ok {
+				return nil, tender.ErrInvalidArgumentType{
+					Name:     "first",
+					Expected: "string",
+					Found:    args[0].TypeName(),
+				}
+			}
+			buffer := new(bytes.Buffer)
+
+			if format == "png" {
+				err := png.Encode(buffer, img)
+				if err != nil {
+					return wrapError(err), nil
+				}
+			} else if format == "jpeg" {
+				err := jpeg.Encode(buffer, img, nil)
+				if err != nil {
+					return wrapError(err), nil
+				}
+			} else if format == "tiff" {
+				err := tiff.Encode(buffer, img, nil)
+				if err != nil {
+					return wrapError(err), nil
+				}
+			} else if format == "bmp" {
+				err := bmp.Encode(buffer, img)
+				if err != nil {
+					return wrapError(err), nil
+				}
+			}
+
+			return &tender.Bytes{Value: buffer.Bytes()}, nil
+		}),
+		"bounds": imageMethod(func(img image.Image, args ...tender.Object) (tender.Object, error) {
+			if len(args) != 0 {
+				return nil, tender.ErrWrongNumArguments
+			}
+			rect := img.Bounds()
+			return makeRectangle(rect), nil
+		}),
+		"at": imageMethod(func(img image.Image, args ...tender.Object) (tender.Object, error) {
+			if len(args) != 2 {
+				return nil, tender.ErrWrongNumArguments
+			}
+
+			x, ok1 := tender.ToInt(args[0])
+			y, ok2 := tender.ToInt(args[1])
+
+			if !ok1 || !ok2 {
+				return nil, tender.ErrInvalidArgumentType{
+					Name:     "x/y",
+					Expected: "int",
+					Found:    args[0].TypeName(),
+				}
+			}
+
+			color := img.At(x, y)
+			return makeColor(color), nil
+		}),
+		"pixels": imageMethod(func(img image.Image, args ...tender.Object) (tender.Object, error) {
+			if len(args) != 0 {
+				return nil, tender.ErrWrongNumArguments
+			}
+			bounds := img.Bounds()
+			return &tender.Int{Value: int64((bounds.Max.X - bounds.Min.X) * (bounds.Max.Y - bounds.Min.Y))}, nil
+		}),	
+		"get_pixels": imageMethod(func(img image.Image, args ...tender.Object) (tender.Object, error) {
+			if len(args) != 0 {
+				return nil, tender.ErrWrongNumArguments
+			}
+
+			rgbaImage, ok := img.(*image.RGBA)
+			if !ok {
+				return nil, nil
+			}
+
+			pixels := make([]tender.Object, len(rgbaImage.Pix))
+			for i, p := range rgbaImage.Pix {
+				pixels[i] = &tender.Int{Value: int64(p)}
+			}
+
+			return &tender.Array{Value: pixels}, nil
+		}),	
+		"set_pixels": imageMethod(func(img image.Image, args ...tender.Object) (tender.Object, error) {
+			if len(args) != 1 {
+				return nil, tender.ErrWrongNumArguments
+			}
+
+			pixelArray, ok := args[0].(*tender.Array)
+			if !ok {
+				return nil, tender.ErrInvalidArgumentType{
+					Name:     "pixels",
+					Expected: "array",
+					Found:    args[0].TypeName(),
+				}
+			}
+
+			rgbaImage, ok := img.(*image.RGBA)
+			if !ok {
+				return nil, nil
+			}
+
+			if len(pixelArray.Value) > len(rgbaImage.Pix) {
+				return &tender.Error{Value: &tender.String{Value: "Failed to set pixels: Length of pixel array is greater than image dimensions"}}, nil 
+			}
+
+			for i, pixel := range pixelArray.Value {
+				val, _ := tender.ToInt(pixel)
+				rgbaImage.Pix[i] = uint8(val)
+			}
+
+			return nil, nil
+		}),
+		"set": imageMethod(func(img image.Image, args ...tender.Object) (tender.Object, error) {
+			if len(args) != 3 {
+				return nil, tender.ErrWrongNumArguments
+			}
+
+			x, ok1 := tender.ToInt(args[0])
+			y, ok2 := tender.ToInt(args[1])
+
+			if !ok1 || !ok2 {
+				return nil, tender.ErrInvalidArgumentType{
+					Name:     "x/y",
+					Expected: "int",
+					Found:    args[0].TypeName(),
+				}
+			}
+
+			arr, ok := args[2].(*tender.Array)
+			if !ok || len(arr.Value) != 4 {
+				return nil, tender.ErrInvalidArgumentType{
+					Name:     "color",
+					Expected: "[4]array",
+					Found:    args[2].TypeName(),
+				}
+			}
+
+			red, ok1 := tender.ToUint8(arr.Value[0])
+			green, ok2 := tender.ToUint8(arr.Value[1])
+			blue, ok3 := tender.ToUint8(arr.Value[2])
+			alpha, ok4 := tender.ToUint8(arr.Value[3])
+
+			if !ok1 || !ok2 || !ok3 || !ok4 {
+				return nil, nil
+			}
+
+			img.(*image.RGBA).Set(x, y, color.RGBA{red, green, blue, alpha})
+			return nil, nil
+		}),
+		"save": imageMethod(func(img image.Image, args ...tender.Object) (tender.Object, error) {
+			if len(args) != 2 {
+				return nil, tender.ErrWrongNumArguments
+			}
+
+			path, ok := tender.ToString(args[0])
+			if !ok {
+				return nil, tender.ErrInvalidArgumentType{
+					Name:     "path",
+					Expected: "string",
+					Found:    args[0].TypeName(),
+				}
+			}
+			format, ok := tender.ToString(args[1])
+			if !ok {
+				return nil, tender.ErrInvalidArgumentType{
+					Name:     "path",
+					Expected: "string",
+					Found:    args[1].TypeName(),
+				}
+			}
+
+			file, err := os.Create(path)
+			if err != nil {
+				return wrapError(err), nil
+			}
+
+			defer file.Close()
+
+			if format == "png" {
+				err = png.Encode(file, img)
+				if err != nil {
+					return wrapError(err), nil
+				}
+			} else if format == "jpeg" {
+				err = jpeg.Encode(file, img, nil)
+				if err != nil {
+					return wrapError(err), nil
+				}
+			} else if format == "tiff" {
+				err = tiff.Encode(file, img, nil)
+				if err != nil {
+					return wrapError(err), nil
+				}
+			} else if format == "bmp" {
+				err = bmp.Encode(file, img)
+				if err != nil {
+					return wrapError(err), nil
+				}
+			}
+
+			return nil, nil
+		}),
+	}, map[string]tender.PropertyFunc{
+		"filters": func(recv interface{}) tender.Object {
+			return imageFilterMethods.New(recv)
+		},
+	})
+}
+
+// imageMethod returns a method of images from a function that takes the
+// image with the arguments of the call.
+func imageMethod(fn func(img image.Image, args ...tender.Object) (tender.Object, error)) tender.MethodFunc {
+	return func(recv interface{}, args ...tender.Object) (tender.Object, error) {
+		return fn(recv.(image.Image), args...)
+	}
+}
+
+func makeImage(img image.Image) tender.Object {
 	// Convert the image to *image.RGBA if it's not already
 	if _, ok := img.(*image.RGBA); !ok {
 		bounds := img.Bounds()
 		newImg := image.NewRGBA(bounds)
 		draw.Draw(newImg, bounds, img, bounds.Min, draw.Src)
 		img = newImg
-	}	
-
-	return &tender.ImmutableMap{
-		Value: map[string]tender.Object{
-			"filters": makeImageFilters(img),
-			"encode" : &tender.UserFunction{
-				Name: "encode",
-				Value: func(args ...tender.Object) (tender.Object, error) {
-					if len(args) != 1 {
-						return nil, tender.ErrWrongNumArguments
-					}
-					format, ok := tender.ToString(args[0])
-					if !ok {
-						return nil, tender.ErrInvalidArgumentType{
-							Name:     "first",
-							Expected: "string",
-							Found:    args[0].TypeName(),
-						}
-					}
-					buffer := new(bytes.Buffer)
-
-					if format == "png" {
-						err := png.Encode(buffer, img)
-						if err != nil {
-							return wrapError(err), nil
-						}
-					} else if format == "jpeg" {
-						err := jpeg.Encode(buffer, img, nil)
-						if err != nil {
-							return wrapError(err), nil
-						}
-					} else if format == "tiff" {
-						err := tiff.Encode(buffer, img, nil)
-						if err != nil {
-							return wrapError(err), nil
-						}
-					} else if format == "bmp" {
-						err := bmp.Encode(buffer, img)
-						if err != nil {
-							return wrapError(err), nil
-						}
-					}
-
-					return &tender.Bytes{Value: buffer.Bytes()}, nil
-				},
-			},
-			"bounds": &tender.UserFunction{
-				Name: "bounds",
-				Value: func(args ...tender.Object) (tender.Object, error) {
-					if len(args) != 0 {
-						return nil, tender.ErrWrongNumArguments
-					}
-					rect := img.Bounds()
-					return makeRectangle(rect), nil
-				},
-			},
-			"at": &tender.UserFunction{
-				Name: "at",
-				Value: func(args ...tender.Object) (tender.Object, error) {
-					if len(args) != 2 {
-						return nil, tender.ErrWrongNumArguments
-					}
-
-					x, ok1 := tender.ToInt(args[0])
-					y, ok2 := tender.ToInt(args[1])
-
-					if !ok1 || !ok2 {
-						return nil, tender.ErrInvalidArgumentType{
-							Name:     "x/y",
-							Expected: "int",
-							Found:    args[0].TypeName(),
-						}
-					}
-
-					color := img.At(x, y)
-					return makeColor(color), nil
-				},
-			},
-			"pixels": &tender.UserFunction{
-				Name: "pixels",
-				Value: func(args ...tender.Object) (tender.Object, error) {
-					if len(args) != 0 {
-						return nil, tender.ErrWrongNumArguments
-					}
-					bounds := img.Bounds()
-					return &tender.Int{Value: int64((bounds.Max.X - bounds.Min.X) * (bounds.Max.Y - bounds.Min.Y))}, nil
-				},
-			},	
-			"get_pixels": &tender.UserFunction{
-				Name: "get_pixels",
-				Value: func(args ...tender.Object) (tender.Object, error) {
-					if len(args) != 0 {
-						return nil, tender.ErrWrongNumArguments
-					}
-
-					rgbaImage, ok := img.(*image.RGBA)
-					if !ok {
-						return nil, nil
-					}
-
-					pixels := make([]tender.Object, len(rgbaImage.Pix))
-					for i, p := range rgbaImage.Pix {
-						pixels[i] = &tender.Int{Value: int64(p)}
-					}
-
-					return &tender.Array{Value: pixels}, nil
-				},
-			},	
-			"set_pixels": &tender.UserFunction{
-				Name: "set_pixels",
-				Value: func(args ...tender.Object) (tender.Object, error) {
-					if len(args) != 1 {
-						return nil, tender.ErrWrongNumArguments
-					}
-
-					pixelArray, ok := args[0].(*tender.Array)
-					if !ok {
-						return nil, tender.ErrInvalidArgumentType{
-							Name:     "pixels",
-							Expected: "array",
-							Found:    args[0].TypeName(),
-						}
-					}
-
-					rgbaImage, ok := img.(*image.RGBA)
-					if !ok {
-						return nil, nil
-					}
-
-					if len(pixelArray.Value) > len(rgbaImage.Pix) {
-						return &tender.Error{Value: &tender.String{Value: "Failed to set pixels: Length of pixel array is greater than image dimensions"}}, nil 
-					}
-
-					for i, pixel := range pixelArray.Value {
-						val, _ := tender.ToInt(pixel)
-						rgbaImage.Pix[i] = uint8(val)
-					}
-
-					return nil, nil
-				},
-			},
-			"set": &tender.UserFunction{
-				Name: "set",
-				Value: func(args ...tender.Object) (tender.Object, error) {
-					if len(args) != 3 {
-						return nil, tender.ErrWrongNumArguments
-					}
-
-					x, ok1 := tender.ToInt(args[0])
-					y, ok2 := tender.ToInt(args[1])
-
-					if !ok1 || !ok2 {
-						return nil, tender.ErrInvalidArgumentType{
-							Name:     "x/y",
-							Expected: "int",
-							Found:    args[0].TypeName(),
-						}
-					}
-
-					arr, ok := args[2].(*tender.Array)
-					if !ok || len(arr.Value) != 4 {
-						return nil, tender.ErrInvalidArgumentType{
-							Name:     "color",
-							Expected: "[4]array",
-							Found:    args[2].TypeName(),
-						}
-					}
-
-					red, ok1 := tender.ToUint8(arr.Value[0])
-					green, ok2 := tender.ToUint8(arr.Value[1])
-					blue, ok3 := tender.ToUint8(arr.Value[2])
-					alpha, ok4 := tender.ToUint8(arr.Value[3])
-
-					if !ok1 || !ok2 || !ok3 || !ok4 {
-						return nil, nil
-					}
-
-					img.(*image.RGBA).Set(x, y, color.RGBA{red, green, blue, alpha})
-					return nil, nil
-				},
-			},
-			"save": &tender.UserFunction{
-				Name: "save",
-				Value: func(args ...tender.Object) (tender.Object, error) {
-					if len(args) != 2 {
-						return nil, tender.ErrWrongNumArguments
-					}
-
-					path, ok := tender.ToString(args[0])
-					if !ok {
-						return nil, tender.ErrInvalidArgumentType{
-							Name:     "path",
-							Expected: "string",
-							Found:    args[0].TypeName(),
-						}
-					}
-					format, ok := tender.ToString(args[1])
-					if !ok {
-						return nil, tender.ErrInvalidArgumentType{
-							Name:     "path",
-							Expected: "string",
-							Found:    args[1].TypeName(),
-						}
-					}
-
-					file, err := os.Create(path)
-					if err != nil {
-						return wrapError(err), nil
-					}
-
-					defer file.Close()
-
-					if format == "png" {
-						err = png.Encode(file, img)
-						if err != nil {
-							return wrapError(err), nil
-						}
-					} else if format == "jpeg" {
-						err = jpeg.Encode(file, img, nil)
-						if err != nil {
-							return wrapError(err), nil
-						}
-					} else if format == "tiff" {
-						err = tiff.Encode(file, img, nil)
-						if err != nil {
-							return wrapError(err), nil
-						}
-					} else if format == "bmp" {
-						err = bmp.Encode(file, img)
-						if err != nil {
-							return wrapError(err), nil
-						}
-					}
-
-					return nil, nil
-				},
-			},
-		},
 	}
+
+	return imageMethods.New(img)
 }
 
 func makeRectangle(rect image.Rectangle) *tender.ImmutableMap {
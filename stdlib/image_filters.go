@@ -11,234 +11,186 @@ import (
 	"github.com/2dprototype/tender"
 )
 
-// makeImageFilters wraps an image.Image and returns an ImmutableMap of filter functions.
-func makeImageFilters(img image.Image) *tender.ImmutableMap {
-	return &tender.ImmutableMap{
-		Value: map[string]tender.Object{
-			"blur": &tender.UserFunction{
-				Value: func(args ...tender.Object) (tender.Object, error) {
-					if len(args) != 1 {
-						return nil, tender.ErrWrongNumArguments
-					}
-					r, _ := tender.ToInt(args[0])
-					newImg := applyBlurParallelOptimized(img, r)
-					return makeImage(newImg), nil
-				},
-			},
-			"bnw": &tender.UserFunction{
-				Name: "bnw",
-				Value: func(args ...tender.Object) (tender.Object, error) {
-					if len(args) != 1 {
-						return nil, tender.ErrWrongNumArguments
-					}
-					t, _ := tender.ToUint8(args[0])
-					return makeImage(applyBnWParallel(img, t)), nil
-				},
-			},
-			// "glitch" filter: optionally accepts a maxShift (default 20)
-			"glitch": &tender.UserFunction{
-				Value: func(args ...tender.Object) (tender.Object, error) {
-					if len(args) != 1 {
-						return nil, tender.ErrWrongNumArguments
-					}
-					m, _ := tender.ToInt(args[0])
-					return makeImage(applyGlitchParallel(img, m)), nil
-				},
-			},
-			"invert": &tender.UserFunction{
-				Name: "invert",
-				Value: func(args ...tender.Object) (tender.Object, error) {
-					newImg := applyInvertParallel(img)
-					return makeImage(newImg), nil
-				},
-			},
-			"grayscale": &tender.UserFunction{
-				Name: "grayscale",
-				Value: func(args ...tender.Object) (tender.Object, error) {
-					newImg := applyGrayscaleParallel(img)
-					return makeImage(newImg), nil
-				},
-			},
-			"sepia": &tender.UserFunction{
-				Name: "sepia",
-				Value: func(args ...tender.Object) (tender.Object, error) {
-					newImg := applySepiaParallel(img)
-					return makeImage(newImg), nil
-				},
-			},
-			"brightness": &tender.UserFunction{
-				Name: "brightness",
-				Value: func(args ...tender.Object) (tender.Object, error) {
-					// Required: offset (can be negative)
-					if len(args) != 1 {
-						return nil, tender.ErrWrongNumArguments
-					}
-					offset, ok := tender.ToInt(args[0])
-					if !ok {
-						return nil, tender.ErrInvalidArgumentType{
-							Name:     "offset",
-							Expected: "int",
-							Found:    args[0].TypeName(),
-						}
-					}
-					newImg := applyBrightnessParallel(img, offset)
-					return makeImage(newImg), nil
-				},
-			},
-			"contrast": &tender.UserFunction{
-				Name: "contrast",
-				Value: func(args ...tender.Object) (tender.Object, error) {
-					// Required: factor (float, e.g., 1.0 = no change)
-					if len(args) != 1 {
-						return nil, tender.ErrWrongNumArguments
-					}
-					factor, ok := tender.ToFloat64(args[0])
-					if !ok {
-						return nil, tender.ErrInvalidArgumentType{
-							Name:     "factor",
-							Expected: "float",
-							Found:    args[0].TypeName(),
-						}
-					}
-					newImg := applyContrastParallel(img, factor)
-					return makeImage(newImg), nil
-				},
-			},
-			"saturation": &tender.UserFunction{
-				Name: "saturation",
-				Value: func(args ...tender.Object) (tender.Object, error) {
-					// Required: factor (float; 0 = grayscale, 1 = original)
-					if len(args) != 1 {
-						return nil, tender.ErrWrongNumArguments
-					}
-					factor, ok := tender.ToFloat64(args[0])
-					if !ok {
-						return nil, tender.ErrInvalidArgumentType{
-							Name:     "factor",
-							Expected: "float",
-							Found:    args[0].TypeName(),
-						}
-					}
-					newImg := applySaturationParallel(img, factor)
-					return makeImage(newImg), nil
-				},
-			},
-			"sharpen": &tender.UserFunction{
-				Name: "sharpen",
-				Value: func(args ...tender.Object) (tender.Object, error) {
-					newImg := applyConvolutionParallel(img, [][]float64{
-						{0, -1, 0},
-						{-1, 5, -1},
-						{0, -1, 0},
-					}, 1, 0)
-					return makeImage(newImg), nil
-				},
-			},
-			"emboss": &tender.UserFunction{
-				Name: "emboss",
-				Value: func(args ...tender.Object) (tender.Object, error) {
-					// Using an emboss kernel with an offset to recenter colors.
-					newImg := applyConvolutionParallel(img, [][]float64{
-						{-2, -1, 0},
-						{-1, 1, 1},
-						{0, 1, 2},
-					}, 1, 128)
-					return makeImage(newImg), nil
-				},
-			},
-			"edge": &tender.UserFunction{
-				Name: "edge",
-				Value: func(args ...tender.Object) (tender.Object, error) {
-					// Edge detection kernel; offset added for visibility.
-					newImg := applyConvolutionParallel(img, [][]float64{
-						{1, 1, 1},
-						{1, -8, 1},
-						{1, 1, 1},
-					}, 1, 128)
-					return makeImage(newImg), nil
-				},
-			},
-            // New filters
-            "hue": &tender.UserFunction{
-                Name: "hue",
-                Value: func(args ...tender.Object) (tender.Object, error) {
-                    if len(args) != 1 {
-                        return nil, tender.ErrWrongNumArguments
-                    }
-                    hue, ok := tender.ToFloat64(args[0])
-                    if !ok {
-                        return nil, tender.ErrInvalidArgumentType{
-                            Name:     "hue",
-                            Expected: "float",
-                            Found:    args[0].TypeName(),
-                        }
-                    }
-                    return makeImage(applyHueParallel(img, hue)), nil
-                },
-            },
-            
-            "temperature": &tender.UserFunction{
-                Name: "temperature",
-                Value: func(args ...tender.Object) (tender.Object, error) {
-                    if len(args) != 1 {
-                        return nil, tender.ErrWrongNumArguments
-                    }
-                    temp, ok := tender.ToFloat64(args[0])
-                    if !ok {
-                        return nil, tender.ErrInvalidArgumentType{
-                            Name:     "temperature",
-                            Expected: "float",
-                            Found:    args[0].TypeName(),
-                        }
-                    }
-                    return makeImage(applyTemperatureParallel(img, temp)), nil
-                },
-            },
-            
-            "vignette": &tender.UserFunction{
-                Name: "vignette",
-                Value: func(args ...tender.Object) (tender.Object, error) {
-                    if len(args) != 1 {
-                        return nil, tender.ErrWrongNumArguments
-                    }
-                    intensity, ok := tender.ToFloat64(args[0])
-                    if !ok {
-                        return nil, tender.ErrInvalidArgumentType{
-                            Name:     "intensity",
-                            Expected: "float",
-                            Found:    args[0].TypeName(),
-                        }
-                    }
-                    return makeImage(applyVignetteParallel(img, intensity)), nil
-                },
-            },
-            
-            "pixelate": &tender.UserFunction{
-                Name: "pixelate",
-                Value: func(args ...tender.Object) (tender.Object, error) {
-                    if len(args) != 1 {
-                        return nil, tender.ErrWrongNumArguments
-                    }
-                    size, ok := tender.ToInt(args[0])
-                    if !ok {
-                        return nil, tender.ErrInvalidArgumentType{
-                            Name:     "size",
-                            Expected: "int",
-                            Found:    args[0].TypeName(),
-                        }
-                    }
-                    return makeImage(applyPixelateParallel(img, size)), nil
-                },
-            },
-            
-            "sobel": &tender.UserFunction{
-                Name: "sobel",
-                Value: func(args ...tender.Object) (tender.Object, error) {
-                    return makeImage(applySobelEdgeDetection(img)), nil
-                },
-            },
-        },
-    }
+// imageFilterMethods is the method table of the filters of an image, which
+// return filtered copies of the image.
+var imageFilterMethods *tender.MethodTable
+
+func init() {
+	imageFilterMethods = tender.NewMethodTable("image-filters", map[string]tender.MethodFunc{
+		"blur": imageMethod(func(img image.Image, args ...tender.Object) (tender.Object, error) {
+			if len(args) != 1 {
+				return nil, tender.ErrWrongNumArguments
+			}
+			r, _ := tender.ToInt(args[0])
+			newImg := applyBlurParallelOptimized(img, r)
+			return makeImage(newImg), nil
+		}),
+		"bnw": imageMethod(func(img image.Image, args ...tender.Object) (tender.Object, error) {
+			if len(args) != 1 {
+				return nil, tender.ErrWrongNumArguments
+			}
+			t, _ := tender.ToUint8(args[0])
+			return makeImage(applyBnWParallel(img, t)), nil
+		}),
+		// "glitch" filter: optionally accepts a maxShift (default 20)
+		"glitch": imageMethod(func(img image.Image, args ...tender.Object) (tender.Object, error) {
+			if len(args) != 1 {
+				return nil, tender.ErrWrongNumArguments
+			}
+			m, _ := tender.ToInt(args[0])
+			return makeImage(applyGlitchParallel(img, m)), nil
+		}),
+		"invert": imageMethod(func(img image.Image, args ...tender.Object) (tender.Object, error) {
+			newImg := applyInvertParallel(img)
+			return makeImage(newImg), nil
+		}),
+		"grayscale": imageMethod(func(img image.Image, args ...tender.Object) (tender.Object, error) {
+			newImg := applyGrayscaleParallel(img)
+			return makeImage(newImg), nil
+		}),
+		"sepia": imageMethod(func(img image.Image, args ...tender.Object) (tender.Object, error) {
+			newImg := applySepiaParallel(img)
+			return makeImage(newImg), nil
+		}),
+		"brightness": imageMethod(func(img image.Image, args ...tender.Object) (tender.Object, error) {
+			// Required: offset (can be negative)
+			if len(args) != 1 {
+				return nil, tender.ErrWrongNumArguments
+			}
+			offset, ok := tender.ToInt(args[0])
+			if !ok {
+				return nil, tender.ErrInvalidArgumentType{
+					Name:     "offset",
+					Expected: "int",
+					Found:    args[0].TypeName(),
+				}
+			}
+			newImg := applyBrightnessParallel(img, offset)
+			return makeImage(newImg), nil
+		}),
+		"contrast": imageMethod(func(img image.Image, args ...tender.Object) (tender.Object, error) {
+			// Required: factor (float, e.g., 1.0 = no change)
+			if len(args) != 1 {
+				return nil, tender.ErrWrongNumArguments
+			}
+			factor, ok := tender.ToFloat64(args[0])
+			if !ok {
+				return nil, tender.ErrInvalidArgumentType{
+					Name:     "factor",
+					Expected: "float",
+					Found:    args[0].TypeName(),
+				}
+			}
+			newImg := applyContrastParallel(img, factor)
+			return makeImage(newImg), nil
+		}),
+		"saturation": imageMethod(func(img image.Image, args ...tender.Object) (tender.Object, error) {
+			// Required: factor (float; 0 = grayscale, 1 = original)
+			if len(args) != 1 {
+				return nil, tender.ErrWrongNumArguments
+			}
+			factor, ok := tender.ToFloat64(args[0])
+			if !ok {
+				return nil, tender.ErrInvalidArgumentType{
+					Name:     "factor",
+					Expected: "float",
+					Found:    args[0].TypeName(),
+				}
+			}
+			newImg := applySaturationParallel(img, factor)
+			return makeImage(newImg), nil
+		}),
+		"sharpen": imageMethod(func(img image.Image, args ...tender.Object) (tender.Object, error) {
+			newImg := applyConvolutionParallel(img, [][]float64{
+				{0, -1, 0},
+				{-1, 5, -1},
+				{0, -1, 0},
+			}, 1, 0)
+			return makeImage(newImg), nil
+		}),
+		"emboss": imageMethod(func(img image.Image, args ...tender.Object) (tender.Object, error) {
+			// Using an emboss kernel with an offset to recenter colors.
+			newImg := applyConvolutionParallel(img, [][]float64{
+				{-2, -1, 0},
+				{-1, 1, 1},
+				{0, 1, 2},
+			}, 1, 128)
+			return makeImage(newImg), nil
+		}),
+		"edge": imageMethod(func(img image.Image, args ...tender.Object) (tender.Object, error) {
+			// Edge detection kernel; offset added for visibility.
+			newImg := applyConvolutionParallel(img, [][]float64{
+				{1, 1, 1},
+				{1, -8, 1},
+				{1, 1, 1},
+			}, 1, 128)
+			return makeImage(newImg), nil
+		}),
+        // New filters
+        "hue": imageMethod(func(img image.Image, args ...tender.Object) (tender.Object, error) {
+            if len(args) != 1 {
+                return nil, tender.ErrWrongNumArguments
+            }
+            hue, ok := tender.ToFloat64(args[0])
+            if !ok {
+                return nil, tender.ErrInvalidArgumentType{
+                    Name:     "hue",
+                    Expected: "float",
+                    Found:    args[0].TypeName(),
+                }
+            }
+            return makeImage(applyHueParallel(img, hue)), nil
+        }),
+        
+        "temperature": imageMethod(func(img image.Image, args ...tender.Object) (tender.Object, error) {
+            if len(args) != 1 {
+                return nil, tender.ErrWrongNumArguments
+            }
+            temp, ok := tender.ToFloat64(args[0])
+            if !ok {
+                return nil, tender.ErrInvalidArgumentType{
+                    Name:     "temperature",
+                    Expected: "float",
+                    Found:    args[0].TypeName(),
+                }
+            }
+            return makeImage(applyTemperatureParallel(img, temp)), nil
+        }),
+        
+        "vignette": imageMethod(func(img image.Image, args ...tender.Object) (tender.Object, error) {
+            if len(args) != 1 {
+                return nil, tender.ErrWrongNumArguments
+            }
+            intensity, ok := tender.ToFloat64(args[0])
+            if !ok {
+                return nil, tender.ErrInvalidArgumentType{
+                    Name:     "intensity",
+                    Expected: "float",
+                    Found:    args[0].TypeName(),
+                }
+            }
+            return makeImage(applyVignetteParallel(img, intensity)), nil
+        }),
+        
+        "pixelate": imageMethod(func(img image.Image, args ...tender.Object) (tender.Object, error) {
+            if len(args) != 1 {
+                return nil, tender.ErrWrongNumArguments
+            }
+            size, ok := tender.ToInt(args[0])
+            if !ok {
+                return nil, tender.ErrInvalidArgumentType{
+                    Name:     "size",
+                    Expected: "int",
+                    Found:    args[0].TypeName(),
+                }
+            }
+            return makeImage(applyPixelateParallel(img, size)), nil
+        }),
+        
+        "sobel": imageMethod(func(img image.Image, args ...tender.Object) (tender.Object, error) {
+            return makeImage(applySobelEdgeDetection(img)), nil
+        }),
+	}, nil)
 }
 // -------------------
 // Filter Implementations
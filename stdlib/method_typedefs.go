@@ -0,0 +1,329 @@
+package stdlib
+
+import (
+	"github.com/2dprototype/tender"
+)
+
+// The MethodXxx functions are the FuncXxx functions for the methods of Go
+// objects in a tender.MethodTable. They take a method expression, such as
+// (*gg.Context).DrawLine, and call it with the receiver of each call, so that
+// calling a method does not bind a function to its receiver.
+
+var argNames = [...]string{"first", "second", "third", "fourth", "fifth", "sixth"}
+
+// methodArgs are the converted arguments of a method: floats and ints by
+// position, and the string of a method taking a string first.
+type methodArgs struct {
+	f [len(argNames)]float64
+	i [len(argNames)]int
+	s string
+}
+
+// convertArgs converts the arguments of a method. kinds has an 'f', 'i' or
+// 's' for each argument, to convert it to a float, an int or a string.
+func convertArgs(args []tender.Object, kinds string) (a methodArgs, err error) {
+	if len(args) != len(kinds) {
+		return a, tender.ErrWrongNumArguments
+	}
+	for i, kind := range kinds {
+		var ok bool
+		var expected string
+		switch kind {
+		case 'f':
+			a.f[i], ok = tender.ToFloat64(args[i])
+			expected = "float(compatible)"
+		case 'i':
+			a.i[i], ok = tender.ToInt(args[i])
+			expected = "int(compatible)"
+		case 's':
+			a.s, ok = tender.ToString(args[i])
+			expected = "string(compatible)"
+		}
+		if !ok {
+			return a, tender.ErrInvalidArgumentType{
+				Name:     argNames[i],
+				Expected: expected,
+				Found:    args[i].TypeName(),
+			}
+		}
+	}
+	return a, nil
+}
+
+// floatPair returns an array of two floats.
+func floatPair(v1, v2 float64) tender.Object {
+	return &tender.Array{Value: []tender.Object{
+		&tender.Float{Value: v1},
+		&tender.Float{Value: v2},
+	}}
+}
+
+// MethodAR transform a method of 'func()' signature into MethodFunc type.
+func MethodAR[R any](fn func(R)) tender.MethodFunc {
+	return func(recv interface{}, args ...tender.Object) (tender.Object, error) {
+		if len(args) != 0 {
+			return nil, tender.ErrWrongNumArguments
+		}
+		fn(recv.(R))
+		return tender.NullValue, nil
+	}
+}
+
+// MethodARI transform a method of 'func() int' signature into MethodFunc
+// type.
+func MethodARI[R any](fn func(R) int) tender.MethodFunc {
+	return func(recv interface{}, args ...tender.Object) (tender.Object, error) {
+		if len(args) != 0 {
+			return nil, tender.ErrWrongNumArguments
+		}
+		return &tender.Int{Value: int64(fn(recv.(R)))}, nil
+	}
+}
+
+// MethodARF transform a method of 'func() float64' signature into MethodFunc
+// type.
+func MethodARF[R any](fn func(R) float64) tender.MethodFunc {
+	return func(recv interface{}, args ...tender.Object) (tender.Object, error) {
+		if len(args) != 0 {
+			return nil, tender.ErrWrongNumArguments
+		}
+		return &tender.Float{Value: fn(recv.(R))}, nil
+	}
+}
+
+// MethodAFR transform a method of 'func(float64)' signature into MethodFunc
+// type.
+func MethodAFR[R any](fn func(R, float64)) tender.MethodFunc {
+	return func(recv interface{}, args ...tender.Object) (tender.Object, error) {
+		a, err := convertArgs(args, "f")
+		if err != nil {
+			return nil, err
+		}
+		fn(recv.(R), a.f[0])
+		return tender.NullValue, nil
+	}
+}
+
+// MethodAFFR transform a method of 'func(float64, float64)' signature into
+// MethodFunc type.
+func MethodAFFR[R any](fn func(R, float64, float64)) tender.MethodFunc {
+	return func(recv interface{}, args ...tender.Object) (tender.Object, error) {
+		a, err := convertArgs(args, "ff")
+		if err != nil {
+			return nil, err
+		}
+		fn(recv.(R), a.f[0], a.f[1])
+		return tender.NullValue, nil
+	}
+}
+
+// MethodAFFFR transform a method of 'func(float64, float64, float64)'
+// signature into MethodFunc type.
+func MethodAFFFR[R any](fn func(R, float64, float64, float64)) tender.MethodFunc {
+	return func(recv interface{}, args ...tender.Object) (tender.Object, error) {
+		a, err := convertArgs(args, "fff")
+		if err != nil {
+			return nil, err
+		}
+		fn(recv.(R), a.f[0], a.f[1], a.f[2])
+		return tender.NullValue, nil
+	}
+}
+
+// MethodAFFFFR transform a method of 'func(float64, float64, float64,
+// float64)' signature into MethodFunc type.
+func MethodAFFFFR[R any](fn func(R, float64, float64, float64, float64)) tender.MethodFunc {
+	return func(recv interface{}, args ...tender.Object) (tender.Object, error) {
+		a, err := convertArgs(args, "ffff")
+		if err != nil {
+			return nil, err
+		}
+		fn(recv.(R), a.f[0], a.f[1], a.f[2], a.f[3])
+		return tender.NullValue, nil
+	}
+}
+
+// MethodAFFFFFR transform a method of 'func(float64, float64, float64,
+// float64, float64)' signature into MethodFunc type.
+func MethodAFFFFFR[R any](fn func(R, float64, float64, float64, float64, float64)) tender.MethodFunc {
+	return func(recv interface{}, args ...tender.Object) (tender.Object, error) {
+		a, err := convertArgs(args, "fffff")
+		if err != nil {
+			return nil, err
+		}
+		fn(recv.(R), a.f[0], a.f[1], a.f[2], a.f[3], a.f[4])
+		return tender.NullValue, nil
+	}
+}
+
+// MethodAFFFFFFR transform a method of 'func(float64, float64, float64,
+// float64, float64, float64)' signature into MethodFunc type.
+func MethodAFFFFFFR[R any](fn func(R, float64, float64, float64, float64, float64, float64)) tender.MethodFunc {
+	return func(recv interface{}, args ...tender.Object) (tender.Object, error) {
+		a, err := convertArgs(args, "ffffff")
+		if err != nil {
+			return nil, err
+		}
+		fn(recv.(R), a.f[0], a.f[1], a.f[2], a.f[3], a.f[4], a.f[5])
+		return tender.NullValue, nil
+	}
+}
+
+// MethodAFFRFF transform a method of 'func(float64, float64) (float64,
+// float64)' signature into MethodFunc type.
+func MethodAFFRFF[R any](fn func(R, float64, float64) (float64, float64)) tender.MethodFunc {
+	return func(recv interface{}, args ...tender.Object) (tender.Object, error) {
+		a, err := convertArgs(args, "ff")
+		if err != nil {
+			return nil, err
+		}
+		return floatPair(fn(recv.(R), a.f[0], a.f[1])), nil
+	}
+}
+
+// MethodAIIR transform a method of 'func(int, int)' signature into
+// MethodFunc type.
+func MethodAIIR[R any](fn func(R, int, int)) tender.MethodFunc {
+	return func(recv interface{}, args ...tender.Object) (tender.Object, error) {
+		a, err := convertArgs(args, "ii")
+		if err != nil {
+			return nil, err
+		}
+		fn(recv.(R), a.i[0], a.i[1])
+		return tender.NullValue, nil
+	}
+}
+
+// MethodAIIIR transform a method of 'func(int, int, int)' signature into
+// MethodFunc type.
+func MethodAIIIR[R any](fn func(R, int, int, int)) tender.MethodFunc {
+	return func(recv interface{}, args ...tender.Object) (tender.Object, error) {
+		a, err := convertArgs(args, "iii")
+		if err != nil {
+			return nil, err
+		}
+		fn(recv.(R), a.i[0], a.i[1], a.i[2])
+		return tender.NullValue, nil
+	}
+}
+
+// MethodAIIIIR transform a method of 'func(int, int, int, int)' signature
+// into MethodFunc type.
+func MethodAIIIIR[R any](fn func(R, int, int, int, int)) tender.MethodFunc {
+	return func(recv interface{}, args ...tender.Object) (tender.Object, error) {
+		a, err := convertArgs(args, "iiii")
+		if err != nil {
+			return nil, err
+		}
+		fn(recv.(R), a.i[0], a.i[1], a.i[2], a.i[3])
+		return tender.NullValue, nil
+	}
+}
+
+// MethodASR transform a method of 'func(string)' signature into MethodFunc
+// type.
+func MethodASR[R any](fn func(R, string)) tender.MethodFunc {
+	return func(recv interface{}, args ...tender.Object) (tender.Object, error) {
+		a, err := convertArgs(args, "s")
+		if err != nil {
+			return nil, err
+		}
+		fn(recv.(R), a.s)
+		return tender.NullValue, nil
+	}
+}
+
+// MethodASRE transform a method of 'func(string) error' signature into
+// MethodFunc type.
+func MethodASRE[R any](fn func(R, string) error) tender.MethodFunc {
+	return func(recv interface{}, args ...tender.Object) (tender.Object, error) {
+		a, err := convertArgs(args, "s")
+		if err != nil {
+			return nil, err
+		}
+		return wrapError(fn(recv.(R), a.s)), nil
+	}
+}
+
+// MethodASRFF transform a method of 'func(string) (float64, float64)'
+// signature into MethodFunc type.
+func MethodASRFF[R any](fn func(R, string) (float64, float64)) tender.MethodFunc {
+	return func(recv interface{}, args ...tender.Object) (tender.Object, error) {
+		a, err := convertArgs(args, "s")
+		if err != nil {
+			return nil, err
+		}
+		return floatPair(fn(recv.(R), a.s)), nil
+	}
+}
+
+// MethodASFFR transform a method of 'func(string, float64, float64)'
+// signature into MethodFunc type.
+func MethodASFFR[R any](fn func(R, string, float64, float64)) tender.MethodFunc {
+	return func(recv interface{}, args ...tender.Object) (tender.Object, error) {
+		a, err := convertArgs(args, "sff")
+		if err != nil {
+			return nil, err
+		}
+		fn(recv.(R), a.s, a.f[1], a.f[2])
+		return tender.NullValue, nil
+	}
+}
+
+// MethodASFFFFR transform a method of 'func(string, float64, float64,
+// float64, float64)' signature into MethodFunc type.
+func MethodASFFFFR[R any](fn func(R, string, float64, float64, float64, float64)) tender.MethodFunc {
+	return func(recv interface{}, args ...tender.Object) (tender.Object, error) {
+		a, err := convertArgs(args, "sffff")
+		if err != nil {
+			return nil, err
+		}
+		fn(recv.(R), a.s, a.f[1], a.f[2], a.f[3], a.f[4])
+		return tender.NullValue, nil
+	}
+}
+
+// MethodASFRFF transform a method of 'func(string, float64) (float64,
+// float64)' signature into MethodFunc type.
+func MethodASFRFF[R any](fn func(R, string, float64) (float64, float64)) tender.MethodFunc {
+	return func(recv interface{}, args ...tender.Object) (tender.Object, error) {
+		a, err := convertArgs(args, "sf")
+		if err != nil {
+			return nil, err
+		}
+		return floatPair(fn(recv.(R), a.s, a.f[1])), nil
+	}
+}
+
+// MethodASFRSs transform a method of 'func(string, float64) []string'
+// signature into MethodFunc type.
+func MethodASFRSs[R any](fn func(R, string, float64) []string) tender.MethodFunc {
+	return func(recv interface{}, args ...tender.Object) (tender.Object, error) {
+		if len(args) != 2 {
+			return nil, tender.ErrWrongNumArguments
+		}
+		s, _ := tender.ToString(args[0])
+		f, _ := tender.ToFloat64(args[1])
+		arr := &tender.Array{}
+		for _, elem := range fn(recv.(R), s, f) {
+			if len(elem) > tender.MaxStringLen {
+				return nil, tender.ErrStringLimit
+			}
+			arr.Value = append(arr.Value, &tender.String{Value: elem})
+		}
+		return arr, nil
+	}
+}
+
+// MethodAYFRE transform a method of 'func([]byte, float64) error' signature
+// into MethodFunc type.
+func MethodAYFRE[R any](fn func(R, []byte, float64) error) tender.MethodFunc {
+	return func(recv interface{}, args ...tender.Object) (tender.Object, error) {
+		if len(args) != 2 {
+			return nil, tender.ErrWrongNumArguments
+		}
+		b, _ := tender.ToByteSlice(args[0])
+		f, _ := tender.ToFloat64(args[1])
+		return wrapError(fn(recv.(R), b, f)), nil
+	}
+}
@@ -253,7 +253,7 @@ var osModule = map[string]tender.Object{
 	"read_file": &tender.UserFunction{
 		Name:  "read_file",
 		Value: osReadFile,
-	}, // readfile(name) => array(byte)/error
+	}, // readfile(name, fs) => array(byte)/error
 	"read_dir": &tender.UserFunction{
 		Name:  "read_dir",
 		Value: osReadDir,
@@ -303,7 +303,7 @@ func osReadDir(args ...tender.Object) (ret tender.Object, err error) {
 }
 
 func osReadFile(args ...tender.Object) (ret tender.Object, err error) {
-	if len(args) != 1 {
+	if len(args) != 1 && len(args) != 2 {
 		return nil, tender.ErrWrongNumArguments
 	}
 	fname, ok := tender.ToString(args[0])
@@ -314,14 +314,44 @@ func osReadFile(args ...tender.Object) (ret tender.Object, err error) {
 			Found:    args[0].TypeName(),
 		}
 	}
-	bytes, err := ioutil.ReadFile(fname)
+	fsys, err := fsArg(args, 1)
+	if err != nil {
+		return nil, err
+	}
+	bytes, err := readFile(fsys, fname)
 	if err != nil {
 		return wrapError(err), nil
 	}
 	if len(bytes) > tender.MaxBytesLen {
 		return nil, tender.ErrBytesLimit
 	}
-	return &tender.Bytes{Value: bytes}, nil
+	return &tender.Bytes{Value: append([]byte{}, bytes...)}, nil
+}
+
+// fsArg returns the embedded file system passed as the optional argument i,
+// or nil.
+func fsArg(args []tender.Object, i int) (*tender.FS, error) {
+	if len(args) <= i {
+		return nil, nil
+	}
+	fsys, ok := tender.ToFS(args[i])
+	if !ok {
+		return nil, tender.ErrInvalidArgumentType{
+			Name:     "fs",
+			Expected: "fs",
+			Found:    args[i].TypeName(),
+		}
+	}
+	return fsys, nil
+}
+
+// readFile reads the named file from the embedded file system fsys, or from
+// the disk if fsys is nil.
+func readFile(fsys *tender.FS, name string) ([]byte, error) {
+	if fsys != nil {
+		return fsys.ReadFile(name)
+	}
+	return ioutil.ReadFile(name)
 }
 
 func osStat(args ...tender.Object) (ret tender.Object, err error) {
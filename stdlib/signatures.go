@@ -0,0 +1,109 @@
+package stdlib
+
+// ModuleSignatures are the function signatures of builtin modules used by the
+// static type checker ("tender check"). Members without a signature are not
+// checked.
+var ModuleSignatures = map[string]map[string]string{
+	"math": {
+		"abs":       "fn(int|float) -> float",
+		"acos":      "fn(int|float) -> float",
+		"acosh":     "fn(int|float) -> float",
+		"asin":      "fn(int|float) -> float",
+		"asinh":     "fn(int|float) -> float",
+		"atan":      "fn(int|float) -> float",
+		"atan2":     "fn(int|float, int|float) -> float",
+		"atanh":     "fn(int|float) -> float",
+		"cbrt":      "fn(int|float) -> float",
+		"ceil":      "fn(int|float) -> float",
+		"copysign":  "fn(int|float, int|float) -> float",
+		"cos":       "fn(int|float) -> float",
+		"cosh":      "fn(int|float) -> float",
+		"dim":       "fn(int|float, int|float) -> float",
+		"erf":       "fn(int|float) -> float",
+		"erfc":      "fn(int|float) -> float",
+		"exp":       "fn(int|float) -> float",
+		"exp2":      "fn(int|float) -> float",
+		"expm1":     "fn(int|float) -> float",
+		"floor":     "fn(int|float) -> float",
+		"gamma":     "fn(int|float) -> float",
+		"hypot":     "fn(int|float, int|float) -> float",
+		"ilogb":     "fn(int|float) -> int",
+		"inf":       "fn(int) -> float",
+		"is_inf":    "fn(int|float, int) -> bool",
+		"is_nan":    "fn(int|float) -> bool",
+		"j0":        "fn(int|float) -> float",
+		"j1":        "fn(int|float) -> float",
+		"jn":        "fn(int, int|float) -> float",
+		"ldexp":     "fn(int|float, int) -> float",
+		"log":       "fn(int|float) -> float",
+		"log10":     "fn(int|float) -> float",
+		"log1p":     "fn(int|float) -> float",
+		"log2":      "fn(int|float) -> float",
+		"logb":      "fn(int|float) -> float",
+		"max":       "fn(int|float, int|float) -> float",
+		"min":       "fn(int|float, int|float) -> float",
+		"mod":       "fn(int|float, int|float) -> float",
+		"nan":       "fn() -> float",
+		"nextafter": "fn(int|float, int|float) -> float",
+		"pow":       "fn(int|float, int|float) -> float",
+		"pow10":     "fn(int) -> float",
+		"remainder": "fn(int|float, int|float) -> float",
+		"signbit":   "fn(int|float) -> bool",
+		"sin":       "fn(int|float) -> float",
+		"sinh":      "fn(int|float) -> float",
+		"sqrt":      "fn(int|float) -> float",
+		"tan":       "fn(int|float) -> float",
+		"tanh":      "fn(int|float) -> float",
+		"trunc":     "fn(int|float) -> float",
+		"y0":        "fn(int|float) -> float",
+		"y1":        "fn(int|float) -> float",
+		"yn":        "fn(int, int|float) -> float",
+	},
+	"strings": {
+		"compare":        "fn(string, string) -> int",
+		"contains":       "fn(string, string) -> bool",
+		"contains_any":   "fn(string, string) -> bool",
+		"count":          "fn(string, string) -> int",
+		"equal_fold":     "fn(string, string) -> bool",
+		"fields":         "fn(string) -> array",
+		"has_prefix":     "fn(string, string) -> bool",
+		"has_suffix":     "fn(string, string) -> bool",
+		"index":          "fn(string, string) -> int",
+		"index_any":      "fn(string, string) -> int",
+		"join":           "fn(array, string) -> string",
+		"last_index":     "fn(string, string) -> int",
+		"last_index_any": "fn(string, string) -> int",
+		"repeat":         "fn(string, int) -> string",
+		"replace":        "fn(string, string, string, int) -> string",
+		"split":          "fn(string, string) -> array",
+		"split_after":    "fn(string, string) -> array",
+		"split_after_n":  "fn(string, string, int) -> array",
+		"split_n":        "fn(string, string, int) -> array",
+		"title":          "fn(string) -> string",
+		"to_lower":       "fn(string) -> string",
+		"to_title":       "fn(string) -> string",
+		"to_upper":       "fn(string) -> string",
+		"trim":           "fn(string, string) -> string",
+		"trim_left":      "fn(string, string) -> string",
+		"trim_prefix":    "fn(string, string) -> string",
+		"trim_right":     "fn(string, string) -> string",
+		"trim_space":     "fn(string) -> string",
+		"trim_suffix":    "fn(string, string) -> string",
+		"atoi":           "fn(string) -> int|error",
+		"format_bool":    "fn(bool) -> string",
+		"format_float":   "fn(int|float, char, int, int) -> string",
+		"format_int":     "fn(int, int) -> string",
+		"itoa":           "fn(int) -> string",
+		"parse_bool":     "fn(string) -> bool|error",
+		"parse_float":    "fn(string, int) -> float|error",
+		"parse_int":      "fn(string, int, int) -> int|error",
+		"quote":          "fn(string) -> string",
+		"unquote":        "fn(string) -> string|error",
+	},
+	"fmt": {
+		"print":   "fn(...any) -> null",
+		"println": "fn(...any) -> null",
+		"printf":  "fn(string, ...any) -> null",
+		"sprintf": "fn(string, ...any) -> string",
+	},
+}
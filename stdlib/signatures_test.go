@@ -0,0 +1,63 @@
+package stdlib
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/2dprototype/tender"
+	"github.com/2dprototype/tender/parser"
+)
+
+func TestModuleSignatures(t *testing.T) {
+	for name, members := range ModuleSignatures {
+		mod := BuiltinModules[name]
+		if mod == nil {
+			t.Errorf("signatures of unknown module %s", name)
+			continue
+		}
+		for member, s := range members {
+			if _, ok := mod[member]; !ok {
+				t.Errorf("signature of unknown function %s.%s", name, member)
+			}
+			if _, err := tender.ParseSignature(s); err != nil {
+				t.Errorf("%s.%s: %v", name, member, err)
+			}
+		}
+	}
+}
+
+func TestCheckModuleCalls(t *testing.T) {
+	tests := []struct {
+		src    string
+		errors []string
+	}{
+		{`math := import("math"); math.pow(2, 10); math.sqrt(2.0)`, nil},
+		{`math := import("math"); math.pow(2)`,
+			[]string{"wrong number of arguments in call to 'math.pow': want=2, got=1"}},
+		{`math := import("math"); math.nan(1)`,
+			[]string{"wrong number of arguments in call to 'math.nan': want=0, got=1"}},
+		{`math := import("math"); math.sqrt("2")`,
+			[]string{"invalid type for argument 'first' in call to 'math.sqrt': expected int|float, found string"}},
+		{`strings := import("strings"); strings.join(["a"], ",", 1)`,
+			[]string{"wrong number of arguments in call to 'strings.join': want=2, got=3"}},
+		{`strings := import("strings"); strings.join("a", ",")`,
+			[]string{"invalid type for argument 'first' in call to 'strings.join': expected array, found string"}},
+		{`from "strings" import split; x: int := split("a,b", ",")`,
+			[]string{"cannot assign array to 'x' (type int)"}},
+	}
+	for _, tc := range tests {
+		fileSet := parser.NewFileSet()
+		srcFile := fileSet.AddFile("test", -1, len(tc.src))
+		file, err := parser.NewParser(srcFile, []byte(tc.src), nil).ParseFile()
+		if err != nil {
+			t.Fatal(err)
+		}
+		var msgs []string
+		for _, err := range tender.NewChecker(srcFile, ModuleSignatures).Check(file) {
+			msgs = append(msgs, err.(*tender.CheckError).Err.Error())
+		}
+		if strings.Join(msgs, "\n") != strings.Join(tc.errors, "\n") {
+			t.Errorf("%s\nerrors %q, expected %q", tc.src, msgs, tc.errors)
+		}
+	}
+}
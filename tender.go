@@ -18,16 +18,22 @@ var (
 	// MaxBytesLen is the maximum length for bytes value. Note this limit
 	// applies to all compiler/VM instances in the process.
 	MaxBytesLen = 2147483647
+
+	// Version is the tender version recorded in compiled bytecode headers.
+	Version = "v0.0.2"
 )
 
 const (
-	// GlobalsSize is the maximum number of global variables for a VM.
+	// GlobalsSize is the default number of global variables for a VM. A
+	// VM running bytecode that uses more globals allocates more.
 	GlobalsSize = 10240
 
-	// StackSize is the maximum stack size for a VM.
+	// StackSize is the default maximum stack size for a VM. See
+	// VM.MaxStackSize and Script.SetMaxStackSize.
 	StackSize = 20480
 
-	// MaxFrames is the maximum number of function frames for a VM.
+	// MaxFrames is the default maximum number of function frames for a VM.
+	// See VM.MaxFrames and Script.SetMaxFrames.
 	MaxFrames = 10240
 
 	// SourceFileExtDefault is the default extension for source files.
@@ -746,6 +752,8 @@ func ToInterface(o Object) (res interface{}) {
 		res = errors.New(o.String())
 	case *Null:
 		res = nil
+	case *MethodObject:
+		res = o.Value
 	case Object:
 		return o
 	}
@@ -68,6 +68,7 @@ const (
 	Semicolon    // ;
 	Colon        // :
 	Question     // ?
+	Arrow        // ->
 	_operatorEnd
 	_keywordBeg
 	Break
@@ -149,6 +150,7 @@ var tokens = [...]string{
 	Semicolon:    ";",
 	Colon:        ":",
 	Question:     "?",
+	Arrow:        "->",
 	Break:        "break",
 	Continue:     "continue",
 	Else:         "else",
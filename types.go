@@ -0,0 +1,201 @@
+package tender
+
+import (
+	"fmt"
+	"strings"
+)
+
+// knownTypes are the type names accepted in type annotations.
+var knownTypes = map[string]bool{
+	"any":      true,
+	"int":      true,
+	"float":    true,
+	"bigint":   true,
+	"bigfloat": true,
+	"complex":  true,
+	"string":   true,
+	"bool":     true,
+	"char":     true,
+	"bytes":    true,
+	"array":    true,
+	"map":      true,
+	"time":     true,
+	"error":    true,
+	"null":     true,
+	"fn":       true,
+}
+
+// IsKnownType returns true if name can be used in a type annotation.
+func IsKnownType(name string) bool {
+	return knownTypes[name]
+}
+
+// TypeMatches returns true if the object o satisfies the type annotation
+// types, which is a list of type names separated by "|". An int satisfies
+// float.
+func TypeMatches(o Object, types string) bool {
+	for _, name := range strings.Split(types, "|") {
+		if typeMatches(o, name) {
+			return true
+		}
+	}
+	return false
+}
+
+func typeMatches(o Object, name string) bool {
+	switch name {
+	case "any":
+		return true
+	case "array":
+		switch o.(type) {
+		case *Array, *ImmutableArray:
+			return true
+		}
+		return false
+	case "map":
+		switch o.(type) {
+		case *Map, *ImmutableMap:
+			return true
+		}
+		return false
+	case "fn":
+		switch o.(type) {
+		case *CompiledFunction, *BuiltinFunction, *UserFunction:
+			return true
+		}
+		return false
+	case "float":
+		switch o.(type) {
+		case *Float, *Int:
+			return true
+		}
+		return false
+	}
+	return o.TypeName() == name
+}
+
+// convertsIntToFloat returns true if the type annotation types accepts floats
+// but not ints, so that an int argument is converted to a float.
+func convertsIntToFloat(types string) bool {
+	float := false
+	for _, name := range strings.Split(types, "|") {
+		switch name {
+		case "int", "any":
+			return false
+		case "float":
+			float = true
+		}
+	}
+	return float
+}
+
+// Signature describes the parameter and result types of a function. An empty
+// type name or "any" means the type is not checked.
+type Signature struct {
+	Params  []string
+	VarArgs bool // if true, the last parameter type applies to all remaining arguments
+	Result  string
+}
+
+// ParseSignature parses a signature string such as
+// "fn(float, ...any) -> float".
+func ParseSignature(s string) (*Signature, error) {
+	src := strings.TrimSpace(s)
+	if !strings.HasPrefix(src, "fn(") {
+		return nil, fmt.Errorf("invalid signature: %s", s)
+	}
+	end := strings.Index(src, ")")
+	if end < 0 {
+		return nil, fmt.Errorf("invalid signature: %s", s)
+	}
+
+	sig := &Signature{Result: "any"}
+	params := strings.TrimSpace(src[3:end])
+	if params != "" {
+		for _, param := range strings.Split(params, ",") {
+			param = strings.TrimSpace(param)
+			if strings.HasPrefix(param, "...") {
+				sig.VarArgs = true
+				param = param[3:]
+			}
+			sig.Params = append(sig.Params, param)
+		}
+	}
+
+	rest := strings.TrimSpace(src[end+1:])
+	if rest != "" {
+		if !strings.HasPrefix(rest, "->") {
+			return nil, fmt.Errorf("invalid signature: %s", s)
+		}
+		sig.Result = strings.TrimSpace(rest[2:])
+	}
+	return sig, nil
+}
+
+// String returns the signature in the same form accepted by ParseSignature.
+func (s *Signature) String() string {
+	params := make([]string, len(s.Params))
+	for i, p := range s.Params {
+		if s.VarArgs && i == len(s.Params)-1 {
+			p = "..." + p
+		}
+		params[i] = p
+	}
+	return "fn(" + strings.Join(params, ", ") + ") -> " + s.Result
+}
+
+// builtinSignatures are the signatures of the builtin functions used by the
+// static type checker.
+var builtinSignatures = map[string]string{
+	"len":                "fn(any) -> int",
+	"cap":                "fn(array) -> int",
+	"copy":               "fn(any) -> any",
+	"append":             "fn(array, ...any) -> array",
+	"delete":             "fn(map, string) -> null",
+	"splice":             "fn(array, ...any) -> array",
+	"reverse":            "fn(array|string|bytes) -> any",
+	"sort":               "fn(array|string|bytes) -> any",
+	"includes":           "fn(any, any) -> bool",
+	"indexof":            "fn(any, any) -> int",
+	"lastindexof":        "fn(any, any) -> int",
+	"rune":               "fn(char|string) -> int",
+	"string":             "fn(any, ...any) -> string",
+	"int":                "fn(any, ...any) -> int",
+	"bigint":             "fn(any, ...any) -> bigint",
+	"bool":               "fn(any) -> bool",
+	"float":              "fn(any, ...any) -> float",
+	"bigfloat":           "fn(any, ...any) -> bigfloat",
+	"complex":            "fn(int|float, int|float) -> complex",
+	"char":               "fn(any, ...any) -> char",
+	"bytes":              "fn(any, ...any) -> bytes",
+	"time":               "fn(any, ...any) -> time",
+	"typeof":             "fn(any) -> string",
+	"format":             "fn(string, ...any) -> string",
+	"range":              "fn(int, int, ...int) -> array",
+	"print":              "fn(...any) -> null",
+	"println":            "fn(...any) -> null",
+	"sysout":             "fn(...any) -> null",
+	"is_cycle":           "fn(any) -> bool",
+	"is_int":             "fn(any) -> bool",
+	"is_float":           "fn(any) -> bool",
+	"is_bigint":          "fn(any) -> bool",
+	"is_bigfloat":        "fn(any) -> bool",
+	"is_complex":         "fn(any) -> bool",
+	"is_string":          "fn(any) -> bool",
+	"is_bool":            "fn(any) -> bool",
+	"is_char":            "fn(any) -> bool",
+	"is_bytes":           "fn(any) -> bool",
+	"is_array":           "fn(any) -> bool",
+	"is_immutable_array": "fn(any) -> bool",
+	"is_map":             "fn(any) -> bool",
+	"is_immutable_map":   "fn(any) -> bool",
+	"is_iterable":        "fn(any) -> bool",
+	"is_time":            "fn(any) -> bool",
+	"is_error":           "fn(any) -> bool",
+	"is_null":            "fn(any) -> bool",
+	"is_function":        "fn(any) -> bool",
+	"is_callable":        "fn(any) -> bool",
+	"is_pointer":         "fn(any) -> bool",
+	"go":                 "fn(fn, ...any) -> map",
+	"makechan":           "fn(...any) -> any",
+}
@@ -0,0 +1,167 @@
+package tender
+
+import (
+	"fmt"
+
+	"github.com/2dprototype/tender/parser"
+)
+
+// Verify checks that all instructions of the bytecode are well-formed:
+// opcodes are known, operands are complete, constant, global, local, free
+// variable and builtin indexes are in range, and jumps land on instruction
+// boundaries. Bytecode from untrusted sources should be verified before it
+// is run.
+func (b *Bytecode) Verify() error {
+	if b.MainFunction == nil {
+		return fmt.Errorf("%w: missing main function", ErrInvalidBytecode)
+	}
+
+	// number of free variables of each function constant, from the closures
+	// that create them
+	numFree := make(map[int]int)
+	scanClosures(b.MainFunction, numFree)
+	for _, cn := range b.Constants {
+		if fn, ok := cn.(*CompiledFunction); ok {
+			scanClosures(fn, numFree)
+		}
+	}
+
+	if err := b.verifyFunction("main", b.MainFunction, 0); err != nil {
+		return err
+	}
+	for cidx, cn := range b.Constants {
+		if fn, ok := cn.(*CompiledFunction); ok {
+			name := fmt.Sprintf("fn[%d]", cidx)
+			if err := b.verifyFunction(name, fn, numFree[cidx]); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func scanClosures(fn *CompiledFunction, numFree map[int]int) {
+	ins := fn.Instructions
+	for ip := 0; ip < len(ins); {
+		op := ins[ip]
+		if int(op) >= len(parser.OpcodeOperands) {
+			return // reported by verifyFunction
+		}
+		widths := parser.OpcodeOperands[op]
+		if ip+1+sumWidths(widths) > len(ins) {
+			return
+		}
+		operands, read := parser.ReadOperands(widths, ins[ip+1:])
+		if (op == parser.OpClosure || op == parser.OpClosureW) &&
+			operands[1] > numFree[operands[0]] {
+			numFree[operands[0]] = operands[1]
+		}
+		ip += 1 + read
+	}
+}
+
+func (b *Bytecode) verifyFunction(
+	name string,
+	fn *CompiledFunction,
+	numFree int,
+) error {
+	errorf := func(ip int, format string, args ...interface{}) error {
+		return fmt.Errorf("%w: %s at %04d: %s", ErrInvalidBytecode, name,
+			ip, fmt.Sprintf(format, args...))
+	}
+
+	ins := fn.Instructions
+	boundaries := make(map[int]bool)
+	var jumps [][2]int // instruction offset and jump target
+	for ip := 0; ip < len(ins); {
+		op := ins[ip]
+		if int(op) >= len(parser.OpcodeNames) || parser.OpcodeNames[op] == "" {
+			return errorf(ip, "unknown opcode %d", op)
+		}
+		widths := parser.OpcodeOperands[op]
+		if ip+1+sumWidths(widths) > len(ins) {
+			return errorf(ip, "incomplete operands for %s",
+				parser.OpcodeNames[op])
+		}
+		operands, read := parser.ReadOperands(widths, ins[ip+1:])
+		boundaries[ip] = true
+
+		switch op {
+		case parser.OpConstant, parser.OpConstantW:
+			if operands[0] >= len(b.Constants) {
+				return errorf(ip, "constant index %d out of range", operands[0])
+			}
+		case parser.OpClosure, parser.OpClosureW:
+			if operands[0] >= len(b.Constants) {
+				return errorf(ip, "constant index %d out of range", operands[0])
+			}
+			if _, ok := b.Constants[operands[0]].(*CompiledFunction); !ok {
+				return errorf(ip, "closure of non-function constant %d",
+					operands[0])
+			}
+		case parser.OpCheckType:
+			if operands[0] >= fn.NumLocals {
+				return errorf(ip, "local index %d out of range", operands[0])
+			}
+			for _, cidx := range operands[1:] {
+				if cidx >= len(b.Constants) {
+					return errorf(ip, "constant index %d out of range", cidx)
+				}
+			}
+		case parser.OpSelector:
+			if operands[0] >= len(b.Constants) {
+				return errorf(ip, "constant index %d out of range", operands[0])
+			}
+			if _, ok := b.Constants[operands[0]].(*String); !ok {
+				return errorf(ip, "selector of non-string constant %d",
+					operands[0])
+			}
+		case parser.OpLocalBinaryOp:
+			if operands[0] >= fn.NumLocals {
+				return errorf(ip, "local index %d out of range", operands[0])
+			}
+			if operands[1] >= len(b.Constants) {
+				return errorf(ip, "constant index %d out of range", operands[1])
+			}
+		case parser.OpGetGlobal, parser.OpSetGlobal, parser.OpSetSelGlobal,
+			parser.OpGetGlobalW, parser.OpSetGlobalW, parser.OpSetSelGlobalW:
+			if operands[0] >= b.globalsSize() {
+				return errorf(ip, "global index %d out of range", operands[0])
+			}
+		case parser.OpGetLocal, parser.OpSetLocal, parser.OpDefineLocal,
+			parser.OpGetLocalPtr, parser.OpSetSelLocal:
+			if operands[0] >= fn.NumLocals {
+				return errorf(ip, "local index %d out of range", operands[0])
+			}
+		case parser.OpGetFree, parser.OpGetFreePtr, parser.OpSetFree,
+			parser.OpSetSelFree:
+			if operands[0] >= numFree {
+				return errorf(ip, "free variable index %d out of range",
+					operands[0])
+			}
+		case parser.OpGetBuiltin:
+			if operands[0] >= len(builtinFuncs) {
+				return errorf(ip, "builtin index %d out of range", operands[0])
+			}
+		}
+		if n := jumpOperand(op); n >= 0 {
+			jumps = append(jumps, [2]int{ip, operands[n]})
+		}
+		ip += 1 + read
+	}
+
+	for _, j := range jumps {
+		if !boundaries[j[1]] {
+			return errorf(j[0], "invalid jump target %04d", j[1])
+		}
+	}
+	return nil
+}
+
+func sumWidths(widths []int) int {
+	n := 0
+	for _, w := range widths {
+		n += w
+	}
+	return n
+}
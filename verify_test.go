@@ -0,0 +1,185 @@
+package tender
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"math"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/2dprototype/tender/parser"
+)
+
+func TestVerify(t *testing.T) {
+	inst := func(op parser.Opcode, operands ...int) []byte {
+		return MakeInstruction(op, operands...)
+	}
+	concat := func(insts ...[]byte) []byte {
+		return bytes.Join(insts, nil)
+	}
+	closure := &CompiledFunction{
+		Instructions: concat(inst(parser.OpGetFree, 0), inst(parser.OpReturn, 1)),
+		NumLocals:    1,
+	}
+	constants := []Object{&Int{Value: 1}, &String{Value: "x"}, closure}
+
+	tests := []struct {
+		name  string
+		insts []byte
+		err   string // error message; or "" if the bytecode is valid
+	}{
+		{"valid", concat(
+			inst(parser.OpConstant, 0),
+			inst(parser.OpSetGlobal, 0),
+			inst(parser.OpGetBuiltin, 0),
+			inst(parser.OpPop),
+			inst(parser.OpConstant, 0),
+			inst(parser.OpClosure, 2, 1),
+			inst(parser.OpJump, 0),
+			inst(parser.OpSuspend),
+		), ""},
+		{"bad opcode", []byte{255},
+			"main at 0000: unknown opcode 255"},
+		{"truncated operands", inst(parser.OpConstant, 0)[:2],
+			"main at 0000: incomplete operands for CONST"},
+		{"constant index", inst(parser.OpConstant, 3),
+			"main at 0000: constant index 3 out of range"},
+		{"wide constant index", inst(parser.OpConstantW, 1<<20),
+			"main at 0000: constant index 1048576 out of range"},
+		{"closure of non-function", inst(parser.OpClosure, 0, 0),
+			"main at 0000: closure of non-function constant 0"},
+		{"selector of non-string", inst(parser.OpSelector, 0, 0),
+			"main at 0000: selector of non-string constant 0"},
+		{"global index", inst(parser.OpGetGlobal, GlobalsSize),
+			fmt.Sprintf("main at 0000: global index %d out of range", GlobalsSize)},
+		{"local index", inst(parser.OpGetLocal, 0),
+			"main at 0000: local index 0 out of range"},
+		{"free index", inst(parser.OpGetFree, 0),
+			"main at 0000: free variable index 0 out of range"},
+		{"builtin index", inst(parser.OpGetBuiltin, len(builtinFuncs)),
+			"out of range"},
+		{"jump into an instruction", concat(
+			inst(parser.OpConstant, 0),
+			inst(parser.OpJump, 1),
+		), "main at 0003: invalid jump target 0001"},
+		{"jump past the end", inst(parser.OpJump, 10),
+			"main at 0000: invalid jump target 0010"},
+	}
+	for _, tc := range tests {
+		b := &Bytecode{
+			FileSet:      parser.NewFileSet(),
+			MainFunction: &CompiledFunction{Instructions: tc.insts},
+			Constants:    constants,
+		}
+		err := b.Verify()
+		switch {
+		case tc.err == "" && err != nil:
+			t.Errorf("%s: error %v", tc.name, err)
+		case tc.err != "" && err == nil:
+			t.Errorf("%s: no error, expected %q", tc.name, tc.err)
+		case tc.err != "" && (!errors.Is(err, ErrInvalidBytecode) ||
+			!strings.Contains(err.Error(), tc.err)):
+			t.Errorf("%s: error %v, expected %q", tc.name, err, tc.err)
+		}
+	}
+
+	// free variables are checked against the closures creating the function
+	b := &Bytecode{
+		FileSet: parser.NewFileSet(),
+		MainFunction: &CompiledFunction{Instructions: concat(
+			inst(parser.OpConstant, 0),
+			inst(parser.OpClosure, 2, 1),
+		)},
+		Constants: []Object{&Int{Value: 1}, &String{Value: "x"}, &CompiledFunction{
+			Instructions: inst(parser.OpGetFree, 1),
+		}},
+	}
+	if err := b.Verify(); err == nil ||
+		!strings.Contains(err.Error(), "fn[2] at 0000: free variable index 1 out of range") {
+		t.Errorf("error %v", err)
+	}
+
+	// and so are those of closures of constants past 65535
+	src := `a := ` + bigArray(70000) + `
+	f := fn() { x := len(a); return fn() { return x } }
+	out := f()()`
+	c, err := NewScript([]byte(src)).Compile()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := c.bytecode.Verify(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestBytecodeHeader(t *testing.T) {
+	c, err := NewScript([]byte(`out := 1 + 2`)).Compile()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	if err := c.bytecode.Encode(&buf); err != nil {
+		t.Fatal(err)
+	}
+	encoded := buf.Bytes()
+	header, err := ReadBytecodeHeader(bytes.NewReader(encoded))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if header.FormatVersion != BytecodeFormatVersion || header.Version != Version ||
+		header.BuiltinsHash != BuiltinsHash() {
+		t.Errorf("header %+v", header)
+	}
+	payload := encoded[len(encoded)-int(header.PayloadSize):]
+
+	// withHeader returns the payload behind a header changed by fn
+	withHeader := func(fn func(h *BytecodeHeader)) []byte {
+		h := *header
+		fn(&h)
+		var buf bytes.Buffer
+		if err := h.write(&buf); err != nil {
+			t.Fatal(err)
+		}
+		buf.Write(payload)
+		return buf.Bytes()
+	}
+	tests := []struct {
+		name string
+		data []byte
+		err  string
+	}{
+		{"magic", append([]byte("TDX\x1a"), encoded[4:]...),
+			"not a compiled tender file"},
+		{"empty", nil, "not a compiled tender file"},
+		{"version", withHeader(func(h *BytecodeHeader) { h.FormatVersion-- }),
+			"unsupported format version"},
+		{"builtins hash", withHeader(func(h *BytecodeHeader) { h.BuiltinsHash++ }),
+			"with different builtin functions"},
+		{"truncated header", encoded[:8], "truncated header"},
+		{"truncated payload", encoded[:len(encoded)-1], "truncated data"},
+		{"checksum", withHeader(func(h *BytecodeHeader) { h.Checksum++ }),
+			"checksum mismatch"},
+	}
+	for _, tc := range tests {
+		err := (&Bytecode{}).Decode(bytes.NewReader(tc.data), nil)
+		if !errors.Is(err, ErrInvalidBytecode) || !strings.Contains(err.Error(), tc.err) {
+			t.Errorf("%s: error %v, expected %q", tc.name, err, tc.err)
+		}
+	}
+
+	// the payload size of the header is not allocated before the payload
+	// is read
+	data := withHeader(func(h *BytecodeHeader) { h.PayloadSize = math.MaxUint32 })
+	var before, after runtime.MemStats
+	runtime.ReadMemStats(&before)
+	err = (&Bytecode{}).Decode(bytes.NewReader(data), nil)
+	runtime.ReadMemStats(&after)
+	if !errors.Is(err, ErrInvalidBytecode) || !strings.Contains(err.Error(), "truncated data") {
+		t.Errorf("error %v, expected truncated data", err)
+	}
+	if alloc := after.TotalAlloc - before.TotalAlloc; alloc > 1<<20 {
+		t.Errorf("allocated %d bytes for a short payload", alloc)
+	}
+}
@@ -6,7 +6,6 @@ import (
 	"io"
 	"os"
 	"runtime/debug"
-	"strings"
 	"sync"
 	"sync/atomic"
 
@@ -20,6 +19,7 @@ type frame struct {
 	freeVars    []*ObjectPtr
 	ip          int
 	basePointer int
+	elided      int // number of frames replaced by tail calls
 }
 
 type vmChildCtl struct {
@@ -45,22 +45,36 @@ type VM struct {
 	maxAllocs   int64
 	allocs      int64
 	err         error
+	caches      []selectorCache
 	AbortChan   chan struct{}
 	childCtl    vmChildCtl
+	spawnFrames []StackFrame // call stack that started the goroutine of the VM
+	waitFrames  []StackFrame // call stack of the first wait for a failed goroutine
 	In          io.Reader
 	Out         io.Writer
 	Args        []string
+
+	// MaxStackSize is the maximum number of values on the stack, and
+	// MaxFrames the maximum depth of function calls. The stack and the
+	// frames grow as needed up to these limits.
+	MaxStackSize int
+	MaxFrames    int
 }
 
 const (
 	initialStackSize = 64
 	initialFrames    = 16
+
+	// maxRunCompiledArgs is the maximum number of arguments of RunCompiled,
+	// whose call instruction counts the function and the arguments in one
+	// byte.
+	maxRunCompiledArgs = 254
 )
 
 // NewVM creates a VM.
 func NewVM(bytecode *Bytecode, globals []Object, maxAllocs int64) *VM {
 	if globals == nil {
-		globals = make([]Object, GlobalsSize)
+		globals = make([]Object, bytecode.globalsSize())
 	}
 	v := &VM{
 		constants:   bytecode.Constants,
@@ -71,11 +85,15 @@ func NewVM(bytecode *Bytecode, globals []Object, maxAllocs int64) *VM {
 		framesIndex: 1,
 		ip:          -1,
 		maxAllocs:   maxAllocs,
+		caches:      make([]selectorCache, bytecode.InlineCaches),
 		AbortChan:   make(chan struct{}),
 		childCtl:    vmChildCtl{vmMap: make(map[*VM]struct{})},
 		In:          os.Stdin,
 		Out:         os.Stdout,
 		Args:        os.Args,
+
+		MaxStackSize: StackSize,
+		MaxFrames:    MaxFrames,
 	}
 	frame := &frame{
 		fn: bytecode.MainFunction,
@@ -164,11 +182,15 @@ func (v *VM) ShallowClone() *VM {
 		framesIndex: 1,
 		ip:          -1,
 		maxAllocs:   v.maxAllocs,
+		caches:      make([]selectorCache, len(v.caches)),
 		AbortChan:   make(chan struct{}),
 		childCtl:    vmChildCtl{vmMap: make(map[*VM]struct{})},
 		In:          v.In,
 		Out:         v.Out,
 		Args:        v.Args,
+
+		MaxStackSize: v.MaxStackSize,
+		MaxFrames:    v.MaxFrames,
 	}
 	frame := &frame{
 		fn: emptyEntry,
@@ -198,7 +220,14 @@ func (v *VM) releaseSpace() {
 
 // RunCompiled run the VM with user supplied function fn.
 func (v *VM) RunCompiled(fn *CompiledFunction, args ...Object) (val Object, err error) {
-	v.stack = make([]Object, initialStackSize)
+	if len(args) > maxRunCompiledArgs {
+		return nil, fmt.Errorf("%w: %d arguments, the maximum is %d",
+			ErrWrongNumArguments, len(args), maxRunCompiledArgs)
+	}
+	if 2+len(args) >= v.MaxStackSize {
+		return nil, ErrStackOverflow
+	}
+	v.stack = make([]Object, max(initialStackSize, 3+len(args)))
 	if fn == nil { // normal Run
 		// reset VM states
 		v.sp = 0
@@ -223,6 +252,7 @@ func (v *VM) RunCompiled(fn *CompiledFunction, args ...Object) (val Object, err
 	v.framesIndex = 1
 	v.ip = -1
 	v.allocs = v.maxAllocs + 1
+	v.waitFrames = nil
 
 	defer func() {
 		if perr := recover(); perr != nil {
@@ -238,6 +268,10 @@ func (v *VM) RunCompiled(fn *CompiledFunction, args ...Object) (val Object, err
 	}()
 
 	val = NullValue
+	if fn == nil && v.curFrame.fn.reg != nil {
+		v.runRegisters(v.curFrame.fn, 0)
+		return
+	}
 	v.run()
 	return
 }
@@ -305,48 +339,237 @@ func (v *VM) callers() (frames []frame) {
 	return frames
 }
 
-func (v *VM) callStack(frames []frame) string {
-	if frames == nil {
-		frames = v.callers()
+// stackFrames returns the call stack of the frames, followed by the call
+// stack that started the goroutine of the VM. Frames without source positions,
+// such as the ones calling a function run by RunCompiled, are left out.
+func (v *VM) stackFrames(frames []frame) []StackFrame {
+	var stack []StackFrame
+	for _, f := range frames {
+		if len(f.fn.SourceMap) == 0 {
+			continue
+		}
+		pos := f.fn.SourcePos(f.ip)
+		if rf := f.fn.reg; rf != nil && f.ip >= 0 && f.ip < len(rf.pos) {
+			pos = rf.pos[f.ip]
+		}
+		stack = append(stack, StackFrame{
+			Pos:    v.fileSet.Position(pos),
+			Elided: f.elided,
+			pos:    pos,
+		})
 	}
+	return append(stack, v.spawnFrames...)
+}
 
-	var sb strings.Builder
+// arguments returns the parameters of the innermost function of the frames
+// with their values.
+func (v *VM) arguments(frames []frame) []Argument {
 	for _, f := range frames {
-		filePos := v.fileSet.Position(f.fn.SourcePos(f.ip))
-		fmt.Fprintf(&sb, "\n\tat %s", filePos)
+		if len(f.fn.SourceMap) == 0 {
+			continue
+		}
+		var args []Argument
+		for i := 0; i < f.fn.NumParameters; i++ {
+			if f.basePointer+i >= len(v.stack) || v.stack[f.basePointer+i] == nil {
+				break
+			}
+			value := v.stack[f.basePointer+i]
+			// parameters captured by closures are kept in free variables
+			if p, ok := value.(*ObjectPtr); ok {
+				if p.Value == nil || *p.Value == nil {
+					break
+				}
+				value = *p.Value
+			}
+			name := fmt.Sprintf("arg%d", i+1)
+			if i < len(f.fn.Params) && f.fn.Params[i] != "" {
+				name = f.fn.Params[i]
+			}
+			args = append(args, Argument{Name: name, Value: value})
+		}
+		return args
 	}
-	return sb.String()
+	return nil
 }
 
+// postRun returns the error of the VM and the errors of the goroutines it
+// started as a RuntimeError.
 func (v *VM) postRun() (err error) {
 	err = v.err
 	// ErrVMAborted is user behavior thus it is not an actual runtime error
 	if errors.Is(err, ErrVMAborted) {
 		err = nil
 	}
+	if err == nil && len(v.childCtl.errors) == 0 {
+		return nil
+	}
+	rerr := &RuntimeError{Err: err, fileSet: v.fileSet}
+	for _, cerr := range v.childCtl.errors {
+		// the errors of the goroutines of a goroutine without an error
+		// are reported as errors of this VM's goroutines
+		var gerr *RuntimeError
+		if errors.As(cerr, &gerr) && gerr.Err == nil {
+			rerr.Goroutines = append(rerr.Goroutines, gerr.Goroutines...)
+		} else {
+			rerr.Goroutines = append(rerr.Goroutines, cerr)
+		}
+	}
 	if err != nil {
-		var e ErrPanic
-		if errors.As(err, &e) {
-			err = fmt.Errorf("\nRuntime Panic: %v%s\n%s", e.perr, v.callStack(nil), e.stack)
+		callers := v.callers()
+		rerr.Frames = v.stackFrames(callers)
+		rerr.Args = v.arguments(callers)
+	} else {
+		rerr.Frames = v.waitFrames
+	}
+	return rerr
+}
+
+// readIndex reads the 2-byte operand at ip, or the 4-byte operand if the
+// current instruction is a wide one, and advances ip to its last byte.
+func (v *VM) readIndex() int {
+	ins := v.curInsts
+	if parser.OpcodeOperands[ins[v.ip]][0] == 4 {
+		return v.readUint32()
+	}
+	n := int(ins[v.ip+2]) | int(ins[v.ip+1])<<8
+	v.ip += 2
+	return n
+}
+
+// readUint32 reads the 4-byte operand following ip and advances ip to its
+// last byte.
+func (v *VM) readUint32() int {
+	ins := v.curInsts
+	n := int(ins[v.ip+4]) | int(ins[v.ip+3])<<8 |
+		int(ins[v.ip+2])<<16 | int(ins[v.ip+1])<<24
+	v.ip += 4
+	return n
+}
+
+// spreadArgs expands the array that is the last of the numArgs arguments
+// at the top of the stack if spread is set, and returns the number of
+// arguments.
+func (v *VM) spreadArgs(numArgs int, spread bool) (int, bool) {
+	if !spread {
+		return numArgs, true
+	}
+	v.sp--
+	var items []Object
+	switch arr := v.stack[v.sp].(type) {
+	case *Array:
+		items = arr.Value
+	case *ImmutableArray:
+		items = arr.Value
+	default:
+		v.err = fmt.Errorf("not an array: %s", arr.TypeName())
+		return 0, false
+	}
+	if v.checkGrowStack(len(items)); v.err != nil {
+		return 0, false
+	}
+	for _, item := range items {
+		v.stack[v.sp] = item
+		v.sp++
+	}
+	return numArgs + len(items) - 1, true
+}
+
+// prepareArgs rolls up the variadic arguments of callee at the top of the
+// stack into an array and checks the number of arguments. It returns the
+// number of arguments passed to callee.
+func (v *VM) prepareArgs(callee *CompiledFunction, numArgs int) (int, bool) {
+	if callee.VarArgs {
+		// if the closure is variadic,
+		// roll up all variadic parameters into an array
+		realArgs := callee.NumParameters - 1
+		varArgs := numArgs - realArgs
+		if varArgs >= 0 {
+			numArgs = realArgs + 1
+			args := make([]Object, varArgs)
+			spStart := v.sp - varArgs
+			for i := spStart; i < v.sp; i++ {
+				args[i-spStart] = v.stack[i]
+			}
+			v.stack[spStart] = &Array{Value: args}
+			v.sp = spStart + 1
+		}
+	}
+	if numArgs != callee.NumParameters {
+		if callee.VarArgs {
+			v.err = fmt.Errorf(
+				"wrong number of arguments: want>=%d, got=%d",
+				callee.NumParameters-1, numArgs)
 		} else {
-			err = fmt.Errorf("\nRuntime Error: %w%s", err, v.callStack(nil))
+			v.err = fmt.Errorf(
+				"wrong number of arguments: want=%d, got=%d",
+				callee.NumParameters, numArgs)
 		}
+		return 0, false
 	}
+	return numArgs, true
+}
 
-	var sb strings.Builder
-	for _, cerr := range v.childCtl.errors {
-		fmt.Fprintf(&sb, "%v\n", cerr)
+// callNative calls value, which is not a compiled function, with the
+// numArgs arguments at the top of the stack.
+func (v *VM) callNative(value Object, numArgs int) (Object, bool) {
+	var args []Object
+	if bltnfn, ok := value.(*BuiltinFunction); ok {
+		if bltnfn.NeedVMObj {
+			// pass VM as the first para to builtin functions
+			args = append(args, v.selfObject())
+		}
+	}
+	args = append(args, v.stack[v.sp-numArgs:v.sp]...)
+	ret, e := value.Call(args...)
+
+	// runtime error
+	if e != nil {
+		if e == ErrWrongNumArguments {
+			v.err = fmt.Errorf(
+				"wrong number of arguments in call to '%s'",
+				value.TypeName())
+			return nil, false
+		}
+		if e, ok := e.(ErrInvalidArgumentType); ok {
+			v.err = fmt.Errorf(
+				"invalid type for argument '%s' in call to '%s': "+
+					"expected %s, found %s",
+				e.Name, value.TypeName(), e.Expected, e.Found)
+			return nil, false
+		}
+		v.err = e
+		return nil, false
 	}
-	cerrs := sb.String()
 
-	if err != nil && len(cerrs) != 0 {
-		err = fmt.Errorf("%w\n%s", err, cerrs)
-		return
+	// nil return -> null
+	if ret == nil {
+		ret = NullValue
 	}
-	if len(cerrs) != 0 {
-		err = fmt.Errorf("%s", cerrs)
+	if !v.countAlloc() {
+		return nil, false
 	}
-	return
+	return ret, true
+}
+
+// returnValue returns from the current frame with retVal.
+func (v *VM) returnValue(retVal Object) {
+	v.framesIndex--
+	v.curFrame = v.frames[v.framesIndex-1]
+	v.curInsts = v.curFrame.fn.Instructions
+	v.ip = v.curFrame.ip
+	v.sp = v.frames[v.framesIndex].basePointer
+	// skip stack overflow check because (newSP) <= (oldSP)
+	v.stack[v.sp-1] = retVal
+}
+
+func indexGetError(index Object, err error) error {
+	switch err {
+	case ErrNotIndexable:
+		return fmt.Errorf("not indexable: %s", index.TypeName())
+	case ErrInvalidIndexType:
+		return fmt.Errorf("invalid index type: %s", index.TypeName())
+	}
+	return err
 }
 
 // VMObj exports VM
@@ -368,6 +591,10 @@ func (v *VM) run() {
 			v.ip += 2
 			cidx := int(v.curInsts[v.ip]) | int(v.curInsts[v.ip-1])<<8
 
+			v.stack[v.sp] = v.constants[cidx]
+			v.sp++
+		case parser.OpConstantW:
+			cidx := v.readIndex()
 			v.stack[v.sp] = v.constants[cidx]
 			v.sp++
 		case parser.OpNull:
@@ -378,26 +605,55 @@ func (v *VM) run() {
 			right := v.stack[v.sp-1]
 			left := v.stack[v.sp-2]
 			tok := token.Token(v.curInsts[v.ip])
-			res, e := left.BinaryOp(tok, right)
-			if e != nil {
+			res, ok := v.binaryOp(tok, left, right)
+			if !ok {
 				v.sp -= 2
-				if e == ErrInvalidOperator {
-					v.err = fmt.Errorf("invalid operation: %s %s %s",
-						left.TypeName(), tok.String(), right.TypeName())
-					return
-				}
-				v.err = e
 				return
 			}
 
-			v.allocs--
-			if v.allocs == 0 {
-				v.err = ErrObjectAllocLimit
+			v.stack[v.sp-2] = res
+			v.sp--
+		case parser.OpLocalBinaryOp:
+			localIndex := int(v.curInsts[v.ip+1])
+			cidx := int(v.curInsts[v.ip+3]) | int(v.curInsts[v.ip+2])<<8
+			tok := token.Token(v.curInsts[v.ip+4])
+			v.ip += 4
+
+			left := v.stack[v.curFrame.basePointer+localIndex]
+			if obj, ok := left.(*ObjectPtr); ok {
+				left = *obj.Value
+			}
+			res, ok := v.binaryOp(tok, left, v.constants[cidx])
+			if !ok {
 				return
 			}
 
-			v.stack[v.sp-2] = res
-			v.sp--
+			v.stack[v.sp] = res
+			v.sp++
+		case parser.OpCompareJump:
+			tok := token.Token(v.curInsts[v.ip+1])
+			pos := int(v.curInsts[v.ip+3]) | int(v.curInsts[v.ip+2])<<8
+			v.ip += 3
+
+			right := v.stack[v.sp-1]
+			left := v.stack[v.sp-2]
+			v.sp -= 2
+			var truthy bool
+			switch tok {
+			case token.Equal:
+				truthy = left.Equals(right)
+			case token.NotEqual:
+				truthy = !left.Equals(right)
+			default:
+				res, ok := v.binaryOp(tok, left, right)
+				if !ok {
+					return
+				}
+				truthy = !res.IsFalsy()
+			}
+			if !truthy {
+				v.ip = pos - 1
+			}
 		case parser.OpEqual:
 			right := v.stack[v.sp-1]
 			left := v.stack[v.sp-2]
@@ -460,7 +716,7 @@ func (v *VM) run() {
 
 			switch x := operand.(type) {
 			case *Int:
-				var res Object = &Int{Value: -x.Value}
+				var res Object = newInt(-x.Value)
 				v.allocs--
 				if v.allocs == 0 {
 					v.err = ErrObjectAllocLimit
@@ -489,6 +745,28 @@ func (v *VM) run() {
 				pos := int(v.curInsts[v.ip]) | int(v.curInsts[v.ip-1])<<8
 				v.ip = pos - 1
 			}
+		case parser.OpJumpFalsyW:
+			pos := v.readIndex()
+			v.sp--
+			if v.stack[v.sp].IsFalsy() {
+				v.ip = pos - 1
+			}
+		case parser.OpAndJumpW:
+			pos := v.readIndex()
+			if v.stack[v.sp-1].IsFalsy() {
+				v.ip = pos - 1
+			} else {
+				v.sp--
+			}
+		case parser.OpOrJumpW:
+			pos := v.readIndex()
+			if v.stack[v.sp-1].IsFalsy() {
+				v.sp--
+			} else {
+				v.ip = pos - 1
+			}
+		case parser.OpJumpW:
+			v.ip = v.readIndex() - 1
 		case parser.OpAndJump:
 			v.ip += 2
 			if v.stack[v.sp-1].IsFalsy() {
@@ -513,9 +791,13 @@ func (v *VM) run() {
 			v.sp--
 			globalIndex := int(v.curInsts[v.ip]) | int(v.curInsts[v.ip-1])<<8
 			v.globals[globalIndex] = v.stack[v.sp]
-		case parser.OpSetSelGlobal:
-			v.ip += 3
-			globalIndex := int(v.curInsts[v.ip-1]) | int(v.curInsts[v.ip-2])<<8
+		case parser.OpSetGlobalW:
+			globalIndex := v.readIndex()
+			v.sp--
+			v.globals[globalIndex] = v.stack[v.sp]
+		case parser.OpSetSelGlobal, parser.OpSetSelGlobalW:
+			globalIndex := v.readIndex()
+			v.ip++
 			numSelectors := int(v.curInsts[v.ip])
 
 			// selectors and RHS value
@@ -536,9 +818,12 @@ func (v *VM) run() {
 			val := v.globals[globalIndex]
 			v.stack[v.sp] = val
 			v.sp++
-		case parser.OpArray:
-			v.ip += 2
-			numElements := int(v.curInsts[v.ip]) | int(v.curInsts[v.ip-1])<<8
+		case parser.OpGetGlobalW:
+			globalIndex := v.readIndex()
+			v.stack[v.sp] = v.globals[globalIndex]
+			v.sp++
+		case parser.OpArray, parser.OpArrayW:
+			numElements := v.readIndex()
 
 			var elements []Object
 			for i := v.sp - numElements; i < v.sp; i++ {
@@ -555,9 +840,8 @@ func (v *VM) run() {
 
 			v.stack[v.sp] = arr
 			v.sp++
-		case parser.OpMap:
-			v.ip += 2
-			numElements := int(v.curInsts[v.ip]) | int(v.curInsts[v.ip-1])<<8
+		case parser.OpMap, parser.OpMapW:
+			numElements := v.readIndex()
 			kv := make(map[string]Object)
 			for i := v.sp - numElements; i < v.sp; i += 2 {
 				key := v.stack[i]
@@ -574,6 +858,18 @@ func (v *VM) run() {
 			}
 			v.stack[v.sp] = m
 			v.sp++
+		case parser.OpArrayAppend:
+			numElements := v.readIndex()
+			arr := v.stack[v.sp-numElements-1].(*Array)
+			arr.Value = append(arr.Value, v.stack[v.sp-numElements:v.sp]...)
+			v.sp -= numElements
+		case parser.OpMapInsert:
+			numElements := v.readIndex()
+			m := v.stack[v.sp-numElements-1].(*Map)
+			for i := v.sp - numElements; i < v.sp; i += 2 {
+				m.Value[v.stack[i].(*String).Value] = v.stack[i+1]
+			}
+			v.sp -= numElements
 		case parser.OpError:
 			value := v.stack[v.sp-1]
 			var e Object = &Error{
@@ -616,15 +912,7 @@ func (v *VM) run() {
 
 			val, err := left.IndexGet(index)
 			if err != nil {
-				if err == ErrNotIndexable {
-					v.err = fmt.Errorf("not indexable: %s", index.TypeName())
-					return
-				}
-				if err == ErrInvalidIndexType {
-					v.err = fmt.Errorf("invalid index type: %s", index.TypeName())
-					return
-				}
-				v.err = err
+				v.err = indexGetError(index, err)
 				return
 			}
 			if val == nil {
@@ -632,6 +920,22 @@ func (v *VM) run() {
 			}
 			v.stack[v.sp] = val
 			v.sp++
+		case parser.OpSelector:
+			cidx := int(v.curInsts[v.ip+2]) | int(v.curInsts[v.ip+1])<<8
+			slot := int(v.curInsts[v.ip+4]) | int(v.curInsts[v.ip+3])<<8
+			v.ip += 4
+			left := v.stack[v.sp-1]
+
+			val, err := v.selector(left, cidx, slot)
+			if err != nil {
+				v.sp--
+				v.err = indexGetError(v.constants[cidx], err)
+				return
+			}
+			if val == nil {
+				val = NullValue
+			}
+			v.stack[v.sp-1] = val
 		case parser.OpSliceIndex:
 			high := v.stack[v.sp-1]
 			low := v.stack[v.sp-2]
@@ -799,7 +1103,8 @@ func (v *VM) run() {
 				v.stack[v.sp] = val
 				v.sp++
 			}
-		case parser.OpCall:
+		case parser.OpCall, parser.OpTailCall:
+			tail := v.curInsts[v.ip] == parser.OpTailCall
 			numArgs := int(v.curInsts[v.ip+1])
 			spread := int(v.curInsts[v.ip+2])
 			v.ip += 2
@@ -811,74 +1116,44 @@ func (v *VM) run() {
 			}
 
 			if spread == 1 {
-				v.sp--
-				switch arr := v.stack[v.sp].(type) {
-				case *Array:
-					v.checkGrowStack(len(arr.Value))
-					for _, item := range arr.Value {
-						v.stack[v.sp] = item
-						v.sp++
-					}
-					numArgs += len(arr.Value) - 1
-				case *ImmutableArray:
-					v.checkGrowStack(len(arr.Value))
-					for _, item := range arr.Value {
-						v.stack[v.sp] = item
-						v.sp++
-					}
-					numArgs += len(arr.Value) - 1
-				default:
-					v.err = fmt.Errorf("not an array: %s", arr.TypeName())
+				var ok bool
+				if numArgs, ok = v.spreadArgs(numArgs, true); !ok {
 					return
 				}
 			}
 
 			if callee, ok := value.(*CompiledFunction); ok {
-				if callee.VarArgs {
-					// if the closure is variadic,
-					// roll up all variadic parameters into an array
-					realArgs := callee.NumParameters - 1
-					varArgs := numArgs - realArgs
-					if varArgs >= 0 {
-						numArgs = realArgs + 1
-						args := make([]Object, varArgs)
-						spStart := v.sp - varArgs
-						for i := spStart; i < v.sp; i++ {
-							args[i-spStart] = v.stack[i]
-						}
-						v.stack[spStart] = &Array{Value: args}
-						v.sp = spStart + 1
-					}
-				}
-				if numArgs != callee.NumParameters {
-					if callee.VarArgs {
-						v.err = fmt.Errorf(
-							"wrong number of arguments: want>=%d, got=%d",
-							callee.NumParameters-1, numArgs)
-					} else {
-						v.err = fmt.Errorf(
-							"wrong number of arguments: want=%d, got=%d",
-							callee.NumParameters, numArgs)
+				if callee.VarArgs || numArgs != callee.NumParameters {
+					if numArgs, ok = v.prepareArgs(callee, numArgs); !ok {
+						return
 					}
-					return
 				}
 
-				// test if it's tail-call
-				if callee == v.curFrame.fn { // recursion
+				// test if it's a recursive call followed by a return
+				if !tail && callee == v.curFrame.fn {
 					nextOp := v.curInsts[v.ip+1]
-					if nextOp == parser.OpReturn ||
+					tail = nextOp == parser.OpReturn ||
 						(nextOp == parser.OpPop &&
-							parser.OpReturn == v.curInsts[v.ip+2]) {
-						for p := 0; p < numArgs; p++ {
-							v.stack[v.curFrame.basePointer+p] =
-								v.stack[v.sp-numArgs+p]
-						}
-						v.sp -= numArgs + 1
-						v.ip = -1 // reset IP to beginning of the frame
-						continue
+							parser.OpReturn == v.curInsts[v.ip+2])
+				}
+				if tail && callee.reg == nil {
+					// reuse the current frame, which may need more locals
+					if v.checkGrowStack(callee.NumLocals); v.err != nil {
+						return
+					}
+					base := v.curFrame.basePointer
+					for p := 0; p < numArgs; p++ {
+						v.stack[base+p] = v.stack[v.sp-numArgs+p]
 					}
+					v.curFrame.fn = callee
+					v.curFrame.freeVars = callee.Free
+					v.curFrame.elided++
+					v.curInsts = callee.Instructions
+					v.ip = -1 // reset IP to beginning of the frame
+					v.sp = base + callee.NumLocals
+					continue
 				}
-				if v.framesIndex >= MaxFrames {
+				if v.framesIndex >= v.MaxFrames {
 					v.err = ErrStackOverflow
 					return
 				}
@@ -892,51 +1167,31 @@ func (v *VM) run() {
 				v.curFrame.fn = callee
 				v.curFrame.freeVars = callee.Free
 				v.curFrame.basePointer = v.sp - numArgs
+				v.curFrame.elided = 0
 				v.curInsts = callee.Instructions
 				v.ip = -1
 				v.framesIndex++
 				v.sp = v.sp - numArgs + callee.NumLocals
-			} else {
-				var args []Object
-				if bltnfn, ok := value.(*BuiltinFunction); ok {
-					if bltnfn.NeedVMObj {
-						// pass VM as the first para to builtin functions
-						args = append(args, v.selfObject())
-					}
-				}
-				args = append(args, v.stack[v.sp-numArgs:v.sp]...)
-				ret, e := value.Call(args...)
-				// fmt.Println(ret)
-				v.sp -= numArgs + 1
-
-				// runtime error
-				if e != nil {
-					if e == ErrWrongNumArguments {
-						v.err = fmt.Errorf(
-							"wrong number of arguments in call to '%s'",
-							value.TypeName())
+				if callee.reg != nil {
+					ret, ok := v.runRegisters(callee, v.curFrame.basePointer)
+					if !ok {
 						return
 					}
-					if e, ok := e.(ErrInvalidArgumentType); ok {
-						v.err = fmt.Errorf(
-							"invalid type for argument '%s' in call to '%s': "+
-								"expected %s, found %s",
-							e.Name, value.TypeName(), e.Expected, e.Found)
-						return
+					v.returnValue(ret)
+					if tail {
+						v.returnValue(ret)
 					}
-					v.err = e
-					return
 				}
-
-				// nil return -> null
-				if ret == nil {
-					ret = NullValue
-				}
-				v.allocs--
-				if v.allocs == 0 {
-					v.err = ErrObjectAllocLimit
+			} else {
+				ret, ok := v.callNative(value, numArgs)
+				v.sp -= numArgs + 1
+				if !ok {
 					return
 				}
+				if tail {
+					v.returnValue(ret)
+					continue
+				}
 				v.stack[v.sp] = ret
 				v.sp++
 			}
@@ -948,16 +1203,7 @@ func (v *VM) run() {
 			} else {
 				retVal = NullValue
 			}
-			//v.sp--
-			v.framesIndex--
-			v.curFrame = v.frames[v.framesIndex-1]
-			v.curInsts = v.curFrame.fn.Instructions
-			v.ip = v.curFrame.ip
-			//v.sp = lastFrame.basePointer - 1
-			v.sp = v.frames[v.framesIndex].basePointer
-			// skip stack overflow check because (newSP) <= (oldSP)
-			v.stack[v.sp-1] = retVal
-			//v.sp++
+			v.returnValue(retVal)
 		case parser.OpDefineLocal:
 			v.ip++
 			localIndex := int(v.curInsts[v.ip])
@@ -1017,9 +1263,9 @@ func (v *VM) run() {
 			builtinIndex := int(v.curInsts[v.ip])
 			v.stack[v.sp] = builtinFuncs[builtinIndex]
 			v.sp++
-		case parser.OpClosure:
-			v.ip += 3
-			constIndex := int(v.curInsts[v.ip-1]) | int(v.curInsts[v.ip-2])<<8
+		case parser.OpClosure, parser.OpClosureW:
+			constIndex := v.readIndex()
+			v.ip++
 			numFree := int(v.curInsts[v.ip])
 			fn, ok := v.constants[constIndex].(*CompiledFunction)
 			if !ok {
@@ -1045,6 +1291,10 @@ func (v *VM) run() {
 				VarArgs:       fn.VarArgs,
 				SourceMap:     fn.SourceMap,
 				Free:          free,
+				Params:        fn.Params,
+				Usage:         fn.Usage,
+				Doc:           fn.Doc,
+				reg:           fn.reg,
 			}
 			v.allocs--
 			if v.allocs == 0 {
@@ -1140,13 +1390,40 @@ func (v *VM) run() {
 			val := iterator.(Iterator).Value()
 			v.stack[v.sp] = val
 			v.sp++
+		case parser.OpCheckType:
+			localIndex := int(v.curInsts[v.ip+1])
+			v.ip++
+			nameIndex := v.readUint32()
+			typeIndex := v.readUint32()
+			slot := &v.stack[v.curFrame.basePointer+localIndex]
+			if obj, ok := (*slot).(*ObjectPtr); ok {
+				slot = obj.Value
+			}
+			val := *slot
+			types := v.constants[typeIndex].(*String).Value
+			if !TypeMatches(val, types) {
+				v.err = ErrInvalidArgumentType{
+					Name:     v.constants[nameIndex].(*String).Value,
+					Expected: types,
+					Found:    val.TypeName(),
+				}
+				return
+			}
+			// an int passed for a float parameter is converted
+			if i, ok := val.(*Int); ok && convertsIntToFloat(types) {
+				*slot = &Float{Value: float64(i.Value)}
+			}
 		case parser.OpSuspend:
 			return
 		default:
 			v.err = fmt.Errorf("unknown opcode: %d", v.curInsts[v.ip])
 			return
 		}
-		v.checkGrowStack(0)
+		if v.sp >= len(v.stack) {
+			if v.checkGrowStack(0); v.err != nil {
+				return
+			}
+		}
 	}
 }
 
@@ -1155,7 +1432,7 @@ func (v *VM) checkGrowStack(added int) {
 	if should < len(v.stack) {
 		return
 	}
-	if should >= StackSize {
+	if should >= v.MaxStackSize {
 		v.err = ErrStackOverflow
 		return
 	}
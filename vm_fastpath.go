@@ -0,0 +1,156 @@
+package tender
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/2dprototype/tender/token"
+)
+
+const (
+	smallIntMin = -128
+	smallIntMax = 1023
+)
+
+// smallInts are shared Int objects for small values, so that loop counters
+// and pixel values do not allocate a new object for every result.
+var smallInts = func() []*Int {
+	ints := make([]*Int, smallIntMax-smallIntMin+1)
+	for i := range ints {
+		ints[i] = &Int{Value: int64(i + smallIntMin)}
+	}
+	return ints
+}()
+
+// newInt returns an Int object of value. Small values share a cached
+// object.
+func newInt(value int64) *Int {
+	if value >= smallIntMin && value <= smallIntMax {
+		return smallInts[value-smallIntMin]
+	}
+	return &Int{Value: value}
+}
+
+func boolValue(b bool) Object {
+	if b {
+		return TrueValue
+	}
+	return FalseValue
+}
+
+// fastBinaryOp evaluates arithmetic and comparisons of Int and Float
+// operands without going through Object.BinaryOp. The results are the same
+// as those of BinaryOp. It returns false for other operand types and
+// operators, which must be evaluated by BinaryOp.
+func fastBinaryOp(tok token.Token, left, right Object) (Object, bool) {
+	switch l := left.(type) {
+	case *Int:
+		switch r := right.(type) {
+		case *Int:
+			return intBinaryOp(tok, l.Value, r.Value)
+		case *Float:
+			return floatBinaryOp(tok, float64(l.Value), r.Value)
+		}
+	case *Float:
+		switch r := right.(type) {
+		case *Float:
+			return floatBinaryOp(tok, l.Value, r.Value)
+		case *Int:
+			return floatBinaryOp(tok, l.Value, float64(r.Value))
+		}
+	}
+	return nil, false
+}
+
+func intBinaryOp(tok token.Token, l, r int64) (Object, bool) {
+	switch tok {
+	case token.Add:
+		return newInt(l + r), true
+	case token.Sub:
+		return newInt(l - r), true
+	case token.Mul:
+		return newInt(l * r), true
+	case token.Quo:
+		if r == 0 {
+			return &Float{Value: math.Inf(1)}, true
+		}
+		return newInt(l / r), true
+	case token.Rem:
+		if r == 0 {
+			return nil, false // panics in BinaryOp
+		}
+		return newInt(l % r), true
+	case token.And:
+		return newInt(l & r), true
+	case token.Or:
+		return newInt(l | r), true
+	case token.Xor:
+		return newInt(l ^ r), true
+	case token.AndNot:
+		return newInt(l &^ r), true
+	case token.Shl:
+		return newInt(l << uint64(r)), true
+	case token.Shr:
+		return newInt(l >> uint64(r)), true
+	case token.Less:
+		return boolValue(l < r), true
+	case token.Greater:
+		return boolValue(l > r), true
+	case token.LessEq:
+		return boolValue(l <= r), true
+	case token.GreaterEq:
+		return boolValue(l >= r), true
+	}
+	return nil, false
+}
+
+func floatBinaryOp(tok token.Token, l, r float64) (Object, bool) {
+	switch tok {
+	case token.Add:
+		return &Float{Value: l + r}, true
+	case token.Sub:
+		return &Float{Value: l - r}, true
+	case token.Mul:
+		return &Float{Value: l * r}, true
+	case token.Quo:
+		if r == 0 {
+			return &Float{Value: math.Inf(1)}, true
+		}
+		return &Float{Value: l / r}, true
+	case token.Less:
+		return boolValue(l < r), true
+	case token.Greater:
+		return boolValue(l > r), true
+	case token.LessEq:
+		return boolValue(l <= r), true
+	case token.GreaterEq:
+		return boolValue(l >= r), true
+	}
+	return nil, false
+}
+
+// binaryOp evaluates a binary operation of the VM and counts the allocated
+// result.
+func (v *VM) binaryOp(tok token.Token, left, right Object) (Object, bool) {
+	res, ok := fastBinaryOp(tok, left, right)
+	if !ok {
+		var e error
+		res, e = left.BinaryOp(tok, right)
+		if e != nil {
+			if e == ErrInvalidOperator {
+				v.err = fmt.Errorf("invalid operation: %s %s %s",
+					left.TypeName(), tok.String(), right.TypeName())
+				return nil, false
+			}
+			v.err = e
+			return nil, false
+		}
+	}
+
+	v.allocs--
+	if v.allocs == 0 {
+		v.err = ErrObjectAllocLimit
+		return nil, false
+	}
+	return res, true
+}
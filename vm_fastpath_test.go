@@ -0,0 +1,53 @@
+package tender
+
+import (
+	"testing"
+
+	"github.com/2dprototype/tender/token"
+)
+
+func TestFastBinaryOp(t *testing.T) {
+	values := []Object{
+		&Int{Value: 0}, &Int{Value: 7}, &Int{Value: -3},
+		&Int{Value: 1 << 40}, &Float{Value: 0}, &Float{Value: 2.5},
+		&Float{Value: -1.25},
+	}
+	tokens := []token.Token{
+		token.Add, token.Sub, token.Mul, token.Quo, token.Rem, token.And,
+		token.Or, token.Xor, token.AndNot, token.Shl, token.Shr, token.Less,
+		token.Greater, token.LessEq, token.GreaterEq,
+	}
+	for _, tok := range tokens {
+		for _, left := range values {
+			for _, right := range values {
+				res, ok := fastBinaryOp(tok, left, right)
+				if !ok {
+					continue
+				}
+				expected, err := left.BinaryOp(tok, right)
+				if err != nil {
+					t.Errorf("%s %s %s: fast path for an invalid operation",
+						left, tok, right)
+					continue
+				}
+				if res.TypeName() != expected.TypeName() ||
+					!res.Equals(expected) {
+					t.Errorf("%s %s %s = %s, expected %s",
+						left, tok, right, res, expected)
+				}
+			}
+		}
+	}
+}
+
+func TestNewInt(t *testing.T) {
+	for _, v := range []int64{smallIntMin - 1, smallIntMin, 0, 255,
+		smallIntMax, smallIntMax + 1} {
+		if n := newInt(v); n.Value != v {
+			t.Errorf("newInt(%d) = %d", v, n.Value)
+		}
+	}
+	if newInt(5) != newInt(5) {
+		t.Error("small ints are not cached")
+	}
+}
@@ -0,0 +1,209 @@
+package tender
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestTailCall(t *testing.T) {
+	deep := "50000"
+	// locals declares more locals than the initial stack holds
+	var locals strings.Builder
+	for i := 0; i < 100; i++ {
+		fmt.Fprintf(&locals, "a%d := %d; ", i, i)
+	}
+	tests := []struct {
+		src      string
+		expected string
+	}{
+		{`f := fn(n, acc) { if n == 0 { return acc }; return f(n - 1, acc + 1) }
+		out := f(` + deep + `, 0)`, deep},
+		{`odd := null
+		even := fn(n) { if n == 0 { return true }; return odd(n - 1) }
+		odd = fn(n) { if n == 0 { return false }; return even(n - 1) }
+		out := [even(` + deep + `), odd(` + deep + `)]`, `[true, false]`},
+		{`make := fn(step) {
+			var loop
+			loop = fn(n, acc) { if n <= 0 { return acc }; return loop(n - step, acc + 1) }
+			return loop
+		}
+		out := make(2)(` + deep + `, 0)`, "25000"},
+		{`f := fn(n, ...a) { if n == 0 { return len(a) }; return f(n - 1, a...) }
+		out := f(` + deep + `, 1, 2, 3)`, "3"},
+		{`f := fn(n) { if n == 0 { return string(n) }; return f(n - 1) }
+		out := f(` + deep + `)`, `"0"`},
+		{`g := fn(x) { return x * 2 }
+		f := fn(x) { y := x + 1; return g(y) }
+		out := [f(1), f(2)]`, `[4, 6]`},
+		{`g := fn(x) { ` + locals.String() + `return x + a99 }
+		f := fn(x) { return g(x) }
+		out := f(1)`, "100"},
+	}
+	for _, tc := range tests {
+		c, err := NewScript([]byte(tc.src)).Run()
+		if err != nil {
+			t.Errorf("%s: %v", tc.src, err)
+			continue
+		}
+		if got := c.Get("out").Object().String(); got != tc.expected {
+			t.Errorf("%s: out = %s, expected %s", tc.src, got, tc.expected)
+		}
+	}
+}
+
+func TestTailCallStackTrace(t *testing.T) {
+	for n, marker := range map[int]string{
+		10: "... 10 frames elided (tail calls)",
+		1:  "... 1 frame elided (tail call)",
+	} {
+		src := `f := fn(n) { if n == 0 { return 1 + "x" }; return f(n - 1) }
+f(` + strconv.Itoa(n) + `)`
+		_, err := NewScript([]byte(src)).Run()
+		if err == nil {
+			t.Fatal("expected an error")
+		}
+		if !strings.Contains(err.Error(), marker) {
+			t.Errorf("missing elided frames marker %q in %q", marker, err.Error())
+		}
+	}
+}
+
+// bigMap returns the elements of a map literal of n ints.
+func bigMap(n int) string {
+	var b strings.Builder
+	for i := 0; i < n; i++ {
+		if i > 0 {
+			b.WriteString(",")
+		}
+		fmt.Fprintf(&b, `k%d: %d`, i, i)
+	}
+	return b.String()
+}
+
+// bigArray returns an array literal of n distinct string constants.
+func bigArray(n int) string {
+	var b strings.Builder
+	b.WriteString("[")
+	for i := 0; i < n; i++ {
+		if i > 0 {
+			b.WriteString(",")
+		}
+		fmt.Fprintf(&b, `"s%d"`, i)
+	}
+	b.WriteString("]")
+	return b.String()
+}
+
+func TestWideOperands(t *testing.T) {
+	big := bigArray(70000)
+	tests := []struct {
+		src      string
+		expected string
+	}{
+		{`a := ` + big + `; out := [len(a), a[69999], "after"]`,
+			`[70000, "s69999", "after"]`},
+		{`out := 0; if out == 0 { out = len(` + big + `) } else { out = -1 }`,
+			`70000`},
+		{`f := fn(x) { if x { return len(` + big + `) }; return -1 }
+		out := [f(true), f(false)]`, `[70000, -1]`},
+		{`f := fn(x) { return x && len(` + big + `) > 0 }
+		g := fn(x) { return x || len(` + big + `) > 0 }
+		out := [f(false), f(true), g(false), g(true)]`,
+			`[false, true, true, true]`},
+		{`out := 0; for i := 0; i < 3; i++ { if i == 1 { continue }; out += len(` + big + `) }`,
+			`140000`},
+		{`f := fn(x) { a := [x, ` + big[1:] + `; return [len(a), a[0], a[70000]] }
+		out := f(1)`, `[70001, 1, "s69999"]`},
+		{`m := {` + bigMap(70000) + `}; out := [len(m), m.k0, m.k69999]`,
+			`[70000, 0, 69999]`},
+	}
+	for i, tc := range tests {
+		for _, optimize := range []bool{true, false} {
+			for _, register := range []bool{false, true} {
+				s := NewScript([]byte(tc.src))
+				s.EnableOptimizer(optimize)
+				s.EnableRegisterVM(register)
+				c, err := s.Run()
+				if err != nil {
+					t.Errorf("case %d: %v", i, err)
+					continue
+				}
+				if got := c.Get("out").Object().String(); got != tc.expected {
+					t.Errorf("case %d: out = %s, expected %s", i, got, tc.expected)
+				}
+			}
+		}
+	}
+}
+
+func TestWideOperandsEncode(t *testing.T) {
+	src := `a := ` + bigArray(70000) + `; out := a[69999]`
+	c, err := NewScript([]byte(src)).Compile()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	if err := c.bytecode.Encode(&buf); err != nil {
+		t.Fatal(err)
+	}
+	b := &Bytecode{}
+	if err := b.Decode(&buf, nil); err != nil {
+		t.Fatal(err)
+	}
+	if b.NumGlobals != c.bytecode.NumGlobals {
+		t.Errorf("NumGlobals = %d, expected %d", b.NumGlobals,
+			c.bytecode.NumGlobals)
+	}
+	v := NewVM(b, nil, -1)
+	if err := v.Run(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestScriptLimits(t *testing.T) {
+	var b strings.Builder
+	for i := 0; i < 70000; i++ {
+		fmt.Fprintf(&b, "g%d := %d\n", i, i)
+	}
+	b.WriteString("out := g69999 + g0")
+	globals := b.String()
+
+	_, err := NewScript([]byte(globals)).Compile()
+	if err == nil || !strings.Contains(err.Error(), "exceeding globals limit") {
+		t.Errorf("expected globals limit error, got %v", err)
+	}
+	s := NewScript([]byte(globals))
+	s.SetMaxGlobals(80000)
+	c, err := s.Run()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := c.Get("out").Object().String(); got != "69999" {
+		t.Errorf("out = %s, expected 69999", got)
+	}
+
+	deep := `f := fn(n) { if n == 0 { return 0 }; return 1 + f(n - 1) }
+out := f(50000)`
+	if _, err := NewScript([]byte(deep)).Run(); err == nil ||
+		!strings.Contains(err.Error(), ErrStackOverflow.Error()) {
+		t.Errorf("expected stack overflow, got %v", err)
+	}
+	s = NewScript([]byte(deep))
+	s.SetMaxFrames(60000)
+	s.SetMaxStackSize(200000)
+	c, err = s.Run()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := c.Get("out").Object().String(); got != "50000" {
+		t.Errorf("out = %s, expected 50000", got)
+	}
+	s = NewScript([]byte(deep))
+	s.SetMaxFrames(60000)
+	if _, err = s.Run(); err == nil {
+		t.Error("expected stack overflow with the default stack size")
+	}
+}